@@ -3,11 +3,16 @@ package lamb
 import (
 	"errors"
 	"fmt"
-	"os"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/govel-framework/lamb/evaluator"
+	"github.com/govel-framework/lamb/internal"
 	"github.com/govel-framework/lamb/object"
+
+	"golang.org/x/text/language"
 )
 
 // Init initializes the lamb module.
@@ -23,6 +28,9 @@ func Init(config map[interface{}]interface{}) error {
 		return fmt.Errorf("lamb: config must be a map[interface{}]interface{} but got %T", config["lamb"])
 	}
 
+	// the settings are applied at the end, when the whole config is valid
+	settings := make(map[string]string)
+
 	dir, exists := lambConfig["dir"]
 
 	if !exists {
@@ -70,24 +78,297 @@ func Init(config map[interface{}]interface{}) error {
 			return errors.New("lamb: cache: time must be a valid duration")
 		}
 
-		os.Setenv("GOVEL_LAMB_CACHE_DIR", dir.(string))
-		os.Setenv("GOVEL_LAMB_CACHE_TIME", cacheTimeDuration.String())
+		settings["GOVEL_LAMB_CACHE_DIR"] = dir.(string)
+		settings["GOVEL_LAMB_CACHE_TIME"] = cacheTimeDuration.String()
+	}
+
+	// validate the locale (optional)
+	if locale, exists := lambConfig["locale"]; exists {
+		if _, ok := locale.(string); !ok {
+			return errors.New("lamb: locale must be a string")
+		}
+
+		if _, err := language.Parse(locale.(string)); err != nil {
+			return fmt.Errorf("lamb: locale %s is not valid", locale)
+		}
+
+		settings["GOVEL_LAMB_LOCALE"] = locale.(string)
+	}
+
+	// load the translations (optional)
+	if translations, exists := lambConfig["translations"]; exists {
+		if _, ok := translations.(string); !ok {
+			return errors.New("lamb: translations must be a string")
+		}
+
+		if err := LoadTranslations(translations.(string)); err != nil {
+			return fmt.Errorf("lamb: translations: %s", err)
+		}
+	}
+
+	// validate the session key of the CSRF token (optional)
+	if key, exists := lambConfig["csrf_key"]; exists {
+		if _, ok := key.(string); !ok {
+			return errors.New("lamb: csrf_key must be a string")
+		}
+
+		settings["GOVEL_LAMB_CSRF_KEY"] = key.(string)
+	}
+
+	// validate the class of the fields with errors (optional)
+	if class, exists := lambConfig["error_class"]; exists {
+		if _, ok := class.(string); !ok {
+			return errors.New("lamb: error_class must be a string")
+		}
+
+		settings["GOVEL_LAMB_ERROR_CLASS"] = class.(string)
+	}
+
+	// validate the template of the errors of RenderHTTP (optional)
+	if template, exists := lambConfig["error_template"]; exists {
+		if _, ok := template.(string); !ok {
+			return errors.New("lamb: error_template must be a string")
+		}
+
+		settings["GOVEL_LAMB_ERROR_TEMPLATE"] = template.(string)
+	}
+
+	// validate the URL of the app, used by the absolute routes (optional)
+	if appURL, exists := lambConfig["url"]; exists {
+		if _, ok := appURL.(string); !ok {
+			return errors.New("lamb: url must be a string")
+		}
+
+		if parsed, err := url.Parse(appURL.(string)); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return fmt.Errorf("lamb: url %s is not an absolute URL", appURL)
+		}
+
+		settings["GOVEL_LAMB_URL"] = appURL.(string)
+	}
+
+	// validate the position of the currency symbol (optional)
+	if position, exists := lambConfig["currency_symbol"]; exists {
+		if position != "before" && position != "after" {
+			return errors.New("lamb: currency_symbol must be before or after")
+		}
+
+		settings["GOVEL_LAMB_CURRENCY_SYMBOL"] = position.(string)
+	}
+
+	// validate the labels of the booleans (optional)
+	labels := map[string]string{
+		"true_label":  "GOVEL_LAMB_TRUE_LABEL",
+		"false_label": "GOVEL_LAMB_FALSE_LABEL",
+	}
+
+	for key, envVar := range labels {
+		label, exists := lambConfig[key]
+
+		if !exists {
+			continue
+		}
+
+		if _, ok := label.(string); !ok {
+			return fmt.Errorf("lamb: %s must be a string", key)
+		}
+
+		settings[envVar] = label.(string)
+	}
+
+	// validate the versioning of the assets (optional)
+	if assets, exists := lambConfig["assets"]; exists {
+		assetsMap, ok := assets.(map[interface{}]interface{})
+
+		if !ok {
+			return errors.New("lamb: assets must be a map[interface{}]interface{}")
+		}
+
+		assetSettings := map[string]string{
+			"manifest": "GOVEL_LAMB_ASSETS_MANIFEST",
+			"dir":      "GOVEL_LAMB_ASSETS_DIR",
+			"version":  "GOVEL_LAMB_ASSETS_VERSION",
+			"cdn":      "GOVEL_LAMB_ASSETS_CDN",
+		}
+
+		for key, envVar := range assetSettings {
+			value, exists := assetsMap[key]
+
+			if !exists {
+				continue
+			}
+
+			if _, ok := value.(string); !ok {
+				return fmt.Errorf("lamb: assets: %s must be a string", key)
+			}
+
+			settings[envVar] = value.(string)
+		}
+
+		if version, exists := assetsMap["version"]; exists && version != "mtime" && version != "hash" {
+			return errors.New("lamb: assets: version must be mtime or hash")
+		}
+	}
+
+	// validate the debug mode (optional)
+	if debug, exists := lambConfig["debug"]; exists {
+		if _, ok := debug.(bool); !ok {
+			return errors.New("lamb: debug must be a bool")
+		}
+
+		settings["GOVEL_LAMB_DEBUG"] = strconv.FormatBool(debug.(bool))
+	}
+
+	// validate the lenient mode (optional)
+	if lenient, exists := lambConfig["lenient"]; exists {
+		if _, ok := lenient.(bool); !ok {
+			return errors.New("lamb: lenient must be a bool")
+		}
+
+		settings["GOVEL_LAMB_LENIENT"] = strconv.FormatBool(lenient.(bool))
+	}
+
+	// validate the minification of the output (optional)
+	if minify, exists := lambConfig["minify"]; exists {
+		if _, ok := minify.(bool); !ok {
+			return errors.New("lamb: minify must be a bool")
+		}
+
+		settings["GOVEL_LAMB_MINIFY"] = strconv.FormatBool(minify.(bool))
+	}
+
+	// validate the diagnostics of the renders (optional)
+	if diagnostics, exists := lambConfig["diagnostics"]; exists {
+		if _, ok := diagnostics.(bool); !ok {
+			return errors.New("lamb: diagnostics must be a bool")
+		}
+
+		settings["GOVEL_LAMB_DIAGNOSTICS"] = strconv.FormatBool(diagnostics.(bool))
+	}
+
+	// validate the compilation of the templates (optional)
+	if compile, exists := lambConfig["compile"]; exists {
+		if _, ok := compile.(bool); !ok {
+			return errors.New("lamb: compile must be a bool")
+		}
+
+		settings["GOVEL_LAMB_COMPILE"] = strconv.FormatBool(compile.(bool))
+	}
+
+	// validate the threshold of the slow renders (optional)
+	if slowRender, exists := lambConfig["slow_render"]; exists {
+		threshold, ok := slowRender.(string)
+
+		if !ok {
+			return errors.New("lamb: slow_render must be a string")
+		}
+
+		duration, err := time.ParseDuration(threshold)
+
+		if err != nil || duration <= 0 {
+			return errors.New("lamb: slow_render must be a positive duration")
+		}
+
+		settings["GOVEL_LAMB_SLOW_RENDER"] = duration.String()
 	}
 
-	// set var in the environment
-	os.Setenv("GOVEL_LAMB_BASE_DIR", dir.(string))
+	// validate the case-insensitive fields (optional)
+	if caseInsensitive, exists := lambConfig["case_insensitive_fields"]; exists {
+		if _, ok := caseInsensitive.(bool); !ok {
+			return errors.New("lamb: case_insensitive_fields must be a bool")
+		}
+
+		settings["GOVEL_LAMB_CASE_INSENSITIVE_FIELDS"] = strconv.FormatBool(caseInsensitive.(bool))
+	}
+
+	// validate the environment variables the templates can read (optional)
+	if allowed, exists := lambConfig["env"]; exists {
+		list, ok := allowed.([]interface{})
+
+		if !ok {
+			return errors.New("lamb: env must be a list of environment variables")
+		}
+
+		names := make([]string, len(list))
+
+		for i, name := range list {
+			if names[i], ok = name.(string); !ok || names[i] == "" || strings.Contains(names[i], ",") {
+				return fmt.Errorf("lamb: env: %v is not a valid environment variable", name)
+			}
+		}
+
+		settings["GOVEL_LAMB_ENV"] = strings.Join(names, ",")
+	}
+
+	// validate the execution limits (optional)
+	limits := map[string]string{
+		"max_include_depth":   "GOVEL_LAMB_MAX_INCLUDE_DEPTH",
+		"max_loop_iterations": "GOVEL_LAMB_MAX_LOOP_ITERATIONS",
+		"max_output_size":     "GOVEL_LAMB_MAX_OUTPUT_SIZE",
+	}
+
+	for key, envVar := range limits {
+		limit, exists := lambConfig[key]
+
+		if !exists {
+			continue
+		}
+
+		value, ok := limit.(int)
+
+		if !ok || value < 1 {
+			return fmt.Errorf("lamb: %s must be a positive integer", key)
+		}
+
+		settings[envVar] = strconv.Itoa(value)
+	}
+
+	// validate from how many elements a loop is reported (optional)
+	if largeLoop, exists := lambConfig["large_loop"]; exists {
+		value, ok := largeLoop.(int)
+
+		if !ok || value < 1 {
+			return errors.New("lamb: large_loop must be a positive integer")
+		}
+
+		settings["GOVEL_LAMB_LARGE_LOOP"] = strconv.Itoa(value)
+	}
+
+	// the builtins added to the deprecated map are not seen by the templates
+	for name := range evaluator.Builtins {
+		if _, registered := evaluator.Registry.Get(name); !registered {
+			internal.Log().Warn("evaluator.Builtins is deprecated and its builtins are ignored, register them with evaluator.Registry", "builtin", name)
+		}
+	}
+
+	settings["GOVEL_LAMB_BASE_DIR"] = dir.(string)
+
+	internal.SetSettings(settings)
 
 	return nil
 }
 
 func LoadLambFuntions(funcs map[string]*object.Builtin) {
 	for k, f := range funcs {
-		_, exists := evaluator.Builtins[k]
+		if err := evaluator.Registry.Register(k, f); err != nil {
+			panic(fmt.Sprintf("lamb: %s", err))
+		}
+	}
+}
 
-		if exists {
-			panic(fmt.Sprintf("lamb: function %s already exists", k))
+// LoadFuncs registers Go functions of any signature as lamb functions, like
+// the FuncMap of html/template. The arguments are checked and converted to the
+// types of the parameters of each function, and a function can return a value,
+// an error or both.
+func LoadFuncs(funcs map[string]interface{}) {
+	for k, f := range funcs {
+		builtin, err := evaluator.NewFuncBuiltin(k, f)
+
+		if err != nil {
+			panic(fmt.Sprintf("lamb: %s", err))
 		}
 
-		evaluator.Builtins[k] = f
+		if err := evaluator.Registry.Register(k, builtin); err != nil {
+			panic(fmt.Sprintf("lamb: %s", err))
+		}
 	}
 }
@@ -0,0 +1,246 @@
+package lamb
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/govel-framework/lamb/ast"
+	"github.com/govel-framework/lamb/evaluator"
+	"github.com/govel-framework/lamb/internal"
+)
+
+// TemplateInfo describes what a template needs and provides, as found by
+// Analyze.
+type TemplateInfo struct {
+	Vars      []string // The vars read by the template and not set by it, e.g. the vars of Render.
+	Functions []string // The functions called by the template.
+	Includes  []string // The templates included.
+	Extends   string   // The layout, empty if the template does not extend one.
+	Sections  []string // The sections filled by the template for its layout.
+	Defines   []string // The sections the template defines for the templates that extend it.
+	Props     []Prop   // The props declared by the template, in source order.
+	Problems  []string // The problems found checking the template against the types of its props.
+}
+
+// Prop is a var declared by the props of a template.
+type Prop struct {
+	Name     string
+	Type     string // The type, e.g. User, []Product or map[string]int.
+	Required bool   // The prop has no default value.
+}
+
+// Analyze parses the template with the given name, without rendering it, and
+// returns what it needs and provides, e.g. to check in CI that a handler
+// passes every var its template reads.
+func Analyze(name string) (*TemplateInfo, error) {
+	program, err := internal.ParseTemplate(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	a := &analyzer{
+		bound:     make(map[string]int),
+		vars:      make(map[string]bool),
+		functions: make(map[string]bool),
+		includes:  make(map[string]bool),
+		sections:  make(map[string]bool),
+		defines:   make(map[string]bool),
+		types:     make(map[string]reflect.Type),
+		problems:  make(map[string]bool),
+	}
+
+	a.analyze(program)
+
+	return &TemplateInfo{
+		Vars:      sortedSet(a.vars),
+		Functions: sortedSet(a.functions),
+		Includes:  sortedSet(a.includes),
+		Extends:   a.extends,
+		Sections:  sortedSet(a.sections),
+		Defines:   sortedSet(a.defines),
+		Props:     a.props,
+		Problems:  sortedSet(a.problems),
+	}, nil
+}
+
+type analyzer struct {
+	bound     map[string]int // The vars set by the template, with the number of scopes that set them.
+	vars      map[string]bool
+	functions map[string]bool
+	includes  map[string]bool
+	extends   string
+	sections  map[string]bool
+	defines   map[string]bool
+	props     []Prop
+	types     map[string]reflect.Type // The types of the props, if they are known.
+	problems  map[string]bool
+}
+
+func (a *analyzer) analyze(node ast.Node) {
+	ast.Inspect(node, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.Identifier:
+			if a.bound[node.Value] == 0 {
+				a.vars[node.Value] = true
+			}
+
+		case *ast.VarStatement:
+			if node.Value != nil {
+				a.analyze(node.Value)
+			}
+
+			// the var is set until the end of the template
+			a.bound[node.Name.Value]++
+
+			return false
+
+		case *ast.CallExpression:
+			if function, isIdentifier := node.Function.(*ast.Identifier); isIdentifier && a.bound[function.Value] == 0 {
+				a.functions[function.Value] = true
+			} else {
+				a.analyze(node.Function)
+			}
+
+			for _, arg := range node.Arguments {
+				a.analyze(arg)
+			}
+
+			return false
+
+		case *ast.DotExpression:
+			a.fieldType(node)
+
+			// the right side is a field, not a var
+			a.analyze(node.Left)
+
+			return false
+
+		case *ast.PropsStatement:
+			a.declare(node)
+
+		case *ast.ForExpression:
+			a.analyze(node.In)
+
+			if node.Block != nil {
+				a.scoped([]string{node.Key, node.Value, "loop"}, node.Block)
+			}
+
+			return false
+
+		case *ast.LambdaLiteral:
+			a.scoped([]string{node.Parameter.Value}, node.Body)
+
+			return false
+
+		case *ast.TryStatement:
+			if node.Block != nil {
+				a.analyze(node.Block)
+			}
+
+			if node.Rescue != nil {
+				a.scoped([]string{node.Err}, node.Rescue)
+			}
+
+			return false
+
+		case *ast.IncludeStatement:
+			a.includes[node.File] = true
+
+		case *ast.ExtendsStatement:
+			a.extends = node.From
+
+		case *ast.SectionStatement:
+			a.sections[node.Name] = true
+
+		case *ast.DefineStatement:
+			a.defines[node.Name] = true
+		}
+
+		return true
+	})
+}
+
+// declare adds the props of the statement, with the types that are known.
+func (a *analyzer) declare(node *ast.PropsStatement) {
+	for _, prop := range node.Props {
+		a.props = append(a.props, Prop{Name: prop.Name, Type: prop.Type, Required: prop.Default == nil})
+
+		t, err := evaluator.PropType(prop.Type)
+
+		if err != nil {
+			a.problems[fmt.Sprintf("%d: %d: prop %s: %s", prop.Token.Line, prop.Token.Col, prop.Name, err)] = true
+
+			continue
+		}
+
+		a.types[prop.Name] = t
+	}
+}
+
+// fieldType returns the type of the value of the expression if it is a prop
+// of a known type or a field of one, and adds the fields that the types do
+// not have to the problems.
+func (a *analyzer) fieldType(node ast.Expression) (reflect.Type, bool) {
+	switch node := node.(type) {
+	case *ast.Identifier:
+		t, exists := a.types[node.Value]
+
+		return t, exists && a.bound[node.Value] == 0
+
+	case *ast.DotExpression:
+		t, known := a.fieldType(node.Left)
+
+		// the fields of any are not known
+		if !known || t.Kind() == reflect.Interface {
+			return nil, false
+		}
+
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+
+		field, exists := evaluator.FieldType(t, node.Right.Value)
+
+		if !exists {
+			a.problems[fmt.Sprintf("%d: %d: field %s does not exist in %s", node.Token.Line, node.Token.Col, node.Right.Value, t)] = true
+		}
+
+		return field, exists
+	}
+
+	return nil, false
+}
+
+// scoped analyzes the block with the names set, the vars set in the block only
+// exist in it.
+func (a *analyzer) scoped(names []string, node ast.Node) {
+	outer := make(map[string]int, len(a.bound))
+
+	for name, count := range a.bound {
+		outer[name] = count
+	}
+
+	for _, name := range names {
+		if name != "" {
+			a.bound[name]++
+		}
+	}
+
+	a.analyze(node)
+
+	a.bound = outer
+}
+
+func sortedSet(set map[string]bool) []string {
+	list := make([]string, 0, len(set))
+
+	for item := range set {
+		list = append(list, item)
+	}
+
+	sort.Strings(list)
+
+	return list
+}
@@ -10,6 +10,8 @@ import (
 type Node interface {
 	TokenLiteral() string
 	String() string
+	Pos() token.Position // The position of the first character of the node.
+	End() token.Position // The position just after the last character of the node.
 }
 
 type Statement interface {
@@ -23,7 +25,10 @@ type Expression interface {
 }
 
 type Program struct {
+	Span
 	Statements []Statement
+	Chunks     *ChunkTable // The interned static fragments of the template.
+	compiled
 }
 
 func (p *Program) String() string {
@@ -45,6 +50,7 @@ func (p *Program) TokenLiteral() string {
 }
 
 type VarStatement struct {
+	Span
 	Token token.Token // the token.VAR token
 	Name  *Identifier
 	Value Expression
@@ -68,6 +74,7 @@ func (vs *VarStatement) String() string {
 }
 
 type Identifier struct {
+	Span
 	Token token.Token // the token.IDENT token
 	Value string
 }
@@ -79,6 +86,7 @@ func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
 func (i *Identifier) String() string { return i.Value }
 
 type ExpressionStatement struct {
+	Span
 	Token      token.Token // the first token of the expression
 	Expression Expression
 }
@@ -96,6 +104,7 @@ func (es *ExpressionStatement) String() string {
 }
 
 type IntegerLiteral struct {
+	Span
 	Token token.Token
 	Value int
 }
@@ -104,7 +113,18 @@ func (il *IntegerLiteral) expressionNode()      {}
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
 func (il *IntegerLiteral) String() string       { return il.Token.Literal }
 
+type FloatLiteral struct {
+	Span
+	Token token.Token
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode()      {}
+func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FloatLiteral) String() string       { return fl.Token.Literal }
+
 type PrefixExpression struct {
+	Span
 	Token    token.Token // The prefix token, e.g. !
 	Operator string
 	Right    Expression
@@ -126,6 +146,7 @@ func (pe *PrefixExpression) String() string {
 }
 
 type InfixExpression struct {
+	Span
 	Token    token.Token // The operator token, e.g. +
 	Left     Expression
 	Operator string
@@ -148,6 +169,7 @@ func (oe *InfixExpression) String() string {
 }
 
 type Boolean struct {
+	Span
 	Token token.Token
 	Value bool
 }
@@ -160,6 +182,7 @@ func (b *Boolean) String() string {
 }
 
 type IfExpression struct {
+	Span
 	Token       token.Token // the 'if' token
 	Condition   Expression
 	Consequence *BlockStatement
@@ -186,8 +209,10 @@ func (ie *IfExpression) String() string {
 }
 
 type BlockStatement struct {
+	Span
 	Token      token.Token // the { token
 	Statements []Statement
+	compiled
 }
 
 func (bs *BlockStatement) statementNode() {}
@@ -205,6 +230,7 @@ func (bs *BlockStatement) String() string {
 }
 
 type CallExpression struct {
+	Span
 	Token     token.Token // The '(' token
 	Function  Expression  // Identifier or FunctionLiteral
 	Arguments []Expression
@@ -229,6 +255,7 @@ func (ce *CallExpression) String() string {
 }
 
 type StringLiteral struct {
+	Span
 	Token  token.Token
 	Value  string
 	Closed bool // whether the string is closed
@@ -239,6 +266,7 @@ func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
 func (sl *StringLiteral) String() string       { return sl.Token.Literal }
 
 type ArrayLiteral struct {
+	Span
 	Token    token.Token // the '[' token
 	Elements []Expression
 }
@@ -262,6 +290,7 @@ func (al *ArrayLiteral) String() string {
 }
 
 type IndexExpression struct {
+	Span
 	Token token.Token // The [ token
 	Left  Expression
 	Index Expression
@@ -282,8 +311,14 @@ func (ie *IndexExpression) String() string {
 }
 
 type MapLiteral struct {
+	Span
 	Token token.Token // the '{' token
-	Pairs map[Expression]Expression
+	Pairs []MapPair   // In source order.
+}
+
+type MapPair struct {
+	Key   Expression
+	Value Expression
 }
 
 func (hl *MapLiteral) expressionNode()      {}
@@ -293,8 +328,8 @@ func (hl *MapLiteral) String() string {
 
 	pairs := []string{}
 
-	for key, value := range hl.Pairs {
-		pairs = append(pairs, key.String()+":"+value.String())
+	for _, pair := range hl.Pairs {
+		pairs = append(pairs, pair.Key.String()+":"+pair.Value.String())
 	}
 
 	out.WriteString("{")
@@ -305,6 +340,7 @@ func (hl *MapLiteral) String() string {
 }
 
 type ForExpression struct {
+	Span
 	Token token.Token // The 'for' token
 	Key   string
 	Value string
@@ -324,6 +360,7 @@ func (fe *ForExpression) String() string {
 }
 
 type ExtendsStatement struct {
+	Span
 	Token token.Token // The 'extends' token
 	From  string
 }
@@ -340,7 +377,62 @@ func (ee *ExtendsStatement) String() string {
 	return out.String()
 }
 
+// AutoescapeStatement sets the output mode of a template, the escaper of the
+// values it writes: autoescape("xml").
+type AutoescapeStatement struct {
+	Span
+	Token token.Token // The 'autoescape' token
+	Mode  string
+}
+
+func (as *AutoescapeStatement) expressionNode()      {}
+func (as *AutoescapeStatement) TokenLiteral() string { return as.Token.Literal }
+func (as *AutoescapeStatement) String() string {
+	return `autoescape("` + as.Mode + `")`
+}
+
+// PropsStatement declares the vars a template expects, with their types:
+// props(user: User, title: string = "Home").
+type PropsStatement struct {
+	Span
+	Token token.Token // The 'props' token
+	Props []Prop
+}
+
+// Prop is a var declared by props.
+type Prop struct {
+	Token   token.Token // The name of the prop
+	Name    string
+	Type    string     // The type, e.g. User, []Product or map[string]int.
+	Default Expression // The value of the prop when it is not set, nil if it is required.
+}
+
+func (ps *PropsStatement) expressionNode()      {}
+func (ps *PropsStatement) TokenLiteral() string { return ps.Token.Literal }
+func (ps *PropsStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("props(")
+
+	for i, prop := range ps.Props {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+
+		out.WriteString(prop.Name + ": " + prop.Type)
+
+		if prop.Default != nil {
+			out.WriteString(" = " + prop.Default.String())
+		}
+	}
+
+	out.WriteString(")")
+
+	return out.String()
+}
+
 type SectionStatement struct {
+	Span
 	Token token.Token // The 'section' token
 	Block *BlockStatement
 	Name  string
@@ -359,6 +451,7 @@ func (ss *SectionStatement) String() string {
 }
 
 type DefineStatement struct {
+	Span
 	Token   token.Token // The 'define' token
 	Name    string
 	Content *BlockStatement
@@ -377,8 +470,9 @@ func (ds *DefineStatement) String() string {
 }
 
 type DotExpression struct {
+	Span
 	Token token.Token // The '.' token
-	Left  Identifier
+	Left  Expression  // Identifier, IndexExpression, DotExpression or CallExpression
 	Right Identifier
 }
 
@@ -393,6 +487,7 @@ func (de *DotExpression) String() string {
 }
 
 type IncludeStatement struct {
+	Span
 	Token token.Token // The 'include' token
 	File  string
 	Vars  Expression
@@ -417,10 +512,105 @@ func (is *IncludeStatement) String() string {
 }
 
 type HtmlLiteral struct {
+	Span
 	Token token.Token
 	Value string
+	Chunk int // The index of Value in the program's chunk table.
 }
 
 func (hl *HtmlLiteral) expressionNode()      {}
 func (hl *HtmlLiteral) TokenLiteral() string { return hl.Token.Literal }
-func (hl *HtmlLiteral) String() string       { return hl.Token.Literal }
+func (hl *HtmlLiteral) String() string       { return hl.Value }
+
+type LambdaLiteral struct {
+	Span
+	Token     token.Token // The '=>' token
+	Parameter *Identifier
+	Body      Expression
+}
+
+func (ll *LambdaLiteral) expressionNode()      {}
+func (ll *LambdaLiteral) TokenLiteral() string { return ll.Token.Literal }
+func (ll *LambdaLiteral) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(ll.Parameter.String())
+	out.WriteString(" => ")
+	out.WriteString(ll.Body.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
+type TryStatement struct {
+	Span
+	Token  token.Token // The 'try' token
+	Block  *BlockStatement
+	Err    string // The name of the variable that holds the error in the rescue block
+	Rescue *BlockStatement
+}
+
+func (ts *TryStatement) expressionNode()      {}
+func (ts *TryStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *TryStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("try ")
+	out.WriteString(ts.Block.String())
+
+	if ts.Rescue != nil {
+		out.WriteString(" rescue ")
+		out.WriteString(ts.Err)
+		out.WriteString(" ")
+		out.WriteString(ts.Rescue.String())
+	}
+
+	return out.String()
+}
+
+type SpacelessStatement struct {
+	Span
+	Token token.Token // The 'spaceless' token
+	Block *BlockStatement
+}
+
+func (ss *SpacelessStatement) expressionNode()      {}
+func (ss *SpacelessStatement) TokenLiteral() string { return ss.Token.Literal }
+func (ss *SpacelessStatement) String() string {
+	return "spaceless " + ss.Block.String()
+}
+
+type DirectiveStatement struct {
+	Span
+	Token      token.Token // The keyword of the directive
+	Name       string
+	Arguments  []Expression
+	Block      *BlockStatement // nil if the directive has no block
+	EndKeyword string          // The keyword that ended the block
+}
+
+func (ds *DirectiveStatement) expressionNode()      {}
+func (ds *DirectiveStatement) TokenLiteral() string { return ds.Token.Literal }
+func (ds *DirectiveStatement) String() string {
+	var out bytes.Buffer
+
+	var args []string
+	for _, a := range ds.Arguments {
+		args = append(args, a.String())
+	}
+
+	out.WriteString(ds.Name)
+	out.WriteString(token.LPAREN)
+	out.WriteString(strings.Join(args, token.COMMA+" "))
+	out.WriteString(token.RPAREN)
+
+	if ds.Block != nil {
+		out.WriteString(" ")
+		out.WriteString(ds.Block.String())
+		out.WriteString(" ")
+		out.WriteString(ds.EndKeyword)
+	}
+
+	return out.String()
+}
@@ -27,3 +27,79 @@ func TestString(t *testing.T) {
 		t.Errorf("program.String() wrong, got=%q", program.String())
 	}
 }
+
+func TestInspect(t *testing.T) {
+	// var total = price * count(items)
+	program := &Program{
+		Statements: []Statement{
+			&VarStatement{
+				Token: token.Token{Type: token.VAR, Literal: "var"},
+				Name:  &Identifier{Token: token.Token{Type: token.IDENT, Literal: "total"}, Value: "total"},
+				Value: &InfixExpression{
+					Token:    token.Token{Type: token.ASTERISK, Literal: "*"},
+					Left:     &Identifier{Token: token.Token{Type: token.IDENT, Literal: "price"}, Value: "price"},
+					Operator: "*",
+					Right: &CallExpression{
+						Token:     token.Token{Type: token.LPAREN, Literal: "("},
+						Function:  &Identifier{Token: token.Token{Type: token.IDENT, Literal: "count"}, Value: "count"},
+						Arguments: []Expression{&Identifier{Token: token.Token{Type: token.IDENT, Literal: "items"}, Value: "items"}},
+					},
+				},
+			},
+		},
+	}
+
+	var identifiers []string
+
+	Inspect(program, func(node Node) bool {
+		if ident, isIdentifier := node.(*Identifier); isIdentifier {
+			identifiers = append(identifiers, ident.Value)
+		}
+
+		// do not enter the calls
+		_, isCall := node.(*CallExpression)
+
+		return !isCall
+	})
+
+	expected := []string{"total", "price"}
+
+	if len(identifiers) != len(expected) {
+		t.Fatalf("wrong identifiers, expected=%v, got=%v", expected, identifiers)
+	}
+
+	for i, ident := range expected {
+		if identifiers[i] != ident {
+			t.Errorf("identifiers[%d] wrong, expected=%q, got=%q", i, ident, identifiers[i])
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	span := Span{
+		From: token.Position{Offset: 5, Line: 2, Col: 4},
+		To:   token.Position{Offset: 9, Line: 2, Col: 8},
+	}
+
+	node := &ExpressionStatement{
+		Span:  span,
+		Token: token.Token{Type: token.IDENT, Literal: "name", Line: 2, Col: 4},
+		Expression: &Identifier{
+			Span:  span,
+			Token: token.Token{Type: token.IDENT, Literal: "name", Line: 2, Col: 4},
+			Value: "name",
+		},
+	}
+
+	tree, err := JSON(node)
+
+	if err != nil {
+		t.Fatalf("JSON returned an error: %s", err)
+	}
+
+	expected := `{"col":4,"end":{"col":8,"line":2,"offset":9},"expression":{"col":4,"end":{"col":8,"line":2,"offset":9},"line":2,"pos":{"col":4,"line":2,"offset":5},"type":"Identifier","value":"name"},"line":2,"pos":{"col":4,"line":2,"offset":5},"type":"ExpressionStatement"}`
+
+	if string(tree) != expected {
+		t.Errorf("JSON wrong.\nexpected=%s\ngot=%s", expected, tree)
+	}
+}
@@ -0,0 +1,41 @@
+package ast
+
+// ChunkTable interns the static HTML fragments of a template, so markup that
+// repeats (e.g. the rows of a table rendered in a for loop) is stored once and
+// written from a shared byte slice instead of being copied on every render.
+type ChunkTable struct {
+	chunks [][]byte
+	index  map[string]int
+}
+
+func NewChunkTable() *ChunkTable {
+	return &ChunkTable{index: make(map[string]int)}
+}
+
+// Intern adds the fragment to the table (if it is not already there) and
+// returns its index.
+func (ct *ChunkTable) Intern(fragment string) int {
+	if i, exists := ct.index[fragment]; exists {
+		return i
+	}
+
+	ct.chunks = append(ct.chunks, []byte(fragment))
+	ct.index[fragment] = len(ct.chunks) - 1
+
+	return len(ct.chunks) - 1
+}
+
+// Bytes returns the fragment stored at the given index, or nil if the index
+// is out of range. The returned slice is shared and must not be modified.
+func (ct *ChunkTable) Bytes(i int) []byte {
+	if i < 0 || i >= len(ct.chunks) {
+		return nil
+	}
+
+	return ct.chunks[i]
+}
+
+// Len returns the number of distinct fragments in the table.
+func (ct *ChunkTable) Len() int {
+	return len(ct.chunks)
+}
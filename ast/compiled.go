@@ -0,0 +1,20 @@
+package ast
+
+import "sync/atomic"
+
+// compiled holds the IR the evaluator compiled for a node (a program or a
+// block), so a cached AST is compiled once.
+type compiled struct {
+	code atomic.Value
+}
+
+// Compiled returns the IR the evaluator compiled for the node, nil if the
+// node was not compiled.
+func (c *compiled) Compiled() interface{} {
+	return c.code.Load()
+}
+
+// SetCompiled keeps the IR of the node with it.
+func (c *compiled) SetCompiled(code interface{}) {
+	c.code.Store(code)
+}
@@ -0,0 +1,92 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/govel-framework/lamb/token"
+)
+
+var (
+	nodeType  = reflect.TypeOf((*Node)(nil)).Elem()
+	tokenType = reflect.TypeOf(token.Token{})
+	spanType  = reflect.TypeOf(Span{})
+)
+
+// JSON returns the tree of the node as JSON, for the tools that read lamb
+// templates (e.g. editors or syntax highlighters). Every node is an object
+// with its type, the line and column of its token, the range of its source and
+// its fields:
+//
+//	{"type": "Identifier", "line": 1, "col": 4, "value": "name",
+//		"pos": {"offset": 3, "line": 1, "col": 4}, "end": {"offset": 7, "line": 1, "col": 8}}
+func JSON(node Node) ([]byte, error) {
+	var out bytes.Buffer
+
+	// the HTML of the templates is kept readable
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+
+	if err := encoder.Encode(jsonValue(reflect.ValueOf(node))); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(out.Bytes(), []byte("\n")), nil
+}
+
+func jsonValue(value reflect.Value) interface{} {
+	switch value.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if value.IsNil() {
+			return nil
+		}
+
+		return jsonValue(value.Elem())
+
+	case reflect.Slice:
+		list := make([]interface{}, value.Len())
+
+		for i := range list {
+			list[i] = jsonValue(value.Index(i))
+		}
+
+		return list
+
+	case reflect.Struct:
+		object := make(map[string]interface{})
+
+		if reflect.PointerTo(value.Type()).Implements(nodeType) {
+			object["type"] = value.Type().Name()
+		}
+
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+
+			switch {
+			case field.Type == tokenType:
+				tok := value.Field(i).Interface().(token.Token)
+
+				object["line"] = tok.Line
+				object["col"] = tok.Col
+
+			case field.Type == spanType:
+				span := value.Field(i).Interface().(Span)
+
+				object["pos"] = jsonValue(reflect.ValueOf(span.From))
+				object["end"] = jsonValue(reflect.ValueOf(span.To))
+
+			// the chunk table and the IR are optimizations of the evaluator
+			case field.Name == "Chunks" || field.Name == "Chunk" || !field.IsExported():
+
+			default:
+				object[strings.ToLower(field.Name[:1])+field.Name[1:]] = jsonValue(value.Field(i))
+			}
+		}
+
+		return object
+	}
+
+	return value.Interface()
+}
@@ -0,0 +1,22 @@
+package ast
+
+import "github.com/govel-framework/lamb/token"
+
+// Span is the range of the source a node comes from, it is embedded in every
+// node and set by the parser.
+type Span struct {
+	From token.Position // The position of the first character.
+	To   token.Position // The position just after the last character.
+}
+
+// Pos returns the position of the first character of the node.
+func (s *Span) Pos() token.Position { return s.From }
+
+// End returns the position just after the last character of the node.
+func (s *Span) End() token.Position { return s.To }
+
+// SetSpan sets the range of the source of the node.
+func (s *Span) SetSpan(from token.Position, to token.Position) {
+	s.From = from
+	s.To = to
+}
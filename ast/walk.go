@@ -0,0 +1,177 @@
+package ast
+
+import "fmt"
+
+// A Visitor's Visit method is invoked for each node encountered by Walk. If
+// the result visitor w is not nil, Walk visits each of the children of node
+// with the visitor w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses an AST in depth-first order: it starts by calling
+// v.Visit(node); node must not be nil. If the visitor w returned by
+// v.Visit(node) is not nil, Walk is invoked recursively with visitor w for
+// each of the non-nil children of node, followed by a call of w.Visit(nil).
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		walkStatements(v, n.Statements)
+
+	case *BlockStatement:
+		walkStatements(v, n.Statements)
+
+	case *VarStatement:
+		if n.Name != nil {
+			Walk(v, n.Name)
+		}
+
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+
+	case *ExpressionStatement:
+		if n.Expression != nil {
+			Walk(v, n.Expression)
+		}
+
+	case *PrefixExpression:
+		Walk(v, n.Right)
+
+	case *InfixExpression:
+		Walk(v, n.Left)
+		Walk(v, n.Right)
+
+	case *IfExpression:
+		Walk(v, n.Condition)
+
+		if n.Consequence != nil {
+			Walk(v, n.Consequence)
+		}
+
+		if n.Alternative != nil {
+			Walk(v, n.Alternative)
+		}
+
+	case *CallExpression:
+		Walk(v, n.Function)
+		walkExpressions(v, n.Arguments)
+
+	case *ArrayLiteral:
+		walkExpressions(v, n.Elements)
+
+	case *IndexExpression:
+		Walk(v, n.Left)
+		Walk(v, n.Index)
+
+	case *MapLiteral:
+		for _, pair := range n.Pairs {
+			Walk(v, pair.Key)
+			Walk(v, pair.Value)
+		}
+
+	case *ForExpression:
+		Walk(v, n.In)
+
+		if n.Block != nil {
+			Walk(v, n.Block)
+		}
+
+	case *PropsStatement:
+		for _, prop := range n.Props {
+			if prop.Default != nil {
+				Walk(v, prop.Default)
+			}
+		}
+
+	case *SectionStatement:
+		if n.Block != nil {
+			Walk(v, n.Block)
+		}
+
+	case *DefineStatement:
+		if n.Content != nil {
+			Walk(v, n.Content)
+		}
+
+	case *DotExpression:
+		Walk(v, n.Left)
+		Walk(v, &n.Right)
+
+	case *IncludeStatement:
+		if n.Vars != nil {
+			Walk(v, n.Vars)
+		}
+
+	case *LambdaLiteral:
+		if n.Parameter != nil {
+			Walk(v, n.Parameter)
+		}
+
+		Walk(v, n.Body)
+
+	case *TryStatement:
+		if n.Block != nil {
+			Walk(v, n.Block)
+		}
+
+		if n.Rescue != nil {
+			Walk(v, n.Rescue)
+		}
+
+	case *SpacelessStatement:
+		if n.Block != nil {
+			Walk(v, n.Block)
+		}
+
+	case *DirectiveStatement:
+		walkExpressions(v, n.Arguments)
+
+		if n.Block != nil {
+			Walk(v, n.Block)
+		}
+
+	case *Identifier, *IntegerLiteral, *FloatLiteral, *Boolean, *StringLiteral,
+		*HtmlLiteral, *ExtendsStatement, *AutoescapeStatement:
+		// nothing to do
+
+	default:
+		panic(fmt.Sprintf("ast.Walk: unexpected node type %T", n))
+	}
+
+	v.Visit(nil)
+}
+
+func walkStatements(v Visitor, statements []Statement) {
+	for _, statement := range statements {
+		Walk(v, statement)
+	}
+}
+
+func walkExpressions(v Visitor, expressions []Expression) {
+	for _, expression := range expressions {
+		Walk(v, expression)
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+
+	return nil
+}
+
+// Inspect traverses an AST in depth-first order: it starts by calling
+// f(node); node must not be nil. If f returns true, Inspect invokes f
+// recursively for each of the non-nil children of node, followed by a call
+// of f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
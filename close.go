@@ -0,0 +1,15 @@
+package lamb
+
+import (
+	"context"
+
+	"github.com/govel-framework/lamb/internal"
+)
+
+// Close waits for the work lamb does in background (e.g. writing rendered
+// templates to the cache) to finish, so nothing is lost or truncated when the
+// process exits. It returns ctx.Err() if ctx is done first, the renders made
+// while it waits are not written to the cache.
+func Close(ctx context.Context) error {
+	return internal.WaitPendingWrites(ctx)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/govel-framework/lamb"
+	"github.com/govel-framework/lamb/internal"
+)
+
+// runCheck parses the templates of the dirs and checks their includes and
+// layouts, a dir that ends with /... is checked with its subdirs.
+func runCheck(args []string) int {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	baseDir := flags.String("base-dir", "", "the dir of the templates, by default the first dir checked")
+
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: lamb check [-base-dir dir] <dir>[/...] ...")
+		flags.PrintDefaults()
+	}
+
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	if *baseDir == "" {
+		*baseDir = strings.TrimSuffix(flags.Arg(0), "/...")
+	}
+
+	internal.SetSetting("GOVEL_LAMB_BASE_DIR", strings.TrimSuffix(*baseDir, "/")+"/")
+
+	var diagnostics []string
+
+	for _, pattern := range flags.Args() {
+		files, err := checkedFiles(pattern)
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "lamb: %s\n", err)
+			return 1
+		}
+
+		for _, file := range files {
+			diagnostics = append(diagnostics, checkTemplate(*baseDir, file)...)
+		}
+	}
+
+	for _, diagnostic := range diagnostics {
+		fmt.Fprintln(os.Stderr, diagnostic)
+	}
+
+	if len(diagnostics) != 0 {
+		fmt.Fprintf(os.Stderr, "lamb: %d problems found\n", len(diagnostics))
+		return 1
+	}
+
+	return 0
+}
+
+// checkedFiles returns the .lamb.html files of the dir of the pattern, and of
+// its subdirs if the pattern ends with /...
+func checkedFiles(pattern string) ([]string, error) {
+	if !strings.HasSuffix(pattern, "/...") {
+		return filepath.Glob(filepath.Join(pattern, "*.lamb.html"))
+	}
+
+	return templateFiles([]string{strings.TrimSuffix(pattern, "/...")})
+}
+
+// checkTemplate returns the problems of the template file.
+func checkTemplate(baseDir string, file string) []string {
+	relative, err := filepath.Rel(baseDir, file)
+
+	if err != nil {
+		return []string{fmt.Sprintf("%s: %s", file, err)}
+	}
+
+	name := templateName(filepath.ToSlash(relative))
+
+	info, err := lamb.Analyze(name)
+
+	if err != nil {
+		return []string{strings.TrimSpace(err.Error())}
+	}
+
+	var problems []string
+
+	for _, problem := range info.Problems {
+		problems = append(problems, fmt.Sprintf("%s: %s", file, problem))
+	}
+
+	for _, include := range info.Includes {
+		if !templateExists(include) {
+			problems = append(problems, fmt.Sprintf("%s: included template %s does not exist", file, include))
+		}
+	}
+
+	if info.Extends == "" {
+		return problems
+	}
+
+	if !templateExists(info.Extends) {
+		return append(problems, fmt.Sprintf("%s: layout %s does not exist", file, info.Extends))
+	}
+
+	// the sections must be defined by the layout (or by its own layouts)
+	layout, err := lamb.Analyze(info.Extends)
+
+	if err != nil {
+		return problems
+	}
+
+	defined := make(map[string]bool)
+
+	for _, define := range layout.Defines {
+		defined[define] = true
+	}
+
+	for _, section := range info.Sections {
+		if defined[section] || layout.Extends != "" {
+			continue
+		}
+
+		problems = append(problems, fmt.Sprintf("%s: section %s is not defined by the layout %s", file, section, info.Extends))
+	}
+
+	return problems
+}
+
+func templateExists(name string) bool {
+	_, err := os.Stat(internal.TemplatePath(name))
+
+	return err == nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/govel-framework/lamb/ast"
+	"github.com/govel-framework/lamb/lexer"
+	"github.com/govel-framework/lamb/parser"
+)
+
+// runAST writes the tree of the template as JSON.
+func runAST(args []string) int {
+	src, err := readSource(args)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "lamb: %s\n", err)
+		return 1
+	}
+
+	p := parser.New(lexer.New(string(src)))
+
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		for _, e := range p.Errors() {
+			fmt.Fprintf(os.Stderr, "lamb: %s\n", e)
+		}
+
+		return 1
+	}
+
+	tree, err := ast.JSON(program)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "lamb: %s\n", err)
+		return 1
+	}
+
+	return writeJSON(tree)
+}
+
+// runTokens writes the tokens of the template as JSON.
+func runTokens(args []string) int {
+	src, err := readSource(args)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "lamb: %s\n", err)
+		return 1
+	}
+
+	var tokens bytes.Buffer
+
+	// the HTML of the templates is kept readable
+	encoder := json.NewEncoder(&tokens)
+	encoder.SetEscapeHTML(false)
+
+	if err := encoder.Encode(lexer.Tokens(string(src))); err != nil {
+		fmt.Fprintf(os.Stderr, "lamb: %s\n", err)
+		return 1
+	}
+
+	return writeJSON(tokens.Bytes())
+}
+
+// readSource reads the file of the args, or the standard input if there is
+// none.
+func readSource(args []string) ([]byte, error) {
+	if len(args) == 0 {
+		return io.ReadAll(os.Stdin)
+	}
+
+	return os.ReadFile(args[0])
+}
+
+// writeJSON writes the JSON indented to the standard output.
+func writeJSON(data []byte) int {
+	var out bytes.Buffer
+
+	if err := json.Indent(&out, data, "", "  "); err != nil {
+		fmt.Fprintf(os.Stderr, "lamb: %s\n", err)
+		return 1
+	}
+
+	out.WriteByte('\n')
+	out.WriteTo(os.Stdout)
+
+	return 0
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/govel-framework/lamb/format"
+)
+
+// runFmt formats the templates given (the files and the .lamb.html files in
+// the dirs), or the standard input if there are none.
+func runFmt(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	write := flags.Bool("w", false, "write the result to the file instead of the standard output")
+	list := flags.Bool("l", false, "list the files whose formatting differs")
+
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: lamb fmt [-w] [-l] [path ...]")
+		flags.PrintDefaults()
+	}
+
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		src, err := io.ReadAll(os.Stdin)
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "lamb: %s\n", err)
+			return 1
+		}
+
+		formatted, err := format.Source(src)
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "lamb: <stdin>:%s\n", err)
+			return 1
+		}
+
+		os.Stdout.Write(formatted)
+
+		return 0
+	}
+
+	files, err := templateFiles(flags.Args())
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "lamb: %s\n", err)
+		return 1
+	}
+
+	exitCode := 0
+
+	for _, file := range files {
+		if err := formatFile(file, *write, *list); err != nil {
+			fmt.Fprintf(os.Stderr, "lamb: %s\n", err)
+			exitCode = 1
+		}
+	}
+
+	return exitCode
+}
+
+func formatFile(file string, write bool, list bool) error {
+	src, err := os.ReadFile(file)
+
+	if err != nil {
+		return err
+	}
+
+	formatted, err := format.Source(src)
+
+	if err != nil {
+		return fmt.Errorf("%s:%s", file, err)
+	}
+
+	changed := !bytes.Equal(src, formatted)
+
+	if list && changed {
+		fmt.Println(file)
+	}
+
+	if write {
+		if changed {
+			return os.WriteFile(file, formatted, 0644)
+		}
+
+		return nil
+	}
+
+	if !list {
+		os.Stdout.Write(formatted)
+	}
+
+	return nil
+}
+
+// templateFiles returns the files of the paths, the dirs are walked for the
+// .lamb.html files.
+func templateFiles(paths []string) ([]string, error) {
+	var files []string
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if !entry.IsDir() && strings.HasSuffix(file, ".lamb.html") {
+				files = append(files, file)
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/govel-framework/lamb"
+	"github.com/govel-framework/lamb/generate"
+	"github.com/govel-framework/lamb/internal"
+)
+
+// runGenerate compiles the templates of the dirs to a Go file, a dir that
+// ends with /... is compiled with its subdirs.
+func runGenerate(args []string) int {
+	flags := flag.NewFlagSet("generate", flag.ExitOnError)
+	baseDir := flags.String("base-dir", "", "the dir of the templates, by default the first dir compiled")
+	output := flags.String("o", "lamb_templates.go", "the Go file to write")
+	pkg := flags.String("pkg", "", "the package of the Go file, by default the name of its dir")
+
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: lamb generate [-base-dir dir] [-o file] [-pkg name] <dir>[/...] ...")
+		flags.PrintDefaults()
+	}
+
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	if *baseDir == "" {
+		*baseDir = strings.TrimSuffix(flags.Arg(0), "/...")
+	}
+
+	if *pkg == "" {
+		dir, err := filepath.Abs(filepath.Dir(*output))
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "lamb: %s\n", err)
+			return 1
+		}
+
+		*pkg = filepath.Base(dir)
+	}
+
+	internal.SetSetting("GOVEL_LAMB_BASE_DIR", strings.TrimSuffix(*baseDir, "/")+"/")
+
+	var templates []generate.Template
+
+	for _, pattern := range flags.Args() {
+		files, err := checkedFiles(pattern)
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "lamb: %s\n", err)
+			return 1
+		}
+
+		for _, file := range files {
+			template, err := generatedTemplate(*baseDir, file)
+
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "lamb: %s\n", strings.TrimSpace(err.Error()))
+				return 1
+			}
+
+			templates = append(templates, template)
+		}
+	}
+
+	src, err := generate.File(*pkg, templates)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "lamb: %s\n", err)
+		return 1
+	}
+
+	if err := os.WriteFile(*output, src, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "lamb: %s\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// generatedTemplate parses the template file, its name has dots, e.g.
+// users.show for users/show.lamb.html.
+func generatedTemplate(baseDir string, file string) (generate.Template, error) {
+	relative, err := filepath.Rel(baseDir, file)
+
+	if err != nil {
+		return generate.Template{}, err
+	}
+
+	name := strings.ReplaceAll(templateName(filepath.ToSlash(relative)), "/", ".")
+
+	program, err := internal.ParseTemplate(name)
+
+	if err != nil {
+		return generate.Template{}, err
+	}
+
+	info, err := lamb.Analyze(name)
+
+	if err != nil {
+		return generate.Template{}, err
+	}
+
+	return generate.Template{Name: name, Program: program, Vars: info.Vars}, nil
+}
@@ -0,0 +1,71 @@
+// Command lamb is the command line tool of the lamb templates.
+//
+// Usage:
+//
+//	lamb <command> [arguments]
+//
+// The commands are:
+//
+//	ast       write the tree of a template as JSON
+//	check     check the templates
+//	fmt       format templates
+//	generate  compile templates to Go
+//	render    render a template to the standard output
+//	tokens    write the tokens of a template as JSON
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+// command runs a subcommand with its arguments and returns the exit code.
+type command struct {
+	run   func(args []string) int
+	usage string
+}
+
+var commands = map[string]command{
+	"ast":      {run: runAST, usage: "write the tree of a template as JSON"},
+	"check":    {run: runCheck, usage: "check the templates"},
+	"fmt":      {run: runFmt, usage: "format templates"},
+	"generate": {run: runGenerate, usage: "compile templates to Go"},
+	"render":   {run: runRender, usage: "render a template to the standard output"},
+	"tokens":   {run: runTokens, usage: "write the tokens of a template as JSON"},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, exists := commands[os.Args[1]]
+
+	if !exists {
+		fmt.Fprintf(os.Stderr, "lamb: unknown command %s\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	os.Exit(cmd.run(os.Args[2:]))
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: lamb <command> [arguments]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "The commands are:")
+
+	names := make([]string, 0, len(commands))
+
+	for name := range commands {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "\t%-8s %s\n", name, commands[name].usage)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/govel-framework/lamb"
+	"github.com/govel-framework/lamb/internal"
+)
+
+// runRender renders a template to the standard output.
+func runRender(args []string) int {
+	flags := flag.NewFlagSet("render", flag.ExitOnError)
+	varsFile := flags.String("vars", "", "the JSON file with the vars of the template")
+	baseDir := flags.String("base-dir", ".", "the dir of the templates")
+
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: lamb render <template> [-vars data.json] [-base-dir dir]")
+		flags.PrintDefaults()
+	}
+
+	// the flags can go before or after the template
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	name := flags.Arg(0)
+
+	flags.Parse(flags.Args()[1:])
+
+	vars := make(map[string]interface{})
+
+	if *varsFile != "" {
+		content, err := os.ReadFile(*varsFile)
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "lamb: %s\n", err)
+			return 1
+		}
+
+		if err := json.Unmarshal(content, &vars); err != nil {
+			fmt.Fprintf(os.Stderr, "lamb: %s: %s\n", *varsFile, err)
+			return 1
+		}
+
+		vars = integers(vars).(map[string]interface{})
+	}
+
+	internal.SetSetting("GOVEL_LAMB_BASE_DIR", strings.TrimSuffix(*baseDir, "/")+"/")
+
+	if err := lamb.RenderTo(os.Stdout, templateName(name), vars); err != nil {
+		fmt.Fprintf(os.Stderr, "lamb: %s\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// templateName returns the name of the template of the path, e.g. users/show
+// for users/show.lamb.html.
+func templateName(path string) string {
+	return strings.TrimPrefix(strings.TrimSuffix(path, ".lamb.html"), "./")
+}
+
+// integers converts the whole numbers decoded from JSON (float64) to int, as
+// the numbers of the templates are.
+func integers(value interface{}) interface{} {
+	switch value := value.(type) {
+	case float64:
+		if value == math.Trunc(value) && math.Abs(value) < 1<<53 {
+			return int(value)
+		}
+
+	case map[string]interface{}:
+		for key, item := range value {
+			value[key] = integers(item)
+		}
+
+	case []interface{}:
+		for i, item := range value {
+			value[i] = integers(item)
+		}
+	}
+
+	return value
+}
@@ -0,0 +1,23 @@
+package lamb
+
+import (
+	"fmt"
+
+	"github.com/govel-framework/lamb/internal"
+)
+
+// Composer registers a view composer: fn runs every time a template whose
+// name matches pattern renders (including through include and extends), so
+// shared data such as the current user or the menu items gets injected
+// without every handler passing it.
+//
+//	lamb.Composer("partials.nav", func(vars map[string]interface{}) {
+//		vars["menu"] = menuItems()
+//	})
+//
+// Patterns support the wildcards of path.Match, e.g. "partials.*".
+func Composer(pattern string, fn func(vars map[string]interface{})) {
+	if err := internal.AddComposer(pattern, fn); err != nil {
+		panic(fmt.Sprintf("lamb: invalid composer pattern %s: %s", pattern, err))
+	}
+}
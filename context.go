@@ -0,0 +1,140 @@
+package lamb
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// RenderContext is the request-derived info available as the `ctx` variable
+// in every template rendered by Render.
+type RenderContext struct {
+	URL       string            // The full URL of the request.
+	Path      string            // The path of the request.
+	Method    string            // The HTTP method of the request.
+	Query     map[string]string // The first value of every query param.
+	Header    http.Header       // The headers of the request.
+	Locale    string            // The preferred locale of the Accept-Language header.
+	UserAgent string            // The User-Agent header.
+	IsHTMX    bool              // Whether the request was made by htmx.
+	Nonce     string            // The Content-Security-Policy nonce of the request, see WithNonce.
+	User      interface{}       // The authenticated user, nil for guests, see WithUser.
+
+	sessions []session // The sessions of the request, set by Render.
+}
+
+// session is a session of the request.
+type session struct {
+	name   string
+	values map[interface{}]interface{}
+	save   func() error // Saves the changes of values.
+	dirty  bool         // Whether values changed in the render.
+}
+
+// SessionValue returns the value of the key in the sessions of the request,
+// the first session that has it wins.
+func (ctx *RenderContext) SessionValue(key string) (interface{}, bool) {
+	for _, session := range ctx.sessions {
+		if value, exists := session.values[key]; exists {
+			return value, true
+		}
+	}
+
+	return nil, false
+}
+
+// Flash returns the one-time message of the key in the sessions of the
+// request and removes it, so it is not shown again. The sessions are saved
+// by Render once the template is rendered.
+func (ctx *RenderContext) Flash(key string) (interface{}, bool) {
+	for i := range ctx.sessions {
+		session := &ctx.sessions[i]
+		value, exists := session.values[key]
+
+		if !exists {
+			continue
+		}
+
+		delete(session.values, key)
+		session.dirty = true
+
+		// the flashes of a key are stored as a list
+		if flashes, isList := value.([]interface{}); isList && len(flashes) == 1 {
+			value = flashes[0]
+		}
+
+		return value, true
+	}
+
+	return nil, false
+}
+
+// saveSessions saves the sessions changed in the render.
+func (ctx *RenderContext) saveSessions() error {
+	for _, session := range ctx.sessions {
+		if !session.dirty || session.save == nil {
+			continue
+		}
+
+		if err := session.save(); err != nil {
+			return fmt.Errorf("lamb: cannot save the session %s: %w", session.name, err)
+		}
+	}
+
+	return nil
+}
+
+type nonceKey struct{}
+
+type userKey struct{}
+
+// WithNonce returns a copy of the request with the Content-Security-Policy
+// nonce of the response, the middleware that sets the CSP header calls it so
+// csp_nonce(), script() and style() use the same nonce.
+func WithNonce(r *http.Request, nonce string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), nonceKey{}, nonce))
+}
+
+// WithUser returns a copy of the request with the authenticated user, the
+// auth middleware calls it so auth() and guest() know who is logged in.
+func WithUser(r *http.Request, user interface{}) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), userKey{}, user))
+}
+
+// NewRenderContext creates the RenderContext of the request.
+func NewRenderContext(r *http.Request) *RenderContext {
+	ctx := &RenderContext{
+		URL:       r.URL.String(),
+		Path:      r.URL.Path,
+		Method:    r.Method,
+		Query:     make(map[string]string),
+		Header:    r.Header,
+		Locale:    preferredLocale(r.Header.Get("Accept-Language")),
+		UserAgent: r.UserAgent(),
+		IsHTMX:    r.Header.Get("HX-Request") == "true",
+	}
+
+	if nonce, isString := r.Context().Value(nonceKey{}).(string); isString {
+		ctx.Nonce = nonce
+	}
+
+	ctx.User = r.Context().Value(userKey{})
+
+	for key, values := range r.URL.Query() {
+		if len(values) > 0 {
+			ctx.Query[key] = values[0]
+		}
+	}
+
+	return ctx
+}
+
+// preferredLocale returns the first locale of an Accept-Language header,
+// e.g. "en-US" for "en-US,en;q=0.9".
+func preferredLocale(acceptLanguage string) string {
+	locale := strings.Split(acceptLanguage, ",")[0]
+	locale = strings.Split(locale, ";")[0]
+
+	return strings.TrimSpace(locale)
+}
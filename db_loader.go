@@ -0,0 +1,139 @@
+package lamb
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync"
+	"time"
+)
+
+// DBLoader loads the templates from a table of a database, e.g. the templates
+// managed by a CMS, with a column for their name, one for their source and
+// one for their version:
+//
+//	CREATE TABLE templates (name VARCHAR(255) PRIMARY KEY, source TEXT, version INTEGER)
+//
+// The sources are cached by their version: a cached source is used for TTL,
+// then the loader reads the version of the template again and reads its
+// source only if the version changed, so an update of a template must change
+// its version. It can be chained with the FileLoader, see ChainLoader.
+//
+// A DBLoader must not be copied after its first use.
+type DBLoader struct {
+	DB *sql.DB
+
+	Table         string // The table of the templates, templates by default.
+	NameColumn    string // name by default.
+	SourceColumn  string // source by default.
+	VersionColumn string // version by default.
+
+	// Placeholder is the placeholder of the name in the queries, ? by default
+	// ($1 for PostgreSQL).
+	Placeholder string
+
+	// TTL is how long a cached source is used before its version is checked,
+	// the version is checked for every load if it is 0.
+	TTL time.Duration
+
+	mu    sync.Mutex
+	cache map[string]dbTemplate
+}
+
+// dbTemplate is a source cached by a DBLoader.
+type dbTemplate struct {
+	source  []byte
+	version string
+	checked time.Time
+}
+
+// NewDBLoader returns a loader of the templates of the table, with the
+// default columns.
+func NewDBLoader(db *sql.DB, table string) *DBLoader {
+	return &DBLoader{DB: db, Table: table}
+}
+
+// Load returns the source of the template, its error is fs.ErrNotExist if the
+// table does not have it.
+func (l *DBLoader) Load(name string) ([]byte, error) {
+	l.mu.Lock()
+	cached, isCached := l.cache[name]
+	l.mu.Unlock()
+
+	if isCached && time.Since(cached.checked) < l.TTL {
+		return cached.source, nil
+	}
+
+	// the source is read again only if its version changed
+	if isCached {
+		var version string
+
+		err := l.DB.QueryRow(l.query(orDefault(l.VersionColumn, "version")), name).Scan(&version)
+
+		if err != nil {
+			return nil, l.loadError(name, err)
+		}
+
+		if version == cached.version {
+			l.store(name, dbTemplate{source: cached.source, version: version, checked: time.Now()})
+
+			return cached.source, nil
+		}
+	}
+
+	var (
+		source  []byte
+		version string
+	)
+
+	columns := orDefault(l.SourceColumn, "source") + ", " + orDefault(l.VersionColumn, "version")
+
+	if err := l.DB.QueryRow(l.query(columns), name).Scan(&source, &version); err != nil {
+		return nil, l.loadError(name, err)
+	}
+
+	l.store(name, dbTemplate{source: source, version: version, checked: time.Now()})
+
+	return source, nil
+}
+
+// query returns the query of the columns of a template.
+func (l *DBLoader) query(columns string) string {
+	return "SELECT " + columns + " FROM " + orDefault(l.Table, "templates") +
+		" WHERE " + orDefault(l.NameColumn, "name") + " = " + orDefault(l.Placeholder, "?")
+}
+
+// orDefault returns the setting, or its default if it is empty.
+func orDefault(setting string, defaultSetting string) string {
+	if setting == "" {
+		return defaultSetting
+	}
+
+	return setting
+}
+
+func (l *DBLoader) store(name string, template dbTemplate) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.cache == nil {
+		l.cache = make(map[string]dbTemplate)
+	}
+
+	l.cache[name] = template
+}
+
+// loadError returns the error of the load of the template, a template that is
+// not in the table is fs.ErrNotExist (and no longer cached).
+func (l *DBLoader) loadError(name string, err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		l.mu.Lock()
+		delete(l.cache, name)
+		l.mu.Unlock()
+
+		return fmt.Errorf("lamb: template %s does not exist in the database: %w", name, fs.ErrNotExist)
+	}
+
+	return fmt.Errorf("lamb: template %s can not be loaded from the database: %w", name, err)
+}
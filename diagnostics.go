@@ -0,0 +1,41 @@
+package lamb
+
+import (
+	"errors"
+	"io"
+
+	"github.com/govel-framework/lamb/evaluator"
+	"github.com/govel-framework/lamb/internal"
+	"github.com/govel-framework/lamb/object"
+)
+
+// Diagnostic is a problem of a render that does not stop it:
+//
+//   - unused-var: a var passed to the template that it never reads
+//   - unused-section: a section the layout does not define
+//   - deprecated: a call to a builtin with Deprecated set
+//   - large-loop: a for loop over more elements than lamb.large_loop (10000)
+//
+// The renders log them as they are found. With lamb.diagnostics on, a render
+// collects them, checks the unused vars and logs them once it ends, and in
+// the debug mode adds them to its HTML output as an overlay.
+type Diagnostic = object.Diagnostic
+
+// RenderDiagnostics renders a lamb template to w like RenderTo and returns
+// its diagnostics (they are not logged), e.g. to check the templates in the
+// tests.
+func RenderDiagnostics(w io.Writer, file string, vars map[string]interface{}) ([]Diagnostic, error) {
+	env := object.NewEnvironment()
+	env.Diagnostics = &object.Diagnostics{}
+
+	err := internal.LoadFile(internal.ResolveRender(file, vars), vars, w, evaluator.Eval, *env)
+
+	// dd() replaces the output with its dump
+	var halt *object.HaltError
+
+	if errors.As(err, &halt) {
+		_, err = w.Write([]byte(halt.Output))
+	}
+
+	return env.Diagnostics.List(), err
+}
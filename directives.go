@@ -0,0 +1,69 @@
+package lamb
+
+import (
+	"fmt"
+
+	"github.com/govel-framework/lamb/ast"
+	"github.com/govel-framework/lamb/evaluator"
+	"github.com/govel-framework/lamb/object"
+	"github.com/govel-framework/lamb/parser"
+	"github.com/govel-framework/lamb/token"
+)
+
+// Directive is a keyword added to the templates by AddDirective.
+type Directive struct {
+	Keyword string   // e.g. feature, it can no longer be used as a var name
+	Ends    []string // The keywords that end its block, e.g. endfeature, none if it has no block
+
+	// Parse parses the directive, nil parses the keyword followed by optional
+	// arguments between parentheses and, if there are Ends, a block.
+	Parse func(p *parser.DirectiveParser, directive *ast.DirectiveStatement) bool
+
+	// Eval returns the output of the directive, its arguments and block are
+	// evaluated with evaluator.Eval.
+	Eval func(directive *ast.DirectiveStatement, env *object.Environment) (string, error)
+}
+
+// AddDirective adds a directive to the templates parsed after the call:
+//
+//	lamb.AddDirective(lamb.Directive{
+//		Keyword: "feature",
+//		Ends:    []string{"endfeature"},
+//		Eval: func(d *ast.DirectiveStatement, env *object.Environment) (string, error) {
+//			flag, isString := evaluator.Eval(d.Arguments[0], env).(string)
+//
+//			if !isString || !features.Enabled(flag) {
+//				return "", nil
+//			}
+//
+//			output := evaluator.Eval(d.Block, env)
+//
+//			if err, isError := output.(error); isError {
+//				return "", err
+//			}
+//
+//			return output.(string), nil
+//		},
+//	})
+//
+// so {? feature("new-checkout") ?} ... {? endfeature ?} renders its block only
+// when the flag is enabled. It panics if the keyword is already a keyword.
+func AddDirective(d Directive) {
+	keyword, registered := token.RegisterKeyword(d.Keyword)
+
+	if !registered {
+		panic(fmt.Sprintf("lamb: directive %s is already a keyword", d.Keyword))
+	}
+
+	// an end can be shared with other directives (e.g. end)
+	var ends []token.TokenType
+
+	for _, end := range d.Ends {
+		token.RegisterKeyword(end)
+
+		ends = append(ends, token.LookUpIdent(end))
+	}
+
+	parser.RegisterDirective(keyword, ends, d.Parse)
+	evaluator.RegisterDirective(d.Keyword, d.Eval)
+}
@@ -0,0 +1,34 @@
+// Package lamb is the template engine of govel.
+//
+// Render and RenderTo can be called by many goroutines at once: every render
+// has its own vars and scopes, and the state shared by the renders (the config
+// set by Init, the functions, the shared vars, the translations, the sandboxes
+// and the hooks) is guarded, so it can also be changed while templates are
+// being rendered.
+//
+// With lamb.compile on, a template is parsed once while its source does not
+// change, and compiled to an IR run by a small stack machine: its vars are
+// resolved to slots and its builtins are bound when it is compiled. The
+// compiled templates render like the others, and nothing is compiled while
+// there are statement hooks.
+//
+// The lamb generate command compiles templates ahead of time to Go functions,
+// registered with RegisterGenerated when their package is imported: they are
+// rendered without loading nor parsing the templates.
+//
+// The values written by a template are not escaped, unless it has an output
+// mode: a .lamb.xml file or {? autoescape("xml") ?} escapes them for XML (e.g.
+// a sitemap or an RSS feed), and a .lamb.json file or {? autoescape("json") ?}
+// writes them encoded as JSON. The output of the blocks, the includes and the
+// sections is written as is.
+//
+// RenderSection and RenderSectionTo render a single section or define of a
+// template, e.g. the fragment swapped by an HTMX or Turbo request, so the
+// page and its fragments come from the same template.
+//
+// The problems that do not stop a render (a section the layout does not
+// define, a deprecated builtin, a large loop) are logged as diagnostics. With
+// lamb.diagnostics on, the renders also report the vars they do not use, and
+// show their diagnostics in an overlay in the debug mode; RenderDiagnostics
+// returns them.
+package lamb
@@ -0,0 +1,30 @@
+package lamb
+
+import (
+	"bytes"
+
+	"github.com/govel-framework/lamb/internal"
+)
+
+// Email is a template rendered as an email by RenderEmail.
+type Email struct {
+	HTML string // The HTML, with its CSS inlined.
+	Text string // The plain text alternative, generated from the HTML.
+}
+
+// RenderEmail renders a lamb template as an email, outside of a request like
+// RenderTo: the rules of its style blocks are inlined in the style attributes
+// of its elements (most email clients ignore the style blocks), and its plain
+// text alternative is generated from the same output, so an email needs a
+// single template.
+func RenderEmail(name string, vars map[string]interface{}) (*Email, error) {
+	var out bytes.Buffer
+
+	if err := RenderTo(&out, name, vars); err != nil {
+		return nil, err
+	}
+
+	html := internal.InlineCSS(out.String())
+
+	return &Email{HTML: html, Text: internal.PlainText(html)}, nil
+}
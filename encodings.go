@@ -0,0 +1,87 @@
+package lamb
+
+import (
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/govel-framework/lamb/internal"
+)
+
+// RegisterEncoding registers the encoder of a content coding (e.g. br with a
+// brotli package) for the cached renders: their output is compressed with
+// every encoding when it is cached, and RenderHTTP serves the variant of the
+// Accept-Encoding of the request. gzip is built in, and the encodings
+// registered later are preferred when the request accepts several of them as
+// much. A nil encoder removes the encoding.
+func RegisterEncoding(name string, encoder func(w io.Writer) io.WriteCloser) {
+	internal.AddEncoding(name, encoder)
+}
+
+// cachedVariant returns the encoding of the Accept-Encoding header that the
+// cached output of the template has a fresh variant for, and the variant.
+// The encoding is empty if there is none. The name of the template is already
+// resolved.
+func cachedVariant(file string, acceptEncoding string) (string, []byte) {
+	// the output of a middleware is not the cached one
+	if acceptEncoding == "" || internal.HasMiddlewares(file) {
+		return "", nil
+	}
+
+	var (
+		best    string
+		bestQ   float64
+		variant []byte
+	)
+
+	for _, encoding := range internal.Encodings() {
+		q := acceptQuality(acceptEncoding, encoding)
+
+		if q <= bestQ {
+			continue
+		}
+
+		if content, exists := internal.CachedVariant(file, encoding); exists {
+			best, bestQ, variant = encoding, q, content
+		}
+	}
+
+	return best, variant
+}
+
+// acceptQuality returns the quality (the q param) of the encoding in the
+// Accept-Encoding header, 0 if it is not accepted.
+func acceptQuality(acceptEncoding string, encoding string) float64 {
+	var wildcard float64
+
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		params := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(params[0]))
+
+		if coding != encoding && coding != "*" {
+			continue
+		}
+
+		q := 1.0
+
+		for _, param := range params[1:] {
+			if key, value, found := strings.Cut(strings.TrimSpace(param), "="); found && key == "q" {
+				q, _ = strconv.ParseFloat(value, 64)
+			}
+		}
+
+		if coding == encoding {
+			return q
+		}
+
+		wildcard = q
+	}
+
+	return wildcard
+}
+
+// variantETag returns the ETag of the variant of the output with the
+// encoding, a strong ETag is different for every content coding.
+func variantETag(etag string, encoding string) string {
+	return strings.TrimSuffix(etag, `"`) + "-" + encoding + `"`
+}
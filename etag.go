@@ -0,0 +1,50 @@
+package lamb
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/govel-framework/lamb/internal"
+)
+
+// CachedETag returns the strong ETag of the cached output of the template
+// (rendered with the __cache var), and reports whether the template has a
+// cached output that is still fresh. RenderHTTP answers the conditional
+// requests with it, a custom integration can compare it with the
+// If-None-Match header of a request before rendering the template. The name
+// of the template is resolved like the one of a render with the vars, see
+// Resolver.
+func CachedETag(file string, vars map[string]interface{}) (string, bool) {
+	return internal.CachedETag(internal.ResolveRender(file, vars))
+}
+
+// renderETag returns the ETag of the render of the template with the vars,
+// empty if the render is not cached or its output is not cached yet. The name
+// of the template is already resolved.
+func renderETag(file string, vars map[string]interface{}) string {
+	if cache, _ := vars["__cache"].(string); cache == "" {
+		return ""
+	}
+
+	etag, _ := internal.CachedETag(file)
+
+	return etag
+}
+
+// notModified reports whether the request already has the output with the
+// ETag, see If-None-Match.
+func notModified(r *http.Request, etag string) bool {
+	if etag == "" || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+		return false
+	}
+
+	for _, candidate := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+
+	return false
+}
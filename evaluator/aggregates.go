@@ -0,0 +1,209 @@
+package evaluator
+
+import (
+	"reflect"
+
+	"github.com/govel-framework/lamb/object"
+)
+
+// aggregateValues returns the elements of the slice of the first argument or,
+// when a field is given as second argument, that field of every element.
+func aggregateValues(name string, env *object.Environment, args []interface{}) ([]interface{}, error) {
+	if err := checkArgs(name, args, 1, 2); err != nil {
+		return nil, err
+	}
+
+	list, err := sliceArg(name, args, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]interface{}, list.Len())
+
+	for i := range values {
+		values[i] = list.Index(i).Interface()
+	}
+
+	if len(args) == 1 {
+		return values, nil
+	}
+
+	field, err := stringArg(name, args, 1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range values {
+		if values[i], err = fieldValue(env, values[i], field); err != nil {
+			return nil, err
+		}
+	}
+
+	return values, nil
+}
+
+// sumValues adds the numbers, the result is an int unless one of them is a
+// float.
+func sumValues(name string, values []interface{}) (interface{}, error) {
+	intSum := 0
+	floatSum := 0.0
+	isFloat := false
+
+	for _, value := range values {
+		number, isNumber := toFloat(value)
+
+		if !isNumber {
+			return nil, builtInError("cannot add %T in `%s`, want=number", value, name)
+		}
+
+		switch reflect.ValueOf(value).Kind() {
+		case reflect.Float32, reflect.Float64:
+			isFloat = true
+
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			intSum += int(reflect.ValueOf(value).Uint())
+
+		default:
+			intSum += int(reflect.ValueOf(value).Int())
+		}
+
+		floatSum += number
+	}
+
+	if isFloat {
+		return floatSum, nil
+	}
+
+	return intSum, nil
+}
+
+// sumBuiltIn adds the numbers of a slice: sum(prices) or sum(items, "Price").
+func sumBuiltIn(env *object.Environment, args ...interface{}) interface{} {
+	values, err := aggregateValues("sum", env, args)
+
+	if err != nil {
+		return err
+	}
+
+	sum, err := sumValues("sum", values)
+
+	if err != nil {
+		return err
+	}
+
+	return sum
+}
+
+// avgBuiltIn returns the average of the numbers of a slice as a float, nil
+// when the slice is empty.
+func avgBuiltIn(env *object.Environment, args ...interface{}) interface{} {
+	values, err := aggregateValues("avg", env, args)
+
+	if err != nil {
+		return err
+	}
+
+	if len(values) == 0 {
+		return nil
+	}
+
+	sum, err := sumValues("avg", values)
+
+	if err != nil {
+		return err
+	}
+
+	total, _ := toFloat(sum)
+
+	return total / float64(len(values))
+}
+
+// minBuiltIn returns the lowest number, string or time of a slice (nil when
+// the slice is empty) or of its arguments: min(price, 100).
+func minBuiltIn(env *object.Environment, args ...interface{}) interface{} {
+	return extremeValue("min", env, args, -1)
+}
+
+// maxBuiltIn returns the highest number, string or time of a slice (nil when
+// the slice is empty) or of its arguments: max(stock, 0).
+func maxBuiltIn(env *object.Environment, args ...interface{}) interface{} {
+	return extremeValue("max", env, args, 1)
+}
+
+func extremeValue(name string, env *object.Environment, args []interface{}, want int) interface{} {
+	values := args
+	scalars := false
+
+	// the values are the arguments unless the first one is a slice
+	if len(args) > 1 {
+		_, scalars = toFloat(args[0])
+	}
+
+	if !scalars {
+		var err error
+
+		if values, err = aggregateValues(name, env, args); err != nil {
+			return err
+		}
+	}
+
+	var result interface{}
+
+	for i, value := range values {
+		if i == 0 {
+			result = value
+
+			continue
+		}
+
+		order, comparable := compare(value, result)
+
+		if !comparable {
+			return builtInError("cannot compare %T and %T in `%s`", value, result, name)
+		}
+
+		if order == want {
+			result = value
+		}
+	}
+
+	return result
+}
+
+// countIfBuiltIn counts the elements of a slice for which the lambda is
+// truthy: count_if(orders, o => o.Paid).
+func countIfBuiltIn(args ...interface{}) interface{} {
+	if err := checkArgs("count_if", args, 2, 2); err != nil {
+		return err
+	}
+
+	list, err := sliceArg("count_if", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	fn, err := lambdaArg("count_if", args, 1)
+
+	if err != nil {
+		return err
+	}
+
+	count := 0
+
+	for i := 0; i < list.Len(); i++ {
+		result := callLambda(fn, list.Index(i).Interface())
+
+		if isError(result) {
+			return result
+		}
+
+		if isTruthy(result) {
+			count++
+		}
+	}
+
+	return count
+}
@@ -0,0 +1,216 @@
+package evaluator
+
+import (
+	"math"
+	"reflect"
+
+	"github.com/govel-framework/lamb/token"
+)
+
+// isNumber returns the value of a signed or unsigned integer as an int, the
+// uints that do not fit in an int are not integers.
+func isNumber(num interface{}) (int, bool) {
+	switch num := num.(type) {
+	case int:
+		return num, true
+
+	case nil, string, bool, float64:
+		return 0, false
+	}
+
+	valueOf := reflect.ValueOf(num)
+
+	switch valueOf.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(valueOf.Int()), true
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if valueOf.Uint() > math.MaxInt {
+			return 0, false
+		}
+
+		return int(valueOf.Uint()), true
+
+	default:
+		return 0, false
+	}
+}
+
+// toFloat converts any number into a float64.
+func toFloat(value interface{}) (float64, bool) {
+	switch value := value.(type) {
+	case int:
+		return float64(value), true
+
+	case float64:
+		return value, true
+
+	case nil, string, bool:
+		return 0, false
+	}
+
+	valueOf := reflect.ValueOf(value)
+
+	switch valueOf.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(valueOf.Int()), true
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(valueOf.Uint()), true
+
+	case reflect.Float32, reflect.Float64:
+		return valueOf.Float(), true
+
+	default:
+		return 0, false
+	}
+}
+
+// evalNumberInfixExpression evaluates an operation between two numbers of any
+// kind: the integers give an int (but a division that is not exact gives a
+// float) and the rest a float64.
+func evalNumberInfixExpression(operator string, left, right interface{}, t token.Token) interface{} {
+	leftInt, isLeftInt := isNumber(left)
+	rightInt, isRightInt := isNumber(right)
+
+	if isLeftInt && isRightInt && (operator != "/" || rightInt == 0 || leftInt%rightInt == 0) {
+		return evalIntegerInfixExpression(operator, leftInt, rightInt, t)
+	}
+
+	leftFloat, _ := toFloat(left)
+	rightFloat, _ := toFloat(right)
+
+	return evalFloatInfixExpression(operator, leftFloat, rightFloat, t)
+}
+
+func evalIntegerInfixExpression(operator string, leftVal, rightVal int, t token.Token) interface{} {
+	switch operator {
+	case "+":
+		return leftVal + rightVal
+
+	case "-":
+		return leftVal - rightVal
+
+	case "*":
+		return leftVal * rightVal
+
+	case "/":
+		if rightVal == 0 {
+			return newError(t, "division by zero")
+		}
+
+		return leftVal / rightVal
+
+	case "**":
+		return power(leftVal, rightVal)
+
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+
+	default:
+		return newError(t, "unknown operator: %T %s %T", leftVal, operator, rightVal)
+	}
+}
+
+// power raises base to exp, a negative exponent or a result that overflows an
+// int gives a float.
+func power(base, exp int) interface{} {
+	if exp < 0 {
+		return math.Pow(float64(base), float64(exp))
+	}
+
+	result, square := 1, base
+
+	for e := exp; e > 0; e >>= 1 {
+		var ok bool
+
+		if e&1 == 1 {
+			if result, ok = multiply(result, square); !ok {
+				return math.Pow(float64(base), float64(exp))
+			}
+		}
+
+		// the last square is not used, it can overflow
+		if e > 1 {
+			if square, ok = multiply(square, square); !ok {
+				return math.Pow(float64(base), float64(exp))
+			}
+		}
+	}
+
+	return result
+}
+
+// multiply returns a * b, and false if it overflows an int.
+func multiply(a, b int) (int, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+
+	product := a * b
+
+	if product/b != a || (a == -1 && b == math.MinInt) || (b == -1 && a == math.MinInt) {
+		return 0, false
+	}
+
+	return product, true
+}
+
+func evalFloatInfixExpression(operator string, leftVal, rightVal float64, t token.Token) interface{} {
+	switch operator {
+	case "+":
+		return leftVal + rightVal
+
+	case "-":
+		return leftVal - rightVal
+
+	case "*":
+		return leftVal * rightVal
+
+	case "/":
+		if rightVal == 0 {
+			return newError(t, "division by zero")
+		}
+
+		return leftVal / rightVal
+
+	case "**":
+		return math.Pow(leftVal, rightVal)
+
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+
+	default:
+		return newError(t, "unknown operator: %T %s %T", leftVal, operator, rightVal)
+	}
+}
@@ -0,0 +1,251 @@
+package evaluator
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/govel-framework/lamb/internal"
+)
+
+// assetManifest is the build manifest (Vite or webpack) read by asset(), it is
+// read again when the file changes.
+var assetManifest struct {
+	sync.Mutex
+
+	file    string
+	modTime time.Time
+	entries map[string]string
+}
+
+// assetDigest holds the hashes of an asset file.
+type assetDigest struct {
+	modTime   time.Time
+	version   string // The start of the SHA-256 in hex.
+	integrity string // The SHA-384 for the integrity attribute.
+}
+
+// assetDigests caches the hashes of the asset files by path, until they
+// change.
+var assetDigests sync.Map
+
+// fileDigest returns the hashes of the file.
+func fileDigest(file string) (assetDigest, error) {
+	stat, err := os.Stat(file)
+
+	if err != nil {
+		return assetDigest{}, err
+	}
+
+	if cached, exists := assetDigests.Load(file); exists && cached.(assetDigest).modTime.Equal(stat.ModTime()) {
+		return cached.(assetDigest), nil
+	}
+
+	content, err := os.ReadFile(file)
+
+	if err != nil {
+		return assetDigest{}, err
+	}
+
+	version := sha256.Sum256(content)
+	integrity := sha512.Sum384(content)
+
+	digest := assetDigest{
+		modTime:   stat.ModTime(),
+		version:   hex.EncodeToString(version[:])[:12],
+		integrity: "sha384-" + base64.StdEncoding.EncodeToString(integrity[:]),
+	}
+
+	assetDigests.Store(file, digest)
+
+	return digest, nil
+}
+
+// assetFile returns the path of the asset in lamb.assets.dir.
+func assetFile(asset string) string {
+	return filepath.Join(internal.Setting("GOVEL_LAMB_ASSETS_DIR"), filepath.FromSlash(asset))
+}
+
+// manifestEntry returns the built file of the asset in the manifest set in
+// lamb.assets.manifest.
+func manifestEntry(asset string) (string, bool, error) {
+	file := internal.Setting("GOVEL_LAMB_ASSETS_MANIFEST")
+
+	if file == "" {
+		return "", false, nil
+	}
+
+	stat, err := os.Stat(file)
+
+	if err != nil {
+		return "", false, err
+	}
+
+	assetManifest.Lock()
+	defer assetManifest.Unlock()
+
+	if assetManifest.file != file || !assetManifest.modTime.Equal(stat.ModTime()) {
+		entries, err := readManifest(file)
+
+		if err != nil {
+			return "", false, err
+		}
+
+		assetManifest.file = file
+		assetManifest.modTime = stat.ModTime()
+		assetManifest.entries = entries
+	}
+
+	entry, exists := assetManifest.entries[asset]
+
+	return entry, exists, nil
+}
+
+// readManifest reads a manifest of Vite ({"app.js": {"file": "app-4ed93.js"}})
+// or webpack ({"app.js": "app.4ed93.js"}).
+func readManifest(file string) (map[string]string, error) {
+	content, err := os.ReadFile(file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest map[string]json.RawMessage
+
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, err
+	}
+
+	entries := make(map[string]string, len(manifest))
+
+	for asset, raw := range manifest {
+		var entry struct {
+			File string `json:"file"`
+		}
+
+		var name string
+
+		if err := json.Unmarshal(raw, &name); err == nil {
+			entries[asset] = name
+		} else if err := json.Unmarshal(raw, &entry); err == nil && entry.File != "" {
+			entries[asset] = entry.File
+		}
+	}
+
+	return entries, nil
+}
+
+// fileVersion returns the version of the asset file set in
+// lamb.assets.version: its modification time ("mtime") or the start of the
+// hash of its content ("hash"). It is empty if the file does not exist.
+func fileVersion(asset string) string {
+	mode := internal.Setting("GOVEL_LAMB_ASSETS_VERSION")
+
+	if mode == "" {
+		return ""
+	}
+
+	file := assetFile(asset)
+
+	if mode == "mtime" {
+		stat, err := os.Stat(file)
+
+		if err != nil {
+			return ""
+		}
+
+		return strconv.FormatInt(stat.ModTime().Unix(), 10)
+	}
+
+	digest, err := fileDigest(file)
+
+	if err != nil {
+		return ""
+	}
+
+	return digest.version
+}
+
+// versionedAsset returns the path of the asset that changes with each build:
+// the hashed file of the manifest or, without a manifest entry, the file with
+// its version in the query string.
+func versionedAsset(asset string) (string, bool, error) {
+	entry, exists, err := manifestEntry(asset)
+
+	if err != nil {
+		return "", false, builtInError("cannot read the assets manifest: %s", err)
+	}
+
+	if exists {
+		// absolute paths and URLs are not under the static path
+		absolute := strings.HasPrefix(entry, "/") || strings.Contains(entry, "://")
+
+		return entry, absolute, nil
+	}
+
+	if version := fileVersion(asset); version != "" {
+		return asset + "?v=" + version, false, nil
+	}
+
+	return asset, false, nil
+}
+
+// cdnURL prefixes the path of an asset with the host set in lamb.assets.cdn.
+func cdnURL(path string) string {
+	cdn := internal.Setting("GOVEL_LAMB_ASSETS_CDN")
+
+	if cdn == "" || strings.Contains(path, "://") {
+		return path
+	}
+
+	return strings.TrimSuffix(cdn, "/") + "/" + strings.TrimPrefix(path, "/")
+}
+
+// sriBuiltIn returns the integrity and crossorigin attributes of an asset, so
+// the browser checks the file served by the CDN is the local one:
+//
+//	<script src="{? asset("app.js") ?}" {? sri("app.js") ?}></script>
+//
+// The hash is computed from the file in lamb.assets.dir (the built file when
+// the asset is in the manifest) and cached until the file changes.
+func sriBuiltIn(args ...interface{}) interface{} {
+	if err := checkArgs("sri", args, 1, 1); err != nil {
+		return err
+	}
+
+	asset, err := stringArg("sri", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	entry, exists, err := manifestEntry(asset)
+
+	if err != nil {
+		return builtInError("cannot read the assets manifest: %s", err)
+	}
+
+	if exists {
+		if strings.Contains(entry, "://") {
+			return builtInError("cannot compute the integrity of %s, it is not a local file", entry)
+		}
+
+		asset = strings.TrimPrefix(entry, "/")
+	}
+
+	digest, err := fileDigest(assetFile(asset))
+
+	if err != nil {
+		return builtInError("cannot compute the integrity of %s: %s", asset, err)
+	}
+
+	return `integrity="` + digest.integrity + `" crossorigin="anonymous"`
+}
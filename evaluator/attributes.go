@@ -0,0 +1,97 @@
+package evaluator
+
+import (
+	"fmt"
+	"html"
+	"reflect"
+	"strings"
+)
+
+// htmlAttributes returns the markup of the attributes of a map, sorted by
+// name. A true value is a boolean attribute and a false or nil one is left
+// out.
+func htmlAttributes(attributes reflect.Value) string {
+	var out strings.Builder
+
+	for _, key := range sortedKeys(attributes) {
+		value := attributes.MapIndex(key).Interface()
+
+		if boolean, isBool := value.(bool); isBool && !boolean || isNil(value) {
+			continue
+		}
+
+		out.WriteString(" " + html.EscapeString(fmt.Sprintf("%v", key.Interface())))
+
+		if value == true {
+			continue
+		}
+
+		out.WriteString(`="` + html.EscapeString(fmt.Sprintf("%v", value)) + `"`)
+	}
+
+	return out.String()
+}
+
+// classList returns the classes of a map whose values are true, sorted.
+func classList(classes reflect.Value) string {
+	var list []string
+
+	for _, key := range sortedKeys(classes) {
+		if isTruthy(classes.MapIndex(key).Interface()) {
+			list = append(list, fmt.Sprintf("%v", key.Interface()))
+		}
+	}
+
+	return strings.Join(list, " ")
+}
+
+// classBuiltIn returns the classes whose condition is true:
+//
+//	<a class="{? class({"active": request().is("posts/*"), "link": true}) ?}">
+func classBuiltIn(args ...interface{}) interface{} {
+	if err := checkArgs("class", args, 1, 1); err != nil {
+		return err
+	}
+
+	classes, err := mapArg("class", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	return html.EscapeString(classList(classes))
+}
+
+// attrsBuiltIn returns the escaped markup of the attributes of a map:
+//
+//	<button {? attrs({"disabled": !user.Active, "data-id": user.ID}) ?}>
+//
+// A true value is a boolean attribute, a false or nil one is left out and the
+// class can be a map of conditions like the one of class().
+func attrsBuiltIn(args ...interface{}) interface{} {
+	if err := checkArgs("attrs", args, 1, 1); err != nil {
+		return err
+	}
+
+	attributes, err := mapArg("attrs", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	if class := attributes.MapIndex(reflect.ValueOf("class")); class.IsValid() {
+		if classes := reflect.ValueOf(class.Interface()); classes.Kind() == reflect.Map {
+			copied := make(map[interface{}]interface{})
+
+			for _, key := range attributes.MapKeys() {
+				copied[key.Interface()] = attributes.MapIndex(key).Interface()
+			}
+
+			copied["class"] = classList(classes)
+
+			attributes = reflect.ValueOf(copied)
+		}
+	}
+
+	return strings.TrimPrefix(htmlAttributes(attributes), " ")
+}
@@ -0,0 +1,54 @@
+package evaluator
+
+import (
+	"github.com/govel-framework/lamb/object"
+)
+
+// authUser returns the authenticated user of the request (ctx.User), nil for
+// guests.
+func authUser(env *object.Environment) interface{} {
+	user, exists := contextField(env, "User")
+
+	if !exists || !user.CanInterface() || isNil(user.Interface()) {
+		return nil
+	}
+
+	return user.Interface()
+}
+
+// authBuiltIn returns the auth state of the request:
+//
+//	{? if auth().check() ?}Hi, {? auth().user().Name ?}{? endif ?}
+func authBuiltIn(env *object.Environment, args ...interface{}) interface{} {
+	if err := checkArgs("auth", args, 0, 0); err != nil {
+		return err
+	}
+
+	user := authUser(env)
+
+	return map[string]interface{}{
+		"check": &object.Builtin{Fn: func(args ...interface{}) interface{} {
+			if err := checkArgs("check", args, 0, 0); err != nil {
+				return err
+			}
+
+			return user != nil
+		}},
+		"user": &object.Builtin{Fn: func(args ...interface{}) interface{} {
+			if err := checkArgs("user", args, 0, 0); err != nil {
+				return err
+			}
+
+			return user
+		}},
+	}
+}
+
+// guestBuiltIn reports whether nobody is logged in.
+func guestBuiltIn(env *object.Environment, args ...interface{}) interface{} {
+	if err := checkArgs("guest", args, 0, 0); err != nil {
+		return err
+	}
+
+	return authUser(env) == nil
+}
@@ -2,10 +2,15 @@ package evaluator
 
 import (
 	"fmt"
+	"math"
+	"net/url"
 	"reflect"
+	"strings"
+	"unicode"
 
 	"github.com/govel-framework/govel"
 
+	"github.com/govel-framework/lamb/internal"
 	"github.com/govel-framework/lamb/object"
 )
 
@@ -13,9 +18,22 @@ func builtInError(format string, a ...interface{}) error {
 	return fmt.Errorf(format, a...)
 }
 
+// Registry holds the builtin functions available to the templates.
+var Registry *object.BuiltinRegistry
+
+func init() {
+	// set in init, so builtins can use the registry without an initialization cycle
+	Registry = object.NewBuiltinRegistry(Builtins)
+}
+
+// namedRoute returns the URL of a named route of the app, replaced by the
+// tests since govel only registers the routes when it starts the server.
+var namedRoute = govel.Route
+
 // Builtins is a map of builtin functions.
 //
-// DO NOT USE THIS MAP DIRECTLY as it is for private use only.
+// Deprecated: the map is not safe for concurrent use and changes made to it
+// are not seen by the templates, use Registry instead.
 var Builtins = map[string]*object.Builtin{
 	"len": {
 		Fn: lenBuiltIn,
@@ -35,9 +53,270 @@ var Builtins = map[string]*object.Builtin{
 	"config": {
 		Fn: configBuiltIn,
 	},
+	"env": {
+		Fn: envBuiltIn,
+	},
 	"asset": {
 		Fn: assetBuiltIn,
 	},
+	"sri": {
+		Fn: sriBuiltIn,
+	},
+	"csp_nonce": {
+		EnvFn: cspNonceBuiltIn,
+	},
+	"script": {
+		EnvFn: scriptBuiltIn,
+	},
+	"style": {
+		EnvFn: styleBuiltIn,
+	},
+	"csrf_token": {
+		EnvFn: csrfTokenBuiltIn,
+	},
+	"csrf_field": {
+		EnvFn: csrfFieldBuiltIn,
+	},
+	"method_field": {
+		Fn: methodFieldBuiltIn,
+	},
+	"old": {
+		EnvFn: oldBuiltIn,
+	},
+	"errors": {
+		EnvFn: errorsBuiltIn,
+	},
+	"has_error": {
+		EnvFn: hasErrorBuiltIn,
+	},
+	"flash": {
+		EnvFn: flashBuiltIn,
+	},
+	"has_flash": {
+		EnvFn: hasFlashBuiltIn,
+	},
+	"auth": {
+		EnvFn: authBuiltIn,
+	},
+	"guest": {
+		EnvFn: guestBuiltIn,
+	},
+	"request": {
+		EnvFn: requestBuiltIn,
+	},
+	"form_open": {
+		EnvFn: formOpenBuiltIn,
+	},
+	"form_close": {
+		Fn: formCloseBuiltIn,
+	},
+	"input": {
+		EnvFn: inputBuiltIn,
+	},
+	"select": {
+		EnvFn: selectBuiltIn,
+	},
+	"checkbox": {
+		EnvFn: checkboxBuiltIn,
+	},
+	"class": {
+		Fn: classBuiltIn,
+	},
+	"attrs": {
+		Fn: attrsBuiltIn,
+	},
+	"yesno": {
+		Fn: yesnoBuiltIn,
+	},
+	"upper": {
+		Fn: stringBuiltIn("upper", strings.ToUpper),
+	},
+	"lower": {
+		Fn: stringBuiltIn("lower", strings.ToLower),
+	},
+	"title": {
+		Fn: stringBuiltIn("title", title),
+	},
+	"capitalize": {
+		Fn: stringBuiltIn("capitalize", capitalize),
+	},
+	"trim": {
+		Fn: trimBuiltIn("trim", strings.TrimSpace, strings.Trim),
+	},
+	"ltrim": {
+		Fn: trimBuiltIn("ltrim", func(s string) string { return strings.TrimLeftFunc(s, unicode.IsSpace) }, strings.TrimLeft),
+	},
+	"rtrim": {
+		Fn: trimBuiltIn("rtrim", func(s string) string { return strings.TrimRightFunc(s, unicode.IsSpace) }, strings.TrimRight),
+	},
+	"pad_left": {
+		Fn: padBuiltIn("pad_left", true),
+	},
+	"pad_right": {
+		Fn: padBuiltIn("pad_right", false),
+	},
+	"replace": {
+		Fn: replaceBuiltIn,
+	},
+	"split": {
+		Fn: splitBuiltIn,
+	},
+	"join": {
+		Fn: joinBuiltIn,
+	},
+	"repeat": {
+		Fn: repeatBuiltIn,
+	},
+	"contains": {
+		Fn: containsBuiltIn,
+	},
+	"starts_with": {
+		Fn: affixBuiltIn("starts_with", strings.HasPrefix),
+	},
+	"ends_with": {
+		Fn: affixBuiltIn("ends_with", strings.HasSuffix),
+	},
+	"matches": {
+		Fn: matchesBuiltIn,
+	},
+	"truncate": {
+		Fn: truncateBuiltIn,
+	},
+	"excerpt": {
+		Fn: excerptBuiltIn,
+	},
+	"nl2br": {
+		Fn: nl2brBuiltIn,
+	},
+	"date": {
+		EnvFn: dateBuiltIn,
+	},
+	"now": {
+		Fn: nowBuiltIn,
+	},
+	"ago": {
+		Fn: agoBuiltIn,
+	},
+	"currency": {
+		EnvFn: currencyBuiltIn,
+	},
+	"number_format": {
+		EnvFn: numberFormatBuiltIn,
+	},
+	"first_day_of_week": {
+		EnvFn: firstDayOfWeekBuiltIn,
+	},
+	"json": {
+		Fn: jsonBuiltIn,
+	},
+	"json_decode": {
+		Fn: jsonDecodeBuiltIn,
+	},
+	"url_encode": {
+		Fn: urlEncodeBuiltIn,
+	},
+	"query": {
+		Fn: queryBuiltIn,
+	},
+	"url": {
+		EnvFn: urlBuiltIn,
+	},
+	"sort": {
+		Fn: sortBuiltIn,
+	},
+	"sort_by": {
+		EnvFn: sortByBuiltIn,
+	},
+	"reverse": {
+		Fn: reverseBuiltIn,
+	},
+	"unique": {
+		Fn: uniqueBuiltIn,
+	},
+	"first": {
+		Fn: firstBuiltIn,
+	},
+	"last": {
+		Fn: lastBuiltIn,
+	},
+	"keys": {
+		Fn: keysBuiltIn,
+	},
+	"values": {
+		Fn: valuesBuiltIn,
+	},
+	"merge": {
+		Fn: mergeBuiltIn,
+	},
+	"concat": {
+		Fn: concatBuiltIn,
+	},
+	"filter": {
+		Fn: filterBuiltIn,
+	},
+	"map": {
+		Fn: mapBuiltIn,
+	},
+	"pluck": {
+		EnvFn: pluckBuiltIn,
+	},
+	"where": {
+		EnvFn: whereBuiltIn,
+	},
+	"chunk": {
+		Fn: chunkBuiltIn,
+	},
+	"group_by": {
+		EnvFn: groupByBuiltIn,
+	},
+	"sum": {
+		EnvFn: sumBuiltIn,
+	},
+	"avg": {
+		EnvFn: avgBuiltIn,
+	},
+	"min": {
+		EnvFn: minBuiltIn,
+	},
+	"max": {
+		EnvFn: maxBuiltIn,
+	},
+	"abs": {
+		Fn: absBuiltIn,
+	},
+	"ceil": {
+		Fn: roundingBuiltIn("ceil", math.Ceil),
+	},
+	"floor": {
+		Fn: roundingBuiltIn("floor", math.Floor),
+	},
+	"round": {
+		Fn: roundBuiltIn,
+	},
+	"sqrt": {
+		Fn: sqrtBuiltIn,
+	},
+	"pow": {
+		Fn: powBuiltIn,
+	},
+	"count_if": {
+		Fn: countIfBuiltIn,
+	},
+	"dump": {
+		Fn: dumpBuiltIn,
+	},
+	"dd": {
+		Fn: ddBuiltIn,
+	},
+	"t": {
+		EnvFn: tBuiltIn,
+	},
+	"tn": {
+		EnvFn: tnBuiltIn,
+	},
+	"choice": {
+		EnvFn: choiceBuiltIn,
+	},
 }
 
 func lenBuiltIn(args ...interface{}) interface{} {
@@ -45,6 +324,14 @@ func lenBuiltIn(args ...interface{}) interface{} {
 		return builtInError("wrong number of arguments in len. got=%d, want=1", len(args))
 	}
 
+	switch arg := args[0].(type) {
+	case string:
+		return int64(len(arg))
+
+	case []interface{}:
+		return int64(len(arg))
+	}
+
 	valueOf := reflect.ValueOf(args[0])
 
 	switch valueOf.Kind() {
@@ -110,7 +397,7 @@ func rangeBuiltIn(args ...interface{}) interface{} {
 
 	end := args[1]
 
-	if reflect.TypeOf(start).Kind() != reflect.Int || reflect.TypeOf(end).Kind() != reflect.Int {
+	if reflect.ValueOf(start).Kind() != reflect.Int || reflect.ValueOf(end).Kind() != reflect.Int {
 		return builtInError("argument to `range` not supported, got %T, want=int", args[0])
 	}
 
@@ -127,34 +414,34 @@ func rangeBuiltIn(args ...interface{}) interface{} {
 	return result
 }
 
+// routeBuiltIn returns the URL of a named route: route("users.show",
+// {"id": user.ID}, {"absolute": true, "query": {"tab": "posts"}}). The
+// options (optional) make the URL absolute with lamb.url and append a query
+// string.
 func routeBuiltIn(args ...interface{}) interface{} {
-	routeArgs := make(map[interface{}]string)
-
-	// validate the number of arguments, min 1: string, max 2: map[string]string
-	if len(args) < 1 {
-		return builtInError("wrong number of arguments in route. got=%d, want=1", len(args))
+	if err := checkArgs("route", args, 1, 3); err != nil {
+		return err
 	}
 
-	route := args[0]
+	route, err := stringArg("route", args, 0)
 
-	if fmt.Sprintf("%T", route) != "string" {
-		return builtInError("argument to `route` not supported, got %T, want=string", route)
+	if err != nil {
+		return err
 	}
 
-	if len(args) > 2 {
-		return builtInError("wrong number of arguments in route. got=%d, want=2", len(args))
-	}
+	routeArgs := make(map[string]string)
 
-	if len(args) == 2 {
-		rArgs, isMap := args[1].(map[interface{}]interface{})
+	if len(args) > 1 {
+		rArgs, err := mapArg("route", args, 1)
 
-		if !isMap {
-			return builtInError("argument to `route` not supported, got %T, want=map", rArgs)
+		if err != nil {
+			return err
 		}
 
-		for key, value := range rArgs {
-			keyType := reflect.TypeOf(key).Kind()
-			valueType := reflect.TypeOf(value).Kind()
+		for _, key := range rArgs.MapKeys() {
+			value := rArgs.MapIndex(key).Interface()
+			keyType := reflect.ValueOf(key.Interface()).Kind()
+			valueType := reflect.ValueOf(value).Kind()
 
 			if keyType != reflect.String && keyType != reflect.Int {
 				return builtInError("argument to `route` not supported, all elements of map must be strings or integers. got=%s", keyType)
@@ -164,72 +451,128 @@ func routeBuiltIn(args ...interface{}) interface{} {
 				return builtInError("argument to `route` not supported, all elements of map must be strings or integers. got=%s", valueType)
 			}
 
-			routeArgs[key] = fmt.Sprintf("%v", value)
+			routeArgs[fmt.Sprintf("%v", key.Interface())] = fmt.Sprintf("%v", value)
 		}
 	}
 
-	// convert routeArgs (map[interface{}]string) to (map[string]string)
-	routeArgsString := make(map[string]string)
+	routeURL := namedRoute(route, routeArgs)
+
+	if routeURL == "" {
+		return builtInError("route %s does not exist", route)
+	}
+
+	if len(args) < 3 {
+		return routeURL
+	}
+
+	options, err := mapArg("route", args, 2)
+
+	if err != nil {
+		return err
+	}
+
+	absolute := false
+
+	for _, key := range options.MapKeys() {
+		option := fmt.Sprintf("%v", key.Interface())
+		value := options.MapIndex(key).Interface()
+
+		switch option {
+
+		case "query":
+			params := make(url.Values)
+
+			if err := addQueryParams(params, value); err != nil {
+				return builtInError("the query option of `route` must be a map, got %T", value)
+			}
+
+			if len(params) > 0 {
+				routeURL += "?" + params.Encode()
+			}
+
+		case "absolute":
+			isAbsolute, isBool := value.(bool)
+
+			if !isBool {
+				return builtInError("the absolute option of `route` must be a bool, got %T", value)
+			}
+
+			absolute = isAbsolute
 
-	for key, value := range routeArgs {
-		routeArgsString[fmt.Sprintf("%v", key)] = value
+		default:
+			return builtInError("unknown option of `route`: %s", option)
+		}
 	}
 
-	url := govel.Route(route.(string), routeArgsString)
+	if absolute {
+		base := internal.Setting("GOVEL_LAMB_URL")
+
+		if base == "" {
+			return builtInError("absolute routes need the URL of the app, lamb.url is not set")
+		}
 
-	if url == "" {
-		panic(fmt.Sprintf("Route %s not found", route))
+		routeURL = strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(routeURL, "/")
 	}
 
-	return url
+	return routeURL
 }
 
+// configBuiltIn returns a value of the config of the app: config("app.name")
+// or config("features.beta", false). The default (optional) is used when the
+// key does not exist.
 func configBuiltIn(args ...interface{}) interface{} {
-	if len(args) != 1 {
-		return builtInError("wrong number of arguments in config. got=%d, want=1", len(args))
+	if err := checkArgs("config", args, 1, 2); err != nil {
+		return err
 	}
 
-	arg := args[0]
-	argType := reflect.TypeOf(arg).Kind()
+	key, err := stringArg("config", args, 0)
 
-	if argType != reflect.String {
-		return builtInError("argument to `config` not supported, got %s, want=string", argType.String())
+	if err != nil {
+		return err
 	}
 
-	// split the string
-	key := arg.(string)
-
 	exists, value := lookForConfigKeys(govel.GetKeyFromYAML("").(map[interface{}]interface{}), key)
 
 	if !exists {
+		if len(args) == 2 {
+			return args[1]
+		}
+
 		return builtInError("config key not found: %s", key)
 	}
 
-	var returnValue string
-
 	switch value.(type) {
-	case string:
-		returnValue = value.(string)
 
-	case int:
-		returnValue = fmt.Sprintf("%d", value.(int))
+	case string, int, bool, float64, []interface{}:
+		return value
 
 	default:
-		return builtInError("keys %s has not a valid type, only string and int are allowed, got=%s", key, reflect.TypeOf(value))
+		return builtInError("keys %s has not a valid type, only string, int, bool, float and list are allowed, got=%s", key, reflect.TypeOf(value))
 	}
-
-	return returnValue
 }
 
+// assetBuiltIn returns the URL of a static file, with the hashed name of the
+// build manifest or the version of the file, under the CDN host if there is
+// one (see lamb.assets).
 func assetBuiltIn(args ...interface{}) interface{} {
 	if len(args) != 1 {
 		return builtInError("wrong number of arguments in asset. got=%d, want=1", len(args))
 	}
 
-	arg := args[0]
+	arg, isString := args[0].(string)
 
-	if fmt.Sprintf("%T", arg) != "string" {
-		return builtInError("argument to `asset` not supported, got %T, want=string", arg)
+	if !isString {
+		return builtInError("argument to `asset` not supported, got %T, want=string", args[0])
+	}
+
+	arg, absolute, err := versionedAsset(arg)
+
+	if err != nil {
+		return err
+	}
+
+	if absolute {
+		return cdnURL(arg)
 	}
 
 	pathExists, path := lookForConfigKeys(govel.GetKeyFromYAML("").(map[interface{}]interface{}), "static.path")
@@ -240,7 +583,42 @@ func assetBuiltIn(args ...interface{}) interface{} {
 		pathString = path.(string)
 	}
 
-	s := pathString + "/" + arg.(string)
+	s := pathString + "/" + arg
+
+	return cdnURL(s)
+}
+
+// yesnoBuiltIn returns a label for a value: yesno(value, "Yes", "No", "—"),
+// the last label (optional) is used for nil values.
+func yesnoBuiltIn(args ...interface{}) interface{} {
+	if len(args) < 1 || len(args) > 4 {
+		return builtInError("wrong number of arguments in yesno. got=%d, want=1 to 4", len(args))
+	}
+
+	labels := []string{"yes", "no"}
+
+	for i, arg := range args[1:] {
+		label, isString := arg.(string)
+
+		if !isString {
+			return builtInError("argument to `yesno` not supported, got %T, want=string", arg)
+		}
+
+		if i < len(labels) {
+			labels[i] = label
+		} else {
+			labels = append(labels, label)
+		}
+	}
+
+	switch {
+	case args[0] == nil && len(labels) == 3:
+		return labels[2]
+
+	case isTruthy(args[0]):
+		return labels[0]
 
-	return s
+	default:
+		return labels[1]
+	}
 }
@@ -0,0 +1,855 @@
+package evaluator
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/govel-framework/govel"
+	"github.com/govel-framework/lamb/internal"
+	"github.com/govel-framework/lamb/lexer"
+	"github.com/govel-framework/lamb/object"
+	"github.com/govel-framework/lamb/parser"
+)
+
+// builtinTest is a template that calls builtins, the vars it reads and its
+// output or the message of its error.
+type builtinTest struct {
+	input    string
+	vars     map[string]interface{}
+	expected string
+}
+
+func testBuiltins(t *testing.T, tests []builtinTest) {
+	t.Helper()
+
+	for i, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+
+		program := p.ParseProgram()
+
+		if len(p.Errors()) != 0 {
+			t.Errorf("tests[%d] - %s: parse errors: %v", i, tt.input, p.Errors())
+
+			continue
+		}
+
+		env := object.NewEnvironment()
+
+		for name, value := range tt.vars {
+			env.Set(name, value)
+		}
+
+		result := Eval(program, env)
+
+		if err, isError := result.(error); isError {
+			result = err.Error()
+		}
+
+		if result != tt.expected {
+			t.Errorf("tests[%d] - %s: wrong result, expected=%q, got=%q", i, tt.input, tt.expected, result)
+		}
+	}
+}
+
+func TestStringBuiltins(t *testing.T) {
+	testBuiltins(t, []builtinTest{
+		{`{? upper("ça va") ?}|{? lower("ÇA VA") ?}`, nil, "ÇA VA|ça va"},
+		{`{? title("hello wORLD, it's 2nd") ?}|{? capitalize("élan vital") ?}|{? capitalize("") ?}`, nil, "Hello WORLD, It's 2nd|Élan vital|"},
+		{`[{? trim("  a b ") ?}]|{? trim("--a--", "-") ?}|[{? ltrim("  a  ") ?}]|{? rtrim("xxaxx", "x") ?}`, nil, "[a b]|a|[a  ]|xxa"},
+		{`{? pad_left("7", 3, "0") ?}|[{? pad_right("ab", 4) ?}]|{? pad_left("é", 4, "xy") ?}|{? pad_left("abc", 2) ?}`, nil, "007|[ab  ]|xyxé|abc"},
+		{`{? upper(1) ?}`, nil, ": 1: 9: argument 1 to `upper` not supported, got int, want=string"},
+		{`{? lower() ?}`, nil, ": 1: 9: wrong number of arguments in lower. got=0, want=1"},
+		{`{? trim("a", "b", "c") ?}`, nil, ": 1: 8: wrong number of arguments in trim. got=3, want=1 to 2"},
+		{`{? pad_left("a", "3") ?}`, nil, ": 1: 12: argument 2 to `pad_left` not supported, got string, want=int"},
+		{`{? pad_right("a", 3, "") ?}`, nil, ": 1: 13: argument 3 to `pad_right` can not be empty"},
+	})
+}
+
+func TestReplaceSplitJoinRepeat(t *testing.T) {
+	testBuiltins(t, []builtinTest{
+		{`{? replace("a-b-c", "-", "+") ?}|{? replace("ñañ", "ñ", "n") ?}`, nil, "a+b+c|nan"},
+		{`{? len(split("a,b,,c", ",")) ?}|{? join(split("a b", " "), "-") ?}|{? join([1, 2.5, "x"], ", ") ?}`, nil, "4|a-b|1, 2.5, x"},
+		{`{? repeat("ab", 3) ?}|{? repeat("x", 0) ?}|`, nil, "ababab||"},
+		{`{? replace("a", "b") ?}`, nil, ": 1: 11: wrong number of arguments in replace. got=2, want=3"},
+		{`{? split("a", 1) ?}`, nil, ": 1: 9: argument 2 to `split` not supported, got int, want=string"},
+		{`{? join("abc", ",") ?}`, nil, ": 1: 8: argument 1 to `join` not supported, got string, want=list"},
+		{`{? repeat("a", -1) ?}`, nil, ": 1: 10: argument 2 to `repeat` can not be negative, got -1"},
+		{`{? repeat("a", 100000000000) ?}`, nil, ": 1: 10: the string built by `repeat` exceeds the max size of 67108864 bytes"},
+		{`{? repeat("ab", 9223372036854775807) ?}`, nil, ": 1: 10: the string built by `repeat` exceeds the max size of 67108864 bytes"},
+		{`[{? repeat("", 9223372036854775807) ?}]`, nil, "[]"},
+	})
+
+	internal.SetSettings(map[string]string{"GOVEL_LAMB_MAX_OUTPUT_SIZE": "4"})
+	t.Cleanup(func() { internal.SetSettings(nil) })
+
+	testBuiltins(t, []builtinTest{
+		{`{? repeat("ab", 2) ?}`, nil, "abab"},
+		{`{? repeat("ab", 3) ?}`, nil, ": 1: 10: the string built by `repeat` exceeds the max size of 4 bytes"},
+	})
+}
+
+func TestPredicateBuiltins(t *testing.T) {
+	testBuiltins(t, []builtinTest{
+		{`{? contains("lamb", "am") ?}|{? contains([1, 2.0], 2) ?}|{? contains({"a": 1}, "a") ?}|{? contains(m, "b") ?}`, map[string]interface{}{"m": map[string]int{"a": 1}}, "true|true|true|false"},
+		{`{? starts_with("lamb", "la") ?}|{? ends_with("lamb", "la") ?}|{? ends_with("ñu", "u") ?}`, nil, "true|false|true"},
+		{`{? matches("a-12", "^[a-z]-\d+$") ?}|{? matches("a-x", "\d") ?}`, nil, "true|false"},
+		{`{? contains(1, 1) ?}`, nil, ": 1: 12: argument 1 to `contains` not supported, got int, want=string, list or map"},
+		{`{? contains("a", 1) ?}`, nil, ": 1: 12: argument 2 to `contains` not supported, got int, want=string"},
+		{`{? starts_with("a") ?}`, nil, ": 1: 15: wrong number of arguments in starts_with. got=1, want=2"},
+		{`{? ends_with("a", 1) ?}`, nil, ": 1: 13: argument 2 to `ends_with` not supported, got int, want=string"},
+		{`{? matches("a", "(") ?}`, nil, ": 1: 11: invalid pattern in `matches`: error parsing regexp: missing closing ): `(`"},
+	})
+}
+
+func TestTruncateExcerpt(t *testing.T) {
+	testBuiltins(t, []builtinTest{
+		{`{? truncate("héllo wörld", 4) ?}|{? truncate("日本語テキスト", 3, "") ?}|{? truncate("👍👍👍", 2, "...") ?}`, nil, "héll…|日本語|👍👍..."},
+		{`{? truncate("ça va très bien", 9, "...", true) ?}|{? truncate("ça va très", 5, "…", true) ?}|{? truncate("ñu", 2) ?}|{? truncate("ñu", -1) ?}`, nil, "ça va...|ça va…|ñu|ñu"},
+		{`{? excerpt("  un  deux trois ", 2) ?}|{? excerpt("ünë dëüx", 5, "!") ?}|{? excerpt("a b c", 1, " [more]") ?}`, nil, "un deux…|ünë dëüx|a [more]"},
+		{`{? truncate("a") ?}`, nil, ": 1: 12: wrong number of arguments in truncate. got=1, want=2 to 4"},
+		{`{? truncate("a", 1, "…", "yes") ?}`, nil, ": 1: 12: argument 4 to `truncate` not supported, got string, want=bool"},
+		{`{? truncate(1, 1) ?}`, nil, ": 1: 12: argument 1 to `truncate` not supported, got int, want=string"},
+		{`{? excerpt("a", "1") ?}`, nil, ": 1: 11: argument 2 to `excerpt` not supported, got string, want=int"},
+	})
+}
+
+func TestDateBuiltins(t *testing.T) {
+	vars := map[string]interface{}{
+		"t":      time.Date(2023, 9, 3, 22, 5, 1, 0, time.UTC),
+		"past":   time.Now().Add(-3*time.Minute - time.Second),
+		"future": time.Now().Add(49 * time.Hour),
+	}
+
+	testBuiltins(t, []builtinTest{
+		{`{? date(t, "DD MMM YYYY") ?}|{? date(t, "dddd D [of] MMMM, h:mm a") ?}|{? date(t, "2006/01/02") ?}`, vars, "03 Sep 2023|Sunday 3 of September, 10:05 pm|2023/09/03"},
+		{`{? date(t) ?}|{? date(t, "HH:mm Z", "Europe/Madrid") ?}|{? date("2023-09-13", "DD/MM/YY") ?}|{? date(0, "YYYY", "UTC") ?}`, vars, "2023-09-03T22:05:01Z|00:05 +02:00|13/09/23|1970"},
+		{`{? date(now(), "YYYY") ?}`, nil, time.Now().Format("2006")},
+		{`{? ago(past) ?}|{? ago(future) ?}|{? ago(now()) ?}`, vars, "3 minutes ago|in 2 days|just now"},
+		{`{? date("yesterday") ?}`, nil, `: 1: 8: argument to ` + "`date`" + ` not supported, "yesterday" is not a RFC3339 date`},
+		{`{? date(t, "YYYY", "Mars/Base") ?}`, vars, ": 1: 8: unknown timezone in `date`: Mars/Base"},
+		{`{? date(t, 1) ?}`, vars, ": 1: 8: argument 2 to `date` not supported, got int, want=string"},
+		{`{? now(1) ?}`, nil, ": 1: 7: wrong number of arguments in now. got=1, want=0"},
+		{`{? ago(true) ?}`, nil, ": 1: 7: argument to `ago` not supported, got bool, want=time, int or string"},
+	})
+}
+
+func TestCurrency(t *testing.T) {
+	locales := []struct {
+		locale   string
+		expected string
+	}{
+		{"cs-CZ", "-1\u00a0234,50\u00a0€"},
+		{"da-DK", "-1.234,50\u00a0€"},
+		{"de-DE", "-1.234,50\u00a0€"},
+		{"de-AT", "-€1\u00a0234,50"},
+		{"de-CH", "-EUR1’234.50"},
+		{"de-LI", "-EUR1’234.50"},
+		{"es-ES", "-1.234,50\u00a0€"},
+		{"es-419", "-EUR1,234.50"},
+		{"es-MX", "-EUR1,234.50"},
+		{"fi-FI", "-1\u00a0234,50\u00a0€"},
+		{"fr-FR", "-1\u00a0234,50\u00a0€"},
+		{"fr-CA", "-1\u00a0234,50\u00a0€"},
+		{"it-IT", "-1.234,50\u00a0€"},
+		{"it-CH", "-€1’234.50"},
+		{"nb-NO", "-1\u00a0234,50\u00a0€"},
+		{"pl-PL", "-1\u00a0234,50\u00a0€"},
+		{"pt-BR", "-€1.234,50"},
+		{"pt-PT", "-1\u00a0234,50\u00a0€"},
+		{"ru-RU", "-1\u00a0234,50\u00a0€"},
+		{"sk-SK", "-1\u00a0234,50\u00a0€"},
+		{"sv-SE", "-1\u00a0234,50\u00a0€"},
+		{"en-US", "-€1,234.50"},
+		{"ja-JP", "-€1,234.50"},
+		{"nl-NL", "-€1.234,50"},
+	}
+
+	tests := []builtinTest{
+		{`{? currency(1234.5, "USD") ?}|{? currency(1234.5, "JPY", "ja-JP") ?}|{? currency(3, "EUR", "de") ?}`, nil, "$1,234.50|￥1,234|3,00\u00a0€"},
+		{`{? currency("1", "EUR") ?}`, nil, ": 1: 12: argument 1 to `currency` not supported, got string, want=number"},
+		{`{? currency(1, "EURO") ?}`, nil, ": 1: 12: unknown currency in `currency`: EURO"},
+		{`{? currency(1, "EUR", "not a locale") ?}`, nil, ": 1: 12: unknown locale in `currency`: not a locale"},
+		{`{? currency(1) ?}`, nil, ": 1: 12: wrong number of arguments in currency. got=1, want=2 to 3"},
+	}
+
+	for _, l := range locales {
+		tests = append(tests, builtinTest{`{? currency(-1234.5, "EUR", "` + l.locale + `") ?}`, nil, l.expected})
+	}
+
+	testBuiltins(t, tests)
+
+	internal.SetSettings(map[string]string{"GOVEL_LAMB_CURRENCY_SYMBOL": "after"})
+	t.Cleanup(func() { internal.SetSettings(nil) })
+
+	testBuiltins(t, []builtinTest{
+		{`{? currency(2, "USD") ?}`, nil, "2.00\u00a0$"},
+	})
+}
+
+func TestJSONBuiltins(t *testing.T) {
+	testBuiltins(t, []builtinTest{
+		{`{? json({"a": [1, "<b>"], "n": nil}) ?}|{? json("ñ") ?}`, map[string]interface{}{"nil": nil}, `{"a":[1,"\u003cb\u003e"],"n":null}|"ñ"`},
+		{`{? var v = json_decode(src) ?}{? v.n + 1 ?}|{? v.f ?}|{? v.l[0] ?}`, map[string]interface{}{"src": `{"n": 2, "f": 1.5, "l": [true]}`}, "3|1.5|true"},
+		{`{? json(f) ?}`, map[string]interface{}{"f": func() {}}, ": 1: 8: cannot encode func() in `json`: json: unsupported type: func()"},
+		{`{? json() ?}`, nil, ": 1: 8: wrong number of arguments in json. got=0, want=1"},
+		{`{? json_decode("{") ?}`, nil, ": 1: 15: invalid JSON in `json_decode`: unexpected EOF"},
+		{`{? json_decode(1) ?}`, nil, ": 1: 15: argument 1 to `json_decode` not supported, got int, want=string"},
+	})
+}
+
+// testContext has the fields of the ctx var (lamb.RenderContext) the builtins
+// read.
+type testContext struct {
+	URL    string
+	Path   string
+	Method string
+	Query  map[string]string
+	Header http.Header
+	Locale string
+	Nonce  string
+	User   interface{}
+
+	session map[string]interface{}
+}
+
+func (ctx *testContext) SessionValue(key string) (interface{}, bool) {
+	value, exists := ctx.session[key]
+
+	return value, exists
+}
+
+func (ctx *testContext) Flash(key string) (interface{}, bool) {
+	value, exists := ctx.session[key]
+
+	delete(ctx.session, key)
+
+	return value, exists
+}
+
+func TestURLBuiltins(t *testing.T) {
+	vars := map[string]interface{}{
+		"ctx": &testContext{URL: "/users?page=1&q=a+b", Path: "/users", Query: map[string]string{"page": "1", "q": "a b"}},
+	}
+
+	testBuiltins(t, []builtinTest{
+		{`{? url_encode("a b&c=ñ") ?}`, nil, "a+b%26c%3D%C3%B1"},
+		{`{? query({"sort": "name", "page": 2, "tag": ["a", "b"], "empty": "", "none": nil}) ?}`, map[string]interface{}{"nil": nil}, "page=2&sort=name&tag=a&tag=b"},
+		{`{? url() ?}|{? url({"page": 2}) ?}|{? url({"page": "", "q": ""}) ?}`, vars, "/users?page=1&q=a+b|/users?page=2&q=a+b|/users"},
+		{`{? url_encode(1) ?}`, nil, ": 1: 14: argument 1 to `url_encode` not supported, got int, want=string"},
+		{`{? query("a=1") ?}`, nil, ": 1: 9: argument 1 to `query` not supported, got string, want=map"},
+		{`{? url() ?}`, nil, ": 1: 7: `url` needs the request context, ctx is not set"},
+		{`{? url([1]) ?}`, vars, ": 1: 7: argument 1 to `url` not supported, got []interface {}, want=map"},
+	})
+}
+
+type sortUser struct {
+	Name string
+	Age  int
+}
+
+func TestSortBuiltins(t *testing.T) {
+	vars := map[string]interface{}{
+		"users":   []sortUser{{"b", 30}, {"a", 40}, {"c", 30}},
+		"maps":    []interface{}{map[string]interface{}{"n": 2.5}, map[string]interface{}{"n": 1}},
+		"days":    []time.Time{time.Date(2023, 9, 2, 0, 0, 0, 0, time.UTC), time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)},
+		"numbers": [3]int{3, 1, 2},
+	}
+
+	testBuiltins(t, []builtinTest{
+		{`{? join(sort([3, 1.5, 2, -1]), ",") ?}|{? join(sort(["b", "B", "a"]), ",") ?}|{? join(sort(numbers), ",") ?}|{? join(numbers, ",") ?}`, vars, "-1,1.5,2,3|B,a,b|1,2,3|3,1,2"},
+		{`{? date(sort(days)[0], "D") ?}|{? join(pluck(sort_by(users, "Age"), "Name"), ",") ?}|{? join(pluck(sort_by(maps, "n"), "n"), ",") ?}`, vars, "1|b,c,a|1,2.5"},
+		{`{? join(reverse([1, 2, 3]), ",") ?}|{? reverse("añb") ?}|{? join(unique([1, 1.0, "1", 2, 1]), ",") ?}`, vars, "3,2,1|bña|1,1,2"},
+		{`{? sort([1, "a"]) ?}`, nil, ": 1: 8: cannot compare string and int in `sort`"},
+		{`{? sort([days[0], 1]) ?}`, vars, ": 1: 8: cannot compare int and time.Time in `sort`"},
+		{`{? sort_by(maps, 1) ?}`, vars, ": 1: 11: argument 2 to `sort_by` not supported, got int, want=string"},
+		{`{? sort_by(users, "Missing") ?}`, vars, ": 1: 11: field Missing does not exist in struct evaluator.sortUser"},
+		{`{? sort_by([1, 2], "n") ?}`, nil, ": 1: 11: cannot get the field n of int, want=struct or map"},
+		{`{? reverse(1) ?}`, nil, ": 1: 11: argument 1 to `reverse` not supported, got int, want=slice"},
+		{`{? unique({"a": 1}) ?}`, nil, ": 1: 10: argument 1 to `unique` not supported, got map[interface {}]interface {}, want=slice"},
+	})
+}
+
+func TestFirstLastKeysValues(t *testing.T) {
+	vars := map[string]interface{}{
+		"empty": []int{},
+		"m":     map[string]int{"b": 2, "a": 1, "c": 3},
+	}
+
+	testBuiltins(t, []builtinTest{
+		{`{? first([1, 2]) ?}|{? last([1, 2]) ?}|{? first("ñu") ?}|{? last("añ") ?}|{? first(empty) ?}|{? last("") ?}`, vars, "1|2|ñ|ñ||"},
+		{`{? join(keys(m), ",") ?}|{? join(values(m), ",") ?}|{? join(keys({2: "x", 10: "y", 1: "z"}), ",") ?}`, vars, "a,b,c|1,2,3|1,2,10"},
+		{`{? var merged = merge(m, {"a": 5, "d": 4}) ?}{? join(values(merged), ",") ?}|{? join(values(merge(m, m)), ",") ?}|{? m.a ?}`, vars, "5,2,3,4|1,2,3|1"},
+		{`{? join(concat([1], ["a"]), ",") ?}|{? len(concat(empty, empty)) ?}`, vars, "1,a|0"},
+		{`{? first(1) ?}`, nil, ": 1: 9: argument 1 to `first` not supported, got int, want=slice"},
+		{`{? last() ?}`, nil, ": 1: 8: wrong number of arguments in last. got=0, want=1"},
+		{`{? keys([1]) ?}`, nil, ": 1: 8: argument 1 to `keys` not supported, got []interface {}, want=map"},
+		{`{? values("a") ?}`, nil, ": 1: 10: argument 1 to `values` not supported, got string, want=map"},
+		{`{? merge(m, 1) ?}`, vars, ": 1: 9: argument 2 to `merge` not supported, got int, want=map"},
+		{`{? concat([1], m) ?}`, vars, ": 1: 10: argument 2 to `concat` not supported, got map[string]int, want=slice"},
+	})
+}
+
+func TestLambdaBuiltins(t *testing.T) {
+	vars := map[string]interface{}{
+		"users": []sortUser{{"ann", 30}, {"bob", 17}, {"cy", 30}},
+	}
+
+	testBuiltins(t, []builtinTest{
+		{`{? join(filter([1, 2, 3, 4], x => x > 2), ",") ?}|{? join(map([1, 2], x => x * 10), ",") ?}|{? var n = 5 ?}{? join(map([1], x => x + n), ",") ?}`, nil, "3,4|10,20|6"},
+		{`{? join(pluck(users, "Name"), ",") ?}|{? join(pluck(where(users, "Age", 30.0), "Name"), ",") ?}|{? len(where(users, "Age", "30")) ?}`, vars, "ann,bob,cy|ann,cy|0"},
+		{`{? join(map(filter(users, u => u.Age >= 18), u => upper(u.Name)), " ") ?}`, vars, "ANN CY"},
+		{`{? filter([1], 1) ?}`, nil, ": 1: 10: argument 2 to `filter` not supported, got int, want=lambda"},
+		{`{? map("ab", x => x) ?}`, nil, ": 1: 7: argument 1 to `map` not supported, got string, want=slice"},
+		{`{? map([1], x => missing) ?}`, nil, ": 1: 18: identifier not found: missing"},
+		{`{? pluck(users, "Email") ?}`, vars, ": 1: 9: field Email does not exist in struct evaluator.sortUser"},
+		{`{? where(users, "Age") ?}`, vars, ": 1: 9: wrong number of arguments in where. got=2, want=3"},
+	})
+}
+
+func TestChunkGroupBy(t *testing.T) {
+	vars := map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{"c": 1, "n": "a"},
+			map[string]interface{}{"c": "1", "n": "b"},
+			map[string]interface{}{"c": 1.5, "n": "c"},
+			map[string]interface{}{"c": 1, "n": "d"},
+			map[string]interface{}{"n": "e"},
+		},
+		"users": []sortUser{{"ann", 30}, {"bob", 17}, {"cy", 30}},
+		"lists": []interface{}{map[string]interface{}{"c": []int{1}}},
+	}
+
+	testBuiltins(t, []builtinTest{
+		{`{? for row in chunk([1, 2, 3, 4, 5], 2) ?}[{? join(row, ",") ?}]{? endfor ?}|{? len(chunk([], 3)) ?}|{? len(chunk([1], 3)[0]) ?}`, nil, "[1,2][3,4][5]|0|1"},
+		{`{? var g = group_by(items, "c") ?}{? len(keys(g)) ?}|{? join(pluck(g[1], "n"), ",") ?}|{? join(pluck(g["1"], "n"), ",") ?}|{? join(pluck(g[1.5], "n"), ",") ?}`, vars, "4|a,d|b|c"},
+		{`{? var g = group_by(users, "Age") ?}{? join(pluck(g[30], "Name"), ",") ?}|{? g[17][0].Name ?}`, vars, "ann,cy|bob"},
+		{`{? chunk([1], 0) ?}`, nil, ": 1: 9: the size of the chunks in `chunk` must be greater than 0, got 0"},
+		{`{? chunk("abc", 1) ?}`, nil, ": 1: 9: argument 1 to `chunk` not supported, got string, want=slice"},
+		{`{? group_by(lists, "c") ?}`, vars, ": 1: 12: cannot group by c in `group_by`, []int can not be a map key"},
+		{`{? group_by([1, "a"], "c") ?}`, nil, ": 1: 12: cannot get the field c of int, want=struct or map"},
+		{`{? group_by(users, 1) ?}`, vars, ": 1: 12: argument 2 to `group_by` not supported, got int, want=string"},
+	})
+}
+
+func TestAggregateBuiltins(t *testing.T) {
+	vars := map[string]interface{}{
+		"users":  []sortUser{{"ann", 30}, {"bob", 17}, {"cy", 31}},
+		"prices": []float64{1.5, 2.5},
+		"counts": []uint8{1, 2},
+	}
+
+	testBuiltins(t, []builtinTest{
+		{`{? sum([1, 2, 3]) ?}|{? sum(prices) ?}|{? sum([1, 0.5]) ?}|{? sum(counts) ?}|{? sum(users, "Age") ?}|{? sum([]) ?}`, vars, "6|4|1.5|3|78|0"},
+		{`{? avg([1, 2]) ?}|{? avg(users, "Age") ?}|{? avg([]) ?}`, vars, "1.5|26|"},
+		{`{? min([3, 1.5, 2]) ?}|{? max(["b", "c", "a"]) ?}|{? min(users, "Name") ?}|{? max(5, 10) ?}|{? min(5, 2.5, 4) ?}|{? max([]) ?}`, vars, "1.5|c|ann|10|2.5|"},
+		{`{? count_if(users, u => u.Age >= 18) ?}|{? count_if([], x => x) ?}`, vars, "2|0"},
+		{`{? sum([1, "2"]) ?}`, nil, ": 1: 7: cannot add string in `sum`, want=number"},
+		{`{? avg(1) ?}`, nil, ": 1: 7: argument 1 to `avg` not supported, got int, want=slice"},
+		{`{? max([1, "a"]) ?}`, nil, ": 1: 7: cannot compare string and int in `max`"},
+		{`{? min(users, 1) ?}`, vars, ": 1: 7: argument 2 to `min` not supported, got int, want=string"},
+		{`{? count_if([1], 1) ?}`, nil, ": 1: 12: argument 2 to `count_if` not supported, got int, want=lambda"},
+	})
+}
+
+func TestDumpBuiltins(t *testing.T) {
+	vars := map[string]interface{}{
+		"user": &sortUser{"<ann>", 30},
+	}
+
+	// nothing is dumped unless lamb.debug is enabled
+	testBuiltins(t, []builtinTest{
+		{`a{? dump(user) ?}b{? dd(user) ?}c`, vars, "abc"},
+	})
+
+	internal.SetSettings(map[string]string{"GOVEL_LAMB_DEBUG": "true"})
+	t.Cleanup(func() { internal.SetSettings(nil) })
+
+	testBuiltins(t, []builtinTest{
+		{`a{? dump(user, [1]) ?}b`, vars, "a<pre class=\"lamb-dump\">&amp;evaluator.sortUser {\n  Name: string(5) &#34;&lt;ann&gt;&#34;\n  Age: int 30\n}</pre>\n" +
+			"<pre class=\"lamb-dump\">[]interface {} (len=1) [\n  0 =&gt; int 1\n]</pre>\nb"},
+		{`a{? dump() ?}b`, nil, "ab"},
+	})
+
+	// dd stops the render with the dump as its output
+	result := Eval(parser.New(lexer.New(`a{? dd(1, "x") ?}b`)).ParseProgram(), object.NewEnvironment())
+
+	var halt *object.HaltError
+
+	if err, isError := result.(error); !isError || !errors.As(err, &halt) {
+		t.Fatalf("dd did not halt the render. got=%v", result)
+	}
+
+	if expected := "<pre class=\"lamb-dump\">int 1</pre>\n<pre class=\"lamb-dump\">string(1) &#34;x&#34;</pre>\n"; halt.Output != expected {
+		t.Errorf("wrong output of dd. expected=%q, got=%q", expected, halt.Output)
+	}
+}
+
+func TestPresenceChecks(t *testing.T) {
+	vars := map[string]interface{}{
+		"user":   &sortUser{"ann", 0},
+		"none":   nil,
+		"spaces": " \t",
+		"zero":   0.0,
+		"m":      map[string]int{},
+	}
+
+	testBuiltins(t, []builtinTest{
+		{`{? isset(user) ?}{? isset(none) ?}{? isset(missing) ?}{? isset(user.Name) ?}{? isset(user.Missing) ?}{? isset(missing.a.b) ?}`, vars, "truefalsefalsetruefalsefalse"},
+		{`{? empty(zero) ?}{? empty(spaces) ?}{? empty(m) ?}{? empty([0]) ?}{? empty(false) ?}{? empty(missing) ?}{? empty(user.Age) ?}`, vars, "truefalsetruefalsetruetruetrue"},
+		{`{? blank(zero) ?}{? blank(spaces) ?}{? blank(m) ?}{? blank("a") ?}{? blank(false) ?}{? blank(none) ?}`, vars, "falsetruetruefalsetruetrue"},
+		{`{? var isset = x => "var" ?}{? isset(1) ?}`, nil, "var"},
+		{`{? isset() ?}`, nil, ": 1: 9: wrong number of arguments in isset. got=0, want=1"},
+		{`{? empty(1, 2) ?}`, nil, ": 1: 9: wrong number of arguments in empty. got=2, want=1"},
+	})
+}
+
+func TestTranslationBuiltins(t *testing.T) {
+	internal.AddTranslations("en", map[string]string{
+		"evaluator_test.greeting": "Hello, :name!",
+		"evaluator_test.names":    ":names and :name",
+		"evaluator_test.only_en":  "English",
+	})
+	internal.AddTranslations("es", map[string]string{
+		"evaluator_test.greeting": "¡Hola, :name!",
+	})
+
+	es := map[string]interface{}{"ctx": &testContext{Locale: "es-MX"}}
+
+	testBuiltins(t, []builtinTest{
+		{`{? t("evaluator_test.greeting", {"name": "Bob"}) ?}|{? t("evaluator_test.names", {"name": 1, "names": 2}) ?}|{? t("evaluator_test.missing") ?}`, nil, "Hello, Bob!|2 and 1|evaluator_test.missing"},
+		{`{? t("evaluator_test.greeting", {"name": "Bob"}) ?}|{? t("evaluator_test.only_en") ?}`, es, "¡Hola, Bob!|English"},
+		{`{? t() ?}`, nil, ": 1: 5: wrong number of arguments in t. got=0, want=1 to 2"},
+		{`{? t(1) ?}`, nil, ": 1: 5: argument 1 to `t` not supported, got int, want=string"},
+		{`{? t("evaluator_test.greeting", "Bob") ?}`, nil, ": 1: 5: argument 2 to `t` not supported, got string, want=map"},
+	})
+}
+
+func TestPluralBuiltins(t *testing.T) {
+	internal.AddTranslations("en", map[string]string{
+		"evaluator_test.items.one":   ":count item",
+		"evaluator_test.items.other": ":count items in :cart",
+		"evaluator_test.only":        "no plurals :count",
+	})
+	internal.AddTranslations("pl", map[string]string{
+		"evaluator_test.items.one":   ":count plik",
+		"evaluator_test.items.few":   ":count pliki",
+		"evaluator_test.items.many":  ":count plików",
+		"evaluator_test.items.other": ":count pliku",
+	})
+
+	locale := func(locale string) map[string]interface{} {
+		return map[string]interface{}{"ctx": &testContext{Locale: locale}}
+	}
+
+	testBuiltins(t, []builtinTest{
+		{`{? tn("evaluator_test.items", 1) ?}|{? tn("evaluator_test.items", 0, {"cart": "A"}) ?}|{? tn("evaluator_test.items", 1.5, {"cart": "B"}) ?}|{? tn("evaluator_test.only", 2) ?}|{? tn("evaluator_test.none", 2) ?}`, nil, "1 item|0 items in A|1.5 items in B|no plurals 2|evaluator_test.none"},
+		{`{? tn("evaluator_test.items", 1) ?}|{? tn("evaluator_test.items", 3) ?}|{? tn("evaluator_test.items", 5) ?}|{? tn("evaluator_test.items", 22) ?}|{? tn("evaluator_test.items", 1.5) ?}`, locale("pl-PL"), "1 plik|3 pliki|5 plików|22 pliki|1.5 pliku"},
+		{`{? choice("one apple|:n apples", 1) ?}|{? choice("one apple|:n apples", 0) ?}|{? choice(":n :what", 2, {"what": "x"}) ?}`, nil, "one apple|0 apples|2 x"},
+		{`{? choice("une pomme|:n pommes", 0) ?}|{? choice("une pomme|:n pommes", 1.5) ?}|{? choice("une pomme|:n pommes", 2) ?}`, locale("fr"), "une pomme|une pomme|2 pommes"},
+		{`{? tn("evaluator_test.items") ?}`, nil, ": 1: 6: wrong number of arguments in tn. got=1, want=2 to 3"},
+		{`{? tn("evaluator_test.items", "2") ?}`, nil, ": 1: 6: argument 2 to `tn` not supported, got string, want=number"},
+		{`{? tn("evaluator_test.items", 2, [1]) ?}`, nil, ": 1: 6: argument 3 to `tn` not supported, got []interface {}, want=map"},
+		{`{? choice("a|b|c", 2) ?}`, nil, ": 1: 10: `choice` takes a singular and a plural, got 3 forms, use tn for more plural forms"},
+		{`{? choice(1, 2) ?}`, nil, ": 1: 10: argument 1 to `choice` not supported, got int, want=string"},
+	})
+}
+
+func TestLocaleBuiltins(t *testing.T) {
+	locale := func(locale string) map[string]interface{} {
+		return map[string]interface{}{"ctx": &testContext{Locale: locale}, "t": time.Date(2023, 9, 3, 0, 0, 0, 0, time.UTC)}
+	}
+
+	testBuiltins(t, []builtinTest{
+		{`{? number_format(1234.567, 2) ?}|{? number_format(1234.4) ?}|{? number_format(-0.5, 1) ?}|{? first_day_of_week() ?}|{? date(t, "dddd D MMMM") ?}`, locale("en-US"), "1,234.57|1,234|-0.5|0|Sunday 3 September"},
+		{`{? number_format(1234.567, 2) ?}|{? first_day_of_week() ?}|{? date(t, "dddd D MMMM") ?}`, locale("de-DE"), "1.234,57|1|Sonntag 3 September"},
+		{`{? number_format(1234567.8, 1) ?}|{? first_day_of_week() ?}|{? date(t, "ddd D MMM") ?}`, locale("fr-FR"), "1 234 567,8|1|dim. 3 sept."},
+		{`{? first_day_of_week() ?}|{? first_day_of_week() ?}`, locale("ar-EG"), "6|6"},
+		{`{? number_format("1") ?}`, nil, ": 1: 17: argument 1 to `number_format` not supported, got string, want=number"},
+		{`{? number_format(1, -1) ?}`, nil, ": 1: 17: the decimals in `number_format` can not be negative, got -1"},
+		{`{? first_day_of_week(1) ?}`, nil, ": 1: 21: wrong number of arguments in first_day_of_week. got=1, want=0"},
+	})
+}
+
+func TestAssetBuiltins(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"app.css":              "body{}",
+		"assets/app-4ed93.js":  "run()",
+		"manifest.json":        `{"app.js": {"file": "assets/app-4ed93.js"}, "lib.js": "https://cdn.example.com/lib.js", "root.js": "/root.js"}`,
+		"assets/not-built.txt": "",
+	}
+
+	for name, content := range files {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), os.ModePerm)
+
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	version := sha256.Sum256([]byte("body{}"))
+
+	internal.SetSettings(map[string]string{
+		"GOVEL_LAMB_ASSETS_DIR":      dir,
+		"GOVEL_LAMB_ASSETS_MANIFEST": filepath.Join(dir, "manifest.json"),
+		"GOVEL_LAMB_ASSETS_VERSION":  "hash",
+	})
+	t.Cleanup(func() { internal.SetSettings(nil) })
+
+	testBuiltins(t, []builtinTest{
+		{`{? asset("app.css") ?}|{? asset("app.js") ?}|{? asset("lib.js") ?}|{? asset("root.js") ?}|{? asset("missing.css") ?}`, nil, "/app.css?v=" + hex.EncodeToString(version[:])[:12] + "|/assets/app-4ed93.js|https://cdn.example.com/lib.js|/root.js|/missing.css"},
+		{`{? asset(1) ?}`, nil, ": 1: 9: argument to `asset` not supported, got int, want=string"},
+	})
+
+	internal.SetSetting("GOVEL_LAMB_ASSETS_MANIFEST", filepath.Join(dir, "missing.json"))
+
+	testBuiltins(t, []builtinTest{
+		{`{? asset("app.css") ?}`, nil, ": 1: 9: cannot read the assets manifest: stat " + filepath.Join(dir, "missing.json") + ": no such file or directory"},
+	})
+
+	stat, err := os.Stat(filepath.Join(dir, "app.css"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	internal.SetSetting("GOVEL_LAMB_ASSETS_MANIFEST", "")
+	internal.SetSetting("GOVEL_LAMB_ASSETS_VERSION", "mtime")
+
+	testBuiltins(t, []builtinTest{
+		{`{? asset("app.css") ?}|{? asset("app.js") ?}`, nil, "/app.css?v=" + strconv.FormatInt(stat.ModTime().Unix(), 10) + "|/app.js"},
+	})
+}
+
+func TestAssetCDN(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"app.css":             "body{}",
+		"assets/app-4ed93.js": "run()",
+		"manifest.json":       `{"app.js": {"file": "/assets/app-4ed93.js"}, "lib.js": "https://cdn.example.com/lib.js"}`,
+	}
+
+	for name, content := range files {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), os.ModePerm)
+
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	css := sha512.Sum384([]byte("body{}"))
+	js := sha512.Sum384([]byte("run()"))
+
+	internal.SetSettings(map[string]string{
+		"GOVEL_LAMB_ASSETS_DIR":      dir,
+		"GOVEL_LAMB_ASSETS_MANIFEST": filepath.Join(dir, "manifest.json"),
+		"GOVEL_LAMB_ASSETS_CDN":      "https://static.example.com/",
+	})
+	t.Cleanup(func() { internal.SetSettings(nil) })
+
+	testBuiltins(t, []builtinTest{
+		{`{? asset("app.css") ?}|{? asset("app.js") ?}|{? asset("lib.js") ?}`, nil, "https://static.example.com/app.css|https://static.example.com/assets/app-4ed93.js|https://cdn.example.com/lib.js"},
+		{`{? sri("app.css") ?}`, nil, `integrity="sha384-` + base64.StdEncoding.EncodeToString(css[:]) + `" crossorigin="anonymous"`},
+		{`{? sri("app.js") ?}`, nil, `integrity="sha384-` + base64.StdEncoding.EncodeToString(js[:]) + `" crossorigin="anonymous"`},
+		{`{? sri("lib.js") ?}`, nil, ": 1: 7: cannot compute the integrity of https://cdn.example.com/lib.js, it is not a local file"},
+		{`{? sri("missing.js") ?}`, nil, ": 1: 7: cannot compute the integrity of missing.js: stat " + filepath.Join(dir, "missing.js") + ": no such file or directory"},
+		{`{? sri(1) ?}`, nil, ": 1: 7: argument 1 to `sri` not supported, got int, want=string"},
+	})
+}
+
+func TestCSPBuiltins(t *testing.T) {
+	vars := map[string]interface{}{"ctx": &testContext{Nonce: `r4nd"m`}}
+
+	testBuiltins(t, []builtinTest{
+		{`{? csp_nonce() ?}|{? script("/app.js?a=1&b=2") ?}|{? style("/app.css") ?}`, vars, `r4nd"m|<script src="/app.js?a=1&amp;b=2" nonce="r4nd&#34;m"></script>|<link rel="stylesheet" href="/app.css" nonce="r4nd&#34;m">`},
+		{`[{? csp_nonce() ?}]|{? script("/app.js") ?}|{? style("/app.css") ?}`, nil, `[]|<script src="/app.js"></script>|<link rel="stylesheet" href="/app.css">`},
+		{`{? csp_nonce(1) ?}`, nil, ": 1: 13: wrong number of arguments in csp_nonce. got=1, want=0"},
+		{`{? script(1) ?}`, nil, ": 1: 10: argument 1 to `script` not supported, got int, want=string"},
+		{`{? style() ?}`, nil, ": 1: 9: wrong number of arguments in style. got=0, want=1"},
+	})
+}
+
+func TestCSRFBuiltins(t *testing.T) {
+	vars := map[string]interface{}{"ctx": &testContext{session: map[string]interface{}{"csrf_token": "t<k>", "_token": 12}}}
+
+	testBuiltins(t, []builtinTest{
+		{`{? csrf_token() ?}|{? csrf_field() ?}|{? method_field("delete") ?}`, vars, `t<k>|<input type="hidden" name="csrf_token" value="t&lt;k&gt;">|<input type="hidden" name="_method" value="DELETE">`},
+		{`{? csrf_token() ?}`, map[string]interface{}{"ctx": &testContext{}}, ": 1: 14: there is no CSRF token in the session (key csrf_token)"},
+		{`{? csrf_field() ?}`, nil, ": 1: 14: `csrf_field` needs the sessions of the request, ctx is not set by lamb.Render"},
+		{`{? csrf_token(1) ?}`, vars, ": 1: 14: wrong number of arguments in csrf_token. got=1, want=0"},
+		{`{? method_field(1) ?}`, nil, ": 1: 16: argument 1 to `method_field` not supported, got int, want=string"},
+	})
+
+	internal.SetSettings(map[string]string{"GOVEL_LAMB_CSRF_KEY": "_token"})
+	t.Cleanup(func() { internal.SetSettings(nil) })
+
+	testBuiltins(t, []builtinTest{
+		{`{? csrf_field() ?}`, vars, `<input type="hidden" name="_token" value="12">`},
+	})
+}
+
+func TestOldInputBuiltins(t *testing.T) {
+	vars := map[string]interface{}{"ctx": &testContext{session: map[string]interface{}{
+		"_old_input": map[string]interface{}{"email": "a@b.c"},
+		"_errors":    map[string]interface{}{"email": []string{"taken", "too long"}, "name": "required", "none": nil},
+	}}}
+
+	testBuiltins(t, []builtinTest{
+		{`{? old("email") ?}|{? old("name", "Ann") ?}|[{? old("name") ?}]`, vars, "a@b.c|Ann|[]"},
+		{`{? join(errors("email"), ",") ?}|{? join(errors("name"), ",") ?}|{? len(errors("none")) ?}|{? len(errors("missing")) ?}`, vars, "taken,too long|required|0|0"},
+		{`{? has_error() ?}|{? has_error("email") ?}|{? has_error("none") ?}`, vars, "true|true|false"},
+		{`{? has_error() ?}|{? old("email", 1) ?}`, map[string]interface{}{"ctx": &testContext{}}, "false|1"},
+		{`{? old("email") ?}`, nil, ": 1: 7: `old` needs the sessions of the request, ctx is not set by lamb.Render"},
+		{`{? old(1) ?}`, vars, ": 1: 7: argument 1 to `old` not supported, got int, want=string"},
+		{`{? errors() ?}`, vars, ": 1: 10: wrong number of arguments in errors. got=0, want=1"},
+		{`{? has_error("a", "b") ?}`, vars, ": 1: 13: wrong number of arguments in has_error. got=2, want=0 to 1"},
+	})
+}
+
+func TestFlashBuiltins(t *testing.T) {
+	vars := map[string]interface{}{"ctx": &testContext{session: map[string]interface{}{"status": "Saved!"}}}
+
+	testBuiltins(t, []builtinTest{
+		{`{? has_flash("status") ?}|{? flash("status") ?}|{? has_flash("status") ?}|[{? flash("status") ?}]|{? flash("status", "none") ?}`, vars, "true|Saved!|false|[]|none"},
+		{`{? flash("status") ?}`, nil, ": 1: 9: `flash` needs the sessions of the request, ctx is not set by lamb.Render"},
+		{`{? has_flash("status") ?}`, map[string]interface{}{"ctx": "request"}, ": 1: 13: `has_flash` needs the sessions of the request, ctx is not set by lamb.Render"},
+		{`{? flash() ?}`, vars, ": 1: 9: wrong number of arguments in flash. got=0, want=1 to 2"},
+		{`{? has_flash(1) ?}`, vars, ": 1: 13: argument 1 to `has_flash` not supported, got int, want=string"},
+	})
+}
+
+func TestAuthBuiltins(t *testing.T) {
+	user := map[string]interface{}{"ctx": &testContext{User: &sortUser{"ann", 30}}}
+	guest := map[string]interface{}{"ctx": &testContext{User: (*sortUser)(nil)}}
+
+	testBuiltins(t, []builtinTest{
+		{`{? auth().check() ?}|{? guest() ?}|{? auth().user().Name ?}`, user, "true|false|ann"},
+		{`{? auth().check() ?}|{? guest() ?}|[{? auth().user() ?}]`, guest, "false|true|[]"},
+		{`{? auth().check() ?}|{? guest() ?}`, nil, "false|true"},
+		{`{? auth(1) ?}`, nil, ": 1: 8: wrong number of arguments in auth. got=1, want=0"},
+		{`{? auth().user(1) ?}`, user, ": 1: 15: wrong number of arguments in user. got=1, want=0"},
+		{`{? guest(1) ?}`, nil, ": 1: 9: wrong number of arguments in guest. got=1, want=0"},
+	})
+}
+
+func TestRequestBuiltin(t *testing.T) {
+	vars := map[string]interface{}{"ctx": &testContext{
+		URL:    "/admin/users?page=2",
+		Path:   "/admin/users/",
+		Method: "POST",
+		Query:  map[string]string{"page": "2"},
+		Header: http.Header{"X-Requested-With": {"fetch"}},
+	}}
+
+	testBuiltins(t, []builtinTest{
+		{`{? request().path() ?}|{? request().method() ?}|{? request().is("admin/*") ?}|{? request().is("/users", "admin") ?}|{? request().is("admin/users") ?}`, vars, "admin/users|POST|true|false|true"},
+		{`{? request().query("page") ?}|{? request().query("sort", "name") ?}|[{? request().query("sort") ?}]|{? request().header("x-requested-with") ?}|[{? request().header("Accept") ?}]`, vars, "2|name|[]|fetch|[]"},
+		{`{? request() ?}`, nil, ": 1: 11: `request` needs the request context, ctx is not set"},
+		{`{? request(1) ?}`, vars, ": 1: 11: wrong number of arguments in request. got=1, want=0"},
+		{`{? request().is() ?}`, vars, ": 1: 16: wrong number of arguments in is. got=0, want=at least 1"},
+		{`{? request().is(1) ?}`, vars, ": 1: 16: argument 1 to `is` not supported, got int, want=string"},
+		{`{? request().query() ?}`, vars, ": 1: 19: wrong number of arguments in query. got=0, want=1 to 2"},
+		{`{? request().header(1) ?}`, vars, ": 1: 20: argument 1 to `header` not supported, got int, want=string"},
+	})
+}
+
+func TestFormBuilderBuiltins(t *testing.T) {
+	vars := map[string]interface{}{"ctx": &testContext{session: map[string]interface{}{"csrf_token": "t"}}, "roles": []string{"admin", "user"}}
+	submitted := map[string]interface{}{"ctx": &testContext{session: map[string]interface{}{
+		"csrf_token": "t",
+		"_old_input": map[string]interface{}{"email": "a@b.c", "role": "user", "password": "secret"},
+		"_errors":    map[string]interface{}{"email": "taken"},
+	}}, "roles": map[string]string{"a": "Admin", "u": "User"}}
+
+	testBuiltins(t, []builtinTest{
+		{`{? form_open("/users") ?}{? form_close() ?}`, vars, `<form action="/users" method="POST"><input type="hidden" name="csrf_token" value="t"></form>`},
+		{`{? form_open("/users/1", "put", {"id": "edit"}) ?}`, vars, `<form action="/users/1" id="edit" method="POST"><input type="hidden" name="csrf_token" value="t"><input type="hidden" name="_method" value="PUT">`},
+		{`{? form_open("/search", "get") ?}`, nil, `<form action="/search" method="GET">`},
+		{`{? input("email", {"type": "email", "value": "x"}) ?}|{? input("password", {"type": "password", "value": "x"}) ?}`, vars, `<input name="email" type="email" value="x">|<input name="password" type="password">`},
+		{`{? input("email", {"class": "field", "value": "x"}) ?}|{? input("password", {"type": "password"}) ?}`, submitted, `<input class="field is-invalid" name="email" type="text" value="a@b.c">|<input name="password" type="password">`},
+		{`{? select("role", roles, "admin") ?}`, vars, `<select name="role"><option value="admin" selected>admin</option><option value="user">user</option></select>`},
+		{`{? select("role", roles, "a") ?}`, submitted, `<select name="role"><option value="a">Admin</option><option value="u">User</option></select>`},
+		{`{? checkbox("remember", "1", true) ?}|{? checkbox("remember") ?}`, vars, `<input checked name="remember" type="checkbox" value="1">|<input name="remember" type="checkbox" value="1">`},
+		{`{? checkbox("remember", "1", true) ?}`, submitted, `<input name="remember" type="checkbox" value="1">`},
+		{`{? form_open() ?}`, vars, ": 1: 13: wrong number of arguments in form_open. got=0, want=1 to 3"},
+		{`{? form_open("/users", 1) ?}`, vars, ": 1: 13: argument 2 to `form_open` not supported, got int, want=string"},
+		{`{? form_open("/users") ?}`, nil, ": 1: 13: `csrf_field` needs the sessions of the request, ctx is not set by lamb.Render"},
+		{`{? form_close(1) ?}`, nil, ": 1: 14: wrong number of arguments in form_close. got=1, want=0"},
+		{`{? input("email", "text") ?}`, nil, ": 1: 9: argument 2 to `input` not supported, got string, want=map"},
+		{`{? select("role") ?}`, nil, ": 1: 10: wrong number of arguments in select. got=1, want=2 to 4"},
+		{`{? select("role", "admin") ?}`, nil, ": 1: 10: argument 2 to `select` not supported, got string, want=slice or map"},
+		{`{? checkbox("remember", "1", "yes") ?}`, nil, ": 1: 12: argument 3 to `checkbox` not supported, got string, want=bool"},
+	})
+}
+
+func TestAttributeBuiltins(t *testing.T) {
+	vars := map[string]interface{}{"active": true, "id": 7, "title": `a "b"`, "missing": nil}
+
+	testBuiltins(t, []builtinTest{
+		{`{? class({"link": true, "active": active, "hidden": false, "x<y": 1}) ?}`, vars, "active link x&lt;y"},
+		{`[{? class({"hidden": false}) ?}]`, nil, "[]"},
+		{`{? attrs({"disabled": !active, "data-id": id, "title": title, "hidden": missing, "required": true}) ?}`, vars, `data-id="7" required title="a &#34;b&#34;"`},
+		{`{? attrs({"class": {"btn": true, "active": active, "off": false}, "id": "save"}) ?}`, vars, `class="active btn" id="save"`},
+		{`{? class() ?}`, nil, ": 1: 9: wrong number of arguments in class. got=0, want=1"},
+		{`{? class("link") ?}`, nil, ": 1: 9: argument 1 to `class` not supported, got string, want=map"},
+		{`{? attrs({}, {}) ?}`, nil, ": 1: 9: wrong number of arguments in attrs. got=2, want=1"},
+		{`{? attrs([1]) ?}`, nil, ": 1: 9: argument 1 to `attrs` not supported, got []interface {}, want=map"},
+	})
+}
+
+func TestRouteBuiltin(t *testing.T) {
+	namedRoute = func(name string, params govel.SMap) string {
+		switch name {
+		case "home":
+			return "/"
+		case "users.show":
+			return "/users/" + params["id"]
+		}
+
+		return ""
+	}
+	t.Cleanup(func() { namedRoute = govel.Route })
+
+	vars := map[string]interface{}{"id": 7}
+
+	testBuiltins(t, []builtinTest{
+		{`{? route("home") ?}|{? route("users.show", {"id": id}) ?}|{? route("users.show", {"id": id}, {"query": {"tab": "posts", "page": 2}}) ?}`, vars, "/|/users/7|/users/7?page=2&tab=posts"},
+		{`{? route("home", {}, {"absolute": true}) ?}`, nil, ": 1: 9: absolute routes need the URL of the app, lamb.url is not set"},
+		{`{? route("users") ?}`, nil, ": 1: 9: route users does not exist"},
+		{`{? route() ?}`, nil, ": 1: 9: wrong number of arguments in route. got=0, want=1 to 3"},
+		{`{? route(1) ?}`, nil, ": 1: 9: argument 1 to `route` not supported, got int, want=string"},
+		{`{? route("home", "id") ?}`, nil, ": 1: 9: argument 2 to `route` not supported, got string, want=map"},
+		{`{? route("users.show", {"id": [1]}) ?}`, nil, ": 1: 9: argument to `route` not supported, all elements of map must be strings or integers. got=slice"},
+		{`{? route("home", {}, {"query": "tab"}) ?}`, nil, ": 1: 9: the query option of `route` must be a map, got string"},
+		{`{? route("home", {}, {"absolute": 1}) ?}`, nil, ": 1: 9: the absolute option of `route` must be a bool, got int"},
+		{`{? route("home", {}, {"signed": true}) ?}`, nil, ": 1: 9: unknown option of `route`: signed"},
+	})
+
+	internal.SetSettings(map[string]string{"GOVEL_LAMB_URL": "https://example.com/"})
+	t.Cleanup(func() { internal.SetSettings(nil) })
+
+	testBuiltins(t, []builtinTest{
+		{`{? route("users.show", {"id": id}, {"absolute": true}) ?}|{? route("home", {}, {"absolute": false}) ?}`, vars, "https://example.com/users/7|/"},
+	})
+}
+
+func TestConfigBuiltin(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+
+	config := "port: 8080\napp:\n  name: Blog\n  debug: true\n  ratio: 1.5\n  locales: [en, es]\n"
+
+	if err := os.WriteFile(file, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	govel.LoadConfigFileForTests(nil, t, file)
+
+	testBuiltins(t, []builtinTest{
+		{`{? config("app.name") ?}|{? config("port") + 1 ?}|{? config("app.debug") ?}|{? config("app.ratio") * 2 ?}|{? join(config("app.locales"), ",") ?}`, nil, "Blog|8081|true|3|en,es"},
+		{`{? config("features.beta", false) ?}|{? config("app.missing", "none") ?}|{? config("app.name", "none") ?}`, nil, "false|none|Blog"},
+		{`{? config("app.missing") ?}`, nil, ": 1: 10: config key not found: app.missing"},
+		{`{? config("app") ?}`, nil, ": 1: 10: keys app has not a valid type, only string, int, bool, float and list are allowed, got=map[interface {}]interface {}"},
+		{`{? config() ?}`, nil, ": 1: 10: wrong number of arguments in config. got=0, want=1 to 2"},
+		{`{? config(1) ?}`, nil, ": 1: 10: argument 1 to `config` not supported, got int, want=string"},
+	})
+}
+
+func TestEnvBuiltin(t *testing.T) {
+	t.Setenv("APP_ENV", "testing")
+	t.Setenv("APP_EMPTY", "")
+	t.Setenv("FEATURE_BETA", "on")
+	t.Setenv("DB_PASSWORD", "secret")
+
+	internal.SetSettings(map[string]string{"GOVEL_LAMB_ENV": "APP_ENV,APP_EMPTY,,APP_MISSING,FEATURE_*"})
+	t.Cleanup(func() { internal.SetSettings(nil) })
+
+	testBuiltins(t, []builtinTest{
+		{`{? env("APP_ENV") ?}|{? env("FEATURE_BETA", "off") ?}|[{? env("APP_EMPTY", "none") ?}]|{? env("APP_MISSING", "none") ?}|[{? env("FEATURE_NEW") ?}]`, nil, "testing|on|[]|none|[]"},
+		{`{? env("DB_PASSWORD") ?}`, nil, ": 1: 7: the environment variable DB_PASSWORD is not allowed, add it to lamb.env"},
+		{`{? env("FEATURE") ?}`, nil, ": 1: 7: the environment variable FEATURE is not allowed, add it to lamb.env"},
+		{`{? env() ?}`, nil, ": 1: 7: wrong number of arguments in env. got=0, want=1 to 2"},
+		{`{? env(1) ?}`, nil, ": 1: 7: argument 1 to `env` not supported, got int, want=string"},
+	})
+
+	internal.SetSettings(nil)
+
+	testBuiltins(t, []builtinTest{
+		{`{? env("APP_ENV") ?}`, nil, ": 1: 7: the environment variable APP_ENV is not allowed, add it to lamb.env"},
+	})
+}
+
+func TestNl2brSpaceless(t *testing.T) {
+	vars := map[string]interface{}{"body": "Hi <b>you</b>\r\nsecond\rthird\n\nend", "items": []string{"a", "b"}}
+
+	testBuiltins(t, []builtinTest{
+		{`{? nl2br(body) ?}`, vars, "Hi &lt;b&gt;you&lt;/b&gt;<br>\nsecond<br>\nthird<br>\n<br>\nend"},
+		{`{? nl2br("") ?}`, nil, ""},
+		{"{? spaceless ?}\n<ul>\n  {? for i in items ?}<li> {? i ?} </li>\n  {? endfor ?}\n</ul>\n{? endspaceless ?}", vars, "<ul><li> a </li><li> b </li></ul>"},
+		{"{? spaceless ?} text  between {? endspaceless ?}|", nil, "text  between|"},
+		{`{? spaceless ?}<p>{? 1 / 0 ?}</p>{? endspaceless ?}`, nil, ": 1: 24: division by zero"},
+		{`{? nl2br() ?}`, nil, ": 1: 9: wrong number of arguments in nl2br. got=0, want=1"},
+		{`{? nl2br(1) ?}`, nil, ": 1: 9: argument 1 to `nl2br` not supported, got int, want=string"},
+	})
+}
+
+func TestFuncBuiltinConversions(t *testing.T) {
+	vars := map[string]interface{}{
+		"twice":    func(n int) int { return n * 2 },
+		"unsigned": func(n uint) uint { return n },
+		"small":    func(n int8) int8 { return n },
+		"ratio":    func(f float32) float32 { return f },
+		"address":  func(p uintptr) uintptr { return p },
+	}
+
+	testBuiltins(t, []builtinTest{
+		{`{? twice(2) ?}|{? twice(2.0) ?}|{? unsigned(3) ?}|{? small(-128) ?}|{? ratio(0.5) ?}|{? ratio(0.1) ?}`, vars, "4|4|3|-128|0.5|0.1"},
+		{`{? twice(2.9) ?}`, vars, ": 1: 9: argument 1 to `twice` does not fit in int, got 2.9"},
+		{`{? unsigned(-1) ?}`, vars, ": 1: 12: argument 1 to `unsigned` does not fit in uint, got -1"},
+		{`{? unsigned(-1.0) ?}`, vars, ": 1: 12: argument 1 to `unsigned` does not fit in uint, got -1"},
+		{`{? small(300) ?}`, vars, ": 1: 9: argument 1 to `small` does not fit in int8, got 300"},
+		{`{? ratio(huge) ?}`, map[string]interface{}{"ratio": vars["ratio"], "huge": 1e300}, ": 1: 9: argument 1 to `ratio` does not fit in float32, got 1e+300"},
+		{`{? address(1) ?}`, vars, ": 1: 11: argument 1 to `address` not supported, got int, want=uintptr"},
+		{`{? twice("2") ?}`, vars, ": 1: 9: argument 1 to `twice` not supported, got string, want=int"},
+		{`{? twice() ?}`, vars, ": 1: 9: wrong number of arguments in twice. got=0, want=1"},
+	})
+}
+
+func TestGoFuncVars(t *testing.T) {
+	vars := map[string]interface{}{
+		"twice":  func(n int) int { return n * 2 },
+		"thrice": func(n int) int { return n * 3 },
+		"check":  func(s string) (string, error) { return s, errors.New("invalid " + s) },
+		"pair":   func() (int, int) { return 1, 2 },
+		"many":   func() (int, int, error) { return 1, 2, nil },
+	}
+
+	testBuiltins(t, []builtinTest{
+		{`{? twice(1) ?}|{? twice(2) ?}|{? thrice(2) ?}`, vars, "2|4|6"},
+		{`{? check("a") ?}`, vars, ": 1: 9: invalid a"},
+		{`{? pair() ?}`, vars, ": 1: 8: the second value returned by pair must be an error, got int"},
+		{`{? many() ?}`, vars, ": 1: 8: many must return at most 2 values, got 3"},
+	})
+}
+
+func TestAbsBuiltin(t *testing.T) {
+	vars := map[string]interface{}{"min": math.MinInt64, "max": math.MaxInt64}
+
+	testBuiltins(t, []builtinTest{
+		{`{? abs(-3) ?}|{? abs(3) ?}|{? abs(-2.5) ?}|{? abs(max) ?}|{? abs(min + 1) ?}`, vars, "3|3|2.5|9223372036854775807|9223372036854775807"},
+		{`{? abs(min) ?}`, vars, ": 1: 7: the absolute value of -9223372036854775808 overflows an int"},
+		{`{? abs() ?}`, nil, ": 1: 7: wrong number of arguments in abs. got=0, want=1"},
+		{`{? abs("1") ?}`, nil, ": 1: 7: argument 1 to `abs` not supported, got string, want=number"},
+	})
+}
@@ -0,0 +1,632 @@
+package evaluator
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/govel-framework/lamb/object"
+)
+
+// sliceArg returns the argument at index i as a slice or array.
+func sliceArg(name string, args []interface{}, i int) (reflect.Value, error) {
+	valueOf := reflect.ValueOf(args[i])
+
+	if valueOf.Kind() != reflect.Slice && valueOf.Kind() != reflect.Array {
+		return reflect.Value{}, builtInError("argument %d to `%s` not supported, got %T, want=slice", i+1, name, args[i])
+	}
+
+	return valueOf, nil
+}
+
+// copySlice returns a new slice with the elements of list, so the builtins
+// never change the slices of the handlers.
+func copySlice(list reflect.Value) reflect.Value {
+	sliceType := list.Type()
+
+	if list.Kind() == reflect.Array {
+		sliceType = reflect.SliceOf(sliceType.Elem())
+	}
+
+	result := reflect.MakeSlice(sliceType, list.Len(), list.Len())
+	reflect.Copy(result, list)
+
+	return result
+}
+
+// compare returns -1, 0 or 1 when a is less than, equal to or greater than b.
+// Numbers, strings and times can be compared.
+func compare(a, b interface{}) (int, bool) {
+	aNumber, isANumber := toFloat(a)
+	bNumber, isBNumber := toFloat(b)
+
+	if isANumber && isBNumber {
+		switch {
+		case aNumber < bNumber:
+			return -1, true
+
+		case aNumber > bNumber:
+			return 1, true
+
+		default:
+			return 0, true
+		}
+	}
+
+	aString, isAString := a.(string)
+	bString, isBString := b.(string)
+
+	if isAString && isBString {
+		return strings.Compare(aString, bString), true
+	}
+
+	aTime, isATime := a.(time.Time)
+	bTime, isBTime := b.(time.Time)
+
+	if isATime && isBTime {
+		switch {
+		case aTime.Before(bTime):
+			return -1, true
+
+		case aTime.After(bTime):
+			return 1, true
+
+		default:
+			return 0, true
+		}
+	}
+
+	return 0, false
+}
+
+// fieldValue returns the field of a struct or the key of a map with string
+// keys, checking the sandbox of the template.
+func fieldValue(env *object.Environment, item interface{}, field string) (interface{}, error) {
+	valueOf := reflect.Indirect(reflect.ValueOf(item))
+
+	switch valueOf.Kind() {
+
+	case reflect.Map:
+		if valueOf.Type().Key().Kind() != reflect.String && valueOf.Type().Key().Kind() != reflect.Interface {
+			return nil, builtInError("map keys of %T are not strings", item)
+		}
+
+		value := valueOf.MapIndex(reflect.ValueOf(field))
+
+		if !value.IsValid() {
+			return nil, nil
+		}
+
+		return value.Interface(), nil
+
+	case reflect.Struct:
+		structField, exists := structField(valueOf.Type(), field)
+
+		if exists {
+			field = structField.Name
+		}
+
+		if env.Sandbox != nil && !env.Sandbox.CanAccess(valueOf.Type(), field) {
+			return nil, builtInError("field %s of %s is not allowed in the sandbox", field, valueOf.Type())
+		}
+
+		if !exists {
+			return nil, builtInError("field %s does not exist in struct %s", field, valueOf.Type())
+		}
+
+		return fieldInterface(reflect.ValueOf(item), structField)
+
+	default:
+		return nil, builtInError("cannot get the field %s of %T, want=struct or map", field, item)
+	}
+}
+
+// sortSlice sorts a copy of list by the value returned by key.
+func sortSlice(name string, list reflect.Value, key func(interface{}) (interface{}, error)) interface{} {
+	result := copySlice(list)
+	keys := make([]interface{}, result.Len())
+
+	for i := range keys {
+		value, err := key(result.Index(i).Interface())
+
+		if err != nil {
+			return err
+		}
+
+		keys[i] = value
+	}
+
+	var err error
+
+	sort.Stable(&keySorter{
+		keys: keys,
+		swap: reflect.Swapper(result.Interface()),
+		less: func(a, b interface{}) bool {
+			order, comparable := compare(a, b)
+
+			if !comparable && err == nil {
+				err = builtInError("cannot compare %T and %T in `%s`", a, b, name)
+			}
+
+			return order < 0
+		},
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return result.Interface()
+}
+
+// keySorter sorts a slice by its precomputed keys.
+type keySorter struct {
+	keys []interface{}
+	swap func(i, j int)
+	less func(a, b interface{}) bool
+}
+
+func (s *keySorter) Len() int { return len(s.keys) }
+
+func (s *keySorter) Less(i, j int) bool { return s.less(s.keys[i], s.keys[j]) }
+
+func (s *keySorter) Swap(i, j int) {
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+	s.swap(i, j)
+}
+
+// sortBuiltIn sorts a slice of numbers, strings or times in ascending order.
+func sortBuiltIn(args ...interface{}) interface{} {
+	if err := checkArgs("sort", args, 1, 1); err != nil {
+		return err
+	}
+
+	list, err := sliceArg("sort", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	return sortSlice("sort", list, func(item interface{}) (interface{}, error) {
+		return item, nil
+	})
+}
+
+// sortByBuiltIn sorts a slice of structs or maps by a field in ascending
+// order: sort_by(users, "Name").
+func sortByBuiltIn(env *object.Environment, args ...interface{}) interface{} {
+	if err := checkArgs("sort_by", args, 2, 2); err != nil {
+		return err
+	}
+
+	list, err := sliceArg("sort_by", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	field, err := stringArg("sort_by", args, 1)
+
+	if err != nil {
+		return err
+	}
+
+	return sortSlice("sort_by", list, func(item interface{}) (interface{}, error) {
+		return fieldValue(env, item, field)
+	})
+}
+
+// reverseBuiltIn reverses a slice or a string.
+func reverseBuiltIn(args ...interface{}) interface{} {
+	if err := checkArgs("reverse", args, 1, 1); err != nil {
+		return err
+	}
+
+	if s, isString := args[0].(string); isString {
+		runes := []rune(s)
+
+		for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+			runes[i], runes[j] = runes[j], runes[i]
+		}
+
+		return string(runes)
+	}
+
+	list, err := sliceArg("reverse", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	result := copySlice(list)
+	swap := reflect.Swapper(result.Interface())
+
+	for i, j := 0, result.Len()-1; i < j; i, j = i+1, j-1 {
+		swap(i, j)
+	}
+
+	return result.Interface()
+}
+
+// uniqueBuiltIn removes the repeated elements of a slice, keeping the first
+// one.
+func uniqueBuiltIn(args ...interface{}) interface{} {
+	if err := checkArgs("unique", args, 1, 1); err != nil {
+		return err
+	}
+
+	list, err := sliceArg("unique", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	result := reflect.MakeSlice(copySlice(list).Type(), 0, list.Len())
+
+	for i := 0; i < list.Len(); i++ {
+		repeated := false
+
+		for j := 0; j < result.Len(); j++ {
+			if equals(list.Index(i).Interface(), result.Index(j).Interface()) {
+				repeated = true
+
+				break
+			}
+		}
+
+		if !repeated {
+			result = reflect.Append(result, list.Index(i))
+		}
+	}
+
+	return result.Interface()
+}
+
+// firstBuiltIn returns the first element of a slice or the first character of
+// a string, nil when it is empty.
+func firstBuiltIn(args ...interface{}) interface{} {
+	return edgeElement("first", args, true)
+}
+
+// lastBuiltIn returns the last element of a slice or the last character of a
+// string, nil when it is empty.
+func lastBuiltIn(args ...interface{}) interface{} {
+	return edgeElement("last", args, false)
+}
+
+func edgeElement(name string, args []interface{}, first bool) interface{} {
+	if err := checkArgs(name, args, 1, 1); err != nil {
+		return err
+	}
+
+	if s, isString := args[0].(string); isString {
+		runes := []rune(s)
+
+		if len(runes) == 0 {
+			return nil
+		}
+
+		if first {
+			return string(runes[0])
+		}
+
+		return string(runes[len(runes)-1])
+	}
+
+	list, err := sliceArg(name, args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	if list.Len() == 0 {
+		return nil
+	}
+
+	if first {
+		return list.Index(0).Interface()
+	}
+
+	return list.Index(list.Len() - 1).Interface()
+}
+
+// mapArg returns the argument at index i as a map.
+func mapArg(name string, args []interface{}, i int) (reflect.Value, error) {
+	valueOf := reflect.ValueOf(args[i])
+
+	if valueOf.Kind() != reflect.Map {
+		return reflect.Value{}, builtInError("argument %d to `%s` not supported, got %T, want=map", i+1, name, args[i])
+	}
+
+	return valueOf, nil
+}
+
+// sortedKeys returns the keys of the map in ascending order, so the output of
+// a template does not change between renders.
+func sortedKeys(m reflect.Value) []reflect.Value {
+	keys := m.MapKeys()
+
+	sort.SliceStable(keys, func(i, j int) bool {
+		a, b := keys[i].Interface(), keys[j].Interface()
+
+		if order, comparable := compare(a, b); comparable {
+			return order < 0
+		}
+
+		return fmt.Sprintf("%v", a) < fmt.Sprintf("%v", b)
+	})
+
+	return keys
+}
+
+// keysBuiltIn returns the keys of a map in ascending order.
+func keysBuiltIn(args ...interface{}) interface{} {
+	if err := checkArgs("keys", args, 1, 1); err != nil {
+		return err
+	}
+
+	m, err := mapArg("keys", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	result := reflect.MakeSlice(reflect.SliceOf(m.Type().Key()), 0, m.Len())
+
+	for _, key := range sortedKeys(m) {
+		result = reflect.Append(result, key)
+	}
+
+	return result.Interface()
+}
+
+// valuesBuiltIn returns the values of a map in the order of its keys.
+func valuesBuiltIn(args ...interface{}) interface{} {
+	if err := checkArgs("values", args, 1, 1); err != nil {
+		return err
+	}
+
+	m, err := mapArg("values", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	result := reflect.MakeSlice(reflect.SliceOf(m.Type().Elem()), 0, m.Len())
+
+	for _, key := range sortedKeys(m) {
+		result = reflect.Append(result, m.MapIndex(key))
+	}
+
+	return result.Interface()
+}
+
+// mergeBuiltIn returns a new map with the entries of both maps, the second
+// one wins when both have the same key.
+func mergeBuiltIn(args ...interface{}) interface{} {
+	if err := checkArgs("merge", args, 2, 2); err != nil {
+		return err
+	}
+
+	a, err := mapArg("merge", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	b, err := mapArg("merge", args, 1)
+
+	if err != nil {
+		return err
+	}
+
+	// keep the type of the maps when they have the same one
+	mapType := a.Type()
+
+	if a.Type() != b.Type() {
+		mapType = reflect.TypeOf(map[interface{}]interface{}{})
+	}
+
+	result := reflect.MakeMapWithSize(mapType, a.Len()+b.Len())
+
+	for _, m := range []reflect.Value{a, b} {
+		for _, key := range m.MapKeys() {
+			result.SetMapIndex(key, m.MapIndex(key))
+		}
+	}
+
+	return result.Interface()
+}
+
+// concatBuiltIn returns a new slice with the elements of both slices.
+func concatBuiltIn(args ...interface{}) interface{} {
+	if err := checkArgs("concat", args, 2, 2); err != nil {
+		return err
+	}
+
+	a, err := sliceArg("concat", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	b, err := sliceArg("concat", args, 1)
+
+	if err != nil {
+		return err
+	}
+
+	// keep the type of the slices when they have the same one
+	sliceType := reflect.SliceOf(a.Type().Elem())
+
+	if a.Type().Elem() != b.Type().Elem() {
+		sliceType = reflect.TypeOf([]interface{}{})
+	}
+
+	result := reflect.MakeSlice(sliceType, 0, a.Len()+b.Len())
+
+	for _, list := range []reflect.Value{a, b} {
+		for i := 0; i < list.Len(); i++ {
+			result = reflect.Append(result, list.Index(i))
+		}
+	}
+
+	return result.Interface()
+}
+
+// pluckBuiltIn returns a field of every element of a slice of structs or
+// maps: pluck(users, "Email").
+func pluckBuiltIn(env *object.Environment, args ...interface{}) interface{} {
+	if err := checkArgs("pluck", args, 2, 2); err != nil {
+		return err
+	}
+
+	list, err := sliceArg("pluck", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	field, err := stringArg("pluck", args, 1)
+
+	if err != nil {
+		return err
+	}
+
+	result := make([]interface{}, list.Len())
+
+	for i := range result {
+		if result[i], err = fieldValue(env, list.Index(i).Interface(), field); err != nil {
+			return err
+		}
+	}
+
+	return result
+}
+
+// whereBuiltIn returns the elements of a slice of structs or maps whose field
+// is equal to the value: where(users, "Role", "admin").
+func whereBuiltIn(env *object.Environment, args ...interface{}) interface{} {
+	if err := checkArgs("where", args, 3, 3); err != nil {
+		return err
+	}
+
+	list, err := sliceArg("where", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	field, err := stringArg("where", args, 1)
+
+	if err != nil {
+		return err
+	}
+
+	result := reflect.MakeSlice(copySlice(list).Type(), 0, list.Len())
+
+	for i := 0; i < list.Len(); i++ {
+		value, err := fieldValue(env, list.Index(i).Interface(), field)
+
+		if err != nil {
+			return err
+		}
+
+		if equals(value, args[2]) {
+			result = reflect.Append(result, list.Index(i))
+		}
+	}
+
+	return result.Interface()
+}
+
+// chunkBuiltIn splits a slice into slices of size elements, the last one can
+// be shorter: chunk(items, 3) gives the rows of a grid of 3 columns.
+func chunkBuiltIn(args ...interface{}) interface{} {
+	if err := checkArgs("chunk", args, 2, 2); err != nil {
+		return err
+	}
+
+	list, err := sliceArg("chunk", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	size, err := intArg("chunk", args, 1)
+
+	if err != nil {
+		return err
+	}
+
+	if size < 1 {
+		return builtInError("the size of the chunks in `chunk` must be greater than 0, got %d", size)
+	}
+
+	sliceType := copySlice(list).Type()
+	result := reflect.MakeSlice(reflect.SliceOf(sliceType), 0, (list.Len()+size-1)/size)
+
+	for start := 0; start < list.Len(); start += size {
+		end := start + size
+
+		if end > list.Len() {
+			end = list.Len()
+		}
+
+		chunk := reflect.MakeSlice(sliceType, end-start, end-start)
+		reflect.Copy(chunk, list.Slice(start, end))
+
+		result = reflect.Append(result, chunk)
+	}
+
+	return result.Interface()
+}
+
+// groupByBuiltIn groups the elements of a slice of structs or maps by a field:
+// group_by(products, "Category") gives a map of the categories to their
+// products, in the order they are in the slice.
+func groupByBuiltIn(env *object.Environment, args ...interface{}) interface{} {
+	if err := checkArgs("group_by", args, 2, 2); err != nil {
+		return err
+	}
+
+	list, err := sliceArg("group_by", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	field, err := stringArg("group_by", args, 1)
+
+	if err != nil {
+		return err
+	}
+
+	sliceType := copySlice(list).Type()
+	result := reflect.MakeMap(reflect.MapOf(reflect.TypeOf((*interface{})(nil)).Elem(), sliceType))
+
+	for i := 0; i < list.Len(); i++ {
+		value, err := fieldValue(env, list.Index(i).Interface(), field)
+
+		if err != nil {
+			return err
+		}
+
+		if value != nil && !reflect.TypeOf(value).Comparable() {
+			return builtInError("cannot group by %s in `group_by`, %T can not be a map key", field, value)
+		}
+
+		key := reflect.ValueOf(&value).Elem()
+		group := result.MapIndex(key)
+
+		if !group.IsValid() {
+			group = reflect.MakeSlice(sliceType, 0, 1)
+		}
+
+		result.SetMapIndex(key, reflect.Append(group, list.Index(i)))
+	}
+
+	return result.Interface()
+}
@@ -0,0 +1,369 @@
+package evaluator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/govel-framework/lamb/ast"
+	"github.com/govel-framework/lamb/internal"
+	"github.com/govel-framework/lamb/object"
+)
+
+// opcode is an instruction of the IR the templates are compiled to.
+type opcode byte
+
+const (
+	opHTML        opcode = iota // write the HTML fragment a
+	opConst                     // push the constant a
+	opLoad                      // push the var of the slot a, read by the identifier node b
+	opStore                     // pop a value and assign it to the var of the slot a
+	opWrite                     // pop a value and write it, escaped if a is 1
+	opPrefix                    // pop the operand of the prefix expression node a and push the result
+	opInfix                     // pop the operands of the infix expression node a and push the result
+	opIndex                     // pop the index and the left side of the index expression node a and push the result
+	opDot                       // pop the left side of the dot expression node a and push the result
+	opList                      // pop a elements and push them as a list
+	opMap                       // pop the keys and values of the map literal node a and push the map
+	opCall                      // pop the arguments and the function of the call node a and push the result
+	opJump                      // jump to the instruction a
+	opJumpIfFalse               // pop a value and jump to the instruction a if it is not truthy
+	opFor                       // run the loop a, its body are the instructions that follow
+	opEval                      // push the value of the node a, evaluated by walking its tree
+)
+
+var opcodeNames = [...]string{
+	opHTML:        "HTML",
+	opConst:       "CONST",
+	opLoad:        "LOAD",
+	opStore:       "STORE",
+	opWrite:       "WRITE",
+	opPrefix:      "PREFIX",
+	opInfix:       "INFIX",
+	opIndex:       "INDEX",
+	opDot:         "DOT",
+	opList:        "LIST",
+	opMap:         "MAP",
+	opCall:        "CALL",
+	opJump:        "JUMP",
+	opJumpIfFalse: "JUMP_IF_FALSE",
+	opFor:         "FOR",
+	opEval:        "EVAL",
+}
+
+func (op opcode) String() string {
+	return opcodeNames[op]
+}
+
+type instruction struct {
+	op   opcode
+	a, b int
+}
+
+// Code is the IR of a program or a block: a flat list of instructions run by
+// a small stack machine. The vars are resolved to slots and the builtins are
+// bound when the code is compiled, and the nodes the IR does not cover (e.g.
+// includes, sections or lambdas) are evaluated by walking their tree.
+type Code struct {
+	instructions []instruction
+	constants    []interface{}
+	html         [][]byte
+	nodes        []ast.Node
+	names        []string          // The names of the vars of the slots.
+	builtins     []*object.Builtin // The builtins with the names of the slots, when the code was compiled.
+	loops        []compiledLoop
+}
+
+type compiledLoop struct {
+	node  *ast.ForExpression
+	value int // The slot of the value.
+	key   int // The slot of the key, -1 if the loop has no key.
+	loop  int // The slot of the loop var.
+	end   int // The instruction after the body.
+}
+
+// String returns the instructions, one per line, for debugging.
+func (code *Code) String() string {
+	var out strings.Builder
+
+	for pc, ins := range code.instructions {
+		fmt.Fprintf(&out, "%04d %s", pc, ins.op)
+
+		switch ins.op {
+		case opHTML:
+			fmt.Fprintf(&out, " %q", code.html[ins.a])
+
+		case opConst:
+			fmt.Fprintf(&out, " %#v", code.constants[ins.a])
+
+		case opLoad, opStore:
+			fmt.Fprintf(&out, " %s", code.names[ins.a])
+
+		case opPrefix, opInfix, opIndex, opDot, opMap, opCall, opEval:
+			fmt.Fprintf(&out, " %s", code.nodes[ins.a])
+
+		case opList, opJump, opJumpIfFalse:
+			fmt.Fprintf(&out, " %d", ins.a)
+
+		case opFor:
+			fmt.Fprintf(&out, " %s -> %d", code.loops[ins.a].node.In, code.loops[ins.a].end)
+		}
+
+		out.WriteByte('\n')
+	}
+
+	return out.String()
+}
+
+// Compile lowers the statements of a program or a block to the IR.
+func Compile(statements []ast.Statement) *Code {
+	c := &compiler{code: &Code{}, slots: make(map[string]int)}
+
+	c.statements(statements)
+
+	return c.code
+}
+
+// compiled returns the IR of the node (a program or a block) when lamb.compile
+// is on, compiling it the first time. The statement hooks see the statements
+// one by one, so nothing is compiled while they are set.
+func compiled(node interface {
+	Compiled() interface{}
+	SetCompiled(interface{})
+}, statements []ast.Statement) *Code {
+	if internal.Setting("GOVEL_LAMB_COMPILE") != "true" || internal.CurrentHooks() != nil {
+		return nil
+	}
+
+	if code, isCode := node.Compiled().(*Code); isCode {
+		return code
+	}
+
+	code := Compile(statements)
+
+	node.SetCompiled(code)
+
+	return code
+}
+
+type compiler struct {
+	code  *Code
+	slots map[string]int
+}
+
+func (c *compiler) emit(op opcode, a, b int) int {
+	c.code.instructions = append(c.code.instructions, instruction{op: op, a: a, b: b})
+
+	return len(c.code.instructions) - 1
+}
+
+// patch makes the jump at pc go to the next instruction.
+func (c *compiler) patch(pc int) {
+	c.code.instructions[pc].a = len(c.code.instructions)
+}
+
+func (c *compiler) constant(value interface{}) int {
+	c.code.constants = append(c.code.constants, value)
+
+	return len(c.code.constants) - 1
+}
+
+func (c *compiler) node(node ast.Node) int {
+	c.code.nodes = append(c.code.nodes, node)
+
+	return len(c.code.nodes) - 1
+}
+
+// slot returns the slot of the var with the given name.
+func (c *compiler) slot(name string) int {
+	if slot, exists := c.slots[name]; exists {
+		return slot
+	}
+
+	builtin, _ := Registry.Get(name)
+
+	c.slots[name] = len(c.code.names)
+	c.code.names = append(c.code.names, name)
+	c.code.builtins = append(c.code.builtins, builtin)
+
+	return len(c.code.names) - 1
+}
+
+func (c *compiler) statements(statements []ast.Statement) {
+	for _, statement := range statements {
+		c.statement(statement)
+	}
+}
+
+func (c *compiler) statement(statement ast.Statement) {
+	switch statement := statement.(type) {
+	case *ast.ExpressionStatement:
+		switch expression := statement.Expression.(type) {
+		case *ast.HtmlLiteral:
+			c.code.html = append(c.code.html, []byte(expression.Value))
+			c.emit(opHTML, len(c.code.html)-1, 0)
+
+			return
+
+		case *ast.IfExpression:
+			if expression.Consequence != nil {
+				c.ifExpression(expression)
+
+				return
+			}
+
+		case *ast.ForExpression:
+			if expression.Block != nil {
+				c.forExpression(expression)
+
+				return
+			}
+		}
+
+		c.expression(statement.Expression)
+
+		// a value is escaped in the output mode of the template
+		if isInterpolation(statement) {
+			c.emit(opWrite, 1, 0)
+		} else {
+			c.emit(opWrite, 0, 0)
+		}
+
+	case *ast.VarStatement:
+		c.expression(statement.Value)
+		c.emit(opStore, c.slot(statement.Name.Value), 0)
+
+	default:
+		c.emit(opEval, c.node(statement), 0)
+		c.emit(opWrite, 0, 0)
+	}
+}
+
+// ifExpression compiles an if in the place of a statement, its blocks write
+// their output instead of returning it.
+func (c *compiler) ifExpression(node *ast.IfExpression) {
+	c.expression(node.Condition)
+
+	jump := c.emit(opJumpIfFalse, 0, 0)
+
+	c.statements(node.Consequence.Statements)
+
+	if node.Alternative == nil {
+		c.patch(jump)
+
+		return
+	}
+
+	end := c.emit(opJump, 0, 0)
+
+	c.patch(jump)
+	c.statements(node.Alternative.Statements)
+	c.patch(end)
+}
+
+// forExpression compiles a for loop in the place of a statement, its body
+// writes its output instead of returning it.
+func (c *compiler) forExpression(node *ast.ForExpression) {
+	// the loop evaluates a map literal itself, to keep the order of its keys
+	if _, isMapLiteral := node.In.(*ast.MapLiteral); !isMapLiteral {
+		c.expression(node.In)
+	}
+
+	loop := compiledLoop{node: node, value: c.slot(node.Value), key: -1, loop: c.slot("loop")}
+
+	if node.Key != "" {
+		loop.key = c.slot(node.Key)
+	}
+
+	index := len(c.code.loops)
+	c.code.loops = append(c.code.loops, loop)
+
+	c.emit(opFor, index, 0)
+	c.statements(node.Block.Statements)
+
+	c.code.loops[index].end = len(c.code.instructions)
+}
+
+func (c *compiler) expression(node ast.Expression) {
+	switch node := node.(type) {
+	case nil:
+		c.emit(opConst, c.constant(nil), 0)
+
+	case *ast.IntegerLiteral:
+		c.emit(opConst, c.constant(node.Value), 0)
+
+	case *ast.FloatLiteral:
+		c.emit(opConst, c.constant(node.Value), 0)
+
+	case *ast.Boolean:
+		c.emit(opConst, c.constant(node.Value), 0)
+
+	case *ast.HtmlLiteral:
+		c.emit(opConst, c.constant(node.Value), 0)
+
+	case *ast.StringLiteral:
+		if !node.Closed {
+			c.emit(opEval, c.node(node), 0)
+
+			return
+		}
+
+		c.emit(opConst, c.constant(node.Value), 0)
+
+	case *ast.Identifier:
+		c.emit(opLoad, c.slot(node.Value), c.node(node))
+
+	case *ast.PrefixExpression:
+		c.expression(node.Right)
+		c.emit(opPrefix, c.node(node), 0)
+
+	case *ast.InfixExpression:
+		c.expression(node.Left)
+		c.expression(node.Right)
+		c.emit(opInfix, c.node(node), 0)
+
+	case *ast.IndexExpression:
+		c.expression(node.Left)
+		c.expression(node.Index)
+		c.emit(opIndex, c.node(node), 0)
+
+	case *ast.DotExpression:
+		c.expression(node.Left)
+		c.emit(opDot, c.node(node), 0)
+
+	case *ast.ArrayLiteral:
+		for _, element := range node.Elements {
+			c.expression(element)
+		}
+
+		c.emit(opList, len(node.Elements), 0)
+
+	case *ast.MapLiteral:
+		for _, pair := range node.Pairs {
+			c.expression(pair.Key)
+			c.expression(pair.Value)
+		}
+
+		c.emit(opMap, c.node(node), 0)
+
+	case *ast.CallExpression:
+		// isset(x) and the other checks do not evaluate their argument first
+		if identifier, isIdentifier := node.Function.(*ast.Identifier); isIdentifier {
+			if _, isCheck := presenceChecks[identifier.Value]; isCheck {
+				c.emit(opEval, c.node(node), 0)
+
+				return
+			}
+		}
+
+		c.expression(node.Function)
+
+		for _, arg := range node.Arguments {
+			c.expression(arg)
+		}
+
+		c.emit(opCall, c.node(node), 0)
+
+	default:
+		c.emit(opEval, c.node(node), 0)
+	}
+}
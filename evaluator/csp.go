@@ -0,0 +1,63 @@
+package evaluator
+
+import (
+	"html"
+
+	"github.com/govel-framework/lamb/object"
+)
+
+// cspNonceBuiltIn returns the Content-Security-Policy nonce of the request
+// (ctx.Nonce), for inline scripts and styles:
+//
+//	<script nonce="{? csp_nonce() ?}">...</script>
+func cspNonceBuiltIn(env *object.Environment, args ...interface{}) interface{} {
+	if err := checkArgs("csp_nonce", args, 0, 0); err != nil {
+		return err
+	}
+
+	return contextString(env, "Nonce")
+}
+
+// nonceAttribute returns the nonce attribute of a tag, empty when the request
+// has no nonce.
+func nonceAttribute(env *object.Environment) string {
+	nonce := contextString(env, "Nonce")
+
+	if nonce == "" {
+		return ""
+	}
+
+	return ` nonce="` + html.EscapeString(nonce) + `"`
+}
+
+// scriptBuiltIn returns a script tag with the nonce of the request:
+// script(asset("app.js")).
+func scriptBuiltIn(env *object.Environment, args ...interface{}) interface{} {
+	if err := checkArgs("script", args, 1, 1); err != nil {
+		return err
+	}
+
+	src, err := stringArg("script", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	return `<script src="` + html.EscapeString(src) + `"` + nonceAttribute(env) + `></script>`
+}
+
+// styleBuiltIn returns a stylesheet link tag with the nonce of the request:
+// style(asset("app.css")).
+func styleBuiltIn(env *object.Environment, args ...interface{}) interface{} {
+	if err := checkArgs("style", args, 1, 1); err != nil {
+		return err
+	}
+
+	href, err := stringArg("style", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	return `<link rel="stylesheet" href="` + html.EscapeString(href) + `"` + nonceAttribute(env) + `>`
+}
@@ -0,0 +1,129 @@
+package evaluator
+
+import (
+	"github.com/govel-framework/lamb/internal"
+	"github.com/govel-framework/lamb/object"
+
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// symbolAfterAmount lists the locales that write the currency symbol after
+// the amount (e.g. "1.234,50 €") and their regions that write it before, as
+// the currency patterns of CLDR do: x/text formats every amount as "€ 1,234.50"
+// and does not expose these patterns. See symbolAfter.
+var symbolAfterAmount = map[string]bool{
+	"cs":     true,
+	"da":     true,
+	"de":     true,
+	"de-AT":  false,
+	"de-CH":  false,
+	"de-LI":  false,
+	"es":     true,
+	"es-419": false, // the Spanish of Latin America, e.g. es-MX
+	"fi":     true,
+	"fr":     true,
+	"it":     true,
+	"it-CH":  false,
+	"nb":     true,
+	"pl":     true,
+	"pt-PT":  true, // pt is the Portuguese of Brazil
+	"ru":     true,
+	"sk":     true,
+	"sv":     true,
+}
+
+// symbolAfter reports whether the locale writes the currency symbol after the
+// amount, the most specific locale listed in symbolAfterAmount applies, e.g.
+// es-419 to es-MX.
+func symbolAfter(locale language.Tag) bool {
+	for tag := locale; !tag.IsRoot(); tag = tag.Parent() {
+		if after, listed := symbolAfterAmount[tag.String()]; listed {
+			return after
+		}
+	}
+
+	return false
+}
+
+// defaultLocale returns the locale set in lamb.locale, en-US by default.
+func defaultLocale() language.Tag {
+	if tag, err := language.Parse(internal.Setting("GOVEL_LAMB_LOCALE")); err == nil {
+		return tag
+	}
+
+	return language.AmericanEnglish
+}
+
+// currencyBuiltIn formats an amount of money: currency(1234.5, "EUR", "de-DE")
+// gives "1.234,50 €". The locale (optional) defaults to the one of the render,
+// and the position of the symbol can be forced with lamb.currency_symbol
+// ("before" or "after").
+func currencyBuiltIn(env *object.Environment, args ...interface{}) interface{} {
+	if err := checkArgs("currency", args, 2, 3); err != nil {
+		return err
+	}
+
+	amount, isNumber := toFloat(args[0])
+
+	if !isNumber {
+		return builtInError("argument 1 to `currency` not supported, got %T, want=number", args[0])
+	}
+
+	code, err := stringArg("currency", args, 1)
+
+	if err != nil {
+		return err
+	}
+
+	unit, err := currency.ParseISO(code)
+
+	if err != nil {
+		return builtInError("unknown currency in `currency`: %s", code)
+	}
+
+	locale := parseLocale(renderLocale(env))
+
+	if len(args) == 3 {
+		tag, err := stringArg("currency", args, 2)
+
+		if err != nil {
+			return err
+		}
+
+		if locale, err = language.Parse(tag); err != nil {
+			return builtInError("unknown locale in `currency`: %s", tag)
+		}
+	}
+
+	printer := message.NewPrinter(locale)
+
+	sign := ""
+
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	scale, _ := currency.Standard.Rounding(unit)
+	formatted := printer.Sprint(number.Decimal(amount, number.MinFractionDigits(scale), number.MaxFractionDigits(scale)))
+	symbol := printer.Sprint(currency.Symbol(unit))
+
+	after := symbolAfter(locale)
+
+	switch internal.Setting("GOVEL_LAMB_CURRENCY_SYMBOL") {
+	case "before":
+		after = false
+
+	case "after":
+		after = true
+	}
+
+	if after {
+		return sign + formatted + "\u00a0" + symbol // a no-break space keeps the symbol with the amount
+	}
+
+	return sign + symbol + formatted
+}
@@ -0,0 +1,217 @@
+package evaluator
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/govel-framework/lamb/object"
+)
+
+// dateTokens maps the tokens of the human layouts (e.g. "DD MMM YYYY") to the
+// layout of the time package, longest first so "MMMM" is not read as "MM".
+var dateTokens = []struct {
+	token  string
+	layout string
+}{
+	{"YYYY", "2006"},
+	{"MMMM", "January"},
+	{"dddd", "Monday"},
+	{"MMM", "Jan"},
+	{"ddd", "Mon"},
+	{"YY", "06"},
+	{"MM", "01"},
+	{"DD", "02"},
+	{"HH", "15"},
+	{"hh", "03"},
+	{"mm", "04"},
+	{"ss", "05"},
+	{"M", "1"},
+	{"D", "2"},
+	{"h", "3"},
+	{"A", "PM"},
+	{"a", "pm"},
+	{"Z", "-07:00"},
+}
+
+// toTime converts a time.Time, a Unix timestamp or a RFC3339 (or
+// "2006-01-02") string into a time.Time.
+func toTime(name string, value interface{}) (time.Time, error) {
+	switch value := value.(type) {
+	case time.Time:
+		return value, nil
+
+	case *time.Time:
+		if value != nil {
+			return *value, nil
+		}
+
+	case string:
+		for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t, nil
+			}
+		}
+
+		return time.Time{}, builtInError("argument to `%s` not supported, %q is not a RFC3339 date", name, value)
+	}
+
+	if unix, isInt := isNumber(value); isInt {
+		return time.Unix(int64(unix), 0), nil
+	}
+
+	return time.Time{}, builtInError("argument to `%s` not supported, got %T, want=time, int or string", name, value)
+}
+
+// toLayout converts a human layout into a layout of the time package, the
+// layouts that already use the reference time (e.g. "2006-01-02") are kept.
+// Text between brackets is not converted: "DD [of] MMMM".
+func toLayout(layout string) string {
+	if strings.Contains(layout, "2006") || strings.Contains(layout, "Jan") || strings.Contains(layout, "15") {
+		return layout
+	}
+
+	var out strings.Builder
+
+	for i := 0; i < len(layout); {
+		if layout[i] == '[' {
+			if end := strings.IndexByte(layout[i:], ']'); end != -1 {
+				out.WriteString(layout[i+1 : i+end])
+				i += end + 1
+
+				continue
+			}
+		}
+
+		converted := false
+
+		for _, t := range dateTokens {
+			if strings.HasPrefix(layout[i:], t.token) {
+				out.WriteString(t.layout)
+				i += len(t.token)
+				converted = true
+
+				break
+			}
+		}
+
+		if !converted {
+			out.WriteByte(layout[i])
+			i++
+		}
+	}
+
+	return out.String()
+}
+
+// dateBuiltIn formats a date: date(value, "DD MMM YYYY", "Europe/Madrid"),
+// the layout defaults to RFC3339 and the timezone (optional) converts the
+// date before formatting it. The names of the months and the days are in the
+// language of the locale of the render.
+func dateBuiltIn(env *object.Environment, args ...interface{}) interface{} {
+	if err := checkArgs("date", args, 1, 3); err != nil {
+		return err
+	}
+
+	t, err := toTime("date", args[0])
+
+	if err != nil {
+		return err
+	}
+
+	layout := time.RFC3339
+
+	if len(args) > 1 {
+		if layout, err = stringArg("date", args, 1); err != nil {
+			return err
+		}
+	}
+
+	if len(args) > 2 {
+		zone, err := stringArg("date", args, 2)
+
+		if err != nil {
+			return err
+		}
+
+		location, err := time.LoadLocation(zone)
+
+		if err != nil {
+			return builtInError("unknown timezone in `date`: %s", zone)
+		}
+
+		t = t.In(location)
+	}
+
+	return formatLocalized(t, toLayout(layout), parseLocale(renderLocale(env)))
+}
+
+func nowBuiltIn(args ...interface{}) interface{} {
+	if err := checkArgs("now", args, 0, 0); err != nil {
+		return err
+	}
+
+	return time.Now()
+}
+
+// agoBuiltIn describes the time between a date and now: "3 minutes ago",
+// "in 2 days".
+func agoBuiltIn(args ...interface{}) interface{} {
+	if err := checkArgs("ago", args, 1, 1); err != nil {
+		return err
+	}
+
+	t, err := toTime("ago", args[0])
+
+	if err != nil {
+		return err
+	}
+
+	return humanizeDuration(time.Since(t))
+}
+
+func humanizeDuration(d time.Duration) string {
+	future := d < 0
+
+	if future {
+		d = -d
+	}
+
+	if d < time.Minute {
+		return "just now"
+	}
+
+	units := []struct {
+		name     string
+		duration time.Duration
+	}{
+		{"year", 365 * 24 * time.Hour},
+		{"month", 30 * 24 * time.Hour},
+		{"week", 7 * 24 * time.Hour},
+		{"day", 24 * time.Hour},
+		{"hour", time.Hour},
+		{"minute", time.Minute},
+	}
+
+	var text string
+
+	for _, unit := range units {
+		if d >= unit.duration {
+			count := int(math.Floor(float64(d) / float64(unit.duration)))
+			text = fmt.Sprintf("%d %s", count, unit.name)
+
+			if count != 1 {
+				text += "s"
+			}
+
+			break
+		}
+	}
+
+	if future {
+		return "in " + text
+	}
+
+	return text + " ago"
+}
@@ -0,0 +1,18 @@
+package evaluator
+
+import (
+	"github.com/govel-framework/lamb/internal"
+	"github.com/govel-framework/lamb/object"
+)
+
+// diagnose adds the diagnostic to the ones of the render, or logs it if the
+// render does not collect them.
+func diagnose(env *object.Environment, diagnostic object.Diagnostic) {
+	if env.Diagnostics != nil {
+		env.Diagnostics.Add(diagnostic)
+
+		return
+	}
+
+	internal.Log().Warn(diagnostic.Message, "kind", diagnostic.Kind, "file", diagnostic.File, "line", diagnostic.Line)
+}
@@ -0,0 +1,49 @@
+package evaluator
+
+import (
+	"sync"
+
+	"github.com/govel-framework/lamb/ast"
+	"github.com/govel-framework/lamb/object"
+)
+
+// DirectiveFunc evaluates a directive registered with RegisterDirective and
+// returns its output. Its arguments and block are evaluated with Eval.
+type DirectiveFunc func(directive *ast.DirectiveStatement, env *object.Environment) (string, error)
+
+var (
+	directives   = make(map[string]DirectiveFunc)
+	directivesMu sync.RWMutex
+)
+
+// RegisterDirective sets the function that evaluates the directive with the
+// given keyword.
+func RegisterDirective(keyword string, fn DirectiveFunc) {
+	directivesMu.Lock()
+	defer directivesMu.Unlock()
+
+	directives[keyword] = fn
+}
+
+func evalDirectiveStatement(node *ast.DirectiveStatement, env *object.Environment) interface{} {
+	directivesMu.RLock()
+	fn, exists := directives[node.Name]
+	directivesMu.RUnlock()
+
+	if !exists || fn == nil {
+		return newError(node.Token, "directive %s can not be evaluated", node.Name)
+	}
+
+	// a directive is called like a builtin, the sandbox must allow it
+	if env.Sandbox != nil && !env.Sandbox.CanCall(node.Name) {
+		return newError(node.Token, "directive %s is not allowed in the sandbox", node.Name)
+	}
+
+	output, err := fn(node, env)
+
+	if err != nil {
+		return newError(node.Token, "%s: %w", node.Name, err)
+	}
+
+	return output
+}
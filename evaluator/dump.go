@@ -0,0 +1,194 @@
+package evaluator
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"reflect"
+	"strings"
+
+	"github.com/govel-framework/lamb/internal"
+	"github.com/govel-framework/lamb/object"
+)
+
+// maxDumpDepth stops the dump of values that contain themselves.
+const maxDumpDepth = 10
+
+// debugMode reports whether lamb.debug is enabled.
+func debugMode() bool {
+	return internal.Setting("GOVEL_LAMB_DEBUG") == "true"
+}
+
+// dumpBuiltIn pretty prints the values (their type, struct fields and nested
+// values) in a <pre> block. It renders nothing unless lamb.debug is enabled.
+func dumpBuiltIn(args ...interface{}) interface{} {
+	if !debugMode() {
+		return ""
+	}
+
+	return dump(args)
+}
+
+// ddBuiltIn dumps the values like dump() and stops the render, so the
+// response is only the dump. It does nothing unless lamb.debug is enabled.
+func ddBuiltIn(args ...interface{}) interface{} {
+	if !debugMode() {
+		return ""
+	}
+
+	return &object.HaltError{Output: dump(args)}
+}
+
+func dump(args []interface{}) string {
+	var out bytes.Buffer
+
+	for _, arg := range args {
+		var value bytes.Buffer
+
+		dumpValue(&value, reflect.ValueOf(arg), 0)
+
+		out.WriteString(`<pre class="lamb-dump">`)
+		out.WriteString(html.EscapeString(value.String()))
+		out.WriteString("</pre>\n")
+	}
+
+	return out.String()
+}
+
+// dumpValue writes the type and the content of the value, the nested values
+// are indented by depth.
+func dumpValue(out *bytes.Buffer, value reflect.Value, depth int) {
+	indent := strings.Repeat("  ", depth+1)
+
+	if !value.IsValid() {
+		out.WriteString("nil")
+
+		return
+	}
+
+	if depth > maxDumpDepth {
+		fmt.Fprintf(out, "%s ...", value.Type())
+
+		return
+	}
+
+	// values like time.Time are better shown by their own String()
+	if value.CanInterface() && value.Kind() == reflect.Struct {
+		if stringer, isStringer := value.Interface().(fmt.Stringer); isStringer {
+			fmt.Fprintf(out, "%s %s", value.Type(), stringer.String())
+
+			return
+		}
+	}
+
+	switch value.Kind() {
+
+	case reflect.Ptr, reflect.Interface:
+		if value.IsNil() {
+			fmt.Fprintf(out, "%s nil", value.Type())
+
+			return
+		}
+
+		if value.Kind() == reflect.Ptr {
+			out.WriteString("&")
+		}
+
+		dumpValue(out, value.Elem(), depth)
+
+	case reflect.String:
+		fmt.Fprintf(out, "string(%d) %q", value.Len(), value.String())
+
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			fmt.Fprintf(out, "%s nil", value.Type())
+
+			return
+		}
+
+		fmt.Fprintf(out, "%s (len=%d) [\n", value.Type(), value.Len())
+
+		for i := 0; i < value.Len(); i++ {
+			fmt.Fprintf(out, "%s%d => ", indent, i)
+			dumpValue(out, value.Index(i), depth+1)
+			out.WriteString("\n")
+		}
+
+		out.WriteString(indent[2:] + "]")
+
+	case reflect.Map:
+		if value.IsNil() {
+			fmt.Fprintf(out, "%s nil", value.Type())
+
+			return
+		}
+
+		fmt.Fprintf(out, "%s (len=%d) {\n", value.Type(), value.Len())
+
+		for _, key := range sortedKeys(value) {
+			fmt.Fprintf(out, "%s%s => ", indent, dumpKey(key))
+			dumpValue(out, value.MapIndex(key), depth+1)
+			out.WriteString("\n")
+		}
+
+		out.WriteString(indent[2:] + "}")
+
+	case reflect.Struct:
+		fmt.Fprintf(out, "%s {\n", value.Type())
+
+		for i := 0; i < value.NumField(); i++ {
+			fmt.Fprintf(out, "%s%s: ", indent, value.Type().Field(i).Name)
+			dumpValue(out, value.Field(i), depth+1)
+			out.WriteString("\n")
+		}
+
+		out.WriteString(indent[2:] + "}")
+
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		out.WriteString(value.Type().String())
+
+	default:
+		fmt.Fprintf(out, "%s %v", value.Type(), dumpScalar(value))
+	}
+}
+
+// dumpKey returns the key of a map, quoted if it is a string.
+func dumpKey(key reflect.Value) string {
+	if key.Kind() == reflect.Interface {
+		key = key.Elem()
+	}
+
+	if key.Kind() == reflect.String {
+		return fmt.Sprintf("%q", key.String())
+	}
+
+	return fmt.Sprintf("%v", dumpScalar(key))
+}
+
+// dumpScalar returns the value of a bool or a number, also when it comes from
+// an unexported field.
+func dumpScalar(value reflect.Value) interface{} {
+	switch value.Kind() {
+	case reflect.Bool:
+		return value.Bool()
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int()
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return value.Uint()
+
+	case reflect.Float32, reflect.Float64:
+		return value.Float()
+
+	case reflect.Complex64, reflect.Complex128:
+		return value.Complex()
+
+	default:
+		if value.CanInterface() {
+			return value.Interface()
+		}
+
+		return value.Type().String()
+	}
+}
@@ -0,0 +1,54 @@
+package evaluator
+
+import (
+	"os"
+	"strings"
+
+	"github.com/govel-framework/lamb/internal"
+)
+
+// envAllowed reports whether the templates can read an environment variable,
+// only the ones of lamb.env can be read so the secrets are never dumped by
+// accident. A name that ends with "*" allows every variable with its prefix.
+func envAllowed(name string) bool {
+	for _, allowed := range strings.Split(internal.Setting("GOVEL_LAMB_ENV"), ",") {
+		if allowed == "" {
+			continue
+		}
+
+		if allowed == name || strings.HasSuffix(allowed, "*") && strings.HasPrefix(name, strings.TrimSuffix(allowed, "*")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// envBuiltIn returns an environment variable of the lamb.env allowlist:
+// env("APP_ENV", "production"). The default (optional) is used when the
+// variable is not set.
+func envBuiltIn(args ...interface{}) interface{} {
+	if err := checkArgs("env", args, 1, 2); err != nil {
+		return err
+	}
+
+	name, err := stringArg("env", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	if !envAllowed(name) {
+		return builtInError("the environment variable %s is not allowed, add it to lamb.env", name)
+	}
+
+	if value, exists := os.LookupEnv(name); exists {
+		return value
+	}
+
+	if len(args) == 2 {
+		return args[1]
+	}
+
+	return ""
+}
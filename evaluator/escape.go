@@ -0,0 +1,107 @@
+package evaluator
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/govel-framework/lamb/ast"
+	"github.com/govel-framework/lamb/object"
+)
+
+// xmlEscaper escapes the text of the values written in the xml mode.
+var xmlEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	`"`, "&quot;",
+	"'", "&apos;",
+)
+
+// ProgramMode returns the output mode declared by the autoescape statements at
+// the top level of the program, and reports whether it declares one.
+func ProgramMode(program *ast.Program) (string, bool) {
+	var (
+		mode     string
+		declared bool
+	)
+
+	for _, statement := range program.Statements {
+		if expression, isExpression := statement.(*ast.ExpressionStatement); isExpression {
+			if autoescape, isAutoescape := expression.Expression.(*ast.AutoescapeStatement); isAutoescape {
+				mode, declared = autoescape.Mode, true
+			}
+		}
+	}
+
+	return mode, declared
+}
+
+// isInterpolation reports whether the statement writes a value, which is
+// escaped in the output mode of the template, and not the output of a block
+// or a template.
+func isInterpolation(statement ast.Statement) bool {
+	expression, isExpression := statement.(*ast.ExpressionStatement)
+
+	if !isExpression {
+		return false
+	}
+
+	switch expression.Expression.(type) {
+	case *ast.Identifier, *ast.IntegerLiteral, *ast.FloatLiteral, *ast.Boolean, *ast.StringLiteral,
+		*ast.ArrayLiteral, *ast.MapLiteral, *ast.PrefixExpression, *ast.InfixExpression,
+		*ast.CallExpression, *ast.IndexExpression, *ast.DotExpression, *ast.LambdaLiteral:
+		return true
+	}
+
+	return false
+}
+
+// writeStatement writes the result of the statement, a value is escaped in
+// the output mode of the template.
+func writeStatement(out *bytes.Buffer, statement ast.Statement, value interface{}, env *object.Environment) {
+	value = sandboxOutput(value, env)
+
+	if isInterpolation(statement) {
+		writeEscaped(out, value, env)
+
+		return
+	}
+
+	writeValue(out, value)
+}
+
+// writeEscaped writes the value escaped in the output mode of the template:
+// the text of the value in xml, the value encoded as JSON in json (a string
+// is written with its quotes) and the value as is in html.
+func writeEscaped(out *bytes.Buffer, value interface{}, env *object.Environment) {
+	value = sandboxOutput(value, env)
+
+	switch env.Mode {
+	case "xml":
+		text := getBuffer()
+		defer putBuffer(text)
+
+		writeValue(text, value)
+
+		xmlEscaper.WriteString(out, text.String())
+
+	case "json":
+		encoded, err := json.Marshal(value)
+
+		if err != nil {
+			// a value that JSON can not encode is written as its text
+			text := getBuffer()
+			defer putBuffer(text)
+
+			writeValue(text, value)
+
+			encoded, _ = json.Marshal(text.String())
+		}
+
+		out.Write(encoded)
+
+	default:
+		writeValue(out, value)
+	}
+}
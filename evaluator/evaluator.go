@@ -2,9 +2,15 @@ package evaluator
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/govel-framework/lamb/ast"
 	"github.com/govel-framework/lamb/internal"
@@ -24,6 +30,9 @@ func Eval(node ast.Node, env *object.Environment) interface{} {
 	case *ast.IntegerLiteral:
 		return node.Value
 
+	case *ast.FloatLiteral:
+		return node.Value
+
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
 
@@ -52,6 +61,10 @@ func Eval(node ast.Node, env *object.Environment) interface{} {
 		return evalInfixExpression(node.Operator, left, right, node.Token)
 
 	case *ast.BlockStatement:
+		if code := compiled(node, node.Statements); code != nil {
+			return code.output(env)
+		}
+
 		return evalStatements(node.Statements, env)
 
 	case *ast.IfExpression:
@@ -64,12 +77,16 @@ func Eval(node ast.Node, env *object.Environment) interface{} {
 			return val
 		}
 
-		env.Set(node.Name.Value, val)
+		env.Assign(node.Name.Value, val)
 
 	case *ast.Identifier:
 		return evalIdentifier(node, env)
 
 	case *ast.CallExpression:
+		if check, isPresenceCheck := presenceCheck(node, env); isPresenceCheck {
+			return evalPresenceCheck(node, check, env)
+		}
+
 		function := Eval(node.Function, env)
 
 		if isError(function) {
@@ -83,7 +100,7 @@ func Eval(node ast.Node, env *object.Environment) interface{} {
 			return args[0]
 		}
 
-		return applyFunction(function, args, node.Token)
+		return callFunction(node, function, args, env)
 
 	case *ast.StringLiteral:
 		if !node.Closed {
@@ -137,6 +154,28 @@ func Eval(node ast.Node, env *object.Environment) interface{} {
 	case *ast.IncludeStatement:
 		return evalIncludeStatement(node, env)
 
+	case *ast.TryStatement:
+		return evalTryStatement(node, env)
+
+	case *ast.SpacelessStatement:
+		return evalSpacelessStatement(node, env)
+
+	case *ast.DirectiveStatement:
+		return evalDirectiveStatement(node, env)
+
+	case *ast.PropsStatement:
+		return evalPropsStatement(node, env)
+
+	case *ast.AutoescapeStatement:
+		// the mode applies to the whole template, see evalProgram
+		return nil
+
+	case *ast.LambdaLiteral:
+		// the scopes of the lambda can not be reused while it exists
+		env.Capture()
+
+		return &object.Lambda{Parameter: node.Parameter.Value, Body: node.Body, Env: env}
+
 	case *ast.HtmlLiteral:
 		return node.Value
 	}
@@ -146,23 +185,116 @@ func Eval(node ast.Node, env *object.Environment) interface{} {
 
 func evalStatements(stmts []ast.Statement, env *object.Environment) interface{} {
 	// save the result as a string
-	var result string
+	result := getBuffer()
+	defer putBuffer(result)
 
 	for _, statement := range stmts {
-		res := Eval(statement, env)
+		if chunk := htmlChunk(statement, env); chunk != nil {
+			result.Write(chunk)
+
+			continue
+		}
+
+		res := evalStatement(statement, env)
 
 		if isError(res) {
 			return res
 		}
 
 		if res != nil {
-			result += fmt.Sprintf("%v", res)
+			writeStatement(result, statement, res, env)
 		}
 
+		if exceedsOutputSize(env, result.Len()) {
+			return outputSizeError(env.Limits.MaxOutputSize)
+		}
 	}
 
 	// return the output of the statements as an object.String
-	return result
+	return result.String()
+}
+
+// writeValue writes the value to the output, strings (the most common
+// output) are written without going through fmt and the other values go
+// through the formatter of lamb.Formatter first.
+func writeValue(out *bytes.Buffer, value interface{}) {
+	switch value := value.(type) {
+	case string:
+		out.WriteString(value)
+
+	case bool:
+		out.WriteString(boolLabel(value))
+
+	default:
+		if formatted, isFormatted := internal.Format(value); isFormatted {
+			out.WriteString(formatted)
+
+			return
+		}
+
+		writeUnformatted(out, value)
+	}
+}
+
+// writeUnformatted writes the value like fmt's %v does, the common types
+// without going through fmt.
+func writeUnformatted(out *bytes.Buffer, value interface{}) {
+	switch value := value.(type) {
+	case int:
+		out.WriteString(strconv.Itoa(value))
+
+	case float64:
+		out.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+
+	case time.Time:
+		out.WriteString(value.String())
+
+	default:
+		fmt.Fprintf(out, "%v", stringer(value))
+	}
+}
+
+// stringer returns a pointer to a copy of the value if only the pointer
+// implements fmt.Stringer (a String method with a pointer receiver), so fmt
+// outputs it with String() instead of its fields.
+func stringer(value interface{}) interface{} {
+	if _, isStringer := value.(fmt.Stringer); isStringer || value == nil {
+		return value
+	}
+
+	valueOf := reflect.ValueOf(value)
+
+	if !reflect.PointerTo(valueOf.Type()).Implements(stringerType) {
+		return value
+	}
+
+	pointer := reflect.New(valueOf.Type())
+	pointer.Elem().Set(valueOf)
+
+	return pointer.Interface()
+}
+
+// htmlChunk returns the interned bytes of stmt if it is an HTML literal, so
+// the static markup is written from the shared chunk table instead of being
+// evaluated and copied again.
+func htmlChunk(stmt ast.Statement, env *object.Environment) []byte {
+	if env.Chunks == nil {
+		return nil
+	}
+
+	es, isExpression := stmt.(*ast.ExpressionStatement)
+
+	if !isExpression {
+		return nil
+	}
+
+	html, isHtml := es.Expression.(*ast.HtmlLiteral)
+
+	if !isHtml {
+		return nil
+	}
+
+	return env.Chunks.Bytes(html.Chunk)
 }
 
 func nativeBoolToBooleanObject(input bool) bool {
@@ -175,7 +307,7 @@ func nativeBoolToBooleanObject(input bool) bool {
 
 func evalPrefixExpression(operator string, right interface{}, t token.Token) interface{} {
 	switch operator {
-	case "!":
+	case "!", "not":
 		return evalBangOperatorExpression(right)
 
 	case "-":
@@ -200,30 +332,42 @@ func evalBangOperatorExpression(right interface{}) interface{} {
 }
 
 func evalMinusPrefixOperatorExpression(right interface{}, t token.Token) interface{} {
-	if fmt.Sprintf("%T", right) != "int" {
+	if value, isInt := isNumber(right); isInt {
+		return -value
+	}
+
+	value, isNumber := toFloat(right)
+
+	if !isNumber {
 		return newError(t, "unknown operator: -%T", right)
 	}
 
-	value := right.(int)
 	return -value
 }
 
 func evalInfixExpression(operator string, left, right interface{}, t token.Token) interface{} {
-	leftType := fmt.Sprintf("%T", left)
-	rightType := fmt.Sprintf("%T", right)
+	if result, done := evalCommonInfixExpression(operator, left, right, t); done {
+		return result
+	}
 
-	leftNumber, isLeftNumber := isNumber(left)
-	rightNumber, isRightNumber := isNumber(right)
+	_, isLeftString := left.(string)
+	_, isRightString := right.(string)
+
+	_, isLeftNumber := toFloat(left)
+	_, isRightNumber := toFloat(right)
 
 	switch {
+	case operator == "in" || operator == "not in":
+		return evalInExpression(operator, left, right, t)
+
 	case isLeftNumber && isRightNumber:
-		return evalIntegerInfixExpression(operator, leftNumber, rightNumber, t)
+		return evalNumberInfixExpression(operator, left, right, t)
 
 	case operator == "==":
-		return nativeBoolToBooleanObject(left == right)
+		return nativeBoolToBooleanObject(equals(left, right))
 
 	case operator == "!=":
-		return nativeBoolToBooleanObject(left != right)
+		return nativeBoolToBooleanObject(!equals(left, right))
 
 	case operator == "and":
 		leftValue := reflect.ValueOf(left)
@@ -243,50 +387,68 @@ func evalInfixExpression(operator string, left, right interface{}, t token.Token
 
 		return true
 
-	case leftType == "string" && rightType == "string":
+	case isLeftString && isRightString:
 		return evalStringInfixExpression(operator, left, right, t)
 
-	case leftType != rightType:
-		return newError(t, "type mismatch: %s %s %s", leftType, operator, rightType)
+	case reflect.TypeOf(left) != reflect.TypeOf(right):
+		return newError(t, "type mismatch: %T %s %T", left, operator, right)
 
 	default:
-		return newError(t, "unknown operator: %s %s %s", leftType, operator, rightType)
+		return newError(t, "unknown operator: %T %s %T", left, operator, right)
 	}
 }
 
-func evalIntegerInfixExpression(operator string, left, right interface{}, t token.Token) interface{} {
-	leftVal := left.(int)
-
-	rightVal := right.(int)
+// evalCommonInfixExpression evaluates the operations between two operands of
+// the same common type without the reflection of evalInfixExpression, done is
+// false for the rest.
+func evalCommonInfixExpression(operator string, left, right interface{}, t token.Token) (result interface{}, done bool) {
+	if operator == "in" || operator == "not in" || operator == "and" {
+		return nil, false
+	}
 
-	switch operator {
-	case "+":
-		return leftVal + rightVal
+	switch leftVal := left.(type) {
+	case int:
+		rightVal, isInt := right.(int)
 
-	case "-":
-		return leftVal - rightVal
+		// a division that is not exact gives a float
+		if !isInt || operator == "/" && rightVal != 0 && leftVal%rightVal != 0 {
+			return nil, false
+		}
 
-	case "*":
-		return leftVal * rightVal
+		return evalIntegerInfixExpression(operator, leftVal, rightVal, t), true
 
-	case "/":
-		return leftVal / rightVal
+	case float64:
+		if rightVal, isFloat := right.(float64); isFloat {
+			return evalFloatInfixExpression(operator, leftVal, rightVal, t), true
+		}
 
-	case "<":
-		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case string:
+		if rightVal, isString := right.(string); isString {
+			switch operator {
+			case "==":
+				return leftVal == rightVal, true
 
-	case ">":
-		return nativeBoolToBooleanObject(leftVal > rightVal)
+			case "!=":
+				return leftVal != rightVal, true
 
-	case "==":
-		return nativeBoolToBooleanObject(leftVal == rightVal)
+			default:
+				return evalStringInfixExpression(operator, left, right, t), true
+			}
+		}
 
-	case "!=":
-		return nativeBoolToBooleanObject(leftVal != rightVal)
+	case bool:
+		if rightVal, isBool := right.(bool); isBool {
+			switch operator {
+			case "==":
+				return leftVal == rightVal, true
 
-	default:
-		return newError(t, "unknown operator: %T %s %T", left, operator, right)
+			case "!=":
+				return leftVal != rightVal, true
+			}
+		}
 	}
+
+	return nil, false
 }
 
 func evalIfExpression(ie *ast.IfExpression, env *object.Environment) interface{} {
@@ -332,44 +494,96 @@ func newError(t token.Token, format string, a ...interface{}) error {
 }
 
 func evalProgram(program *ast.Program, env *object.Environment) interface{} {
-	var result string
+	output := getBuffer()
+	defer putBuffer(output)
+
+	env.Chunks = program.Chunks
 
-	for _, statement := range program.Statements {
-		r := Eval(statement, env)
+	// the limits of the render are read once, before its first statement
+	renderLimits(env)
 
-		if isError(r) {
-			return fmt.Sprintf("%s: %v", env.FileName, r)
+	if mode, declared := ProgramMode(program); declared {
+		env.Mode = mode
+	}
+
+	if code := compiled(program, program.Statements); code != nil {
+		if err := code.run(output, env); err != nil {
+			return fmt.Errorf("%s: %w", env.FileName, err)
 		}
+	} else {
+		for _, statement := range program.Statements {
+			if chunk := htmlChunk(statement, env); chunk != nil {
+				output.Write(chunk)
+
+				continue
+			}
+
+			r := evalStatement(statement, env)
 
-		if r != nil {
-			result += fmt.Sprintf("%v", r)
+			if isError(r) {
+				return fmt.Errorf("%s: %w", env.FileName, r.(error))
+			}
+
+			if r != nil {
+				writeStatement(output, statement, r, env)
+			}
+
+			if exceedsOutputSize(env, output.Len()) {
+				return fmt.Errorf("%s: %w", env.FileName, outputSizeError(env.Limits.MaxOutputSize))
+			}
 		}
 	}
 
-	if env.InExtends {
-		// eval the file and create the new environment
-		newEnv := object.CopyEnvironment(env)
-		newEnv.IsExtends = true
+	return renderLayout(output.String(), env)
+}
 
-		var out bytes.Buffer
+// renderLayout returns the output of the layout of the template if it extends
+// one, or the output of the template.
+func renderLayout(result string, env *object.Environment) interface{} {
+	// only the fragment is rendered, the layout is not needed once it is found
+	if env.Fragment != nil && env.Fragment.Found {
+		return env.Fragment.Content
+	}
 
-		err := internal.LoadFile(env.ExtendsFrom.From, nil, &out, Eval, *newEnv)
+	if !env.InExtends {
+		return result
+	}
 
-		result = out.String()
+	// eval the file and create the new environment
+	newEnv := object.CopyEnvironment(env)
+	newEnv.IsExtends = true
 
-		// check if any error has occured
-		if err != nil {
-			return errors.New(err.Error())
-		}
+	out := getBuffer()
+	defer putBuffer(out)
 
-		// check if any section is ununsed
-		for _, section := range env.ExtendsFrom.Sections {
-			return newError(section.Token, "section %s does not exist", section.Name)
-		}
+	err := internal.LoadFile(internal.ResolveName(env.ExtendsFrom.From, env.Resolve), nil, out, Eval, *newEnv)
 
+	// check if any error has occured
+	if err != nil {
+		return err
 	}
 
-	return result
+	// the sections the layout does not define are not rendered
+	unused := make([]string, 0, len(env.ExtendsFrom.Sections))
+
+	for name := range env.ExtendsFrom.Sections {
+		unused = append(unused, name)
+	}
+
+	sort.Strings(unused)
+
+	for _, name := range unused {
+		section := env.ExtendsFrom.Sections[name]
+
+		diagnose(env, object.Diagnostic{
+			Kind:    "unused-section",
+			Message: fmt.Sprintf("section %s is not defined by the layout %s", name, env.ExtendsFrom.From),
+			File:    env.FileName,
+			Line:    section.Token.Line,
+		})
+	}
+
+	return out.String()
 }
 
 func isError(obj interface{}) bool {
@@ -387,10 +601,25 @@ func evalIdentifier(node *ast.Identifier, env *object.Environment) interface{} {
 		return val
 	}
 
-	if builtin, ok := Builtins[node.Value]; ok {
+	if val, ok := internal.GetShared(node.Value); ok {
+		return val
+	}
+
+	if builtin, ok := Registry.Get(node.Value); ok {
+		if env.Sandbox != nil && !env.Sandbox.CanCall(node.Value) {
+			return newError(node.Token, "function %s is not allowed in the sandbox", node.Value)
+		}
+
 		return builtin
 	}
 
+	// the lenient mode renders the undefined vars as nothing
+	if internal.Setting("GOVEL_LAMB_LENIENT") == "true" {
+		internal.Log().Warn("undefined var", "var", node.Value, "file", env.FileName, "line", node.Token.Line)
+
+		return nil
+	}
+
 	return newError(node.Token, "identifier not found: %s", node.Value)
 }
 
@@ -410,30 +639,188 @@ func evalExpressions(exps []ast.Expression, env *object.Environment) []interface
 	return result
 }
 
-func applyFunction(fn interface{}, args []interface{}, t token.Token) interface{} {
+// callFunction calls the evaluated function of the call with the evaluated
+// arguments.
+func callFunction(node *ast.CallExpression, function interface{}, args []interface{}, env *object.Environment) interface{} {
+	// the builtins of a sandboxed template only see the allowed fields of the
+	// structs, the ones that receive the environment check the sandbox
+	if builtin, isBuiltin := function.(*object.Builtin); isBuiltin && builtin.EnvFn == nil && env.Sandbox != nil {
+		args = sandboxArgs(args, env.Sandbox)
+	}
+
+	// the Go functions passed in the vars, a returned error is an error of the
+	// template
+	if function != nil && reflect.TypeOf(function).Kind() == reflect.Func {
+		name := node.Function.String()
+		fnValue := reflect.ValueOf(function)
+
+		if err := checkFunc(name, fnValue.Type()); err != nil {
+			return newError(node.Token, "%s", err)
+		}
+
+		result := callFunc(name, fnValue, args)
+
+		if isError(result) {
+			return newError(node.Token, "%w", result)
+		}
+
+		return result
+	}
+
+	if builtin, isBuiltin := function.(*object.Builtin); isBuiltin && builtin.Deprecated != "" {
+		diagnose(env, object.Diagnostic{
+			Kind:    "deprecated",
+			Message: fmt.Sprintf("%s is deprecated: %s", node.Function.String(), builtin.Deprecated),
+			File:    env.FileName,
+			Line:    node.Token.Line,
+		})
+	}
+
+	return applyFunction(function, args, node.Token, env)
+}
+
+func applyFunction(fn interface{}, args []interface{}, t token.Token, env *object.Environment) interface{} {
 	switch fn := fn.(type) {
 
 	case *object.Builtin:
-		return fn.Fn(args...)
+		result := callBuiltin(fn, args, env)
+
+		if err, isLambdaError := result.(lambdaError); isLambdaError {
+			return err.error
+		}
+
+		// add the position of the call to the errors of the builtin
+		if isError(result) {
+			return newError(t, "%w", result)
+		}
+
+		return result
+
+	case *object.Lambda:
+		if len(args) != 1 {
+			return newError(t, "wrong number of arguments in lambda. got=%d, want=1", len(args))
+		}
+
+		result := callLambda(fn, args[0])
+
+		if err, isLambdaError := result.(lambdaError); isLambdaError {
+			return err.error
+		}
+
+		return result
 
 	default:
 		return newError(t, "not a function: %T", fn)
 	}
 }
 
-func evalStringInfixExpression(operator string, left, right interface{}, t token.Token) interface{} {
-	if operator != "+" {
+// callBuiltin calls fn, retrying it with backoff while it returns an error
+// (e.g. a builtin that hits an external service) and has retries left. The
+// cancellation of the context of the render stops the retries.
+func callBuiltin(fn *object.Builtin, args []interface{}, env *object.Environment) interface{} {
+	call := func() interface{} {
+		if fn.EnvFn != nil {
+			return fn.EnvFn(env, args...)
+		}
+
+		return fn.Fn(args...)
+	}
+
+	result := call()
+	backoff := fn.Backoff
+
+	for retry := 0; retry < fn.Retries && isError(result); retry++ {
+		if env.Context == nil {
+			time.Sleep(backoff)
+		} else {
+			select {
+			case <-env.Context.Done():
+				return fmt.Errorf("%s (retries stopped: %w)", result, env.Context.Err())
+
+			case <-time.After(backoff):
+			}
+		}
+
+		backoff *= 2
+		result = call()
+	}
+
+	return result
+}
+
+// evalInExpression checks whether the left value is a substring of the right
+// string, an element of the right list or a key of the right map.
+func evalInExpression(operator string, left, right interface{}, t token.Token) interface{} {
+	contains := containsBuiltIn(right, left)
+
+	if isError(contains) {
 		return newError(t, "unknown operator: %T %s %T", left, operator, right)
 	}
 
+	if operator == "not in" {
+		return !contains.(bool)
+	}
+
+	return contains
+}
+
+// evalStringInfixExpression concatenates two strings or compares them
+// lexicographically (byte by byte, like Go does).
+func evalStringInfixExpression(operator string, left, right interface{}, t token.Token) interface{} {
 	leftVal := left.(string)
 
 	rightVal := right.(string)
 
-	return leftVal + rightVal
+	switch operator {
+	case "+":
+		return leftVal + rightVal
+
+	case "<":
+		return leftVal < rightVal
+
+	case ">":
+		return leftVal > rightVal
+
+	case "<=":
+		return leftVal <= rightVal
+
+	case ">=":
+		return leftVal >= rightVal
+
+	default:
+		return newError(t, "unknown operator: %T %s %T", left, operator, right)
+	}
 }
 
 func evalIndexExpression(left, index interface{}, t token.Token) interface{} {
+	// optional data: a missing value propagates through the rest of the chain
+	if left == nil {
+		return nil
+	}
+
+	// the lists and maps built by the templates or passed as vars
+	switch left := left.(type) {
+	case []interface{}:
+		if id, isInt := index.(int); isInt {
+			if id < 0 || id >= len(left) {
+				return nil
+			}
+
+			return left[id]
+		}
+
+	case map[string]interface{}:
+		if key, isString := index.(string); isString {
+			return left[key]
+		}
+
+	case map[interface{}]interface{}:
+		switch index.(type) {
+		case string, int:
+			return left[index]
+		}
+	}
+
 	leftType := reflect.ValueOf(left).Kind()
 	indexType := reflect.ValueOf(index).Kind()
 
@@ -442,7 +829,7 @@ func evalIndexExpression(left, index interface{}, t token.Token) interface{} {
 		return evalArrayIndexExpression(left, index)
 
 	case leftType == reflect.Map:
-		return evalMapIndexExpression(left, index)
+		return evalMapIndexExpression(left, index, t)
 
 	default:
 		return newError(t, "index operator not supported: %s", leftType.String())
@@ -464,32 +851,62 @@ func evalArrayIndexExpression(array, index interface{}) interface{} {
 }
 
 func evalMapLiteral(node *ast.MapLiteral, env *object.Environment) interface{} {
+	pairs, _, err := evalMapLiteralPairs(node, env)
+
+	if err != nil {
+		return err
+	}
+
+	return pairs
+}
+
+// evalMapLiteralPairs evaluates the pairs of a map literal in source order and
+// returns the map and its keys in that order.
+func evalMapLiteralPairs(node *ast.MapLiteral, env *object.Environment) (map[interface{}]interface{}, []reflect.Value, error) {
 	pairs := make(map[interface{}]interface{})
+	keys := make([]reflect.Value, 0, len(node.Pairs))
 
-	for keyNode, valueNode := range node.Pairs {
-		key := Eval(keyNode, env)
+	for _, pair := range node.Pairs {
+		key := Eval(pair.Key, env)
 
 		if isError(key) {
-			return key
+			return nil, nil, key.(error)
+		}
+
+		if !hashable(key) {
+			return nil, nil, newError(node.Token, "unusable as map key: %T", key)
 		}
 
-		value := Eval(valueNode, env)
+		value := Eval(pair.Value, env)
 
 		if isError(value) {
-			return value
+			return nil, nil, value.(error)
 		}
 
-		pairs[key] = value
+		if _, exists := pairs[key]; !exists {
+			keys = append(keys, reflect.ValueOf(key))
+		}
 
+		pairs[key] = value
 	}
 
-	return pairs
+	return pairs, keys, nil
 }
 
-func evalMapIndexExpression(m, index interface{}) interface{} {
+func evalMapIndexExpression(m, index interface{}, t token.Token) interface{} {
 	mapValue := reflect.ValueOf(m)
+	key := reflect.ValueOf(index)
+
+	if !hashable(index) {
+		return newError(t, "unusable as map key: %T", index)
+	}
+
+	// a key of another type can not be in the map
+	if !key.IsValid() || !key.Type().AssignableTo(mapValue.Type().Key()) {
+		return nil
+	}
 
-	value := mapValue.MapIndex(reflect.ValueOf(index))
+	value := mapValue.MapIndex(key)
 
 	if !value.IsValid() {
 		return nil
@@ -498,77 +915,242 @@ func evalMapIndexExpression(m, index interface{}) interface{} {
 	return value.Interface()
 }
 
+// hashable reports whether the value can be a map key.
+func hashable(value interface{}) bool {
+	return value == nil || reflect.TypeOf(value).Comparable()
+}
+
 func evalForExpression(fe *ast.ForExpression, env *object.Environment) interface{} {
-	value := fe.Value
-	key := fe.Key
+	in, keys, err := evalForIn(fe, env)
+
+	if err != nil {
+		return err
+	}
+
+	// the output of every iteration is written to one buffer, the loops of a
+	// large list would copy the output so far on each one otherwise
+	out := getBuffer()
+	defer putBuffer(out)
+
+	// the vars of the loop only exist in its block
+	scope := object.NewEnclosedEnvironment(env)
+	defer scope.Release()
+
+	res := forEach(fe, in, keys, scope, func(index int, k, v interface{}, length int) interface{} {
+		// set the new values
+		scope.Set(fe.Value, v)
+
+		if fe.Key != "" {
+			scope.Set(fe.Key, k)
+		}
+
+		scope.Set("loop", loopMetadata(index, length))
+
+		res := Eval(fe.Block, scope)
+
+		if isError(res) {
+			return res
+		}
+
+		out.WriteString(res.(string))
+
+		if exceedsOutputSize(scope, out.Len()) {
+			return newError(fe.Token, "%w", outputSizeError(scope.Limits.MaxOutputSize))
+		}
+
+		return nil
+	})
+
+	if res != nil {
+		return res
+	}
+
+	return out.String()
+}
+
+// evalForIn evaluates what the loop iterates. The maps are iterated in the
+// order of their keys, or in source order for a map literal, whose keys are
+// returned in that order.
+func evalForIn(fe *ast.ForExpression, env *object.Environment) (interface{}, []reflect.Value, error) {
+	if literal, isMapLiteral := fe.In.(*ast.MapLiteral); isMapLiteral {
+		pairs, keys, err := evalMapLiteralPairs(literal, env)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return pairs, keys, nil
+	}
 
 	in := Eval(fe.In, env)
 
 	if isError(in) {
-		return in
+		return nil, nil, in.(error)
 	}
 
-	// iterate
-	var out string
+	return in, nil, nil
+}
 
+// forEach calls iterate for every element of in, until it returns an error.
+// length is -1 when it is not known (e.g. a channel).
+func forEach(fe *ast.ForExpression, in interface{}, keys []reflect.Value, env *object.Environment, iterate func(index int, k, v interface{}, length int) interface{}) interface{} {
 	valueOf := reflect.ValueOf(in)
+	limits := renderLimits(env)
+	max := limits.MaxLoopIterations
+
+	if max != 0 && isIterable(valueOf) && valueOf.Len() > max {
+		return newError(fe.Token, "%w", limitErrorf("for loop over %d elements exceeds the max of %d iterations", valueOf.Len(), max))
+	}
+
+	if large := limits.LargeLoop; isIterable(valueOf) && valueOf.Len() > large {
+		diagnose(env, object.Diagnostic{
+			Kind:    "large-loop",
+			Message: fmt.Sprintf("for loop over %d elements, more than %d", valueOf.Len(), large),
+			File:    env.FileName,
+			Line:    fe.Token.Line,
+		})
+	}
+
+	if valueOf.Kind() == reflect.Ptr && !valueOf.IsNil() && valueOf.Elem().Kind() == reflect.Struct {
+		valueOf = valueOf.Elem()
+	}
 
 	switch valueOf.Kind() {
 
 	case reflect.Map:
+		if keys == nil {
+			keys = sortedKeys(valueOf)
+		}
 
-		for _, elem := range valueOf.MapKeys() {
+		for i, elem := range keys {
+			if res := iterate(i, elem.Interface(), valueOf.MapIndex(elem).Interface(), len(keys)); res != nil {
+				return res
+			}
+		}
 
-			// set the new values
-			env.Set(value, elem.Interface())
+	case reflect.Array, reflect.Slice:
+		if list, isList := in.([]interface{}); isList {
+			for i, elem := range list {
+				if res := iterate(i, i, elem, len(list)); res != nil {
+					return res
+				}
+			}
 
-			if key != "" {
-				env.Set(key, elem.Interface())
+			break
+		}
+
+		length := valueOf.Len()
+
+		for i := 0; i < length; i++ {
+			if res := iterate(i, i, valueOf.Index(i).Interface(), length); res != nil {
+				return res
 			}
+		}
 
-			res := Eval(fe.Block, env)
+	case reflect.Struct:
+		// the exported fields, the key is the name of the field
+		var fields []reflect.StructField
 
-			if isError(res) {
+		for _, field := range reflect.VisibleFields(valueOf.Type()) {
+			if !field.IsExported() || field.Anonymous {
+				continue
+			}
+
+			if env.Sandbox != nil && !env.Sandbox.CanAccess(valueOf.Type(), field.Name) {
+				continue
+			}
+
+			fields = append(fields, field)
+		}
+
+		for i, field := range fields {
+			if res := iterate(i, field.Name, valueOf.FieldByIndex(field.Index).Interface(), len(fields)); res != nil {
 				return res
 			}
+		}
 
-			out += res.(string)
+	case reflect.Chan:
+		if valueOf.Type().ChanDir()&reflect.RecvDir == 0 {
+			return newError(fe.Token, "%T is not iterable, it is a send-only channel", in)
 		}
 
-	case reflect.Array, reflect.Slice:
-		len := valueOf.Len()
+		// receive until the channel is closed or the render is cancelled
+		cases := []reflect.SelectCase{{Dir: reflect.SelectRecv, Chan: valueOf}}
+
+		if env.Context != nil {
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(env.Context.Done())})
+		}
 
-		for i := 0; i < len; i++ {
-			elem := valueOf.Index(i).Interface()
+		for i := 0; ; i++ {
+			chosen, elem, received := reflect.Select(cases)
 
-			// set the new values
-			env.Set(value, elem)
+			if chosen == 1 {
+				return newError(fe.Token, "for loop over a channel stopped: %w", env.Context.Err())
+			}
 
-			if key != "" {
-				env.Set(key, i)
+			if !received {
+				break
 			}
 
-			res := Eval(fe.Block, env)
+			if max != 0 && i >= max {
+				return newError(fe.Token, "%w", limitErrorf("for loop over a channel exceeds the max of %d iterations", max))
+			}
 
-			if isError(res) {
+			if res := iterate(i, i, elem.Interface(), -1); res != nil {
 				return res
 			}
-
-			out += res.(string)
 		}
 
 	default:
 		return newError(fe.Token, "%T is not iterable", in)
 	}
 
-	// delete the vars
-	env.Delete(value)
+	return nil
+}
+
+// loopMetadata returns the loop var of the iteration at index, the length and
+// last are only known when length is not -1.
+func loopMetadata(index int, length int) map[string]interface{} {
+	loop := map[string]interface{}{
+		"index":     index,
+		"iteration": index + 1,
+		"first":     index == 0,
+	}
+
+	if length != -1 {
+		loop["length"] = length
+		loop["last"] = index == length-1
+	}
 
-	if key != "" {
-		env.Delete(key)
+	return loop
+}
+
+func isIterable(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Map, reflect.Array, reflect.Slice:
+		return true
+
+	default:
+		return false
 	}
+}
+
+func outputSizeError(max int) error {
+	return limitErrorf("output exceeds the max size of %d bytes", max)
+}
+
+// limitError is the error of an execution limit of the render, try can not
+// rescue it so a template can not get past its own limits.
+type limitError struct {
+	message string
+}
+
+func (e *limitError) Error() string {
+	return e.message
+}
 
-	return out
+func limitErrorf(format string, a ...interface{}) error {
+	return &limitError{message: fmt.Sprintf(format, a...)}
 }
 
 func evalExtendsStatement(node *ast.ExtendsStatement, env *object.Environment) interface{} {
@@ -595,13 +1177,17 @@ func evalSectionStatement(node *ast.SectionStatement, env *object.Environment) i
 		return newError(node.Token, "section statement is not allowed in a section")
 	}
 
+	content := Eval(node.Block, env)
+
 	// save the section
 	env.ExtendsFrom.Sections[node.Name] = object.SectionContent{
-		Content: Eval(node.Block, env),
+		Content: content,
 		Name:    node.Name,
 		Token:   node.Token,
 	}
 
+	env.Fragment.Record(node.Name, content)
+
 	return nil
 }
 
@@ -623,18 +1209,34 @@ func evalDefineStatement(node *ast.DefineStatement, env *object.Environment) int
 		content = Eval(node.Content, env)
 	}
 
+	env.Fragment.Record(node.Name, content)
+
 	return content
 }
 
 func evalDotExpression(node *ast.DotExpression, env *object.Environment) interface{} {
-	var result interface{}
-
-	left := Eval(&node.Left, env)
+	left := Eval(node.Left, env)
 
 	if isError(left) {
 		return left
 	}
 
+	return evalDot(node, left, env)
+}
+
+// evalDot returns the field or the key node.Right of the evaluated left side.
+func evalDot(node *ast.DotExpression, left interface{}, env *object.Environment) interface{} {
+	var result interface{}
+
+	// optional data: a missing value propagates through the rest of the chain
+	if isNil(left) {
+		return nil
+	}
+
+	if m, isMap := left.(map[string]interface{}); isMap {
+		return m[node.Right.Value]
+	}
+
 	leftValue := reflect.ValueOf(left)
 	leftType := reflect.ValueOf(left).Kind()
 
@@ -644,67 +1246,240 @@ func evalDotExpression(node *ast.DotExpression, env *object.Environment) interfa
 		leftType = leftValue.Kind()
 	}
 
+	if leftType == reflect.Map {
+		return evalMapIndexExpression(leftValue.Interface(), node.Right.Value, node.Token)
+	}
+
 	if leftType != reflect.Struct {
-		return newError(node.Token, "left side of dot expression must be a struct, got=%s", leftType)
+		return newError(node.Token, "left side of dot expression must be a struct or a map, got=%s", leftType)
 	}
 
 	leftStruct := reflect.TypeOf(leftValue.Interface())
 
+	// the sandbox allows the fields by their Go name
+	field, ok := structField(leftStruct, node.Right.Value)
+	name := node.Right.Value
+
+	if ok {
+		name = field.Name
+	}
+
+	if env.Sandbox != nil && !env.Sandbox.CanAccess(leftStruct, name) {
+		return newError(node.Token, "field %s of %s is not allowed in the sandbox", name, leftStruct)
+	}
+
 	// check if the field (node.Right) exists
-	if _, ok := leftStruct.FieldByName(node.Right.Value); ok {
+	if !ok {
+		return newError(node.Token, "field %s does not exist in struct %s", node.Right.Value, node.Left.String())
+	}
 
-		result = leftValue.FieldByName(node.Right.Value).Interface()
+	result, err := fieldInterface(reflect.ValueOf(left), field)
 
-	} else {
-		return newError(node.Token, "field %s does not exist in struct %s", node.Right.Value, node.Left.Value)
+	if err != nil {
+		return newError(node.Token, "%s", err)
 	}
 
 	return result
 }
 
-func isNumber(num interface{}) (int, bool) {
-	if num == nil {
-		return 0, false
+// isNil reports whether the value is nil or a nil pointer, map, slice or
+// interface.
+func isNil(value interface{}) bool {
+	switch value.(type) {
+	case nil:
+		return true
+
+	case string, int, bool, float64, time.Time:
+		return false
 	}
 
-	is := reflect.TypeOf(num).Kind() == reflect.Int ||
-		reflect.TypeOf(num).Kind() == reflect.Int8 ||
-		reflect.TypeOf(num).Kind() == reflect.Int16 ||
-		reflect.TypeOf(num).Kind() == reflect.Int32 ||
-		reflect.TypeOf(num).Kind() == reflect.Int64
+	valueOf := reflect.ValueOf(value)
 
-	if !is {
-		return 0, false
-	}
+	switch valueOf.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		return valueOf.IsNil()
 
-	return int(reflect.ValueOf(num).Int()), true
+	default:
+		return false
+	}
 }
 
 func evalIncludeStatement(node *ast.IncludeStatement, env *object.Environment) interface{} {
-	newEnv := object.NewEnvironment()
+	var value interface{}
 
 	if node.Vars != nil {
-		vars, isMap := node.Vars.(*ast.MapLiteral)
+		if value = Eval(node.Vars, env); isError(value) {
+			return value
+		}
+	}
+
+	return includeTemplate(node.Token, node.File, node.Vars != nil, value, env)
+}
+
+// includeTemplate returns the output of the template file rendered with the
+// vars of value (a map or a struct), if hasVars is set.
+func includeTemplate(t token.Token, file string, hasVars bool, value interface{}, env *object.Environment) interface{} {
+	newEnv := object.NewEnvironment()
+	newEnv.Sandbox = env.Sandbox
+	newEnv.Locale = env.Locale
+	newEnv.Context = env.Context
+	newEnv.Resolve = env.Resolve
+	newEnv.Diagnostics = env.Diagnostics
+	newEnv.Limits = renderLimits(env)
+
+	// the render context is available in every template of the render
+	if ctx, exists := env.Get("ctx"); exists {
+		newEnv.Set("ctx", ctx)
+	}
 
-		if !isMap {
-			return newError(node.Token, "vars in include must be a map, got=%s", node.Vars.TokenLiteral())
+	// track the include stack, so recursive includes end
+	newEnv.Includes = append(append([]string{}, env.Includes...), env.FileName)
+
+	if max := newEnv.Limits.MaxIncludeDepth; len(newEnv.Includes) > max {
+		return newError(t, "%w", limitErrorf("max include depth of %d exceeded including %s", max, file))
+	}
+
+	// the include loads the template its name resolves to
+	file = internal.ResolveName(file, env.Resolve)
+
+	// without vars the included template would render the same way forever
+	templateFile := internal.TemplateFile(file)
+
+	if !hasVars {
+		for i, included := range newEnv.Includes {
+			if included == templateFile {
+				cycle := append(newEnv.Includes[i:], templateFile)
+
+				return newError(t, "include cycle detected: %s", strings.Join(cycle, " -> "))
+			}
 		}
+	}
+
+	var vars map[string]interface{}
 
-		for key, value := range vars.Pairs {
-			newEnv.Set(key.String(), Eval(value, env))
+	if hasVars {
+		var ok bool
+
+		if vars, ok = includeVars(value, env.Sandbox); !ok {
+			return newError(t, "vars in include must be a map or a struct, got=%T", value)
 		}
 	}
 
-	var out bytes.Buffer
+	out := getBuffer()
+	defer putBuffer(out)
 
-	err := internal.LoadFile(node.File, nil, &out, Eval, *newEnv)
+	err := internal.LoadFile(file, vars, out, Eval, *newEnv)
 
 	result := out.String()
 
 	// check if any error has occured
 	if err != nil {
-		return errors.New(err.Error())
+		return err
 	}
 
 	return result
 }
+
+// includeVars converts a map (its keys) or a struct (its exported fields, the
+// ones the sandbox allows) into the vars of an included template. It returns
+// false if value is neither.
+func includeVars(value interface{}, sandbox *object.Sandbox) (map[string]interface{}, bool) {
+	vars := make(map[string]interface{})
+
+	valueOf := reflect.ValueOf(value)
+
+	if valueOf.Kind() == reflect.Ptr && !valueOf.IsNil() {
+		valueOf = valueOf.Elem()
+	}
+
+	switch valueOf.Kind() {
+
+	case reflect.Map:
+		for _, key := range valueOf.MapKeys() {
+			vars[fmt.Sprintf("%v", key.Interface())] = valueOf.MapIndex(key).Interface()
+		}
+
+	case reflect.Struct:
+		structType := valueOf.Type()
+
+		for i := 0; i < structType.NumField(); i++ {
+			field := structType.Field(i)
+
+			if field.PkgPath != "" {
+				continue // unexported
+			}
+
+			if sandbox != nil && !sandbox.CanAccess(structType, field.Name) {
+				continue
+			}
+
+			vars[field.Name] = valueOf.Field(i).Interface()
+		}
+
+	default:
+		return nil, false
+	}
+
+	return vars, true
+}
+
+func evalTryStatement(node *ast.TryStatement, env *object.Environment) interface{} {
+	result := Eval(node.Block, env)
+
+	if !isError(result) {
+		return result
+	}
+
+	if !rescuable(result.(error), env) {
+		return result
+	}
+
+	if node.Rescue == nil {
+		return nil
+	}
+
+	// the error is exposed as its message, an error value would abort the rescue
+	// block, and only exists in the rescue block
+	scope := object.NewEnclosedEnvironment(env)
+	defer scope.Release()
+
+	if node.Err != "" {
+		scope.Set(node.Err, result.(error).Error())
+	}
+
+	return Eval(node.Rescue, scope)
+}
+
+// rescuable reports whether try can rescue the error: a halted render, an
+// execution limit and a cancelled render stop the whole render.
+func rescuable(err error, env *object.Environment) bool {
+	var halt *object.HaltError
+	var limit *limitError
+
+	if errors.As(err, &halt) || errors.As(err, &limit) {
+		return false
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	return env.Context == nil || env.Context.Err() == nil
+}
+
+// spacesBetweenTags matches the whitespace between two HTML tags.
+var spacesBetweenTags = regexp.MustCompile(`>\s+<`)
+
+// evalSpacelessStatement removes the whitespace between the tags of the output
+// of the block, the whitespace inside the text of the tags is kept.
+func evalSpacelessStatement(node *ast.SpacelessStatement, env *object.Environment) interface{} {
+	result := Eval(node.Block, env)
+
+	output, isString := result.(string)
+
+	if !isString {
+		return result
+	}
+
+	return spacesBetweenTags.ReplaceAllString(strings.TrimSpace(output), "><")
+}
@@ -0,0 +1,541 @@
+package evaluator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/govel-framework/lamb/ast"
+	"github.com/govel-framework/lamb/internal"
+	"github.com/govel-framework/lamb/lexer"
+	"github.com/govel-framework/lamb/object"
+	"github.com/govel-framework/lamb/parser"
+	"github.com/govel-framework/lamb/token"
+)
+
+var sink interface{}
+
+// BenchmarkTypeCheckSprintf measures the type checks the evaluator used to do.
+func BenchmarkTypeCheckSprintf(b *testing.B) {
+	var value interface{} = "lamb"
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		sink = fmt.Sprintf("%T", value) == "string"
+	}
+}
+
+// BenchmarkTypeCheckAssertion measures the type assertions that replaced them.
+func BenchmarkTypeCheckAssertion(b *testing.B) {
+	var value interface{} = "lamb"
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		_, sink = value.(string)
+	}
+}
+
+func BenchmarkEvalInfixExpression(b *testing.B) {
+	t := token.Token{Type: token.PLUS, Literal: "+"}
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		sink = evalInfixExpression("+", "foo", "bar", t)
+	}
+}
+
+func BenchmarkEvalInfixExpressionInt(b *testing.B) {
+	t := token.Token{Type: token.LT, Literal: "<"}
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		sink = evalInfixExpression("<", i, 100, t)
+	}
+}
+
+// BenchmarkEvalInfixExpressionMixed measures the numbers that still go
+// through reflection.
+func BenchmarkEvalInfixExpressionMixed(b *testing.B) {
+	t := token.Token{Type: token.PLUS, Literal: "+"}
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		sink = evalInfixExpression("+", int64(i), 2.5, t)
+	}
+}
+
+func BenchmarkEvalIndexExpressionList(b *testing.B) {
+	list := []interface{}{"a", "b", "c"}
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		sink = evalIndexExpression(list, 1, token.Token{})
+	}
+}
+
+func BenchmarkEvalIndexExpressionMap(b *testing.B) {
+	m := map[string]interface{}{"name": "lamb"}
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		sink = evalIndexExpression(m, "name", token.Token{})
+	}
+}
+
+// BenchmarkEvalIndexExpressionReflect measures the lists that still go
+// through reflection.
+func BenchmarkEvalIndexExpressionReflect(b *testing.B) {
+	list := []string{"a", "b", "c"}
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		sink = evalIndexExpression(list, 1, token.Token{})
+	}
+}
+
+// BenchmarkCallGoFunc measures the calls to the Go functions of the vars.
+func BenchmarkCallGoFunc(b *testing.B) {
+	node := parser.New(lexer.New(`{? twice(21) ?}`)).ParseProgram().Statements[0].(*ast.ExpressionStatement).Expression.(*ast.CallExpression)
+
+	twice := func(n int) int { return n * 2 }
+	args := []interface{}{21}
+	env := object.NewEnvironment()
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		sink = callFunction(node, twice, args, env)
+	}
+}
+
+func BenchmarkEvalForExpression(b *testing.B) {
+	benchmarkEvalForExpression(b, 100)
+}
+
+// BenchmarkEvalForExpressionLarge measures a loop whose output is far bigger
+// than the output of one iteration.
+func BenchmarkEvalForExpressionLarge(b *testing.B) {
+	benchmarkEvalForExpression(b, 10000)
+}
+
+func benchmarkEvalForExpression(b *testing.B, size int) {
+	input := `<table>{? for i, row in rows ?}<tr><td>{? i ?}</td><td>{? row ?}</td></tr>{? endfor ?}</table>`
+
+	program := parser.New(lexer.New(input)).ParseProgram()
+
+	rows := make([]string, size)
+
+	for i := range rows {
+		rows[i] = fmt.Sprintf("row %d", i)
+	}
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		env := object.NewEnvironment()
+		env.Set("rows", rows)
+
+		sink = Eval(program, env)
+	}
+}
+
+// evalSeeds are valid templates that use every kind of node, with the vars of
+// fuzzEnv.
+var evalSeeds = []string{
+	`<p>{? name ?}</p>`,
+	`{? var x = [1, 2.5, "a", {"k": true}] ?}{? x[0] + x[1] * -2 ?}`,
+	`{? if a > 1 and !b ?}yes{? else ?}no{? endif ?}`,
+	`{? for i, v in items ?}{? loop.index ?}{? v ?}{? endfor ?}`,
+	`{? for k, v in {"a": 1} ?}{? k ?}{? endfor ?}`,
+	`{? try ?}{? x / 0 ?}{? rescue err ?}{? err ?}{? endtry ?}`,
+	`{? spaceless ?} <p> a </p> {? endspaceless ?}`,
+	`{? map(items, x => x ** 2) ?}`,
+	`{? items[10] ?}{? name.Field ?}{? name() ?}`,
+	`{? 1 / 0 ?}{? 2 % 0 ?}{? -"a" ?}{? !items ?}`,
+	`{? upper(name) ?}{? len(items) ?}{? range(1, 3) ?}`,
+	`{? range(none, 1) ?}{? len(none) ?}{? none.x ?}{? none[0] ?}`,
+	`{? m.x ?}{? m[1] ?}{? {"a": 1}[items] ?}{? {[1]: 2} ?}`,
+}
+
+func fuzzEnv() *object.Environment {
+	env := object.NewEnvironment()
+	env.Set("name", "lamb")
+	env.Set("a", 2)
+	env.Set("b", false)
+	env.Set("items", []interface{}{1, "two", 3.5})
+	env.Set("m", map[string]int{"x": 1})
+	env.Set("none", nil)
+
+	return env
+}
+
+// FuzzEval checks that no valid template makes the evaluator panic, the
+// invalid ones are never evaluated.
+func FuzzEval(f *testing.F) {
+	for _, seed := range evalSeeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		p := parser.New(lexer.New(input))
+
+		program := p.ParseProgram()
+
+		if len(p.Errors()) != 0 {
+			return
+		}
+
+		sink = Eval(program, fuzzEnv())
+	})
+}
+
+// FuzzCompile checks that the compiled templates render like the templates
+// evaluated by walking their tree.
+func FuzzCompile(f *testing.F) {
+	for _, seed := range evalSeeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		p := parser.New(lexer.New(input))
+
+		if p.ParseProgram(); len(p.Errors()) != 0 {
+			return
+		}
+
+		walked := fmt.Sprint(Eval(parser.New(lexer.New(input)).ParseProgram(), fuzzEnv()))
+		compiled := fmt.Sprint(evalCompiled(input, fuzzEnv()))
+
+		if walked != compiled {
+			t.Errorf("%q - the compiled template renders %q, want=%q", input, compiled, walked)
+		}
+	})
+}
+
+// evalCompiled evaluates the template with lamb.compile on.
+func evalCompiled(input string, env *object.Environment) interface{} {
+	internal.SetSetting("GOVEL_LAMB_COMPILE", "true")
+	defer internal.SetSettings(nil)
+
+	return Eval(parser.New(lexer.New(input)).ParseProgram(), env)
+}
+
+func TestBlockScopes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// the loop vars hide the vars of the template until the end of the loop
+		{`{? var x = 1 ?}{? for x in [5, 6] ?}{? x ?}{? endfor ?}|{? x ?}`, "56|1"},
+		// a var of the template set in a loop keeps its value
+		{`{? var total = 0 ?}{? for x in [5, 6] ?}{? var total = total + x ?}{? endfor ?}{? total ?}`, "11"},
+		{`{? for a in [1, 2] ?}{? for b in [3] ?}{? loop.index ?}{? endfor ?}{? loop.index ?}{? endfor ?}`, "0001"},
+		{`{? try ?}{? missing ?}{? rescue err ?}E{? endtry ?}`, "E"},
+	}
+
+	for i, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+
+		result := Eval(program, object.NewEnvironment())
+
+		if result != tt.expected {
+			t.Errorf("tests[%d] - wrong result, expected=%q, got=%v", i, tt.expected, result)
+		}
+	}
+}
+
+// TestFastPaths checks that the common types give the same results as the
+// reflection they skip.
+func TestFastPaths(t *testing.T) {
+	values := []interface{}{0, -12, 1.5, 1e21, 1e-7, math.Inf(1), math.NaN(), time.Date(2023, 9, 13, 22, 10, 1, 0, time.UTC)}
+
+	for i, value := range values {
+		var out bytes.Buffer
+
+		writeUnformatted(&out, value)
+
+		if expected := fmt.Sprintf("%v", value); out.String() != expected {
+			t.Errorf("values[%d] - wrong output, expected=%q, got=%q", i, expected, out.String())
+		}
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{? 7 / 2 ?}|{? 6 / 2 ?}|{? 1.5 * 2.0 ?}|{? 2 ** 3 ?}`, "3.5|3|3|8"},
+		{`{? "a" + "b" ?}|{? "a" < "b" ?}|{? "a" == "a" ?}|{? true != false ?}`, "ab|true|true|true"},
+		{`{? list[1] ?}|{? list[5] ?}|{? list[-1] ?}|{? len(list) ?}`, "b|||3"},
+		{`{? user.name ?}|{? user["name"] ?}|{? user.missing ?}|{? {"a": 1}["a"] ?}`, "lamb|lamb||1"},
+		{`{? for i, v in list ?}{? i ?}{? v ?}{? endfor ?}`, "0a1b2c"},
+		{`{? 1 in [1, 2] ?}|{? "a" not in "abc" ?}`, "true|false"},
+	}
+
+	for i, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+
+		env := object.NewEnvironment()
+		env.Set("list", []interface{}{"a", "b", "c"})
+		env.Set("user", map[string]interface{}{"name": "lamb"})
+
+		result := Eval(program, env)
+
+		if result != tt.expected {
+			t.Errorf("tests[%d] - wrong result, expected=%q, got=%v", i, tt.expected, result)
+		}
+	}
+}
+
+func TestBoolLabels(t *testing.T) {
+	internal.SetSettings(map[string]string{"GOVEL_LAMB_TRUE_LABEL": "yes", "GOVEL_LAMB_FALSE_LABEL": ""})
+	t.Cleanup(func() { internal.SetSettings(nil) })
+
+	program := parser.New(lexer.New(`{? true ?}|{? false ?}`)).ParseProgram()
+
+	if result := Eval(program, object.NewEnvironment()); result != "yes|" {
+		t.Errorf("wrong result, expected=%q, got=%v", "yes|", result)
+	}
+}
+
+// TestCompile checks that the compiled templates render like the templates
+// evaluated by walking their tree, mostly the vars the slots of the IR cache.
+func TestCompile(t *testing.T) {
+	tests := []string{
+		`{? var x = 1 ?}{? for x in [5, 6] ?}{? x ?}{? endfor ?}|{? x ?}`,
+		`{? var total = 0 ?}{? for x in [5, 6] ?}{? var total = total + x ?}{? endfor ?}{? total ?}`,
+		`{? for a in [1, 2] ?}{? for b in [3] ?}{? loop.index ?}{? a ?}{? b ?}{? endfor ?}{? loop.index ?}{? endfor ?}`,
+		`{? var x = 1 ?}{? try ?}{? var x = 2 ?}{? missing ?}{? rescue err ?}{? x ?}{? endtry ?}{? x ?}`,
+		`{? for i in [1, 2] ?}{? var f = x => x + i ?}{? map([10], f) ?}{? endfor ?}`,
+		`{? var x = 1 ?}{? if x == 1 ?}{? var x = x + 1 ?}{? endif ?}{? x ?}`,
+		`{? if a > 5 ?}big{? else ?}{? if b ?}b{? else ?}small{? endif ?}{? endif ?}`,
+		`{? for k, v in {"b": 1, "a": 2} ?}{? k ?}={? v ?};{? endfor ?}`,
+		`{? for k, v in m ?}{? k ?}={? v ?}{? endfor ?}`,
+		`{? for name in items ?}{? name ?}{? endfor ?}{? name ?}`,
+		`{? isset(missing) ?}{? empty(items) ?}{? [] ?}{? [1, [2]] ?}`,
+		`{? len(filter(items, x => x == 1)) ?}{? upper(name) ?}`,
+		`{? var upper = x => x ?}{? upper(name) ?}`,
+		`{? for i in items ?}{? missing ?}{? endfor ?}`,
+		`{? "unclosed ?}`,
+		`{? spaceless ?}{? for i in [1, 2] ?} <b>{? i ?}</b> {? endfor ?}{? endspaceless ?}`,
+	}
+
+	for i, input := range tests {
+		walked := fmt.Sprint(Eval(parser.New(lexer.New(input)).ParseProgram(), fuzzEnv()))
+		compiled := fmt.Sprint(evalCompiled(input, fuzzEnv()))
+
+		if walked != compiled {
+			t.Errorf("tests[%d] - the compiled template renders %q, want=%q", i, compiled, walked)
+		}
+	}
+}
+
+type propUser struct {
+	Name string
+}
+
+func TestAutoescape(t *testing.T) {
+	vars := map[string]interface{}{
+		"title": `Tom & "Jerry" <3`,
+		"n":     2,
+		"tags":  []interface{}{"a", "b"},
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`<t>{? title ?}</t>`, `<t>Tom & "Jerry" <3</t>`},
+		{`{? autoescape("xml") ?}<t a="{? title ?}">{? n ?}</t>`, `<t a="Tom &amp; &quot;Jerry&quot; &lt;3">2</t>`},
+		{`{? autoescape("xml") ?}{? if n > 1 ?}<b>{? title + "!" ?}</b>{? endif ?}{? for tag in tags ?}<i>{? tag ?}</i>{? endfor ?}`, `<b>Tom &amp; &quot;Jerry&quot; &lt;3!</b><i>a</i><i>b</i>`},
+		{`{? autoescape("json") ?}{"title": {? title ?}, "n": {? n ?}, "tags": {? tags ?}, "ok": {? n > 1 ?}}`, `{"title": "Tom \u0026 \"Jerry\" \u003c3", "n": 2, "tags": ["a","b"], "ok": true}`},
+		{`{? autoescape("html") ?}{? title ?}`, `Tom & "Jerry" <3`},
+	}
+
+	for i, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+
+		env := object.NewEnvironment()
+
+		for name, value := range vars {
+			env.Set(name, value)
+		}
+
+		result := Eval(program, env)
+
+		if err, isError := result.(error); isError {
+			result = err.Error()
+		}
+
+		if result != tt.expected {
+			t.Errorf("tests[%d] - wrong result, expected=%q, got=%q", i, tt.expected, result)
+		}
+	}
+}
+
+func TestProps(t *testing.T) {
+	internal.AddPropType("User", reflect.TypeOf(propUser{}))
+
+	tests := []struct {
+		input    string
+		vars     map[string]interface{}
+		expected interface{}
+	}{
+		{`{? props(user: User, n: int) ?}{? user.Name ?}{? n ?}`, map[string]interface{}{"user": &propUser{"ann"}, "n": int64(2)}, "ann2"},
+		{`{? props(title: string = "Home", n: float = 1) ?}{? title ?}{? n ?}`, nil, "Home1"},
+		{`{? props(title: string = "Home") ?}{? title ?}`, map[string]interface{}{"title": "Users"}, "Users"},
+		{`{? props(users: []User, m: map[string]int) ?}{? len(users) ?}`, map[string]interface{}{"users": []interface{}{propUser{}, &propUser{}}, "m": map[string]interface{}{"a": 1}}, "2"},
+		{`{? props(users: []*User, any: any, w: Widget) ?}ok`, map[string]interface{}{"users": nil, "any": 1, "w": 1}, "ok"},
+		{`{? props(items: []int) ?}`, nil, ": 1: 10: missing required prop items"},
+		{`{? props(user: User) ?}`, map[string]interface{}{"user": "ann"}, ": 1: 10: prop user must be a User, got=string"},
+		{`{? props(items: []int) ?}`, map[string]interface{}{"items": []interface{}{1, "a"}}, ": 1: 10: prop items must be a []int, got=[]interface {}"},
+	}
+
+	for i, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+
+		env := object.NewEnvironment()
+
+		for name, value := range tt.vars {
+			env.Set(name, value)
+		}
+
+		result := Eval(program, env)
+
+		if err, isError := result.(error); isError {
+			result = err.Error()
+		}
+
+		if result != tt.expected {
+			t.Errorf("tests[%d] - wrong result, expected=%q, got=%q", i, tt.expected, result)
+		}
+	}
+}
+
+func TestPower(t *testing.T) {
+	tests := []struct {
+		base, exp int
+		expected  interface{}
+	}{
+		{2, 3, 8},
+		{2, 0, 1},
+		{0, 0, 1},
+		{-2, 3, -8},
+		{-1, 1 << 40, 1},
+		{2, 62, 1 << 62},
+		{-2, 63, math.MinInt64},
+		{2, -1, 0.5},
+		{2, 63, math.Pow(2, 63)},
+		{2, 64, math.Pow(2, 64)},
+		{-2, 65, math.Pow(-2, 65)},
+		{10, 19, math.Pow(10, 19)},
+		{3, 40, math.Pow(3, 40)},
+		{7, 1000, math.Pow(7, 1000)},
+		{math.MaxInt64, 2, math.Pow(math.MaxInt64, 2)},
+	}
+
+	for _, tt := range tests {
+		if got := power(tt.base, tt.exp); got != tt.expected {
+			t.Errorf("%d ** %d: expected=%v (%T), got=%v (%T)", tt.base, tt.exp, tt.expected, tt.expected, got, got)
+		}
+	}
+
+	// the operator and the builtin overflow to a float too
+	program := parser.New(lexer.New(`{? 2 ** 64 ?}|{? pow(2, 64) ?}|{? 2 ** 10 ?}`)).ParseProgram()
+
+	if got := Eval(program, object.NewEnvironment()); got != "1.8446744073709552e+19|1.8446744073709552e+19|1024" {
+		t.Errorf("wrong output. got=%v", got)
+	}
+}
+
+func TestRetryCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	calls := 0
+
+	// a builtin that fails and cancels the render on its first call
+	Registry.Register("test_flaky", &object.Builtin{
+		Fn: func(args ...interface{}) interface{} {
+			calls++
+			cancel()
+
+			return errors.New("service unavailable")
+		},
+		Retries: 3,
+		Backoff: time.Second,
+	})
+
+	env := object.NewEnvironment()
+	env.Context = ctx
+
+	start := time.Now()
+
+	got := Eval(parser.New(lexer.New(`{? test_flaky() ?}`)).ParseProgram(), env)
+
+	err, isError := got.(error)
+
+	if !isError || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a cancellation error. got=%v", got)
+	}
+
+	if calls != 1 || time.Since(start) > 500*time.Millisecond {
+		t.Errorf("the retries did not stop. calls=%d, elapsed=%s", calls, time.Since(start))
+	}
+}
+
+func TestTryLimits(t *testing.T) {
+	internal.SetSettings(map[string]string{
+		"GOVEL_LAMB_MAX_LOOP_ITERATIONS": "2",
+		"GOVEL_LAMB_MAX_OUTPUT_SIZE":     "20",
+	})
+
+	t.Cleanup(func() {
+		internal.SetSettings(nil)
+	})
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		input    string
+		ctx      context.Context
+		expected string
+	}{
+		{`{? try ?}{? 1 / 0 ?}{? rescue e ?}rescued{? endtry ?}`, nil, "rescued"},
+		{`{? try ?}{? for i in [1, 2, 3] ?}{? i ?}{? endfor ?}{? rescue e ?}rescued{? endtry ?}`, nil, "for loop over 3 elements exceeds the max of 2 iterations"},
+		{`{? try ?}{? for i in [1, 2] ?}01234567890{? endfor ?}{? rescue e ?}rescued{? endtry ?}`, nil, "output exceeds the max size of 20 bytes"},
+		{`{? try ?}{? for v in ch ?}{? v ?}{? endfor ?}{? rescue e ?}rescued{? endtry ?}`, cancelled, "for loop over a channel stopped: context canceled"},
+		{`{? try ?}{? 1 / 0 ?}{? rescue e ?}rescued{? endtry ?}`, cancelled, "division by zero"},
+	}
+
+	for i, tt := range tests {
+		env := object.NewEnvironment()
+		env.Context = tt.ctx
+		env.Set("ch", make(chan int))
+
+		got := Eval(parser.New(lexer.New(tt.input)).ParseProgram(), env)
+
+		if err, isError := got.(error); isError {
+			got = err.Error()
+		}
+
+		// the compiled templates report the output size without a position
+		if got, _ := got.(string); !strings.HasSuffix(got, tt.expected) {
+			t.Errorf("tests[%d] - wrong result. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+}
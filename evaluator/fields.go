@@ -0,0 +1,121 @@
+package evaluator
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/govel-framework/lamb/internal"
+)
+
+// fieldKey identifies a field name resolved in a struct type.
+type fieldKey struct {
+	structType      reflect.Type
+	name            string
+	caseInsensitive bool
+}
+
+// fields caches the fields resolved by structField.
+var fields sync.Map
+
+// caseInsensitiveFields reports whether the fields of the structs can be
+// written in any case in the templates (lamb.case_insensitive_fields).
+func caseInsensitiveFields() bool {
+	return internal.Setting("GOVEL_LAMB_CASE_INSENSITIVE_FIELDS") == "true"
+}
+
+// structField returns the field of a struct type written as name in a
+// template: its Go name, the name of its lamb or json tag or, with
+// lamb.case_insensitive_fields, its Go name in any case (user.email for
+// Email).
+func structField(structType reflect.Type, name string) (reflect.StructField, bool) {
+	key := fieldKey{structType, name, caseInsensitiveFields()}
+
+	if cached, exists := fields.Load(key); exists {
+		field, _ := cached.(reflect.StructField)
+
+		return field, cached != nil
+	}
+
+	field, exists := findField(structType, name, key.caseInsensitive)
+
+	if exists {
+		fields.Store(key, field)
+	} else {
+		fields.Store(key, nil)
+	}
+
+	return field, exists
+}
+
+func findField(structType reflect.Type, name string, caseInsensitive bool) (reflect.StructField, bool) {
+	if field, exists := structType.FieldByName(name); exists {
+		return field, true
+	}
+
+	visible := reflect.VisibleFields(structType)
+
+	for _, tag := range []string{"lamb", "json"} {
+		for _, field := range visible {
+			if tagName := strings.Split(field.Tag.Get(tag), ",")[0]; tagName == name && tagName != "-" {
+				return field, true
+			}
+		}
+	}
+
+	if caseInsensitive {
+		for _, field := range visible {
+			if strings.EqualFold(field.Name, name) {
+				return field, true
+			}
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+// fieldInterface returns the value of a field of the struct (or the pointer to
+// it) value, an unexported field is read with its getter method if
+// lamb.GetterMethods maps it to one.
+func fieldInterface(value reflect.Value, field reflect.StructField) (interface{}, error) {
+	if field.IsExported() {
+		return reflect.Indirect(value).FieldByIndex(field.Index).Interface(), nil
+	}
+
+	structType := reflect.Indirect(value).Type()
+
+	name, hasGetter := internal.GetterMethod(field.Name)
+
+	if !hasGetter {
+		return nil, fmt.Errorf("field %s of %s is unexported", field.Name, structType)
+	}
+
+	method := value.MethodByName(name)
+
+	// the methods with a pointer receiver
+	if !method.IsValid() && value.Kind() != reflect.Ptr {
+		pointer := reflect.New(value.Type())
+		pointer.Elem().Set(value)
+
+		method = pointer.MethodByName(name)
+	}
+
+	if !method.IsValid() {
+		return nil, fmt.Errorf("field %s of %s is unexported and %s has no getter %s", field.Name, structType, structType, name)
+	}
+
+	methodType := method.Type()
+
+	if methodType.NumIn() != 0 || methodType.NumOut() == 0 || methodType.NumOut() > 2 || methodType.NumOut() == 2 && methodType.Out(1) != errorType {
+		return nil, fmt.Errorf("getter %s of %s must take no arguments and return a value and optionally an error", name, structType)
+	}
+
+	results := method.Call(nil)
+
+	if len(results) == 2 && !results[1].IsNil() {
+		return nil, results[1].Interface().(error)
+	}
+
+	return results[0].Interface(), nil
+}
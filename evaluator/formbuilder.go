@@ -0,0 +1,336 @@
+package evaluator
+
+import (
+	"fmt"
+	"html"
+	"reflect"
+	"strings"
+
+	"github.com/govel-framework/lamb/internal"
+	"github.com/govel-framework/lamb/object"
+)
+
+// errorClass returns the class added to the fields with validation errors
+// (lamb.error_class, "is-invalid" by default).
+func errorClass() string {
+	if class := internal.Setting("GOVEL_LAMB_ERROR_CLASS"); class != "" {
+		return class
+	}
+
+	return "is-invalid"
+}
+
+// fieldAttributes returns the attributes (optional) of a field as a map the
+// builder can change.
+func fieldAttributes(name string, args []interface{}, i int) (map[interface{}]interface{}, error) {
+	attributes := make(map[interface{}]interface{})
+
+	if len(args) <= i {
+		return attributes, nil
+	}
+
+	valueOf, err := mapArg(name, args, i)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range valueOf.MapKeys() {
+		attributes[fmt.Sprintf("%v", key.Interface())] = valueOf.MapIndex(key).Interface()
+	}
+
+	return attributes, nil
+}
+
+// formSession returns the sessions of the request, if any, a form can be
+// rendered without the old input and the errors.
+func formSession(env *object.Environment) (sessionContext, bool) {
+	ctx, _ := env.Get("ctx")
+
+	session, isSessionContext := ctx.(sessionContext)
+
+	return session, isSessionContext
+}
+
+// fieldState returns the submitted value of a field and adds the error class
+// to its attributes when it has validation errors.
+func fieldState(env *object.Environment, field string, attributes map[interface{}]interface{}) (interface{}, bool) {
+	session, exists := formSession(env)
+
+	if !exists {
+		return nil, false
+	}
+
+	if len(fieldErrors(session, field)) > 0 {
+		class, _ := attributes["class"].(string)
+
+		attributes["class"] = strings.TrimSpace(class + " " + errorClass())
+	}
+
+	return flashedField(session, oldInputKey, field)
+}
+
+// formOpenBuiltIn opens a form: form_open("users.update", "PUT", attrs). The
+// action is a route name or an URL, the method is POST by default, the methods
+// other than GET and POST are spoofed with method_field and the forms that are
+// not GET have the CSRF field.
+func formOpenBuiltIn(env *object.Environment, args ...interface{}) interface{} {
+	if err := checkArgs("form_open", args, 1, 3); err != nil {
+		return err
+	}
+
+	action, err := stringArg("form_open", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	method := "POST"
+
+	if len(args) > 1 {
+		if method, err = stringArg("form_open", args, 1); err != nil {
+			return err
+		}
+
+		method = strings.ToUpper(method)
+	}
+
+	attributes, err := fieldAttributes("form_open", args, 2)
+
+	if err != nil {
+		return err
+	}
+
+	if !strings.HasPrefix(action, "/") && !strings.Contains(action, "://") {
+		url := routeBuiltIn(action)
+
+		if isError(url) {
+			return url
+		}
+
+		action = url.(string)
+	}
+
+	attributes["action"] = action
+	attributes["method"] = method
+
+	if method != "GET" {
+		attributes["method"] = "POST"
+	}
+
+	out := "<form" + htmlAttributes(reflect.ValueOf(attributes)) + ">"
+
+	if method == "GET" {
+		return out
+	}
+
+	field := csrfFieldBuiltIn(env)
+
+	if isError(field) {
+		return field
+	}
+
+	out += field.(string)
+
+	if method != "POST" {
+		out += hiddenInput("_method", method)
+	}
+
+	return out
+}
+
+// formCloseBuiltIn closes the form opened by form_open.
+func formCloseBuiltIn(args ...interface{}) interface{} {
+	if err := checkArgs("form_close", args, 0, 0); err != nil {
+		return err
+	}
+
+	return "</form>"
+}
+
+// inputBuiltIn returns an input filled with the submitted value of the field:
+// input("email", {"type": "email", "value": user.Email}). The type is text by
+// default and the passwords are never filled.
+func inputBuiltIn(env *object.Environment, args ...interface{}) interface{} {
+	if err := checkArgs("input", args, 1, 2); err != nil {
+		return err
+	}
+
+	name, err := stringArg("input", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	attributes, err := fieldAttributes("input", args, 1)
+
+	if err != nil {
+		return err
+	}
+
+	if _, exists := attributes["type"]; !exists {
+		attributes["type"] = "text"
+	}
+
+	attributes["name"] = name
+
+	old, exists := fieldState(env, name, attributes)
+
+	if exists && attributes["type"] != "password" {
+		attributes["value"] = old
+	}
+
+	if attributes["type"] == "password" {
+		delete(attributes, "value")
+	}
+
+	return "<input" + htmlAttributes(reflect.ValueOf(attributes)) + ">"
+}
+
+// selectBuiltIn returns a select with the options of a list or a map of value
+// => label: select("role", roles, user.Role, attrs). The submitted value is
+// selected over the given one.
+func selectBuiltIn(env *object.Environment, args ...interface{}) interface{} {
+	if err := checkArgs("select", args, 2, 4); err != nil {
+		return err
+	}
+
+	name, err := stringArg("select", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	attributes, err := fieldAttributes("select", args, 3)
+
+	if err != nil {
+		return err
+	}
+
+	var selected interface{}
+
+	if len(args) > 2 {
+		selected = args[2]
+	}
+
+	attributes["name"] = name
+
+	if old, exists := fieldState(env, name, attributes); exists {
+		selected = old
+	}
+
+	var values, labels []interface{}
+
+	options := reflect.ValueOf(args[1])
+
+	switch options.Kind() {
+
+	case reflect.Map:
+		for _, key := range sortedKeys(options) {
+			values = append(values, key.Interface())
+			labels = append(labels, options.MapIndex(key).Interface())
+		}
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < options.Len(); i++ {
+			values = append(values, options.Index(i).Interface())
+			labels = append(labels, options.Index(i).Interface())
+		}
+
+	default:
+		return builtInError("argument 2 to `select` not supported, got %T, want=slice or map", args[1])
+	}
+
+	var out strings.Builder
+
+	out.WriteString("<select" + htmlAttributes(reflect.ValueOf(attributes)) + ">")
+
+	for i, value := range values {
+		out.WriteString(`<option value="` + html.EscapeString(fmt.Sprintf("%v", value)) + `"`)
+
+		if isSelected(value, selected) {
+			out.WriteString(" selected")
+		}
+
+		out.WriteString(">" + html.EscapeString(fmt.Sprintf("%v", labels[i])) + "</option>")
+	}
+
+	out.WriteString("</select>")
+
+	return out.String()
+}
+
+// isSelected reports whether the value of an option is the selected one or one
+// of them, the values are compared as text since the submitted ones are.
+func isSelected(value, selected interface{}) bool {
+	selectedOf := reflect.ValueOf(selected)
+
+	if selectedOf.Kind() == reflect.Slice || selectedOf.Kind() == reflect.Array {
+		for i := 0; i < selectedOf.Len(); i++ {
+			if isSelected(value, selectedOf.Index(i).Interface()) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return !isNil(selected) && fmt.Sprintf("%v", value) == fmt.Sprintf("%v", selected)
+}
+
+// checkboxBuiltIn returns a checkbox: checkbox("remember", "1", user.Remember,
+// attrs). The value is "1" by default and once the form was submitted the
+// checkbox is checked only if it was.
+func checkboxBuiltIn(env *object.Environment, args ...interface{}) interface{} {
+	if err := checkArgs("checkbox", args, 1, 4); err != nil {
+		return err
+	}
+
+	name, err := stringArg("checkbox", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	var value interface{} = "1"
+
+	if len(args) > 1 {
+		value = args[1]
+	}
+
+	checked := false
+
+	if len(args) > 2 {
+		isChecked, isBool := args[2].(bool)
+
+		if !isBool {
+			return builtInError("argument 3 to `checkbox` not supported, got %T, want=bool", args[2])
+		}
+
+		checked = isChecked
+	}
+
+	attributes, err := fieldAttributes("checkbox", args, 3)
+
+	if err != nil {
+		return err
+	}
+
+	attributes["type"] = "checkbox"
+	attributes["name"] = name
+	attributes["value"] = value
+
+	old, exists := fieldState(env, name, attributes)
+
+	// an unchecked checkbox is not submitted, so a submitted form without the
+	// field means it was unchecked
+	if session, hasSession := formSession(env); hasSession {
+		if _, submitted := session.SessionValue(oldInputKey); submitted {
+			checked = exists && isSelected(value, old)
+		}
+	}
+
+	attributes["checked"] = checked
+
+	return "<input" + htmlAttributes(reflect.ValueOf(attributes)) + ">"
+}
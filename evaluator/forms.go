@@ -0,0 +1,309 @@
+package evaluator
+
+import (
+	"fmt"
+	"html"
+	"reflect"
+	"strings"
+
+	"github.com/govel-framework/lamb/internal"
+	"github.com/govel-framework/lamb/object"
+)
+
+// sessionContext is implemented by the render context (ctx) of lamb.Render,
+// it gives the builtins access to the sessions of the request.
+type sessionContext interface {
+	SessionValue(key string) (interface{}, bool)
+}
+
+// renderSession returns the sessions of the request the template is rendered
+// for.
+func renderSession(name string, env *object.Environment) (sessionContext, error) {
+	ctx, _ := env.Get("ctx")
+
+	session, isSessionContext := ctx.(sessionContext)
+
+	if !isSessionContext {
+		return nil, builtInError("`%s` needs the sessions of the request, ctx is not set by lamb.Render", name)
+	}
+
+	return session, nil
+}
+
+// csrfKey returns the key of the CSRF token in the session, which is also the
+// name of the field of the forms (lamb.csrf_key, "csrf_token" by default).
+func csrfKey() string {
+	if key := internal.Setting("GOVEL_LAMB_CSRF_KEY"); key != "" {
+		return key
+	}
+
+	return "csrf_token"
+}
+
+// csrfTokenBuiltIn returns the CSRF token of the session, e.g. for the meta
+// tag read by the AJAX requests.
+func csrfTokenBuiltIn(env *object.Environment, args ...interface{}) interface{} {
+	if err := checkArgs("csrf_token", args, 0, 0); err != nil {
+		return err
+	}
+
+	token, err := csrfToken("csrf_token", env)
+
+	if err != nil {
+		return err
+	}
+
+	return token
+}
+
+// csrfToken returns the CSRF token of the session for the builtin name.
+func csrfToken(name string, env *object.Environment) (string, error) {
+	session, err := renderSession(name, env)
+
+	if err != nil {
+		return "", err
+	}
+
+	token, exists := session.SessionValue(csrfKey())
+
+	if !exists {
+		return "", builtInError("there is no CSRF token in the session (key %s)", csrfKey())
+	}
+
+	return fmt.Sprintf("%v", token), nil
+}
+
+// csrfFieldBuiltIn returns the hidden input with the CSRF token of the
+// session.
+func csrfFieldBuiltIn(env *object.Environment, args ...interface{}) interface{} {
+	if err := checkArgs("csrf_field", args, 0, 0); err != nil {
+		return err
+	}
+
+	token, err := csrfToken("csrf_field", env)
+
+	if err != nil {
+		return err
+	}
+
+	return hiddenInput(csrfKey(), token)
+}
+
+// methodFieldBuiltIn returns the hidden input that spoofs the method of a form,
+// since the browsers only send GET and POST: method_field("DELETE").
+func methodFieldBuiltIn(args ...interface{}) interface{} {
+	if err := checkArgs("method_field", args, 1, 1); err != nil {
+		return err
+	}
+
+	method, err := stringArg("method_field", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	return hiddenInput("_method", strings.ToUpper(method))
+}
+
+func hiddenInput(name, value string) string {
+	return `<input type="hidden" name="` + html.EscapeString(name) + `" value="` + html.EscapeString(value) + `">`
+}
+
+// The session keys of the data flashed when a form fails the validation: the
+// submitted input and the errors by field.
+const (
+	oldInputKey = "_old_input"
+	errorsKey   = "_errors"
+)
+
+// flashedField returns the field of the map flashed under key.
+func flashedField(session sessionContext, key, field string) (interface{}, bool) {
+	flashed, exists := session.SessionValue(key)
+
+	if !exists {
+		return nil, false
+	}
+
+	flashedMap := reflect.ValueOf(flashed)
+
+	if flashedMap.Kind() != reflect.Map || flashedMap.Type().Key().Kind() != reflect.String && flashedMap.Type().Key().Kind() != reflect.Interface {
+		return nil, false
+	}
+
+	value := flashedMap.MapIndex(reflect.ValueOf(field))
+
+	if !value.IsValid() {
+		return nil, false
+	}
+
+	return value.Interface(), true
+}
+
+// oldBuiltIn returns the value the user submitted in a field of the form that
+// failed the validation: old("email", user.Email). The default (optional) is
+// used when nothing was submitted.
+func oldBuiltIn(env *object.Environment, args ...interface{}) interface{} {
+	if err := checkArgs("old", args, 1, 2); err != nil {
+		return err
+	}
+
+	field, err := stringArg("old", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	session, err := renderSession("old", env)
+
+	if err != nil {
+		return err
+	}
+
+	if value, exists := flashedField(session, oldInputKey, field); exists {
+		return value
+	}
+
+	if len(args) == 2 {
+		return args[1]
+	}
+
+	return ""
+}
+
+// fieldErrors returns the validation errors of a field, a single error or a
+// list of them.
+func fieldErrors(session sessionContext, field string) []string {
+	value, exists := flashedField(session, errorsKey, field)
+
+	if !exists || isNil(value) {
+		return []string{}
+	}
+
+	valueOf := reflect.ValueOf(value)
+
+	if valueOf.Kind() != reflect.Slice && valueOf.Kind() != reflect.Array {
+		return []string{fmt.Sprintf("%v", value)}
+	}
+
+	messages := make([]string, valueOf.Len())
+
+	for i := range messages {
+		messages[i] = fmt.Sprintf("%v", valueOf.Index(i).Interface())
+	}
+
+	return messages
+}
+
+// errorsBuiltIn returns the validation errors of a field:
+//
+//	{? for message in errors("email") ?}<p>{? message ?}</p>{? endfor ?}
+func errorsBuiltIn(env *object.Environment, args ...interface{}) interface{} {
+	if err := checkArgs("errors", args, 1, 1); err != nil {
+		return err
+	}
+
+	field, err := stringArg("errors", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	session, err := renderSession("errors", env)
+
+	if err != nil {
+		return err
+	}
+
+	return fieldErrors(session, field)
+}
+
+// hasErrorBuiltIn reports whether a field has validation errors or, without a
+// field, whether the form has any.
+func hasErrorBuiltIn(env *object.Environment, args ...interface{}) interface{} {
+	if err := checkArgs("has_error", args, 0, 1); err != nil {
+		return err
+	}
+
+	session, err := renderSession("has_error", env)
+
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		flashed, exists := session.SessionValue(errorsKey)
+
+		return exists && !isEmpty(flashed)
+	}
+
+	field, err := stringArg("has_error", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	return len(fieldErrors(session, field)) > 0
+}
+
+// flashContext is implemented by the render context (ctx) of lamb.Render, it
+// consumes the one-time messages of the sessions.
+type flashContext interface {
+	Flash(key string) (interface{}, bool)
+}
+
+// flashBuiltIn returns the one-time message of the session and removes it so
+// it does not show again: flash("status"). The default (optional) is used when
+// there is no such message.
+func flashBuiltIn(env *object.Environment, args ...interface{}) interface{} {
+	if err := checkArgs("flash", args, 1, 2); err != nil {
+		return err
+	}
+
+	key, err := stringArg("flash", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	ctx, _ := env.Get("ctx")
+
+	flashes, isFlashContext := ctx.(flashContext)
+
+	if !isFlashContext {
+		return builtInError("`flash` needs the sessions of the request, ctx is not set by lamb.Render")
+	}
+
+	if value, exists := flashes.Flash(key); exists {
+		return value
+	}
+
+	if len(args) == 2 {
+		return args[1]
+	}
+
+	return ""
+}
+
+// hasFlashBuiltIn reports whether the session has a one-time message, without
+// removing it.
+func hasFlashBuiltIn(env *object.Environment, args ...interface{}) interface{} {
+	if err := checkArgs("has_flash", args, 1, 1); err != nil {
+		return err
+	}
+
+	key, err := stringArg("has_flash", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	session, err := renderSession("has_flash", env)
+
+	if err != nil {
+		return err
+	}
+
+	_, exists := session.SessionValue(key)
+
+	return exists
+}
@@ -0,0 +1,197 @@
+package evaluator
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sync"
+
+	"github.com/govel-framework/lamb/object"
+)
+
+var (
+	errorType    = reflect.TypeOf((*error)(nil)).Elem()
+	stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+)
+
+// funcTypes are the types of the Go functions whose signature was checked, so
+// the functions of the vars are checked once and called without a wrapper.
+var funcTypes sync.Map
+
+// NewFuncBuiltin wraps any Go function into a builtin. The arguments of the
+// template are checked against the signature of fn and converted to the types
+// of its parameters, and a trailing error return is returned as a template
+// error.
+func NewFuncBuiltin(name string, fn interface{}) (*object.Builtin, error) {
+	fnValue := reflect.ValueOf(fn)
+
+	if err := checkFunc(name, fnValue.Type()); err != nil {
+		return nil, err
+	}
+
+	builtin := func(args ...interface{}) interface{} {
+		return callFunc(name, fnValue, args)
+	}
+
+	return &object.Builtin{Fn: builtin}, nil
+}
+
+// checkFunc checks that the templates can call the functions of the type.
+func checkFunc(name string, fnType reflect.Type) error {
+	if _, checked := funcTypes.Load(fnType); checked {
+		return nil
+	}
+
+	if fnType.Kind() != reflect.Func {
+		return fmt.Errorf("%s must be a function, got %s", name, fnType)
+	}
+
+	switch fnType.NumOut() {
+	case 0, 1:
+	case 2:
+		if fnType.Out(1) != errorType {
+			return fmt.Errorf("the second value returned by %s must be an error, got %s", name, fnType.Out(1))
+		}
+
+	default:
+		return fmt.Errorf("%s must return at most 2 values, got %d", name, fnType.NumOut())
+	}
+
+	funcTypes.Store(fnType, true)
+
+	return nil
+}
+
+// callFunc calls a Go function checked by checkFunc with the arguments of the
+// template.
+func callFunc(name string, fnValue reflect.Value, args []interface{}) interface{} {
+	in, err := funcArguments(name, fnValue.Type(), args)
+
+	if err != nil {
+		return err
+	}
+
+	return funcResult(fnValue.Call(in))
+}
+
+// funcArguments converts the arguments of the template into the parameters
+// of the function.
+func funcArguments(name string, fnType reflect.Type, args []interface{}) ([]reflect.Value, error) {
+	numIn := fnType.NumIn()
+
+	if fnType.IsVariadic() {
+		if len(args) < numIn-1 {
+			return nil, builtInError("wrong number of arguments in %s. got=%d, want at least %d", name, len(args), numIn-1)
+		}
+
+	} else if len(args) != numIn {
+		return nil, builtInError("wrong number of arguments in %s. got=%d, want=%d", name, len(args), numIn)
+	}
+
+	in := make([]reflect.Value, len(args))
+
+	for i, arg := range args {
+		var paramType reflect.Type
+
+		if fnType.IsVariadic() && i >= numIn-1 {
+			paramType = fnType.In(numIn - 1).Elem()
+		} else {
+			paramType = fnType.In(i)
+		}
+
+		value, ok := convertArgument(arg, paramType)
+
+		if !ok && arg != nil && isNumberKind(reflect.TypeOf(arg).Kind()) && isNumberKind(paramType.Kind()) {
+			return nil, builtInError("argument %d to `%s` does not fit in %s, got %v", i+1, name, paramType, arg)
+		}
+
+		if !ok {
+			return nil, builtInError("argument %d to `%s` not supported, got %T, want=%s", i+1, name, arg, paramType)
+		}
+
+		in[i] = value
+	}
+
+	return in, nil
+}
+
+func convertArgument(arg interface{}, paramType reflect.Type) (reflect.Value, bool) {
+	if arg == nil {
+		switch paramType.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+			return reflect.Zero(paramType), true
+
+		default:
+			return reflect.Value{}, false
+		}
+	}
+
+	value := reflect.ValueOf(arg)
+
+	if value.Type().AssignableTo(paramType) {
+		return value, true
+	}
+
+	// numbers can be converted between them, but not into strings (e.g. 65 into "A")
+	if isNumberKind(value.Kind()) && isNumberKind(paramType.Kind()) {
+		converted := value.Convert(paramType)
+
+		return converted, sameNumber(value, converted)
+	}
+
+	if value.Kind() == reflect.String && paramType.Kind() == reflect.String {
+		return value.Convert(paramType), true
+	}
+
+	return reflect.Value{}, false
+}
+
+func isNumberKind(kind reflect.Kind) bool {
+	return kind >= reflect.Int && kind <= reflect.Float64 && kind != reflect.Uintptr
+}
+
+// sameNumber reports whether a number converted to another type kept its
+// value, so 2.9 is not truncated into an int, -1 does not wrap around into a
+// uint and 300 does not overflow an int8. Between floats only the precision
+// can be lost.
+func sameNumber(value, converted reflect.Value) bool {
+	if isFloatKind(value.Kind()) && isFloatKind(converted.Kind()) {
+		return math.IsInf(value.Float(), 0) || !math.IsInf(converted.Float(), 0)
+	}
+
+	if isUintKind(converted.Kind()) && (isIntKind(value.Kind()) && value.Int() < 0 || isFloatKind(value.Kind()) && value.Float() < 0) {
+		return false
+	}
+
+	return converted.Convert(value.Type()).Interface() == value.Interface()
+}
+
+func isIntKind(kind reflect.Kind) bool {
+	return kind >= reflect.Int && kind <= reflect.Int64
+}
+
+func isUintKind(kind reflect.Kind) bool {
+	return kind >= reflect.Uint && kind <= reflect.Uint64
+}
+
+func isFloatKind(kind reflect.Kind) bool {
+	return kind == reflect.Float32 || kind == reflect.Float64
+}
+
+// funcResult converts the values returned by the function into the result
+// of the builtin.
+func funcResult(out []reflect.Value) interface{} {
+	if len(out) == 0 {
+		return nil
+	}
+
+	if len(out) == 2 && !out[1].IsNil() {
+		return out[1].Interface()
+	}
+
+	if out[0].Type() == errorType && out[0].IsNil() {
+		return nil
+	}
+
+	return out[0].Interface()
+}
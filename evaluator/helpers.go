@@ -1,6 +1,18 @@
 package evaluator
 
-import "strings"
+import (
+	"strconv"
+	"strings"
+
+	"github.com/govel-framework/lamb/internal"
+	"github.com/govel-framework/lamb/object"
+)
+
+// defaultLargeLoop is used when lamb.large_loop is not configured.
+const defaultLargeLoop = 10000
+
+// defaultMaxIncludeDepth is used when lamb.max_include_depth is not configured.
+const defaultMaxIncludeDepth = 32
 
 func lookForConfigKeys(m map[interface{}]interface{}, key string) (exists bool, value interface{}) {
 	split := strings.Split(key, ".")
@@ -29,3 +41,78 @@ func lookForConfigKeys(m map[interface{}]interface{}, key string) (exists bool,
 
 	return lookForConfigKeys(submap, strings.Join(split[1:], "."))
 }
+
+// maxIncludeDepth returns how many includes can be nested inside each other.
+func maxIncludeDepth() int {
+	return limit("GOVEL_LAMB_MAX_INCLUDE_DEPTH", defaultMaxIncludeDepth)
+}
+
+// maxLoopIterations returns how many iterations a for loop can do, 0 means
+// there is no limit.
+func maxLoopIterations() int {
+	return limit("GOVEL_LAMB_MAX_LOOP_ITERATIONS", 0)
+}
+
+// maxOutputSize returns how many bytes a template can render, 0 means there is
+// no limit.
+func maxOutputSize() int {
+	return limit("GOVEL_LAMB_MAX_OUTPUT_SIZE", 0)
+}
+
+// largeLoop returns from how many elements a for loop is reported as a
+// diagnostic.
+func largeLoop() int {
+	return limit("GOVEL_LAMB_LARGE_LOOP", defaultLargeLoop)
+}
+
+// limit returns the execution limit of the setting, or def if it is not set.
+func limit(name string, def int) int {
+	value, err := strconv.Atoi(internal.Setting(name))
+
+	if err != nil || value < 1 {
+		return def
+	}
+
+	return value
+}
+
+// renderLimits returns the execution limits of the render of env, they are
+// read from the settings when it starts instead of on every statement.
+func renderLimits(env *object.Environment) *object.Limits {
+	if env.Limits == nil {
+		env.Limits = &object.Limits{
+			MaxLoopIterations: maxLoopIterations(),
+			MaxOutputSize:     maxOutputSize(),
+			MaxIncludeDepth:   maxIncludeDepth(),
+			LargeLoop:         largeLoop(),
+		}
+	}
+
+	return env.Limits
+}
+
+// exceedsOutputSize reports whether the output has grown past the max output
+// size of the render.
+func exceedsOutputSize(env *object.Environment, size int) bool {
+	max := renderLimits(env).MaxOutputSize
+
+	return max != 0 && size > max
+}
+
+// boolLabel returns how a boolean is rendered, lamb.true_label and
+// lamb.false_label default to "true" and "false".
+func boolLabel(value bool) string {
+	if value {
+		if label, exists := internal.LookupSetting("GOVEL_LAMB_TRUE_LABEL"); exists {
+			return label
+		}
+
+		return "true"
+	}
+
+	if label, exists := internal.LookupSetting("GOVEL_LAMB_FALSE_LABEL"); exists {
+		return label
+	}
+
+	return "false"
+}
@@ -0,0 +1,35 @@
+package evaluator
+
+import (
+	"time"
+
+	"github.com/govel-framework/lamb/ast"
+	"github.com/govel-framework/lamb/internal"
+	"github.com/govel-framework/lamb/object"
+)
+
+// evalStatement evaluates the statement between the hooks of lamb.Hooks, if
+// there are any.
+func evalStatement(statement ast.Statement, env *object.Environment) interface{} {
+	hooks := internal.CurrentHooks()
+
+	if hooks == nil {
+		return Eval(statement, env)
+	}
+
+	if hooks.Before != nil {
+		hooks.Before(statement, env.FileName)
+	}
+
+	start := time.Now()
+
+	result := Eval(statement, env)
+
+	if hooks.After != nil {
+		err, _ := result.(error)
+
+		hooks.After(statement, env.FileName, time.Since(start), err)
+	}
+
+	return result
+}
@@ -0,0 +1,113 @@
+package evaluator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// jsonBuiltIn serializes a value into JSON. The output is safe to be placed
+// inside a <script> tag, since <, > and & are written as \u003c,
+// \u003e and \u0026 (so "</script>" can not close the tag).
+func jsonBuiltIn(args ...interface{}) interface{} {
+	if err := checkArgs("json", args, 1, 1); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(true)
+
+	if err := encoder.Encode(jsonMaps(args[0])); err != nil {
+		return builtInError("cannot encode %T in `json`: %s", args[0], err)
+	}
+
+	// Encode adds a trailing newline
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// jsonMaps replaces the maps created by the map literals (which have
+// interface{} keys and can not be encoded) with map[string]interface{}.
+func jsonMaps(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+
+		for key, element := range v {
+			m[fmt.Sprintf("%v", key)] = jsonMaps(element)
+		}
+
+		return m
+
+	case []interface{}:
+		s := make([]interface{}, len(v))
+
+		for i, element := range v {
+			s[i] = jsonMaps(element)
+		}
+
+		return s
+
+	default:
+		return v
+	}
+}
+
+// jsonDecodeBuiltIn parses a JSON string. Objects are returned as
+// map[string]interface{}, arrays as []interface{} and numbers as int when
+// they have no fraction, so they can be used in the template arithmetic.
+func jsonDecodeBuiltIn(args ...interface{}) interface{} {
+	if err := checkArgs("json_decode", args, 1, 1); err != nil {
+		return err
+	}
+
+	s, err := stringArg("json_decode", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(s))
+	decoder.UseNumber()
+
+	var value interface{}
+
+	if err := decoder.Decode(&value); err != nil {
+		return builtInError("invalid JSON in `json_decode`: %s", err)
+	}
+
+	return jsonValue(value)
+}
+
+// jsonValue replaces the json.Number values with int or float64.
+func jsonValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return int(n)
+		}
+
+		f, _ := v.Float64()
+
+		return f
+
+	case map[string]interface{}:
+		for key, element := range v {
+			v[key] = jsonValue(element)
+		}
+
+		return v
+
+	case []interface{}:
+		for i, element := range v {
+			v[i] = jsonValue(element)
+		}
+
+		return v
+
+	default:
+		return v
+	}
+}
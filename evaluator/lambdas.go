@@ -0,0 +1,115 @@
+package evaluator
+
+import (
+	"reflect"
+
+	"github.com/govel-framework/lamb/object"
+)
+
+// lambdaError is an error in the body of a lambda called by a builtin, it
+// already has the position where it happened.
+type lambdaError struct {
+	error
+}
+
+// callLambda calls the lambda with the argument in a new environment enclosed
+// by the one where the lambda was created.
+func callLambda(fn *object.Lambda, arg interface{}) interface{} {
+	env := object.NewEnclosedEnvironment(fn.Env)
+	defer env.Release()
+
+	env.Set(fn.Parameter, arg)
+
+	var result interface{}
+
+	if fn.Func != nil {
+		result = fn.Func(env)
+	} else {
+		result = Eval(fn.Body, env)
+	}
+
+	if err, isError := result.(error); isError {
+		return lambdaError{err}
+	}
+
+	return result
+}
+
+// lambdaArg returns the argument at index i as a lambda.
+func lambdaArg(name string, args []interface{}, i int) (*object.Lambda, error) {
+	fn, isLambda := args[i].(*object.Lambda)
+
+	if !isLambda {
+		return nil, builtInError("argument %d to `%s` not supported, got %T, want=lambda", i+1, name, args[i])
+	}
+
+	return fn, nil
+}
+
+// filterBuiltIn returns the elements of a slice for which the lambda is
+// truthy: filter(users, u => u.Active).
+func filterBuiltIn(args ...interface{}) interface{} {
+	if err := checkArgs("filter", args, 2, 2); err != nil {
+		return err
+	}
+
+	list, err := sliceArg("filter", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	fn, err := lambdaArg("filter", args, 1)
+
+	if err != nil {
+		return err
+	}
+
+	result := reflect.MakeSlice(copySlice(list).Type(), 0, list.Len())
+
+	for i := 0; i < list.Len(); i++ {
+		keep := callLambda(fn, list.Index(i).Interface())
+
+		if isError(keep) {
+			return keep
+		}
+
+		if isTruthy(keep) {
+			result = reflect.Append(result, list.Index(i))
+		}
+	}
+
+	return result.Interface()
+}
+
+// mapBuiltIn returns the results of calling the lambda with every element of
+// a slice: map(users, u => u.Name).
+func mapBuiltIn(args ...interface{}) interface{} {
+	if err := checkArgs("map", args, 2, 2); err != nil {
+		return err
+	}
+
+	list, err := sliceArg("map", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	fn, err := lambdaArg("map", args, 1)
+
+	if err != nil {
+		return err
+	}
+
+	result := make([]interface{}, list.Len())
+
+	for i := range result {
+		result[i] = callLambda(fn, list.Index(i).Interface())
+
+		if isError(result[i]) {
+			return result[i]
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,151 @@
+package evaluator
+
+import (
+	"strings"
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+// calendarNames are the names of the months and the days of the week in a
+// language, the days start on Sunday like time.Weekday.
+type calendarNames struct {
+	months      [12]string
+	shortMonths [12]string
+	days        [7]string
+	shortDays   [7]string
+}
+
+// calendars holds the names used by date() for each language, English is the
+// one of the time package.
+var calendars = map[string]*calendarNames{
+	"es": {
+		months:      [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		shortMonths: [12]string{"ene", "feb", "mar", "abr", "may", "jun", "jul", "ago", "sept", "oct", "nov", "dic"},
+		days:        [7]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"},
+		shortDays:   [7]string{"dom", "lun", "mar", "mié", "jue", "vie", "sáb"},
+	},
+	"fr": {
+		months:      [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		shortMonths: [12]string{"janv.", "févr.", "mars", "avr.", "mai", "juin", "juil.", "août", "sept.", "oct.", "nov.", "déc."},
+		days:        [7]string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+		shortDays:   [7]string{"dim.", "lun.", "mar.", "mer.", "jeu.", "ven.", "sam."},
+	},
+	"de": {
+		months:      [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		shortMonths: [12]string{"Jan.", "Feb.", "März", "Apr.", "Mai", "Juni", "Juli", "Aug.", "Sept.", "Okt.", "Nov.", "Dez."},
+		days:        [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+		shortDays:   [7]string{"So.", "Mo.", "Di.", "Mi.", "Do.", "Fr.", "Sa."},
+	},
+	"it": {
+		months:      [12]string{"gennaio", "febbraio", "marzo", "aprile", "maggio", "giugno", "luglio", "agosto", "settembre", "ottobre", "novembre", "dicembre"},
+		shortMonths: [12]string{"gen", "feb", "mar", "apr", "mag", "giu", "lug", "ago", "set", "ott", "nov", "dic"},
+		days:        [7]string{"domenica", "lunedì", "martedì", "mercoledì", "giovedì", "venerdì", "sabato"},
+		shortDays:   [7]string{"dom", "lun", "mar", "mer", "gio", "ven", "sab"},
+	},
+	"pt": {
+		months:      [12]string{"janeiro", "fevereiro", "março", "abril", "maio", "junho", "julho", "agosto", "setembro", "outubro", "novembro", "dezembro"},
+		shortMonths: [12]string{"jan.", "fev.", "mar.", "abr.", "mai.", "jun.", "jul.", "ago.", "set.", "out.", "nov.", "dez."},
+		days:        [7]string{"domingo", "segunda-feira", "terça-feira", "quarta-feira", "quinta-feira", "sexta-feira", "sábado"},
+		shortDays:   [7]string{"dom.", "seg.", "ter.", "qua.", "qui.", "sex.", "sáb."},
+	},
+	"nl": {
+		months:      [12]string{"januari", "februari", "maart", "april", "mei", "juni", "juli", "augustus", "september", "oktober", "november", "december"},
+		shortMonths: [12]string{"jan", "feb", "mrt", "apr", "mei", "jun", "jul", "aug", "sep", "okt", "nov", "dec"},
+		days:        [7]string{"zondag", "maandag", "dinsdag", "woensdag", "donderdag", "vrijdag", "zaterdag"},
+		shortDays:   [7]string{"zo", "ma", "di", "wo", "do", "vr", "za"},
+	},
+}
+
+// weekStarts lists the regions whose week does not start on Monday.
+var weekStarts = map[string]time.Weekday{
+	"AE": time.Saturday, "AF": time.Saturday, "BH": time.Saturday, "DJ": time.Saturday,
+	"DZ": time.Saturday, "EG": time.Saturday, "IQ": time.Saturday, "IR": time.Saturday,
+	"JO": time.Saturday, "KW": time.Saturday, "LY": time.Saturday, "OM": time.Saturday,
+	"QA": time.Saturday, "SD": time.Saturday, "SY": time.Saturday,
+
+	"AG": time.Sunday, "AS": time.Sunday, "BD": time.Sunday, "BR": time.Sunday,
+	"BS": time.Sunday, "BT": time.Sunday, "BW": time.Sunday, "BZ": time.Sunday,
+	"CA": time.Sunday, "CN": time.Sunday, "CO": time.Sunday, "DM": time.Sunday,
+	"DO": time.Sunday, "ET": time.Sunday, "GT": time.Sunday, "GU": time.Sunday,
+	"HK": time.Sunday, "HN": time.Sunday, "ID": time.Sunday, "IL": time.Sunday,
+	"IN": time.Sunday, "JM": time.Sunday, "JP": time.Sunday, "KE": time.Sunday,
+	"KH": time.Sunday, "KR": time.Sunday, "LA": time.Sunday, "MH": time.Sunday,
+	"MM": time.Sunday, "MO": time.Sunday, "MT": time.Sunday, "MX": time.Sunday,
+	"MZ": time.Sunday, "NI": time.Sunday, "NP": time.Sunday, "PA": time.Sunday,
+	"PE": time.Sunday, "PH": time.Sunday, "PK": time.Sunday, "PR": time.Sunday,
+	"PT": time.Sunday, "PY": time.Sunday, "SA": time.Sunday, "SG": time.Sunday,
+	"SV": time.Sunday, "TH": time.Sunday, "TT": time.Sunday, "TW": time.Sunday,
+	"UM": time.Sunday, "US": time.Sunday, "VE": time.Sunday, "VI": time.Sunday,
+	"WS": time.Sunday, "YE": time.Sunday, "ZA": time.Sunday, "ZW": time.Sunday,
+}
+
+// parseLocale parses the locale, lamb.locale is used when it is not valid.
+func parseLocale(locale string) language.Tag {
+	tag, err := language.Parse(locale)
+
+	if err != nil {
+		return defaultLocale()
+	}
+
+	return tag
+}
+
+// firstDayOfWeek returns the first day of the week in the region of the
+// locale ("en-US" starts on Sunday, "en-GB" on Monday).
+func firstDayOfWeek(tag language.Tag) time.Weekday {
+	region, _ := tag.Region()
+
+	if day, exists := weekStarts[region.String()]; exists {
+		return day
+	}
+
+	return time.Monday
+}
+
+// formatLocalized formats t with a layout of the time package, writing the
+// names of the months and the days in the language of the locale.
+func formatLocalized(t time.Time, layout string, tag language.Tag) string {
+	base, _ := tag.Base()
+	names, exists := calendars[base.String()]
+
+	if !exists {
+		return t.Format(layout)
+	}
+
+	// the longest first, so "January" is not read as "Jan"
+	elements := []struct {
+		layout string
+		name   string
+	}{
+		{"January", names.months[t.Month()-1]},
+		{"Monday", names.days[t.Weekday()]},
+		{"Jan", names.shortMonths[t.Month()-1]},
+		{"Mon", names.shortDays[t.Weekday()]},
+	}
+
+	var out strings.Builder
+
+	for len(layout) > 0 {
+		index, length, name := -1, 0, ""
+
+		for _, element := range elements {
+			if i := strings.Index(layout, element.layout); i != -1 && (index == -1 || i < index) {
+				index, length, name = i, len(element.layout), element.name
+			}
+		}
+
+		if index == -1 {
+			out.WriteString(t.Format(layout))
+
+			break
+		}
+
+		out.WriteString(t.Format(layout[:index]))
+		out.WriteString(name)
+
+		layout = layout[index+length:]
+	}
+
+	return out.String()
+}
@@ -0,0 +1,143 @@
+package evaluator
+
+import (
+	"math"
+)
+
+// numberArg returns the argument at index i as a float64.
+func numberArg(name string, args []interface{}, i int) (float64, error) {
+	number, isNumber := toFloat(args[i])
+
+	if !isNumber {
+		return 0, builtInError("argument %d to `%s` not supported, got %T, want=number", i+1, name, args[i])
+	}
+
+	return number, nil
+}
+
+// integer returns the float as an int when it has no decimals and fits in an
+// int, so ceil(2.1) outputs 3 instead of 3.0.
+func integer(f float64) interface{} {
+	if f == math.Trunc(f) && f >= math.MinInt && f <= math.MaxInt {
+		return int(f)
+	}
+
+	return f
+}
+
+func absBuiltIn(args ...interface{}) interface{} {
+	if err := checkArgs("abs", args, 1, 1); err != nil {
+		return err
+	}
+
+	if n, isInt := isNumber(args[0]); isInt {
+		if n == math.MinInt {
+			return builtInError("the absolute value of %d overflows an int", n)
+		}
+
+		if n < 0 {
+			return -n
+		}
+
+		return n
+	}
+
+	n, err := numberArg("abs", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	return math.Abs(n)
+}
+
+// roundingBuiltIn creates a builtin that rounds a number to an integer.
+func roundingBuiltIn(name string, round func(float64) float64) func(args ...interface{}) interface{} {
+	return func(args ...interface{}) interface{} {
+		if err := checkArgs(name, args, 1, 1); err != nil {
+			return err
+		}
+
+		n, err := numberArg(name, args, 0)
+
+		if err != nil {
+			return err
+		}
+
+		return integer(round(n))
+	}
+}
+
+// roundBuiltIn rounds a number half away from zero: round(2.5) is 3 and
+// round(3.14159, 2) is 3.14. Without digits (optional) the result is an int.
+func roundBuiltIn(args ...interface{}) interface{} {
+	if err := checkArgs("round", args, 1, 2); err != nil {
+		return err
+	}
+
+	n, err := numberArg("round", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 1 {
+		return integer(math.Round(n))
+	}
+
+	digits, err := intArg("round", args, 1)
+
+	if err != nil {
+		return err
+	}
+
+	scale := math.Pow(10, float64(digits))
+
+	return math.Round(n*scale) / scale
+}
+
+func sqrtBuiltIn(args ...interface{}) interface{} {
+	if err := checkArgs("sqrt", args, 1, 1); err != nil {
+		return err
+	}
+
+	n, err := numberArg("sqrt", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	if n < 0 {
+		return builtInError("cannot get the square root of the negative number %v", args[0])
+	}
+
+	return math.Sqrt(n)
+}
+
+// powBuiltIn raises a number to a power, like the ** operator.
+func powBuiltIn(args ...interface{}) interface{} {
+	if err := checkArgs("pow", args, 2, 2); err != nil {
+		return err
+	}
+
+	base, isIntBase := isNumber(args[0])
+	exp, isIntExp := isNumber(args[1])
+
+	if isIntBase && isIntExp {
+		return power(base, exp)
+	}
+
+	floatBase, err := numberArg("pow", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	floatExp, err := numberArg("pow", args, 1)
+
+	if err != nil {
+		return err
+	}
+
+	return math.Pow(floatBase, floatExp)
+}
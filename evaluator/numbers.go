@@ -0,0 +1,51 @@
+package evaluator
+
+import (
+	"github.com/govel-framework/lamb/object"
+
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// numberFormatBuiltIn formats a number with the separators of the locale of
+// the render: number_format(1234.567, 2) gives "1,234.57" in English and
+// "1.234,57" in German. The decimals default to 0.
+func numberFormatBuiltIn(env *object.Environment, args ...interface{}) interface{} {
+	if err := checkArgs("number_format", args, 1, 2); err != nil {
+		return err
+	}
+
+	value, isNumber := toFloat(args[0])
+
+	if !isNumber {
+		return builtInError("argument 1 to `number_format` not supported, got %T, want=number", args[0])
+	}
+
+	decimals := 0
+
+	if len(args) == 2 {
+		var err error
+
+		if decimals, err = intArg("number_format", args, 1); err != nil {
+			return err
+		}
+
+		if decimals < 0 {
+			return builtInError("the decimals in `number_format` can not be negative, got %d", decimals)
+		}
+	}
+
+	printer := message.NewPrinter(parseLocale(renderLocale(env)))
+
+	return printer.Sprint(number.Decimal(value, number.MinFractionDigits(decimals), number.MaxFractionDigits(decimals)))
+}
+
+// firstDayOfWeekBuiltIn returns the first day of the week in the locale of the
+// render, 0 is Sunday and 1 is Monday, for the calendars.
+func firstDayOfWeekBuiltIn(env *object.Environment, args ...interface{}) interface{} {
+	if err := checkArgs("first_day_of_week", args, 0, 0); err != nil {
+		return err
+	}
+
+	return int(firstDayOfWeek(parseLocale(renderLocale(env))))
+}
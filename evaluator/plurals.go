@@ -0,0 +1,160 @@
+package evaluator
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/govel-framework/lamb/internal"
+	"github.com/govel-framework/lamb/object"
+
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+)
+
+// pluralCategories are the names of the CLDR plural categories, the suffixes
+// of the keys of the plural messages.
+var pluralCategories = map[plural.Form]string{
+	plural.Other: "other",
+	plural.Zero:  "zero",
+	plural.One:   "one",
+	plural.Two:   "two",
+	plural.Few:   "few",
+	plural.Many:  "many",
+}
+
+// pluralForm returns the CLDR plural form of the number in the locale, e.g.
+// 1 is "one" and 2 is "other" in English, but 2 is "few" in Polish.
+func pluralForm(locale string, number float64) plural.Form {
+	tag, err := language.Parse(locale)
+
+	if err != nil {
+		tag = defaultLocale()
+	}
+
+	// the operands of the CLDR rules: the integer digits, and the visible
+	// fraction digits with and without trailing zeros
+	digits := strconv.FormatFloat(math.Abs(number), 'f', -1, 64)
+	integer, fraction, _ := strings.Cut(digits, ".")
+
+	i, _ := strconv.Atoi(integer)
+	f, _ := strconv.Atoi(fraction)
+
+	trimmed := strings.TrimRight(fraction, "0")
+	t, _ := strconv.Atoi(trimmed)
+
+	return plural.Cardinal.MatchPlural(tag, i%10000000, len(fraction), len(trimmed), f, t)
+}
+
+// countArg returns the argument at index i as a number.
+func countArg(name string, args []interface{}, i int) (float64, error) {
+	number, isNumber := toFloat(args[i])
+
+	if !isNumber {
+		return 0, builtInError("argument %d to `%s` not supported, got %T, want=number", i+1, name, args[i])
+	}
+
+	return number, nil
+}
+
+// pluralParams returns the params of a plural message, including the count
+// under name.
+func pluralParams(fn string, args []interface{}, name string) (reflect.Value, error) {
+	params := map[interface{}]interface{}{name: args[1]}
+
+	if len(args) == 3 {
+		extra, err := mapArg(fn, args, 2)
+
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		for _, key := range extra.MapKeys() {
+			params[key.Interface()] = extra.MapIndex(key).Interface()
+		}
+	}
+
+	return reflect.ValueOf(params), nil
+}
+
+// tnBuiltIn translates a key with a count, using the plural rules of the
+// locale of the render: tn("cart.items", count) looks up "cart.items.one",
+// "cart.items.few"... and then "cart.items.other". The count is the :count
+// param.
+func tnBuiltIn(env *object.Environment, args ...interface{}) interface{} {
+	if err := checkArgs("tn", args, 2, 3); err != nil {
+		return err
+	}
+
+	key, err := stringArg("tn", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	count, err := countArg("tn", args, 1)
+
+	if err != nil {
+		return err
+	}
+
+	params, err := pluralParams("tn", args, "count")
+
+	if err != nil {
+		return err
+	}
+
+	locale := renderLocale(env)
+	fallback := defaultLocale().String()
+	category := pluralCategories[pluralForm(locale, count)]
+
+	for _, candidate := range []string{key + "." + category, key + ".other", key} {
+		if message, exists := internal.Translate(locale, fallback, candidate); exists {
+			return interpolate(message, params)
+		}
+	}
+
+	return key
+}
+
+// choiceBuiltIn picks the singular or the plural of "singular|plural" by the
+// plural rules of the locale of the render: choice("one apple|:n apples", n).
+// The number is the :n param.
+func choiceBuiltIn(env *object.Environment, args ...interface{}) interface{} {
+	if err := checkArgs("choice", args, 2, 3); err != nil {
+		return err
+	}
+
+	message, err := stringArg("choice", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	count, err := countArg("choice", args, 1)
+
+	if err != nil {
+		return err
+	}
+
+	params, err := pluralParams("choice", args, "n")
+
+	if err != nil {
+		return err
+	}
+
+	forms := strings.Split(message, "|")
+
+	if len(forms) > 2 {
+		return builtInError("`choice` takes a singular and a plural, got %d forms, use tn for more plural forms", len(forms))
+	}
+
+	form := forms[len(forms)-1]
+
+	if pluralForm(renderLocale(env), count) == plural.One {
+		form = forms[0]
+	}
+
+	return interpolate(form, params)
+}
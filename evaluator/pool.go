@@ -0,0 +1,31 @@
+package evaluator
+
+import (
+	"bytes"
+	"sync"
+)
+
+// buffers are the output buffers of the blocks and templates already
+// rendered, their output is copied to a string before they are reused.
+var buffers = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// maxPooledBuffer is the capacity above which a buffer is not reused, so one
+// big page does not keep its memory alive.
+const maxPooledBuffer = 64 << 10
+
+func getBuffer() *bytes.Buffer {
+	return buffers.Get().(*bytes.Buffer)
+}
+
+func putBuffer(b *bytes.Buffer) {
+	if b.Cap() > maxPooledBuffer {
+		return
+	}
+
+	b.Reset()
+	buffers.Put(b)
+}
@@ -0,0 +1,146 @@
+package evaluator
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// patterns caches the compiled regular expressions of `matches` and
+// request().is().
+var patterns sync.Map
+
+// equals compares two values of a template, numbers are equal if they have
+// the same value whatever their type is.
+func equals(a, b interface{}) bool {
+	switch a := a.(type) {
+	case string:
+		if b, isString := b.(string); isString {
+			return a == b
+		}
+
+	case int:
+		if b, isInt := b.(int); isInt {
+			return a == b
+		}
+
+	case bool:
+		if b, isBool := b.(bool); isBool {
+			return a == b
+		}
+	}
+
+	aNumber, isANumber := isNumber(a)
+	bNumber, isBNumber := isNumber(b)
+
+	if isANumber && isBNumber {
+		return aNumber == bNumber
+	}
+
+	aFloat, isAFloat := toFloat(a)
+	bFloat, isBFloat := toFloat(b)
+
+	if isAFloat && isBFloat {
+		return aFloat == bFloat
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+func containsBuiltIn(args ...interface{}) interface{} {
+	if err := checkArgs("contains", args, 2, 2); err != nil {
+		return err
+	}
+
+	valueOf := reflect.ValueOf(args[0])
+
+	switch valueOf.Kind() {
+
+	case reflect.String:
+		substr, err := stringArg("contains", args, 1)
+
+		if err != nil {
+			return err
+		}
+
+		return strings.Contains(valueOf.String(), substr)
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < valueOf.Len(); i++ {
+			if equals(valueOf.Index(i).Interface(), args[1]) {
+				return true
+			}
+		}
+
+		return false
+
+	case reflect.Map:
+		for _, key := range valueOf.MapKeys() {
+			if equals(key.Interface(), args[1]) {
+				return true
+			}
+		}
+
+		return false
+
+	default:
+		return builtInError("argument 1 to `contains` not supported, got %T, want=string, list or map", args[0])
+	}
+}
+
+// affixBuiltIn creates a builtin that checks a string against a prefix or a
+// suffix.
+func affixBuiltIn(name string, has func(string, string) bool) func(args ...interface{}) interface{} {
+	return func(args ...interface{}) interface{} {
+		if err := checkArgs(name, args, 2, 2); err != nil {
+			return err
+		}
+
+		s, err := stringArg(name, args, 0)
+
+		if err != nil {
+			return err
+		}
+
+		affix, err := stringArg(name, args, 1)
+
+		if err != nil {
+			return err
+		}
+
+		return has(s, affix)
+	}
+}
+
+func matchesBuiltIn(args ...interface{}) interface{} {
+	if err := checkArgs("matches", args, 2, 2); err != nil {
+		return err
+	}
+
+	s, err := stringArg("matches", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	pattern, err := stringArg("matches", args, 1)
+
+	if err != nil {
+		return err
+	}
+
+	re, cached := patterns.Load(pattern)
+
+	if !cached {
+		compiled, err := regexp.Compile(pattern)
+
+		if err != nil {
+			return builtInError("invalid pattern in `matches`: %s", err)
+		}
+
+		re, _ = patterns.LoadOrStore(pattern, compiled)
+	}
+
+	return re.(*regexp.Regexp).MatchString(s)
+}
@@ -0,0 +1,116 @@
+package evaluator
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/govel-framework/lamb/ast"
+	"github.com/govel-framework/lamb/internal"
+	"github.com/govel-framework/lamb/object"
+)
+
+// presenceChecks are the functions that receive their argument unevaluated,
+// so they can test values that do not exist without an error.
+var presenceChecks = map[string]func(value interface{}) bool{
+	"isset": func(value interface{}) bool { return !isNil(value) },
+	"empty": isEmpty,
+	"blank": isBlank,
+}
+
+// IsPresenceCheck reports whether name is one of the checks that receive their
+// argument unevaluated, e.g. isset.
+func IsPresenceCheck(name string) bool {
+	_, isCheck := presenceChecks[name]
+
+	return isCheck
+}
+
+// presenceCheck returns the check called by node, if it is one and its name
+// is not used by a var.
+func presenceCheck(node *ast.CallExpression, env *object.Environment) (func(interface{}) bool, bool) {
+	identifier, isIdentifier := node.Function.(*ast.Identifier)
+
+	if !isIdentifier {
+		return nil, false
+	}
+
+	check, exists := presenceChecks[identifier.Value]
+
+	if !exists {
+		return nil, false
+	}
+
+	if _, isVar := env.Get(identifier.Value); isVar {
+		return nil, false
+	}
+
+	if _, isShared := internal.GetShared(identifier.Value); isShared {
+		return nil, false
+	}
+
+	return check, true
+}
+
+// evalPresenceCheck evaluates isset(x), empty(x) and blank(x). A value that
+// can not be evaluated (e.g. an unknown var or a missing field) is not set,
+// so it is empty and blank.
+func evalPresenceCheck(node *ast.CallExpression, check func(interface{}) bool, env *object.Environment) interface{} {
+	if len(node.Arguments) != 1 {
+		return newError(node.Token, "wrong number of arguments in %s. got=%d, want=1", node.Function.String(), len(node.Arguments))
+	}
+
+	value := Eval(node.Arguments[0], env)
+
+	if isError(value) {
+		value = nil
+	}
+
+	return check(value)
+}
+
+// isEmpty reports whether the value is nil, false, "", 0 or an empty slice or
+// map.
+func isEmpty(value interface{}) bool {
+	if isNil(value) {
+		return true
+	}
+
+	valueOf := reflect.ValueOf(value)
+
+	switch valueOf.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return valueOf.Len() == 0
+
+	case reflect.Bool:
+		return !valueOf.Bool()
+
+	default:
+		number, isNumber := toFloat(value)
+
+		return isNumber && number == 0
+	}
+}
+
+// isBlank reports whether the value is nil, false, a string with only
+// whitespace or an empty slice or map. Unlike empty, 0 is not blank.
+func isBlank(value interface{}) bool {
+	if isNil(value) {
+		return true
+	}
+
+	valueOf := reflect.ValueOf(value)
+
+	switch valueOf.Kind() {
+	case reflect.String:
+		return strings.TrimSpace(valueOf.String()) == ""
+
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return valueOf.Len() == 0
+
+	case reflect.Bool:
+		return !valueOf.Bool()
+
+	default:
+		return false
+	}
+}
@@ -0,0 +1,237 @@
+package evaluator
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/govel-framework/lamb/ast"
+	"github.com/govel-framework/lamb/internal"
+	"github.com/govel-framework/lamb/object"
+	"github.com/govel-framework/lamb/token"
+)
+
+// basicPropTypes are the types of the props that are not registered with
+// lamb.PropType.
+var basicPropTypes = map[string]reflect.Type{
+	"string": reflect.TypeOf(""),
+	"int":    reflect.TypeOf(0),
+	"float":  reflect.TypeOf(0.0),
+	"bool":   reflect.TypeOf(false),
+	"any":    reflect.TypeOf((*interface{})(nil)).Elem(),
+}
+
+// PropType returns the Go type of the type of a prop, e.g. []User. The names
+// are string, int, float, bool, any and the types registered with
+// lamb.PropType.
+func PropType(typ string) (reflect.Type, error) {
+	switch {
+	case strings.HasPrefix(typ, "[]"):
+		elem, err := PropType(typ[2:])
+
+		if err != nil {
+			return nil, err
+		}
+
+		return reflect.SliceOf(elem), nil
+
+	case strings.HasPrefix(typ, "*"):
+		elem, err := PropType(typ[1:])
+
+		if err != nil {
+			return nil, err
+		}
+
+		return reflect.PtrTo(elem), nil
+
+	case strings.HasPrefix(typ, "map["):
+		return mapPropType(typ)
+	}
+
+	if t, exists := basicPropTypes[typ]; exists {
+		return t, nil
+	}
+
+	if t, exists := internal.PropType(typ); exists {
+		return t, nil
+	}
+
+	return nil, fmt.Errorf("unknown type %s", typ)
+}
+
+// mapPropType returns the Go type of map[K]V.
+func mapPropType(typ string) (reflect.Type, error) {
+	depth := 0
+
+	for i := len("map"); i < len(typ); i++ {
+		switch typ[i] {
+		case '[':
+			depth++
+
+		case ']':
+			if depth--; depth != 0 {
+				continue
+			}
+
+			key, err := PropType(typ[len("map["):i])
+
+			if err != nil {
+				return nil, err
+			}
+
+			if !key.Comparable() {
+				return nil, fmt.Errorf("invalid map key type %s", typ[len("map["):i])
+			}
+
+			value, err := PropType(typ[i+1:])
+
+			if err != nil {
+				return nil, err
+			}
+
+			return reflect.MapOf(key, value), nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown type %s", typ)
+}
+
+// FieldType returns the type of the field name of a value of type t, read
+// with a dot expression: a field of a struct or a value of a map. It returns
+// false if t has no such field.
+func FieldType(t reflect.Type, name string) (reflect.Type, bool) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Map:
+		return t.Elem(), true
+
+	case reflect.Struct:
+		field, exists := structField(t, name)
+
+		return field.Type, exists
+
+	default:
+		return nil, false
+	}
+}
+
+// matchesPropType reports whether the value can be a prop of type t. The
+// numbers can be of any size, a pointer to a struct is a struct, the elements
+// of the lists and maps must have the types of the elements of t, and nil is
+// a pointer, a list, a map or any.
+func matchesPropType(t reflect.Type, value reflect.Value) bool {
+	// the elements of []interface{} and map[string]interface{}
+	if value.Kind() == reflect.Interface {
+		value = value.Elem()
+	}
+
+	if !value.IsValid() {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+			return true
+
+		default:
+			return false
+		}
+	}
+
+	if value.Type().AssignableTo(t) {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.Int:
+		return value.Kind() >= reflect.Int && value.Kind() <= reflect.Uint64
+
+	case reflect.Float64:
+		return isNumberKind(value.Kind())
+
+	case reflect.Struct:
+		return value.Kind() == reflect.Ptr && value.Type().Elem() == t
+
+	case reflect.Slice:
+		if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+			return false
+		}
+
+		for i := 0; i < value.Len(); i++ {
+			if !matchesPropType(t.Elem(), value.Index(i)) {
+				return false
+			}
+		}
+
+		return true
+
+	case reflect.Map:
+		if value.Kind() != reflect.Map {
+			return false
+		}
+
+		iter := value.MapRange()
+
+		for iter.Next() {
+			if !matchesPropType(t.Key(), iter.Key()) || !matchesPropType(t.Elem(), iter.Value()) {
+				return false
+			}
+		}
+
+		return true
+
+	default:
+		return false
+	}
+}
+
+// evalPropsStatement checks the props declared by the template.
+func evalPropsStatement(node *ast.PropsStatement, env *object.Environment) interface{} {
+	for _, prop := range node.Props {
+		var defaultValue func() interface{}
+
+		if prop.Default != nil {
+			defaultValue = func() interface{} { return Eval(prop.Default, env) }
+		}
+
+		if err := checkProp(prop.Name, prop.Type, prop.Token, defaultValue, env); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkProp checks that the prop is set (to defaultValue if it is not and it
+// has one) and has its type. The types that are not known are not checked.
+func checkProp(name, typ string, t token.Token, defaultValue func() interface{}, env *object.Environment) error {
+	value, exists := env.Get(name)
+
+	if !exists {
+		value, exists = internal.GetShared(name)
+	}
+
+	if !exists {
+		if defaultValue == nil {
+			return newError(t, "missing required prop %s", name)
+		}
+
+		if value = defaultValue(); isError(value) {
+			return value.(error)
+		}
+
+		env.Set(name, value)
+	}
+
+	propType, err := PropType(typ)
+
+	if err != nil {
+		return nil
+	}
+
+	if !matchesPropType(propType, reflect.ValueOf(value)) {
+		return newError(t, "prop %s must be a %s, got=%T", name, typ, value)
+	}
+
+	return nil
+}
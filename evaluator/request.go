@@ -0,0 +1,134 @@
+package evaluator
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/govel-framework/lamb/object"
+)
+
+// pathPattern converts a pattern of request().is() into a regular expression,
+// "*" matches any text (slashes included).
+func pathPattern(pattern string) (*regexp.Regexp, error) {
+	expression := "^" + strings.ReplaceAll(regexp.QuoteMeta(strings.Trim(pattern, "/")), `\*`, ".*") + "$"
+
+	if cached, exists := patterns.Load(expression); exists {
+		return cached.(*regexp.Regexp), nil
+	}
+
+	re, err := regexp.Compile(expression)
+
+	if err != nil {
+		return nil, err
+	}
+
+	patterns.Store(expression, re)
+
+	return re, nil
+}
+
+// requestBuiltIn returns the accessors of the current request:
+//
+//	<a class="{? if request().is("admin/*") ?}active{? endif ?}">
+//
+// path() returns the path without the leading slash, is(patterns...) reports
+// whether the path matches any of the patterns, query(name, default) returns
+// a query param and header(name) a header.
+func requestBuiltIn(env *object.Environment, args ...interface{}) interface{} {
+	if err := checkArgs("request", args, 0, 0); err != nil {
+		return err
+	}
+
+	if _, exists := requestContext(env); !exists {
+		return builtInError("`request` needs the request context, ctx is not set")
+	}
+
+	path := strings.Trim(contextString(env, "Path"), "/")
+
+	return map[string]interface{}{
+		"path": &object.Builtin{Fn: func(args ...interface{}) interface{} {
+			if err := checkArgs("path", args, 0, 0); err != nil {
+				return err
+			}
+
+			return path
+		}},
+		"method": &object.Builtin{Fn: func(args ...interface{}) interface{} {
+			if err := checkArgs("method", args, 0, 0); err != nil {
+				return err
+			}
+
+			return contextString(env, "Method")
+		}},
+		"is": &object.Builtin{Fn: func(args ...interface{}) interface{} {
+			if len(args) == 0 {
+				return builtInError("wrong number of arguments in is. got=0, want=at least 1")
+			}
+
+			for i := range args {
+				pattern, err := stringArg("is", args, i)
+
+				if err != nil {
+					return err
+				}
+
+				re, err := pathPattern(pattern)
+
+				if err != nil {
+					return builtInError("invalid pattern in `is`: %s", err)
+				}
+
+				if re.MatchString(path) {
+					return true
+				}
+			}
+
+			return false
+		}},
+		"query": &object.Builtin{Fn: func(args ...interface{}) interface{} {
+			if err := checkArgs("query", args, 1, 2); err != nil {
+				return err
+			}
+
+			name, err := stringArg("query", args, 0)
+
+			if err != nil {
+				return err
+			}
+
+			if query, exists := contextField(env, "Query"); exists {
+				if params, isMap := query.Interface().(map[string]string); isMap {
+					if value, exists := params[name]; exists {
+						return value
+					}
+				}
+			}
+
+			if len(args) == 2 {
+				return args[1]
+			}
+
+			return nil
+		}},
+		"header": &object.Builtin{Fn: func(args ...interface{}) interface{} {
+			if err := checkArgs("header", args, 1, 1); err != nil {
+				return err
+			}
+
+			name, err := stringArg("header", args, 0)
+
+			if err != nil {
+				return err
+			}
+
+			if header, exists := contextField(env, "Header"); exists {
+				if headers, isHeader := header.Interface().(http.Header); isHeader {
+					return headers.Get(name)
+				}
+			}
+
+			return ""
+		}},
+	}
+}
@@ -0,0 +1,440 @@
+package evaluator
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/govel-framework/lamb/ast"
+	"github.com/govel-framework/lamb/internal"
+	"github.com/govel-framework/lamb/object"
+	"github.com/govel-framework/lamb/token"
+)
+
+// Runtime is the state of the render of a template generated to Go by lamb
+// generate. The generated code calls its methods where the evaluator would
+// evaluate a node, so both render a template the same way. The line and col
+// of the methods are the position of the node in the template, for the
+// errors.
+type Runtime struct {
+	env       *object.Environment
+	out       *bytes.Buffer
+	maxOutput int
+}
+
+// Generated returns the render of a template generated to Go, its output is
+// the one of its layout if it extends one, like for a parsed template.
+func Generated(fn func(r *Runtime) error) internal.GeneratedFunc {
+	return func(env *object.Environment) interface{} {
+		output := getBuffer()
+		defer putBuffer(output)
+
+		r := &Runtime{env: env, out: output, maxOutput: maxOutputSize()}
+
+		if err := fn(r); err != nil {
+			return fmt.Errorf("%s: %w", env.FileName, err)
+		}
+
+		return renderLayout(output.String(), env)
+	}
+}
+
+func position(line, col int) token.Token {
+	return token.Token{Line: line, Col: col}
+}
+
+// valueOf splits the result of the evaluator into a value and an error.
+func valueOf(result interface{}) (interface{}, error) {
+	if err, isError := result.(error); isError {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// HTML writes an HTML fragment of the template.
+func (r *Runtime) HTML(html string) error {
+	r.out.WriteString(html)
+
+	return r.checkOutput()
+}
+
+// Write writes the value of an expression statement, escaped in the output
+// mode of the template.
+func (r *Runtime) Write(value interface{}) error {
+	if value != nil {
+		writeEscaped(r.out, value, r.env)
+	}
+
+	return r.checkOutput()
+}
+
+// writeOutput writes the output of a template or a section, it is not
+// escaped.
+func (r *Runtime) writeOutput(output interface{}) error {
+	if output != nil {
+		writeValue(r.out, output)
+	}
+
+	return r.checkOutput()
+}
+
+// Autoescape sets the output mode of the template, declared by an autoescape
+// statement.
+func (r *Runtime) Autoescape(mode string) {
+	r.env.Mode = mode
+}
+
+func (r *Runtime) checkOutput() error {
+	if r.maxOutput != 0 && r.out.Len() > r.maxOutput {
+		return outputSizeError(r.maxOutput)
+	}
+
+	return nil
+}
+
+// Var returns the var with the given name, it is looked up like an
+// identifier: the vars, the shared vars and the builtins.
+func (r *Runtime) Var(name string, line, col int) (interface{}, error) {
+	return valueOf(evalIdentifier(&ast.Identifier{Token: position(line, col), Value: name}, r.env))
+}
+
+// Set assigns the value to the var with the given name.
+func (r *Runtime) Set(name string, value interface{}) {
+	r.env.Assign(name, value)
+}
+
+// Prop checks the prop declared with the given name and type, value returns
+// its default and is nil if the prop is required.
+func (r *Runtime) Prop(name, typ string, line, col int, value func() (interface{}, error)) error {
+	var defaultValue func() interface{}
+
+	if value != nil {
+		defaultValue = func() interface{} {
+			v, err := value()
+
+			if err != nil {
+				return err
+			}
+
+			return v
+		}
+	}
+
+	return checkProp(name, typ, position(line, col), defaultValue, r.env)
+}
+
+// Truthy reports whether the value is truthy, e.g. for the condition of an if.
+func (r *Runtime) Truthy(value interface{}) bool {
+	return isTruthy(value)
+}
+
+// Prefix applies the prefix operator to the value.
+func (r *Runtime) Prefix(operator string, right interface{}, line, col int) (interface{}, error) {
+	return valueOf(evalPrefixExpression(operator, right, position(line, col)))
+}
+
+// Infix applies the infix operator to the values.
+func (r *Runtime) Infix(operator string, left, right interface{}, line, col int) (interface{}, error) {
+	return valueOf(evalInfixExpression(operator, left, right, position(line, col)))
+}
+
+// Index returns the element of left at index.
+func (r *Runtime) Index(left, index interface{}, line, col int) (interface{}, error) {
+	return valueOf(evalIndexExpression(left, index, position(line, col)))
+}
+
+// Dot returns the field or the key of left, source is the code of left.
+func (r *Runtime) Dot(left interface{}, field, source string, line, col int) (interface{}, error) {
+	node := &ast.DotExpression{
+		Token: position(line, col),
+		Left:  &ast.Identifier{Value: source},
+		Right: ast.Identifier{Value: field},
+	}
+
+	return valueOf(evalDot(node, left, r.env))
+}
+
+// Map returns the map of the keys and values of pairs, and its keys in source
+// order.
+func (r *Runtime) Map(line, col int, pairs ...interface{}) (map[interface{}]interface{}, []interface{}, error) {
+	m := make(map[interface{}]interface{}, len(pairs)/2)
+	keys := make([]interface{}, 0, len(pairs)/2)
+
+	for i := 0; i < len(pairs); i += 2 {
+		if !hashable(pairs[i]) {
+			return nil, nil, newError(position(line, col), "unusable as map key: %T", pairs[i])
+		}
+
+		if _, exists := m[pairs[i]]; !exists {
+			keys = append(keys, pairs[i])
+		}
+
+		m[pairs[i]] = pairs[i+1]
+	}
+
+	return m, keys, nil
+}
+
+// Call calls the function with the arguments, source is the code of the
+// function.
+func (r *Runtime) Call(function interface{}, args []interface{}, source string, line, col int) (interface{}, error) {
+	node := &ast.CallExpression{Token: position(line, col), Function: &ast.Identifier{Value: source}}
+
+	return valueOf(callFunction(node, function, args, r.env))
+}
+
+// Check calls isset, empty or blank (the check with the given name), its
+// argument is evaluated by arg and a value that can not be evaluated is not
+// set. A var with the name of the check is called instead.
+func (r *Runtime) Check(name string, line, col int, arg func() (interface{}, error)) (interface{}, error) {
+	node := &ast.CallExpression{Token: position(line, col), Function: &ast.Identifier{Value: name}}
+
+	if check, isCheck := presenceCheck(node, r.env); isCheck {
+		value, err := arg()
+
+		if err != nil {
+			value = nil
+		}
+
+		return check(value), nil
+	}
+
+	function, err := r.Var(name, line, col)
+
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := arg()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Call(function, []interface{}{value}, name, line, col)
+}
+
+// Lambda returns a lambda with the given parameter, its body is run with the
+// runtime of the scope of the call.
+func (r *Runtime) Lambda(parameter string, body func(r *Runtime) (interface{}, error)) interface{} {
+	// the scopes of the lambda can not be reused while it exists
+	r.env.Capture()
+
+	return &object.Lambda{
+		Parameter: parameter,
+		Env:       r.env,
+		Func: func(env *object.Environment) interface{} {
+			value, err := body(&Runtime{env: env, maxOutput: r.maxOutput})
+
+			if err != nil {
+				return err
+			}
+
+			return value
+		},
+	}
+}
+
+// For runs body for every element of in, with the key and the value (key is
+// empty if the loop has none) and the loop var set in the scope of the loop.
+// The maps are iterated in the order of keys, if it is not nil.
+func (r *Runtime) For(in interface{}, keys []interface{}, key, value string, line, col int, body func() error) error {
+	node := &ast.ForExpression{Token: position(line, col), Key: key, Value: value}
+
+	var ordered []reflect.Value
+
+	if keys != nil {
+		ordered = make([]reflect.Value, len(keys))
+
+		for i, k := range keys {
+			ordered[i] = reflect.ValueOf(k)
+		}
+	}
+
+	outer := r.env
+	scope := object.NewEnclosedEnvironment(outer)
+
+	r.env = scope
+
+	defer func() {
+		r.env = outer
+
+		scope.Release()
+	}()
+
+	res := forEach(node, in, ordered, scope, func(index int, k, v interface{}, length int) interface{} {
+		scope.Set(value, v)
+
+		if key != "" {
+			scope.Set(key, k)
+		}
+
+		scope.Set("loop", loopMetadata(index, length))
+
+		if err := body(); err != nil {
+			return err
+		}
+
+		if r.checkOutput() != nil {
+			return newError(node.Token, "%w", outputSizeError(r.maxOutput))
+		}
+
+		return nil
+	})
+
+	if res != nil {
+		return res.(error)
+	}
+
+	return nil
+}
+
+// Include writes the output of the template file, rendered with the vars of
+// the map or the struct vars if hasVars is set.
+func (r *Runtime) Include(file string, hasVars bool, vars interface{}, line, col int) error {
+	output, err := valueOf(includeTemplate(position(line, col), file, hasVars, vars, r.env))
+
+	if err != nil {
+		return err
+	}
+
+	return r.writeOutput(output)
+}
+
+// Extends sets the layout of the template.
+func (r *Runtime) Extends(from string, line, col int) error {
+	_, err := valueOf(evalExtendsStatement(&ast.ExtendsStatement{Token: position(line, col), From: from}, r.env))
+
+	return err
+}
+
+// Section saves the output of body as the section with the given name, for
+// the layout.
+func (r *Runtime) Section(name string, line, col int, body func() error) error {
+	t := position(line, col)
+
+	if !r.env.InExtends {
+		return newError(t, "section statement is only allowed in extends")
+	}
+
+	if r.env.IsExtends {
+		return newError(t, "section statement is only allowed with extends")
+	}
+
+	if r.env.InSection {
+		return newError(t, "section statement is not allowed in a section")
+	}
+
+	content, err := r.capture(body)
+
+	if err != nil {
+		return err
+	}
+
+	r.env.ExtendsFrom.Sections[name] = object.SectionContent{Content: content, Name: name, Token: t}
+	r.env.Fragment.Record(name, content)
+
+	return nil
+}
+
+// Define writes the section with the given name of the template that extends
+// the layout, or the output of body if it has none.
+func (r *Runtime) Define(name string, line, col int, body func() error) error {
+	if r.env.InDefine {
+		return newError(position(line, col), "nested defines are not allowed")
+	}
+
+	section, exists := r.env.ExtendsFrom.Sections[name]
+
+	if !exists {
+		if r.env.Fragment == nil {
+			return body()
+		}
+
+		content, err := r.capture(body)
+
+		if err != nil {
+			return err
+		}
+
+		section.Content = content
+	} else {
+		delete(r.env.ExtendsFrom.Sections, name)
+	}
+
+	if err, isError := section.Content.(error); isError {
+		return err
+	}
+
+	r.env.Fragment.Record(name, section.Content)
+
+	return r.writeOutput(section.Content)
+}
+
+// Try writes the output of body, or runs rescue (if it is not nil) with the
+// message of the error of body in the var name.
+func (r *Runtime) Try(name string, body func() error, rescue func() error) error {
+	output, err := r.capture(body)
+
+	if err == nil {
+		r.out.WriteString(output)
+
+		return r.checkOutput()
+	}
+
+	if !rescuable(err, r.env) {
+		return err
+	}
+
+	if rescue == nil {
+		return nil
+	}
+
+	outer := r.env
+	scope := object.NewEnclosedEnvironment(outer)
+
+	r.env = scope
+
+	defer func() {
+		r.env = outer
+
+		scope.Release()
+	}()
+
+	if name != "" {
+		scope.Set(name, err.Error())
+	}
+
+	return rescue()
+}
+
+// Spaceless writes the output of body without the whitespace between its
+// tags.
+func (r *Runtime) Spaceless(body func() error) error {
+	output, err := r.capture(body)
+
+	if err != nil {
+		return err
+	}
+
+	r.out.WriteString(spacesBetweenTags.ReplaceAllString(strings.TrimSpace(output), "><"))
+
+	return r.checkOutput()
+}
+
+// capture returns the output of body instead of writing it.
+func (r *Runtime) capture(body func() error) (string, error) {
+	outer := r.out
+	r.out = getBuffer()
+
+	defer func() {
+		putBuffer(r.out)
+		r.out = outer
+	}()
+
+	err := body()
+
+	return r.out.String(), err
+}
@@ -0,0 +1,169 @@
+package evaluator
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/govel-framework/lamb/internal"
+	"github.com/govel-framework/lamb/object"
+)
+
+// maxSandboxDepth stops the copy of values that contain themselves.
+const maxSandboxDepth = 10
+
+var (
+	interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+	timeType      = reflect.TypeOf(time.Time{})
+)
+
+// sandboxValue returns a copy of the value where every struct (in pointers,
+// lists and maps too) is a map of the fields the sandbox allows, by their Go
+// name, so the builtins that reflect over the values (e.g. json() or dump())
+// and the output only see those fields. A time.Time, the lambdas and the
+// builtins are kept as is.
+func sandboxValue(value interface{}, sandbox *object.Sandbox) interface{} {
+	if value == nil || isCallable(value) || !mayHaveStructs(reflect.TypeOf(value)) {
+		return value
+	}
+
+	return sandboxReflect(reflect.ValueOf(value), sandbox, 0)
+}
+
+func sandboxReflect(value reflect.Value, sandbox *object.Sandbox, depth int) interface{} {
+	if !value.IsValid() || depth > maxSandboxDepth {
+		return nil
+	}
+
+	if !value.CanInterface() {
+		return nil
+	}
+
+	if !mayHaveStructs(value.Type()) || isCallable(value.Interface()) {
+		return value.Interface()
+	}
+
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if value.IsNil() {
+			return nil
+		}
+
+		return sandboxReflect(value.Elem(), sandbox, depth+1)
+
+	case reflect.Struct:
+		fields := make(map[string]interface{})
+
+		for _, field := range reflect.VisibleFields(value.Type()) {
+			if !field.IsExported() || field.Anonymous || !sandbox.CanAccess(value.Type(), field.Name) {
+				continue
+			}
+
+			// a field promoted from a nil embedded pointer has no value
+			if fieldValue, err := value.FieldByIndexErr(field.Index); err == nil {
+				fields[field.Name] = sandboxReflect(fieldValue, sandbox, depth+1)
+			}
+		}
+
+		return fields
+
+	case reflect.Slice, reflect.Array:
+		// a nil slice stays a list, so len() and the like still take it
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			return []interface{}(nil)
+		}
+
+		list := make([]interface{}, value.Len())
+
+		for i := range list {
+			list[i] = sandboxReflect(value.Index(i), sandbox, depth+1)
+		}
+
+		return list
+
+	case reflect.Map:
+		mapType := reflect.MapOf(value.Type().Key(), interfaceType)
+
+		if value.IsNil() {
+			return reflect.Zero(mapType).Interface()
+		}
+
+		m := reflect.MakeMapWithSize(mapType, value.Len())
+
+		for _, key := range value.MapKeys() {
+			element := reflect.ValueOf(sandboxReflect(value.MapIndex(key), sandbox, depth+1))
+
+			if !element.IsValid() {
+				element = reflect.Zero(interfaceType)
+			}
+
+			m.SetMapIndex(key, element)
+		}
+
+		return m.Interface()
+
+	default:
+		return value.Interface()
+	}
+}
+
+// isCallable reports whether the value is a lambda or a builtin, which are
+// called by the templates rather than read.
+func isCallable(value interface{}) bool {
+	switch value.(type) {
+	case *object.Lambda, *object.Builtin, object.Builtin:
+		return true
+	}
+
+	return false
+}
+
+// mayHaveStructs reports whether a value of the type can have a struct other
+// than a time.Time, the values of the other types are not copied.
+func mayHaveStructs(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Struct:
+		return t != timeType
+
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return mayHaveStructs(t.Elem())
+
+	case reflect.Map:
+		return mayHaveStructs(t.Key()) || mayHaveStructs(t.Elem())
+
+	case reflect.Interface:
+		return true
+
+	default:
+		return false
+	}
+}
+
+// sandboxArgs returns the arguments of a builtin called in the sandbox.
+func sandboxArgs(args []interface{}, sandbox *object.Sandbox) []interface{} {
+	sandboxed := make([]interface{}, len(args))
+
+	for i, arg := range args {
+		sandboxed[i] = sandboxValue(arg, sandbox)
+	}
+
+	return sandboxed
+}
+
+// sandboxOutput returns the value written by a template in the sandbox: the
+// values with a String method or a lamb.Formatter are written by them, the
+// structs of the others only have the allowed fields.
+func sandboxOutput(value interface{}, env *object.Environment) interface{} {
+	if env.Sandbox == nil {
+		return value
+	}
+
+	if _, isFormatted := internal.Format(value); isFormatted {
+		return value
+	}
+
+	if value != nil && reflect.TypeOf(stringer(value)).Implements(stringerType) {
+		return value
+	}
+
+	return sandboxValue(value, env.Sandbox)
+}
@@ -0,0 +1,388 @@
+package evaluator
+
+import (
+	"fmt"
+	"html"
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// stringArg returns the argument at index i as a string.
+func stringArg(name string, args []interface{}, i int) (string, error) {
+	s, isString := args[i].(string)
+
+	if !isString {
+		return "", builtInError("argument %d to `%s` not supported, got %T, want=string", i+1, name, args[i])
+	}
+
+	return s, nil
+}
+
+// intArg returns the argument at index i as an int.
+func intArg(name string, args []interface{}, i int) (int, error) {
+	n, isInt := isNumber(args[i])
+
+	if !isInt {
+		return 0, builtInError("argument %d to `%s` not supported, got %T, want=int", i+1, name, args[i])
+	}
+
+	return n, nil
+}
+
+// checkArgs checks that the number of arguments is between min and max.
+func checkArgs(name string, args []interface{}, min, max int) error {
+	if len(args) < min || len(args) > max {
+		if min == max {
+			return builtInError("wrong number of arguments in %s. got=%d, want=%d", name, len(args), min)
+		}
+
+		return builtInError("wrong number of arguments in %s. got=%d, want=%d to %d", name, len(args), min, max)
+	}
+
+	return nil
+}
+
+// stringBuiltIn creates a builtin that takes a string and returns fn(string).
+func stringBuiltIn(name string, fn func(string) string) func(args ...interface{}) interface{} {
+	return func(args ...interface{}) interface{} {
+		if err := checkArgs(name, args, 1, 1); err != nil {
+			return err
+		}
+
+		s, err := stringArg(name, args, 0)
+
+		if err != nil {
+			return err
+		}
+
+		return fn(s)
+	}
+}
+
+// trimBuiltIn creates a builtin that takes a string and an optional cutset
+// (whitespace by default).
+func trimBuiltIn(name string, trimSpace func(string) string, trim func(string, string) string) func(args ...interface{}) interface{} {
+	return func(args ...interface{}) interface{} {
+		if err := checkArgs(name, args, 1, 2); err != nil {
+			return err
+		}
+
+		s, err := stringArg(name, args, 0)
+
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 1 {
+			return trimSpace(s)
+		}
+
+		cutset, err := stringArg(name, args, 1)
+
+		if err != nil {
+			return err
+		}
+
+		return trim(s, cutset)
+	}
+}
+
+// padBuiltIn creates a builtin that pads a string to a length: pad(s, n, " ").
+func padBuiltIn(name string, left bool) func(args ...interface{}) interface{} {
+	return func(args ...interface{}) interface{} {
+		if err := checkArgs(name, args, 2, 3); err != nil {
+			return err
+		}
+
+		s, err := stringArg(name, args, 0)
+
+		if err != nil {
+			return err
+		}
+
+		length, err := intArg(name, args, 1)
+
+		if err != nil {
+			return err
+		}
+
+		pad := " "
+
+		if len(args) == 3 {
+			if pad, err = stringArg(name, args, 2); err != nil {
+				return err
+			}
+
+			if pad == "" {
+				return builtInError("argument 3 to `%s` can not be empty", name)
+			}
+		}
+
+		missing := length - utf8.RuneCountInString(s)
+
+		if missing <= 0 {
+			return s
+		}
+
+		padding := []rune(strings.Repeat(pad, missing))[:missing]
+
+		if left {
+			return string(padding) + s
+		}
+
+		return s + string(padding)
+	}
+}
+
+// capitalize upper cases the first letter of s.
+func capitalize(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+
+	if r == utf8.RuneError {
+		return s
+	}
+
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+// title upper cases the first letter of every word of s.
+func title(s string) string {
+	var out strings.Builder
+
+	inWord := false
+
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '\'' {
+			if !inWord {
+				r = unicode.ToUpper(r)
+			}
+
+			inWord = true
+		} else {
+			inWord = false
+		}
+
+		out.WriteRune(r)
+	}
+
+	return out.String()
+}
+
+func replaceBuiltIn(args ...interface{}) interface{} {
+	if err := checkArgs("replace", args, 3, 3); err != nil {
+		return err
+	}
+
+	var strs [3]string
+
+	for i := range strs {
+		s, err := stringArg("replace", args, i)
+
+		if err != nil {
+			return err
+		}
+
+		strs[i] = s
+	}
+
+	return strings.ReplaceAll(strs[0], strs[1], strs[2])
+}
+
+func splitBuiltIn(args ...interface{}) interface{} {
+	if err := checkArgs("split", args, 2, 2); err != nil {
+		return err
+	}
+
+	s, err := stringArg("split", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	sep, err := stringArg("split", args, 1)
+
+	if err != nil {
+		return err
+	}
+
+	return strings.Split(s, sep)
+}
+
+func joinBuiltIn(args ...interface{}) interface{} {
+	if err := checkArgs("join", args, 2, 2); err != nil {
+		return err
+	}
+
+	list := reflect.ValueOf(args[0])
+
+	if list.Kind() != reflect.Slice && list.Kind() != reflect.Array {
+		return builtInError("argument 1 to `join` not supported, got %T, want=list", args[0])
+	}
+
+	sep, err := stringArg("join", args, 1)
+
+	if err != nil {
+		return err
+	}
+
+	elements := make([]string, list.Len())
+
+	for i := range elements {
+		elements[i] = fmt.Sprintf("%v", list.Index(i).Interface())
+	}
+
+	return strings.Join(elements, sep)
+}
+
+// maxRepeatSize is the size of the longest string repeat() builds when
+// lamb.max_output_size is not set, so a template can not run out of memory.
+const maxRepeatSize = 64 << 20
+
+func repeatBuiltIn(args ...interface{}) interface{} {
+	if err := checkArgs("repeat", args, 2, 2); err != nil {
+		return err
+	}
+
+	s, err := stringArg("repeat", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	count, err := intArg("repeat", args, 1)
+
+	if err != nil {
+		return err
+	}
+
+	if count < 0 {
+		return builtInError("argument 2 to `repeat` can not be negative, got %d", count)
+	}
+
+	max := maxOutputSize()
+
+	if max == 0 {
+		max = maxRepeatSize
+	}
+
+	// compared by division, count * len(s) can overflow
+	if len(s) > 0 && count > max/len(s) {
+		return builtInError("the string built by `repeat` exceeds the max size of %d bytes", max)
+	}
+
+	return strings.Repeat(s, count)
+}
+
+// truncateBuiltIn cuts a string to n characters: truncate(s, n, "…", true),
+// the end is added if the string is cut and the last argument (optional)
+// cuts at the last word boundary instead of in the middle of a word.
+func truncateBuiltIn(args ...interface{}) interface{} {
+	if err := checkArgs("truncate", args, 2, 4); err != nil {
+		return err
+	}
+
+	s, err := stringArg("truncate", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	length, err := intArg("truncate", args, 1)
+
+	if err != nil {
+		return err
+	}
+
+	end := "…"
+
+	if len(args) > 2 {
+		if end, err = stringArg("truncate", args, 2); err != nil {
+			return err
+		}
+	}
+
+	atWord := false
+
+	if len(args) > 3 {
+		var isBool bool
+
+		if atWord, isBool = args[3].(bool); !isBool {
+			return builtInError("argument 4 to `truncate` not supported, got %T, want=bool", args[3])
+		}
+	}
+
+	runes := []rune(s)
+
+	if length < 0 || len(runes) <= length {
+		return s
+	}
+
+	cut := runes[:length]
+
+	// go back to the end of the last whole word, unless the cut is already there
+	if atWord && !unicode.IsSpace(runes[length]) {
+		for i := len(cut) - 1; i >= 0; i-- {
+			if unicode.IsSpace(cut[i]) {
+				cut = cut[:i]
+				break
+			}
+		}
+	}
+
+	return strings.TrimRightFunc(string(cut), unicode.IsSpace) + end
+}
+
+// excerptBuiltIn keeps the first words of a string: excerpt(s, 20, "…").
+func excerptBuiltIn(args ...interface{}) interface{} {
+	if err := checkArgs("excerpt", args, 2, 3); err != nil {
+		return err
+	}
+
+	s, err := stringArg("excerpt", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	count, err := intArg("excerpt", args, 1)
+
+	if err != nil {
+		return err
+	}
+
+	end := "…"
+
+	if len(args) > 2 {
+		if end, err = stringArg("excerpt", args, 2); err != nil {
+			return err
+		}
+	}
+
+	words := strings.Fields(s)
+
+	if count < 0 || len(words) <= count {
+		return strings.Join(words, " ")
+	}
+
+	return strings.Join(words[:count], " ") + end
+}
+
+// nl2brBuiltIn escapes the text and converts its newlines to <br>, for the
+// text typed by the users: nl2br(comment.Body).
+func nl2brBuiltIn(args ...interface{}) interface{} {
+	if err := checkArgs("nl2br", args, 1, 1); err != nil {
+		return err
+	}
+
+	s, err := stringArg("nl2br", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	s = strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(html.EscapeString(s))
+
+	return strings.ReplaceAll(s, "\n", "<br>\n")
+}
@@ -0,0 +1,85 @@
+package evaluator
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/govel-framework/lamb/internal"
+	"github.com/govel-framework/lamb/object"
+)
+
+// renderLocale returns the locale of the render: the "__locale" var, the
+// locale of the request (ctx) or lamb.locale, in that order.
+func renderLocale(env *object.Environment) string {
+	if env.Locale != "" {
+		return env.Locale
+	}
+
+	if locale := contextString(env, "Locale"); locale != "" {
+		return locale
+	}
+
+	return defaultLocale().String()
+}
+
+// tBuiltIn translates a key to the locale of the render:
+// t("greeting", {"name": user.Name}) with the message "Hello, :name!" gives
+// "Hello, Bob!". A key without translation is returned as is.
+func tBuiltIn(env *object.Environment, args ...interface{}) interface{} {
+	if err := checkArgs("t", args, 1, 2); err != nil {
+		return err
+	}
+
+	key, err := stringArg("t", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	message, exists := internal.Translate(renderLocale(env), defaultLocale().String(), key)
+
+	if !exists {
+		message = key
+	}
+
+	if len(args) == 1 {
+		return message
+	}
+
+	params, err := mapArg("t", args, 1)
+
+	if err != nil {
+		return err
+	}
+
+	return interpolate(message, params)
+}
+
+// interpolate replaces the placeholders of the message (":name") with the
+// params.
+func interpolate(message string, params reflect.Value) string {
+	names := make([]string, 0, params.Len())
+	values := make(map[string]string, params.Len())
+
+	for _, key := range params.MapKeys() {
+		name := fmt.Sprintf("%v", key.Interface())
+
+		names = append(names, name)
+		values[name] = fmt.Sprintf("%v", params.MapIndex(key).Interface())
+	}
+
+	// the longest first, so :names is not replaced as :name
+	sort.Slice(names, func(i, j int) bool {
+		return len(names[i]) > len(names[j])
+	})
+
+	replacements := make([]string, 0, len(names)*2)
+
+	for _, name := range names {
+		replacements = append(replacements, ":"+name, values[name])
+	}
+
+	return strings.NewReplacer(replacements...).Replace(message)
+}
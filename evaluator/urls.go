@@ -0,0 +1,185 @@
+package evaluator
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+
+	"github.com/govel-framework/lamb/object"
+)
+
+// urlEncodeBuiltIn escapes a string so it can be placed in a query param.
+func urlEncodeBuiltIn(args ...interface{}) interface{} {
+	if err := checkArgs("url_encode", args, 1, 1); err != nil {
+		return err
+	}
+
+	s, err := stringArg("url_encode", args, 0)
+
+	if err != nil {
+		return err
+	}
+
+	return url.QueryEscape(s)
+}
+
+// queryBuiltIn builds a query string: query({"page": 2, "sort": "name"})
+// gives "page=2&sort=name". A slice adds the param once per element and a nil
+// or empty value leaves the param out.
+func queryBuiltIn(args ...interface{}) interface{} {
+	if err := checkArgs("query", args, 1, 1); err != nil {
+		return err
+	}
+
+	values := make(url.Values)
+
+	if err := addQueryParams(values, args[0]); err != nil {
+		return builtInError("argument 1 to `query` not supported, got %T, want=map", args[0])
+	}
+
+	return values.Encode()
+}
+
+// urlBuiltIn returns the URL of the current request. With a map, it returns
+// the path of the request with its query params replaced by the ones of the
+// map, e.g. url({"page": 2}) keeps the current filters and changes the page,
+// and url({"page": ""}) removes the page.
+func urlBuiltIn(env *object.Environment, args ...interface{}) interface{} {
+	if err := checkArgs("url", args, 0, 1); err != nil {
+		return err
+	}
+
+	ctx, exists := requestContext(env)
+
+	if !exists {
+		return builtInError("`url` needs the request context, ctx is not set")
+	}
+
+	if len(args) == 0 {
+		return fmt.Sprintf("%v", ctx.FieldByName("URL").Interface())
+	}
+
+	values := make(url.Values)
+
+	if err := addQueryParams(values, ctx.FieldByName("Query").Interface()); err != nil {
+		return builtInError("the query of ctx is not a map, got %s", ctx.FieldByName("Query").Type())
+	}
+
+	params := make(url.Values)
+
+	if err := addQueryParams(params, args[0]); err != nil {
+		return builtInError("argument 1 to `url` not supported, got %T, want=map", args[0])
+	}
+
+	for key := range params {
+		values[key] = params[key]
+	}
+
+	// empty values remove the param
+	if paramsMap := reflect.ValueOf(args[0]); paramsMap.Kind() == reflect.Map {
+		for _, key := range paramsMap.MapKeys() {
+			if emptyParam(paramsMap.MapIndex(key).Interface()) {
+				values.Del(fmt.Sprintf("%v", key.Interface()))
+			}
+		}
+	}
+
+	path := fmt.Sprintf("%v", ctx.FieldByName("Path").Interface())
+
+	if len(values) == 0 {
+		return path
+	}
+
+	return path + "?" + values.Encode()
+}
+
+// requestContext returns the ctx var (the RenderContext set by Render).
+func requestContext(env *object.Environment) (reflect.Value, bool) {
+	ctx, exists := env.Get("ctx")
+
+	if !exists || isNil(ctx) {
+		return reflect.Value{}, false
+	}
+
+	ctxValue := reflect.Indirect(reflect.ValueOf(ctx))
+
+	if ctxValue.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+
+	for _, field := range []string{"URL", "Path", "Query"} {
+		if !ctxValue.FieldByName(field).IsValid() {
+			return reflect.Value{}, false
+		}
+	}
+
+	return ctxValue, true
+}
+
+// contextField returns a field of the ctx var.
+func contextField(env *object.Environment, field string) (reflect.Value, bool) {
+	ctx, exists := env.Get("ctx")
+
+	if !exists || isNil(ctx) {
+		return reflect.Value{}, false
+	}
+
+	ctxValue := reflect.Indirect(reflect.ValueOf(ctx))
+
+	if ctxValue.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+
+	value := ctxValue.FieldByName(field)
+
+	return value, value.IsValid()
+}
+
+// contextString returns a string field of the ctx var, empty if there is no
+// such field.
+func contextString(env *object.Environment, field string) string {
+	if value, exists := contextField(env, field); exists && value.Kind() == reflect.String {
+		return value.String()
+	}
+
+	return ""
+}
+
+// addQueryParams adds the entries of the map m to values.
+func addQueryParams(values url.Values, m interface{}) error {
+	mapValue := reflect.ValueOf(m)
+
+	if mapValue.Kind() != reflect.Map {
+		return fmt.Errorf("not a map: %T", m)
+	}
+
+	for _, key := range mapValue.MapKeys() {
+		name := fmt.Sprintf("%v", key.Interface())
+		value := mapValue.MapIndex(key).Interface()
+
+		if emptyParam(value) {
+			continue
+		}
+
+		valueOf := reflect.ValueOf(value)
+
+		if valueOf.Kind() == reflect.Slice || valueOf.Kind() == reflect.Array {
+			for i := 0; i < valueOf.Len(); i++ {
+				values.Add(name, fmt.Sprintf("%v", valueOf.Index(i).Interface()))
+			}
+
+			continue
+		}
+
+		values.Add(name, fmt.Sprintf("%v", value))
+	}
+
+	return nil
+}
+
+// emptyParam reports whether the value of a query param is nil or "".
+func emptyParam(value interface{}) bool {
+	s, isString := value.(string)
+
+	return isNil(value) || (isString && s == "")
+}
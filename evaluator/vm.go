@@ -0,0 +1,342 @@
+package evaluator
+
+import (
+	"bytes"
+	"reflect"
+
+	"github.com/govel-framework/lamb/ast"
+	"github.com/govel-framework/lamb/internal"
+	"github.com/govel-framework/lamb/object"
+)
+
+// vm runs the IR of a program or a block.
+//
+// The slots cache the vars read by the code: a slot is resolved in the
+// environment the first time it is read and kept until the environment may
+// have changed (a loop enters or leaves its scope, or a node is evaluated by
+// walking its tree). The stores write through to the environment, so the
+// nodes evaluated by walking their tree always see the vars of the code.
+type vm struct {
+	code  *Code
+	env   *object.Environment
+	out   *bytes.Buffer
+	stack []interface{}
+
+	slots  []interface{}
+	loaded []uint32 // The generation in which each slot was resolved.
+	gen    uint32
+
+	maxOutput int
+}
+
+// run writes the output of the code to out.
+func (code *Code) run(out *bytes.Buffer, env *object.Environment) error {
+	m := &vm{
+		code:      code,
+		env:       env,
+		out:       out,
+		stack:     make([]interface{}, 0, 16),
+		slots:     make([]interface{}, len(code.names)),
+		loaded:    make([]uint32, len(code.names)),
+		gen:       1,
+		maxOutput: renderLimits(env).MaxOutputSize,
+	}
+
+	return m.exec(0, len(code.instructions))
+}
+
+// output runs the code of a block and returns its output, like the blocks
+// evaluated by walking their tree.
+func (code *Code) output(env *object.Environment) interface{} {
+	out := getBuffer()
+	defer putBuffer(out)
+
+	if err := code.run(out, env); err != nil {
+		return err
+	}
+
+	return out.String()
+}
+
+// exec runs the instructions from the index from to the index to.
+func (m *vm) exec(from, to int) error {
+	for pc := from; pc < to; pc++ {
+		ins := m.code.instructions[pc]
+
+		switch ins.op {
+		case opHTML:
+			m.out.Write(m.code.html[ins.a])
+
+			if m.exceedsOutputSize() {
+				return outputSizeError(m.maxOutput)
+			}
+
+		case opConst:
+			m.push(m.code.constants[ins.a])
+
+		case opLoad:
+			if err := m.load(ins.a, m.code.nodes[ins.b].(*ast.Identifier)); err != nil {
+				return err
+			}
+
+		case opStore:
+			value := m.pop()
+
+			m.env.Assign(m.code.names[ins.a], value)
+			m.set(ins.a, value)
+
+		case opWrite:
+			if value := m.pop(); value != nil && ins.a == 1 {
+				writeEscaped(m.out, value, m.env)
+			} else if value != nil {
+				writeValue(m.out, sandboxOutput(value, m.env))
+			}
+
+			if m.exceedsOutputSize() {
+				return outputSizeError(m.maxOutput)
+			}
+
+		case opPrefix:
+			node := m.code.nodes[ins.a].(*ast.PrefixExpression)
+
+			if err := m.result(evalPrefixExpression(node.Operator, m.pop(), node.Token)); err != nil {
+				return err
+			}
+
+		case opInfix:
+			node := m.code.nodes[ins.a].(*ast.InfixExpression)
+			right := m.pop()
+			left := m.pop()
+
+			if err := m.result(evalInfixExpression(node.Operator, left, right, node.Token)); err != nil {
+				return err
+			}
+
+		case opIndex:
+			node := m.code.nodes[ins.a].(*ast.IndexExpression)
+			index := m.pop()
+			left := m.pop()
+
+			if err := m.result(evalIndexExpression(left, index, node.Token)); err != nil {
+				return err
+			}
+
+		case opDot:
+			node := m.code.nodes[ins.a].(*ast.DotExpression)
+
+			if err := m.result(evalDot(node, m.pop(), m.env)); err != nil {
+				return err
+			}
+
+		case opList:
+			// an empty list literal is a nil list, like evalExpressions returns
+			var list []interface{}
+
+			if ins.a > 0 {
+				list = make([]interface{}, ins.a)
+				copy(list, m.stack[len(m.stack)-ins.a:])
+				m.stack = m.stack[:len(m.stack)-ins.a]
+			}
+
+			m.push(list)
+
+		case opMap:
+			if err := m.result(m.mapLiteral(m.code.nodes[ins.a].(*ast.MapLiteral))); err != nil {
+				return err
+			}
+
+		case opCall:
+			node := m.code.nodes[ins.a].(*ast.CallExpression)
+
+			var args []interface{}
+
+			if n := len(node.Arguments); n > 0 {
+				args = make([]interface{}, n)
+				copy(args, m.stack[len(m.stack)-n:])
+				m.stack = m.stack[:len(m.stack)-n]
+			}
+
+			if err := m.result(callFunction(node, m.pop(), args, m.env)); err != nil {
+				return err
+			}
+
+		case opJump:
+			pc = ins.a - 1
+
+		case opJumpIfFalse:
+			if !isTruthy(m.pop()) {
+				pc = ins.a - 1
+			}
+
+		case opFor:
+			if err := m.loop(&m.code.loops[ins.a], pc); err != nil {
+				return err
+			}
+
+			pc = m.code.loops[ins.a].end - 1
+
+		case opEval:
+			value := Eval(m.code.nodes[ins.a], m.env)
+
+			// the node may have set vars
+			m.gen++
+
+			if err := m.result(value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (m *vm) push(value interface{}) {
+	m.stack = append(m.stack, value)
+}
+
+func (m *vm) pop() interface{} {
+	value := m.stack[len(m.stack)-1]
+	m.stack = m.stack[:len(m.stack)-1]
+
+	return value
+}
+
+// result pushes the value, or returns it if it is an error.
+func (m *vm) result(value interface{}) error {
+	if err, isError := value.(error); isError {
+		return err
+	}
+
+	m.push(value)
+
+	return nil
+}
+
+func (m *vm) set(slot int, value interface{}) {
+	m.slots[slot] = value
+	m.loaded[slot] = m.gen
+}
+
+// load pushes the var of the slot, it is looked up like evalIdentifier does
+// if the slot is not resolved.
+func (m *vm) load(slot int, node *ast.Identifier) error {
+	if m.loaded[slot] == m.gen {
+		m.push(m.slots[slot])
+
+		return nil
+	}
+
+	value := m.resolve(slot, node)
+
+	if err, isError := value.(error); isError {
+		return err
+	}
+
+	m.set(slot, value)
+	m.push(value)
+
+	return nil
+}
+
+func (m *vm) resolve(slot int, node *ast.Identifier) interface{} {
+	if value, exists := m.env.Get(node.Value); exists {
+		return value
+	}
+
+	if value, exists := internal.GetShared(node.Value); exists {
+		return value
+	}
+
+	if builtin := m.code.builtins[slot]; builtin != nil && m.env.Sandbox == nil {
+		return builtin
+	}
+
+	// the sandbox, the builtins registered after the compilation and the
+	// undefined vars
+	return evalIdentifier(node, m.env)
+}
+
+// mapLiteral pops the keys and values of the literal.
+func (m *vm) mapLiteral(node *ast.MapLiteral) interface{} {
+	n := 2 * len(node.Pairs)
+	values := m.stack[len(m.stack)-n:]
+
+	pairs := make(map[interface{}]interface{}, len(node.Pairs))
+
+	for i := 0; i < n; i += 2 {
+		if !hashable(values[i]) {
+			return newError(node.Token, "unusable as map key: %T", values[i])
+		}
+
+		pairs[values[i]] = values[i+1]
+	}
+
+	m.stack = m.stack[:len(m.stack)-n]
+
+	return pairs
+}
+
+// loop runs the loop, its body are the instructions after pc, in the scope of
+// the loop.
+func (m *vm) loop(loop *compiledLoop, pc int) error {
+	var in interface{}
+	var keys []reflect.Value
+
+	if _, isMapLiteral := loop.node.In.(*ast.MapLiteral); isMapLiteral {
+		var err error
+
+		if in, keys, err = evalForIn(loop.node, m.env); err != nil {
+			return err
+		}
+	} else {
+		in = m.pop()
+	}
+
+	outer := m.env
+	scope := object.NewEnclosedEnvironment(outer)
+
+	m.env = scope
+	m.gen++
+
+	defer func() {
+		m.env = outer
+		m.gen++
+
+		scope.Release()
+	}()
+
+	res := forEach(loop.node, in, keys, scope, func(index int, k, v interface{}, length int) interface{} {
+		metadata := loopMetadata(index, length)
+
+		scope.Set(loop.node.Value, v)
+		m.set(loop.value, v)
+
+		if loop.key != -1 {
+			scope.Set(loop.node.Key, k)
+			m.set(loop.key, k)
+		}
+
+		scope.Set("loop", metadata)
+		m.set(loop.loop, metadata)
+
+		if err := m.exec(pc+1, loop.end); err != nil {
+			return err
+		}
+
+		if m.exceedsOutputSize() {
+			return newError(loop.node.Token, "%w", outputSizeError(m.maxOutput))
+		}
+
+		return nil
+	})
+
+	if res != nil {
+		return res.(error)
+	}
+
+	return nil
+}
+
+func (m *vm) exceedsOutputSize() bool {
+	return m.maxOutput != 0 && m.out.Len() > m.maxOutput
+}
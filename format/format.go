@@ -0,0 +1,373 @@
+// Package format formats lamb templates in a canonical style. Only the code
+// blocks ({? ... ?}) and the indentation of the lines that hold nothing but a
+// control block change, the HTML is left untouched.
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/govel-framework/lamb/lexer"
+	"github.com/govel-framework/lamb/token"
+)
+
+// openers, middles and closers are the keywords of the control blocks, the
+// lines of a middle or a closer are indented like the line of their opener.
+var (
+	openers = map[token.TokenType]bool{
+		token.IF:        true,
+		token.FOR:       true,
+		token.SECTION:   true,
+		token.DEFINE:    true,
+		token.TRY:       true,
+		token.SPACELESS: true,
+	}
+
+	middles = map[token.TokenType]bool{
+		token.ELSE:   true,
+		token.RESCUE: true,
+	}
+
+	closers = map[token.TokenType]bool{
+		token.ENDIF:        true,
+		token.ENDFOR:       true,
+		token.ENDSECTION:   true,
+		token.END:          true,
+		token.ENDTRY:       true,
+		token.ENDSPACELESS: true,
+	}
+)
+
+// Source formats the template src:
+//
+//   - the code of every block is written as {? code ?}, with its tokens
+//     separated by one space except around ( ) [ ] . , and :
+//   - the strings use double quotes, unless they contain one.
+//   - the lines that hold nothing but the middle (else, rescue) or the end of
+//     a control block are indented like the line that opened it.
+func Source(src []byte) ([]byte, error) {
+	var out bytes.Buffer
+
+	// the indentation of the lines of the open control blocks
+	var indents []string
+
+	lines := strings.SplitAfter(string(src), "\n")
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		// a code block can span lines
+		for strings.Count(line, "{?") > 0 && !blocksClosed(line) && i+1 < len(lines) {
+			i++
+			line += lines[i]
+		}
+
+		formatted, err := formatLine(line)
+
+		if err != nil {
+			return nil, fmt.Errorf("%d: %s", i+1, err)
+		}
+
+		keyword, alone := controlKeyword(formatted)
+
+		switch {
+		case alone && closers[keyword] && len(indents) > 0:
+			formatted = indents[len(indents)-1] + strings.TrimLeft(formatted, " \t")
+			indents = indents[:len(indents)-1]
+
+		case alone && middles[keyword] && len(indents) > 0:
+			formatted = indents[len(indents)-1] + strings.TrimLeft(formatted, " \t")
+
+		default:
+			// the blocks opened and not closed in the line
+			for _, keyword := range keywords(formatted) {
+				switch {
+				case openers[keyword]:
+					indents = append(indents, indentation(formatted))
+
+				case closers[keyword] && len(indents) > 0:
+					indents = indents[:len(indents)-1]
+				}
+			}
+		}
+
+		out.WriteString(formatted)
+	}
+
+	return out.Bytes(), nil
+}
+
+// formatLine formats the code blocks of the line.
+func formatLine(line string) (string, error) {
+	var out strings.Builder
+
+	for {
+		start := strings.Index(line, "{?")
+
+		if start == -1 {
+			out.WriteString(line)
+
+			return out.String(), nil
+		}
+
+		out.WriteString(line[:start])
+
+		end := codeEnd(line, start+2)
+
+		if end == -1 {
+			return "", fmt.Errorf("the code block is not closed")
+		}
+
+		code, err := formatCode(line[start+2 : end])
+
+		if err != nil {
+			return "", err
+		}
+
+		if code == "" {
+			out.WriteString("{? ?}")
+		} else {
+			out.WriteString("{? " + code + " ?}")
+		}
+
+		line = line[end+2:]
+	}
+}
+
+// codeEnd returns the index of the ?} that closes the code block that starts
+// at i, -1 if it is not closed. The ?} in strings and comments do not close it.
+func codeEnd(s string, i int) int {
+	for i < len(s) {
+		switch s[i] {
+		case '"', '\'', '#':
+			closing := strings.IndexByte(s[i+1:], s[i])
+
+			if closing == -1 {
+				return -1
+			}
+
+			i += closing + 2
+
+		case '?':
+			if i+1 < len(s) && s[i+1] == '}' {
+				return i
+			}
+
+			i++
+
+		default:
+			i++
+		}
+	}
+
+	return -1
+}
+
+// blocksClosed reports whether every code block of s is closed.
+func blocksClosed(s string) bool {
+	for {
+		start := strings.Index(s, "{?")
+
+		if start == -1 {
+			return true
+		}
+
+		end := codeEnd(s, start+2)
+
+		if end == -1 {
+			return false
+		}
+
+		s = s[end+2:]
+	}
+}
+
+// formatCode formats the code of a block, the comments are kept as they are.
+func formatCode(code string) (string, error) {
+	var parts []string
+	var rest strings.Builder
+
+	flush := func() error {
+		formatted, err := formatTokens(rest.String())
+
+		if err != nil {
+			return err
+		}
+
+		if formatted != "" {
+			parts = append(parts, formatted)
+		}
+
+		rest.Reset()
+
+		return nil
+	}
+
+	for i := 0; i < len(code); i++ {
+		switch code[i] {
+		case '"', '\'':
+			closing := strings.IndexByte(code[i+1:], code[i])
+
+			if closing == -1 {
+				return "", fmt.Errorf("the string is not closed")
+			}
+
+			rest.WriteString(code[i : i+closing+2])
+			i += closing + 1
+
+		case '#':
+			if err := flush(); err != nil {
+				return "", err
+			}
+
+			closing := strings.IndexByte(code[i+1:], '#')
+
+			if closing == -1 {
+				return "", fmt.Errorf("the comment is not closed")
+			}
+
+			parts = append(parts, code[i:i+closing+2])
+			i += closing + 1
+
+		default:
+			rest.WriteByte(code[i])
+		}
+	}
+
+	if err := flush(); err != nil {
+		return "", err
+	}
+
+	return strings.Join(parts, " "), nil
+}
+
+// formatTokens writes the tokens of the code with the canonical spacing.
+func formatTokens(code string) (string, error) {
+	var out strings.Builder
+	var prev, beforePrev *token.Token
+
+	l := lexer.New("{?" + code + "?}")
+
+	for tok := l.NextToken(); tok.Type != token.EOC && tok.Type != token.EOF; tok = l.NextToken() {
+		if tok.Type == token.ILLEGAL {
+			return "", fmt.Errorf("illegal character %s", tok.Literal)
+		}
+
+		if tok.Type == token.STRING {
+			tok.Literal = doubleQuoted(tok.Literal)
+		}
+
+		if prev != nil && spaced(*prev, tok, unary(prev, beforePrev)) {
+			out.WriteByte(' ')
+		}
+
+		out.WriteString(tok.Literal)
+
+		current := tok
+		beforePrev, prev = prev, &current
+	}
+
+	return out.String(), nil
+}
+
+// doubleQuoted returns the string literal with double quotes, unless it
+// contains one.
+func doubleQuoted(literal string) string {
+	if literal[0] != '\'' || strings.Contains(literal, "\"") {
+		return literal
+	}
+
+	return "\"" + literal[1:len(literal)-1] + "\""
+}
+
+// operandEnds are the tokens that can end an operand.
+var operandEnds = map[token.TokenType]bool{
+	token.IDENT:    true,
+	token.INT:      true,
+	token.FLOAT:    true,
+	token.STRING:   true,
+	token.TRUE:     true,
+	token.FALSE:    true,
+	token.RPAREN:   true,
+	token.RBRACKET: true,
+	token.RBRACE:   true,
+}
+
+// unary reports whether tok is a unary minus or bang, that is, it does not
+// follow an operand.
+func unary(tok *token.Token, before *token.Token) bool {
+	if tok.Type != token.MINUS && tok.Type != token.BANG {
+		return false
+	}
+
+	return before == nil || !operandEnds[before.Type]
+}
+
+// spaced reports whether a space goes between the tokens.
+func spaced(prev token.Token, cur token.Token, prevIsUnary bool) bool {
+	switch cur.Type {
+	case token.RPAREN, token.RBRACKET, token.RBRACE, token.COMMA, token.COLON, token.DOT:
+		return false
+
+	case token.LPAREN, token.LBRACKET:
+		// a call or an index
+		switch prev.Type {
+		case token.IDENT, token.RPAREN, token.RBRACKET, token.STRING:
+			return false
+		}
+	}
+
+	switch prev.Type {
+	case token.LPAREN, token.LBRACKET, token.LBRACE, token.DOT:
+		return false
+	}
+
+	return !prevIsUnary
+}
+
+// controlKeyword returns the keyword of the line if it holds nothing but a
+// code block.
+func controlKeyword(line string) (token.TokenType, bool) {
+	trimmed := strings.TrimSpace(line)
+
+	if !strings.HasPrefix(trimmed, "{?") || !strings.HasSuffix(trimmed, "?}") || strings.Count(trimmed, "{?") != 1 {
+		return "", false
+	}
+
+	keywords := keywords(trimmed)
+
+	if len(keywords) == 0 {
+		return "", false
+	}
+
+	return keywords[0], true
+}
+
+// keywords returns the first token of every code block of the formatted line.
+func keywords(line string) []token.TokenType {
+	var found []token.TokenType
+
+	for {
+		start := strings.Index(line, "{? ")
+
+		if start == -1 {
+			return found
+		}
+
+		line = line[start+3:]
+
+		word := line
+
+		if space := strings.IndexAny(word, " ("); space != -1 {
+			word = word[:space]
+		}
+
+		found = append(found, token.LookUpIdent(word))
+	}
+}
+
+// indentation returns the leading whitespace of the line.
+func indentation(line string) string {
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
@@ -0,0 +1,51 @@
+package format
+
+import "testing"
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"{?name?}", "{? name ?}"},
+		{"<p>{?  a+b*2 ?}</p>", "<p>{? a + b * 2 ?}</p>"},
+		{"{? upper( 'a' ) ?}", `{? upper("a") ?}`},
+		{`{? 'say "hi"' ?}`, `{? 'say "hi"' ?}`},
+		{"{? x[ 0 ].name ?}", "{? x[0].name ?}"},
+		{"{? a-b ?}{? -1 ?}{? f(-x) ?}{? !ok ?}", "{? a - b ?}{? -1 ?}{? f(-x) ?}{? !ok ?}"},
+		{"{?{a:1,b : [1,2]}?}", "{? {a: 1, b: [1, 2]} ?}"},
+		{"{? # a comment #  name ?}", "{? # a comment # name ?}"},
+		{"{? \"?}\" ?}", "{? \"?}\" ?}"},
+		{
+			"<ul>\n  {?for x in items?}\n    <li>{?x?}</li>\n      {?else?}\n        {?endfor?}\n</ul>\n",
+			"<ul>\n  {? for x in items ?}\n    <li>{? x ?}</li>\n  {? else ?}\n  {? endfor ?}\n</ul>\n",
+		},
+		{"{? if a\n and b ?}x{? endif ?}", "{? if a and b ?}x{? endif ?}"},
+	}
+
+	for _, tt := range tests {
+		formatted, err := Source([]byte(tt.input))
+
+		if err != nil {
+			t.Fatalf("Source(%q) returned an error: %s", tt.input, err)
+		}
+
+		if string(formatted) != tt.expected {
+			t.Errorf("Source(%q) wrong.\nexpected=%q\ngot=%q", tt.input, tt.expected, formatted)
+		}
+	}
+}
+
+func TestSourceErrors(t *testing.T) {
+	tests := []string{
+		"{? name",
+		"{? 'name ?}",
+		"{? @ ?}",
+	}
+
+	for _, input := range tests {
+		if _, err := Source([]byte(input)); err == nil {
+			t.Errorf("Source(%q) did not return an error", input)
+		}
+	}
+}
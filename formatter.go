@@ -0,0 +1,23 @@
+package lamb
+
+import "github.com/govel-framework/lamb/internal"
+
+// Formatter sets how the values of the domain types are written to the
+// output of every template. fn runs before the default formatting (%v) and
+// returns false to leave a value to it:
+//
+//	lamb.Formatter(func(v interface{}) (string, bool) {
+//		switch v := v.(type) {
+//		case decimal.Decimal:
+//			return v.StringFixed(2), true
+//		case time.Time:
+//			return v.Format("2006-01-02"), true
+//		}
+//
+//		return "", false
+//	})
+//
+// The strings and the booleans are not passed to fn.
+func Formatter(fn func(v interface{}) (string, bool)) {
+	internal.SetFormatter(fn)
+}
@@ -0,0 +1,776 @@
+// Package generate compiles lamb templates ahead of time to Go, for the
+// deployments that do not want to load and parse the templates at runtime.
+//
+// Every template becomes a function that renders it through an
+// evaluator.Runtime, registered with lamb.RegisterGenerated in the init of
+// the file, a struct with its vars (the props) and a function that renders it
+// with them. The fields have the types the template declares with props, the
+// names of the types that are not basic are types of the generated package,
+// and a prop with a default is nil when it is not set:
+//
+//	// {? props(user: User, title: string = "User") ?}
+//	type UsersShowProps struct {
+//		User  User
+//		Title *string
+//	}
+//
+//	func RenderUsersShow(w io.Writer, props UsersShowProps) error
+//
+// The fields of the templates that do not declare props are the vars they
+// read, of any type.
+//
+// The directives registered with lamb.AddDirective are evaluated by walking
+// their tree, so a template that uses one can not be generated.
+package generate
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/govel-framework/lamb/ast"
+	"github.com/govel-framework/lamb/evaluator"
+	"github.com/govel-framework/lamb/token"
+)
+
+// Template is a template to generate.
+type Template struct {
+	Name    string       // The name of the template, e.g. users.show.
+	Program *ast.Program // The parsed template.
+	Vars    []string     // The vars read by the template, see lamb.Analyze.
+}
+
+// File returns the source of a Go file of the package pkg with the templates.
+func File(pkg string, templates []Template) ([]byte, error) {
+	var out bytes.Buffer
+
+	fmt.Fprintf(&out, "// Code generated by lamb generate. DO NOT EDIT.\n\npackage %s\n\n", pkg)
+	out.WriteString("import (\n\t\"io\"\n\n\t\"github.com/govel-framework/lamb\"\n\t\"github.com/govel-framework/lamb/evaluator\"\n)\n\n")
+
+	templates = append([]Template{}, templates...)
+
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+
+	names := make(map[string]string)
+
+	for _, template := range templates {
+		name := exported(template.Name)
+
+		if other, exists := names[name]; exists {
+			return nil, fmt.Errorf("the templates %s and %s have the same Go name %s", other, template.Name, name)
+		}
+
+		names[name] = template.Name
+	}
+
+	out.WriteString("func init() {\n")
+
+	for _, template := range templates {
+		fmt.Fprintf(&out, "lamb.RegisterGenerated(%q, %sTemplate)\n", template.Name, unexported(exported(template.Name)))
+	}
+
+	out.WriteString("}\n")
+
+	for _, template := range templates {
+		if err := generateTemplate(&out, template); err != nil {
+			return nil, fmt.Errorf("%s: %w", template.Name, err)
+		}
+	}
+
+	return format.Source(out.Bytes())
+}
+
+// prop is a field of the props of a template.
+type prop struct {
+	name     string // The name of the var.
+	goType   string
+	optional bool // The field is nil when the var is not set, so the template sets its default.
+	pointer  bool // The field is a pointer to the value, to be nil when it is not set.
+}
+
+// templateProps returns the props of the template: the ones it declares with
+// props, or the vars it reads that are not builtins, of any type.
+func templateProps(template Template) []prop {
+	for _, statement := range template.Program.Statements {
+		expression, isExpression := statement.(*ast.ExpressionStatement)
+
+		if !isExpression {
+			continue
+		}
+
+		if declared, isProps := expression.Expression.(*ast.PropsStatement); isProps {
+			props := make([]prop, len(declared.Props))
+
+			for i, p := range declared.Props {
+				props[i] = prop{name: p.Name, goType: goType(p.Type), optional: p.Default != nil}
+
+				// the pointer of an optional value is nil when it is not set
+				if props[i].optional && !nillable(props[i].goType) {
+					props[i].goType = "*" + props[i].goType
+					props[i].pointer = true
+				}
+			}
+
+			return props
+		}
+	}
+
+	var props []prop
+
+	for _, v := range template.Vars {
+		if _, isBuiltin := evaluator.Registry.Get(v); !isBuiltin {
+			props = append(props, prop{name: v, goType: "interface{}"})
+		}
+	}
+
+	return props
+}
+
+// goType returns the Go type of the type of a prop, the names that are not
+// basic types are the names of Go types of the generated package.
+func goType(typ string) string {
+	switch {
+	case strings.HasPrefix(typ, "[]"):
+		return "[]" + goType(typ[2:])
+
+	case strings.HasPrefix(typ, "*"):
+		return "*" + goType(typ[1:])
+
+	case strings.HasPrefix(typ, "map["):
+		depth := 0
+
+		for i := len("map"); i < len(typ); i++ {
+			if typ[i] == '[' {
+				depth++
+			} else if typ[i] == ']' {
+				if depth--; depth == 0 {
+					return "map[" + goType(typ[len("map["):i]) + "]" + goType(typ[i+1:])
+				}
+			}
+		}
+
+	case typ == "float":
+		return "float64"
+
+	case typ == "any":
+		return "interface{}"
+	}
+
+	return typ
+}
+
+func nillable(goType string) bool {
+	return goType == "interface{}" || strings.HasPrefix(goType, "*") || strings.HasPrefix(goType, "[]") || strings.HasPrefix(goType, "map[")
+}
+
+// generateTemplate writes the props, the render and the code of the template.
+func generateTemplate(out *bytes.Buffer, template Template) error {
+	name := exported(template.Name)
+
+	props := templateProps(template)
+
+	fields := make(map[string]string)
+
+	for _, p := range props {
+		field := exported(p.name)
+
+		if other, exists := fields[field]; exists {
+			return fmt.Errorf("the vars %s and %s have the same Go name %s", other, p.name, field)
+		}
+
+		fields[field] = p.name
+	}
+
+	fmt.Fprintf(out, "\n// %sProps are the vars of the template %s.\ntype %sProps struct {\n", name, template.Name, name)
+
+	for _, p := range props {
+		fmt.Fprintf(out, "%s %s\n", exported(p.name), p.goType)
+	}
+
+	out.WriteString("}\n")
+
+	fmt.Fprintf(out, "\n// Render%s renders the template %s to w.\nfunc Render%s(w io.Writer, props %sProps) error {\n", name, template.Name, name, name)
+	out.WriteString("vars := map[string]interface{}{\n")
+
+	for _, p := range props {
+		if !p.optional {
+			fmt.Fprintf(out, "%q: props.%s,\n", p.name, exported(p.name))
+		}
+	}
+
+	out.WriteString("}\n")
+
+	for _, p := range props {
+		if !p.optional {
+			continue
+		}
+
+		value := "props." + exported(p.name)
+
+		if p.pointer {
+			value = "*" + value
+		}
+
+		fmt.Fprintf(out, "if props.%s != nil {\nvars[%q] = %s\n}\n", exported(p.name), p.name, value)
+	}
+
+	fmt.Fprintf(out, "return lamb.RenderTo(w, %q, vars)\n}\n", template.Name)
+
+	g := &generator{fail: "return err"}
+
+	// the output mode applies to the whole template
+	if mode, declared := evaluator.ProgramMode(template.Program); declared {
+		g.printf("r.Autoescape(%q)\n", mode)
+	}
+
+	if err := g.statements(template.Program.Statements); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "\nfunc %sTemplate(r *evaluator.Runtime) error {\n", unexported(name))
+	out.Write(g.out.Bytes())
+	out.WriteString("return nil\n}\n")
+
+	return nil
+}
+
+// generator writes the Go code of the statements of a template.
+type generator struct {
+	out  bytes.Buffer
+	vars int    // The temporary vars declared.
+	fail string // The statement that returns an error from the current function.
+}
+
+// nodeError is an error of a node that can not be generated.
+func nodeError(node ast.Node, format string, a ...interface{}) error {
+	pos := node.(interface{ Pos() token.Position }).Pos()
+
+	return fmt.Errorf("%d: %d: "+format, append([]interface{}{pos.Line, pos.Col}, a...)...)
+}
+
+func (g *generator) printf(format string, a ...interface{}) {
+	fmt.Fprintf(&g.out, format, a...)
+}
+
+// temp returns the name of a new temporary var.
+func (g *generator) temp() string {
+	g.vars++
+
+	return "v" + strconv.Itoa(g.vars)
+}
+
+// check writes the statement call, that returns an error, and its check.
+func (g *generator) check(format string, a ...interface{}) {
+	g.printf("if err := "+format+"; err != nil {\n%s\n}\n", append(a, g.fail)...)
+}
+
+// assign writes the call, that returns a value and an error, to a new
+// temporary var and returns its name.
+func (g *generator) assign(format string, a ...interface{}) string {
+	v := g.temp()
+
+	g.printf("%s, err := "+format+"\nif err != nil {\n%s\n}\n", append(append([]interface{}{v}, a...), g.fail)...)
+
+	return v
+}
+
+// block writes a closure that runs the statements.
+func (g *generator) block(block *ast.BlockStatement) error {
+	fail := g.fail
+	g.fail = "return err"
+
+	g.printf("func() error {\n")
+
+	if block != nil {
+		if err := g.statements(block.Statements); err != nil {
+			return err
+		}
+	}
+
+	g.printf("return nil\n}")
+
+	g.fail = fail
+
+	return nil
+}
+
+func (g *generator) statements(statements []ast.Statement) error {
+	for _, statement := range statements {
+		if err := g.statement(statement); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (g *generator) statement(statement ast.Statement) error {
+	switch statement := statement.(type) {
+	case *ast.ExpressionStatement:
+		return g.expressionStatement(statement.Expression)
+
+	case *ast.VarStatement:
+		value, err := g.expression(statement.Value)
+
+		if err != nil {
+			return err
+		}
+
+		g.printf("r.Set(%q, %s)\n", statement.Name.Value, value)
+
+		return nil
+
+	default:
+		return fmt.Errorf("%T can not be generated", statement)
+	}
+}
+
+func (g *generator) expressionStatement(expression ast.Expression) error {
+	switch node := expression.(type) {
+	case *ast.HtmlLiteral:
+		if node.Value != "" {
+			g.check("r.HTML(%q)", node.Value)
+		}
+
+	case *ast.IfExpression:
+		return g.ifExpression(node)
+
+	case *ast.ForExpression:
+		return g.forExpression(node)
+
+	case *ast.IncludeStatement:
+		vars := "nil"
+
+		if node.Vars != nil {
+			var err error
+
+			if vars, err = g.expression(node.Vars); err != nil {
+				return err
+			}
+		}
+
+		g.check("r.Include(%q, %t, %s, %d, %d)", node.File, node.Vars != nil, vars, node.Token.Line, node.Token.Col)
+
+	case *ast.ExtendsStatement:
+		g.check("r.Extends(%q, %d, %d)", node.From, node.Token.Line, node.Token.Col)
+
+	case *ast.SectionStatement:
+		g.printf("if err := r.Section(%q, %d, %d, ", node.Name, node.Token.Line, node.Token.Col)
+
+		if err := g.block(node.Block); err != nil {
+			return err
+		}
+
+		g.printf("); err != nil {\n%s\n}\n", g.fail)
+
+	case *ast.DefineStatement:
+		g.printf("if err := r.Define(%q, %d, %d, ", node.Name, node.Token.Line, node.Token.Col)
+
+		if err := g.block(node.Content); err != nil {
+			return err
+		}
+
+		g.printf("); err != nil {\n%s\n}\n", g.fail)
+
+	case *ast.TryStatement:
+		g.printf("if err := r.Try(%q, ", node.Err)
+
+		if err := g.block(node.Block); err != nil {
+			return err
+		}
+
+		g.printf(", ")
+
+		if node.Rescue == nil {
+			g.printf("nil")
+		} else if err := g.block(node.Rescue); err != nil {
+			return err
+		}
+
+		g.printf("); err != nil {\n%s\n}\n", g.fail)
+
+	case *ast.SpacelessStatement:
+		g.printf("if err := r.Spaceless(")
+
+		if err := g.block(node.Block); err != nil {
+			return err
+		}
+
+		g.printf("); err != nil {\n%s\n}\n", g.fail)
+
+	case *ast.PropsStatement:
+		for _, prop := range node.Props {
+			if prop.Default == nil {
+				g.check("r.Prop(%q, %q, %d, %d, nil)", prop.Name, prop.Type, prop.Token.Line, prop.Token.Col)
+
+				continue
+			}
+
+			fail := g.fail
+			g.fail = "return nil, err"
+
+			g.printf("if err := r.Prop(%q, %q, %d, %d, func() (interface{}, error) {\n", prop.Name, prop.Type, prop.Token.Line, prop.Token.Col)
+
+			value, err := g.expression(prop.Default)
+
+			if err != nil {
+				return err
+			}
+
+			g.printf("return %s, nil\n}); err != nil {\n%s\n}\n", value, fail)
+
+			g.fail = fail
+		}
+
+	case *ast.AutoescapeStatement:
+		// set at the start of the template
+
+	case *ast.DirectiveStatement:
+		return nodeError(node, "the directive %s can not be generated", node.Name)
+
+	default:
+		value, err := g.expression(expression)
+
+		if err != nil {
+			return err
+		}
+
+		g.check("r.Write(%s)", value)
+	}
+
+	return nil
+}
+
+// ifExpression writes an if in the place of a statement, its blocks write
+// their output.
+func (g *generator) ifExpression(node *ast.IfExpression) error {
+	if node.Consequence == nil {
+		return nodeError(node, "if without a block")
+	}
+
+	condition, err := g.expression(node.Condition)
+
+	if err != nil {
+		return err
+	}
+
+	g.printf("if r.Truthy(%s) {\n", condition)
+
+	if err := g.statements(node.Consequence.Statements); err != nil {
+		return err
+	}
+
+	if node.Alternative != nil {
+		g.printf("} else {\n")
+
+		if err := g.statements(node.Alternative.Statements); err != nil {
+			return err
+		}
+	}
+
+	g.printf("}\n")
+
+	return nil
+}
+
+// forExpression writes a for loop in the place of a statement, its body
+// writes its output.
+func (g *generator) forExpression(node *ast.ForExpression) error {
+	if node.Block == nil {
+		return nodeError(node, "for without a block")
+	}
+
+	in, keys := "", "nil"
+
+	// a map literal is iterated in source order
+	if literal, isMapLiteral := node.In.(*ast.MapLiteral); isMapLiteral {
+		var err error
+
+		if in, keys, err = g.mapLiteral(literal, true); err != nil {
+			return err
+		}
+	} else {
+		var err error
+
+		if in, err = g.expression(node.In); err != nil {
+			return err
+		}
+	}
+
+	g.printf("if err := r.For(%s, %s, %q, %q, %d, %d, ", in, keys, node.Key, node.Value, node.Token.Line, node.Token.Col)
+
+	if err := g.block(node.Block); err != nil {
+		return err
+	}
+
+	g.printf("); err != nil {\n%s\n}\n", g.fail)
+
+	return nil
+}
+
+// expression writes the code that evaluates the expression and returns the
+// Go expression of its value.
+func (g *generator) expression(node ast.Expression) (string, error) {
+	switch node := node.(type) {
+	case nil:
+		return "nil", nil
+
+	case *ast.IntegerLiteral:
+		return strconv.Itoa(node.Value), nil
+
+	case *ast.FloatLiteral:
+		return fmt.Sprintf("float64(%s)", strconv.FormatFloat(node.Value, 'g', -1, 64)), nil
+
+	case *ast.Boolean:
+		return strconv.FormatBool(node.Value), nil
+
+	case *ast.StringLiteral:
+		if !node.Closed {
+			return "", nodeError(node, "unclosed string literal")
+		}
+
+		return strconv.Quote(node.Value), nil
+
+	case *ast.HtmlLiteral:
+		return strconv.Quote(node.Value), nil
+
+	case *ast.Identifier:
+		return g.assign("r.Var(%q, %d, %d)", node.Value, node.Token.Line, node.Token.Col), nil
+
+	case *ast.PrefixExpression:
+		right, err := g.expression(node.Right)
+
+		if err != nil {
+			return "", err
+		}
+
+		return g.assign("r.Prefix(%q, %s, %d, %d)", node.Operator, right, node.Token.Line, node.Token.Col), nil
+
+	case *ast.InfixExpression:
+		left, err := g.expression(node.Left)
+
+		if err != nil {
+			return "", err
+		}
+
+		right, err := g.expression(node.Right)
+
+		if err != nil {
+			return "", err
+		}
+
+		return g.assign("r.Infix(%q, %s, %s, %d, %d)", node.Operator, left, right, node.Token.Line, node.Token.Col), nil
+
+	case *ast.IndexExpression:
+		left, err := g.expression(node.Left)
+
+		if err != nil {
+			return "", err
+		}
+
+		index, err := g.expression(node.Index)
+
+		if err != nil {
+			return "", err
+		}
+
+		return g.assign("r.Index(%s, %s, %d, %d)", left, index, node.Token.Line, node.Token.Col), nil
+
+	case *ast.DotExpression:
+		left, err := g.expression(node.Left)
+
+		if err != nil {
+			return "", err
+		}
+
+		return g.assign("r.Dot(%s, %q, %q, %d, %d)", left, node.Right.Value, node.Left.String(), node.Token.Line, node.Token.Col), nil
+
+	case *ast.ArrayLiteral:
+		// an empty list literal is a nil list, like the evaluator returns
+		if len(node.Elements) == 0 {
+			return "[]interface{}(nil)", nil
+		}
+
+		elements, err := g.expressions(node.Elements)
+
+		if err != nil {
+			return "", err
+		}
+
+		return "[]interface{}{" + strings.Join(elements, ", ") + "}", nil
+
+	case *ast.MapLiteral:
+		m, _, err := g.mapLiteral(node, false)
+
+		return m, err
+
+	case *ast.CallExpression:
+		return g.call(node)
+
+	case *ast.LambdaLiteral:
+		fail := g.fail
+		g.fail = "return nil, err"
+
+		v := g.temp()
+
+		g.printf("%s := r.Lambda(%q, func(r *evaluator.Runtime) (interface{}, error) {\n", v, node.Parameter.Value)
+
+		body, err := g.expression(node.Body)
+
+		if err != nil {
+			return "", err
+		}
+
+		g.printf("return %s, nil\n})\n", body)
+
+		g.fail = fail
+
+		return v, nil
+
+	default:
+		return "", nodeError(node, "%s can not be generated in an expression", node.TokenLiteral())
+	}
+}
+
+func (g *generator) expressions(nodes []ast.Expression) ([]string, error) {
+	values := make([]string, len(nodes))
+
+	for i, node := range nodes {
+		value, err := g.expression(node)
+
+		if err != nil {
+			return nil, err
+		}
+
+		values[i] = value
+	}
+
+	return values, nil
+}
+
+// mapLiteral returns the map and, if ordered is set, its keys in source
+// order.
+func (g *generator) mapLiteral(node *ast.MapLiteral, ordered bool) (string, string, error) {
+	pairs := make([]string, 0, 2*len(node.Pairs))
+
+	for _, pair := range node.Pairs {
+		key, err := g.expression(pair.Key)
+
+		if err != nil {
+			return "", "", err
+		}
+
+		value, err := g.expression(pair.Value)
+
+		if err != nil {
+			return "", "", err
+		}
+
+		pairs = append(pairs, key, value)
+	}
+
+	m, keys := g.temp(), "_"
+
+	if ordered {
+		keys = g.temp()
+	}
+
+	g.printf("%s, %s, err := r.Map(%d, %d", m, keys, node.Token.Line, node.Token.Col)
+
+	for _, pair := range pairs {
+		g.printf(", %s", pair)
+	}
+
+	g.printf(")\nif err != nil {\n%s\n}\n", g.fail)
+
+	return m, keys, nil
+}
+
+func (g *generator) call(node *ast.CallExpression) (string, error) {
+	// isset(x) and the other checks do not fail when x can not be evaluated
+	if identifier, isIdentifier := node.Function.(*ast.Identifier); isIdentifier && evaluator.IsPresenceCheck(identifier.Value) {
+		if len(node.Arguments) != 1 {
+			return "", nodeError(node, "wrong number of arguments in %s. got=%d, want=1", identifier.Value, len(node.Arguments))
+		}
+
+		fail := g.fail
+		g.fail = "return nil, err"
+
+		v := g.temp()
+
+		g.printf("%s, err := r.Check(%q, %d, %d, func() (interface{}, error) {\n", v, identifier.Value, node.Token.Line, node.Token.Col)
+
+		arg, err := g.expression(node.Arguments[0])
+
+		if err != nil {
+			return "", err
+		}
+
+		g.printf("return %s, nil\n})\nif err != nil {\n%s\n}\n", arg, fail)
+
+		g.fail = fail
+
+		return v, nil
+	}
+
+	function, err := g.expression(node.Function)
+
+	if err != nil {
+		return "", err
+	}
+
+	args, err := g.expressions(node.Arguments)
+
+	if err != nil {
+		return "", err
+	}
+
+	list := "nil"
+
+	if len(args) != 0 {
+		list = "[]interface{}{" + strings.Join(args, ", ") + "}"
+	}
+
+	return g.assign("r.Call(%s, %s, %q, %d, %d)", function, list, node.Function.String(), node.Token.Line, node.Token.Col), nil
+}
+
+// exported returns the exported Go name of a template or a var name, e.g.
+// UsersShow for users.show and FirstName for first_name.
+func exported(name string) string {
+	var out strings.Builder
+
+	upper := true
+
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+
+			continue
+		}
+
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+
+		out.WriteRune(r)
+	}
+
+	if out.Len() == 0 || unicode.IsDigit([]rune(out.String())[0]) {
+		return "T" + out.String()
+	}
+
+	return out.String()
+}
+
+// unexported returns the name with its first letter in lower case.
+func unexported(name string) string {
+	runes := []rune(name)
+	runes[0] = unicode.ToLower(runes[0])
+
+	return string(runes)
+}
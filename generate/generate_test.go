@@ -0,0 +1,157 @@
+package generate
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/govel-framework/lamb/ast"
+	"github.com/govel-framework/lamb/lexer"
+	lambparser "github.com/govel-framework/lamb/parser"
+)
+
+func parse(t *testing.T, src string) *ast.Program {
+	t.Helper()
+
+	p := lambparser.New(lexer.New(src))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parse %q: %v", src, p.Errors())
+	}
+
+	return program
+}
+
+func TestFile(t *testing.T) {
+	templates := []Template{
+		{Name: "users.show", Program: parse(t, `<h1>{? user.name ?}</h1>{? for x in [1, 2.5] ?}{? x ?}{? endfor ?}`), Vars: []string{"user", "upper"}},
+		{Name: "layouts.app", Program: parse(t, `{? define("content") ?}{? end ?}{? if isset(title) ?}{? title ?}{? endif ?}`), Vars: []string{"first_name"}},
+		{Name: "users.index", Program: parse(t, `{? props(users: []User, title: string = "Users", m: map[string]any) ?}{? title ?}`), Vars: []string{"users", "title"}},
+		{Name: "feeds.rss", Program: parse(t, `<title>{? title ?}</title>{? autoescape("xml") ?}`), Vars: []string{"title"}},
+	}
+
+	src, err := File("views", templates)
+
+	if err != nil {
+		t.Fatalf("File returned an error: %s", err)
+	}
+
+	if _, err := parser.ParseFile(token.NewFileSet(), "views.go", src, 0); err != nil {
+		t.Fatalf("the generated file can not be parsed: %s\n%s", err, src)
+	}
+
+	expected := []string{
+		"// Code generated by lamb generate. DO NOT EDIT.",
+		"package views",
+		`lamb.RegisterGenerated("layouts.app", layoutsAppTemplate)`,
+		`lamb.RegisterGenerated("users.show", usersShowTemplate)`,
+		"type UsersShowProps struct {\n\tUser interface{}\n}",
+		"type LayoutsAppProps struct {\n\tFirstName interface{}\n}",
+		"func RenderUsersShow(w io.Writer, props UsersShowProps) error {",
+		`"first_name": props.FirstName,`,
+		`r.Dot(v1, "name", "user", 1, 12)`,
+		`r.For([]interface{}{1, float64(2.5)}, nil, "", "x", 1, 28, func() error {`,
+		`r.Check("isset", 1, 44, func() (interface{}, error) {`,
+		"type UsersIndexProps struct {\n\tUsers []User\n\tTitle *string\n\tM     map[string]interface{}\n}",
+		"if props.Title != nil {\n\t\tvars[\"title\"] = *props.Title\n\t}",
+		`r.Prop("users", "[]User", 1, 10, nil)`,
+		`if err := r.Prop("title", "string", 1, 25, func() (interface{}, error) {`,
+		"func feedsRssTemplate(r *evaluator.Runtime) error {\n\tr.Autoescape(\"xml\")\n",
+	}
+
+	for _, e := range expected {
+		if !strings.Contains(string(src), e) {
+			t.Errorf("the generated file does not contain %q\n%s", e, src)
+		}
+	}
+
+	if testing.Short() {
+		return
+	}
+
+	// the types of the props are declared by the package
+	build(t, map[string][]byte{
+		"views/views.go": src,
+		"views/types.go": []byte("package views\n\ntype User struct{}\n"),
+	})
+}
+
+// build builds the files in a module that uses this copy of lamb.
+func build(t *testing.T, files map[string][]byte) {
+	t.Helper()
+
+	goTool, err := exec.LookPath("go")
+
+	if err != nil {
+		t.Skip("the go tool is not installed")
+	}
+
+	root, err := filepath.Abs("..")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sum, err := os.ReadFile(filepath.Join(root, "go.sum"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+
+	files["go.mod"] = []byte("module lambgen\n\ngo 1.19\n\nrequire github.com/govel-framework/lamb v0.0.0\n\n" +
+		"replace github.com/govel-framework/lamb => " + root + "\n")
+	files["go.sum"] = sum
+
+	for name, content := range files {
+		file := filepath.Join(dir, name)
+
+		os.MkdirAll(filepath.Dir(file), os.ModePerm)
+
+		if err := os.WriteFile(file, content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command(goTool, "build", "./...")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOWORK=off", "GOFLAGS=-mod=mod")
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("the generated files do not build: %s\n%s", err, out)
+	}
+}
+
+func TestFileErrors(t *testing.T) {
+	tests := []struct {
+		templates []Template
+		expected  string
+	}{
+		{
+			[]Template{{Name: "a", Program: parse(t, `{? isset(a, b) ?}`)}},
+			"a: 1: 4: wrong number of arguments in isset. got=2, want=1",
+		},
+		{
+			[]Template{{Name: "a", Program: parse(t, `{? x ?}`), Vars: []string{"first_name", "firstName"}}},
+			"a: the vars first_name and firstName have the same Go name FirstName",
+		},
+		{
+			[]Template{{Name: "users.show", Program: parse(t, "")}, {Name: "users_show", Program: parse(t, "")}},
+			"the templates users.show and users_show have the same Go name UsersShow",
+		},
+	}
+
+	for _, tt := range tests {
+		_, err := File("views", tt.templates)
+
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%v", tt.expected, err)
+		}
+	}
+}
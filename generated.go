@@ -0,0 +1,21 @@
+package lamb
+
+import (
+	"github.com/govel-framework/lamb/evaluator"
+	"github.com/govel-framework/lamb/internal"
+)
+
+// RegisterGenerated registers a template generated to Go by lamb generate, it
+// is rendered instead of loading and parsing the template with the given
+// name. The generated files register their templates in their init, so the
+// transforms and the statement hooks do not apply to them. A nil fn removes
+// the template, so it is loaded and parsed again.
+func RegisterGenerated(name string, fn func(r *evaluator.Runtime) error) {
+	if fn == nil {
+		internal.AddGenerated(name, nil)
+
+		return
+	}
+
+	internal.AddGenerated(name, evaluator.Generated(fn))
+}
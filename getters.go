@@ -0,0 +1,17 @@
+package lamb
+
+import "github.com/govel-framework/lamb/internal"
+
+// GetterMethods lets the templates read the unexported fields of the structs
+// through their getter methods: fn returns the name of the method of a field
+// ("" if it has none). The method must take no arguments and return the value
+// and, optionally, an error.
+//
+//	lamb.GetterMethods(func(field string) string {
+//		return strings.ToUpper(field[:1]) + field[1:] // email => Email()
+//	})
+//
+// Without getters, reading an unexported field is an error.
+func GetterMethods(fn func(field string) string) {
+	internal.SetGetterMethod(fn)
+}
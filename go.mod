@@ -2,11 +2,14 @@ module github.com/govel-framework/lamb
 
 go 1.19
 
-require github.com/govel-framework/govel v0.0.0-20230913221001-46c7b15ebfd6
+require (
+	github.com/govel-framework/govel v0.0.0-20230913221001-46c7b15ebfd6
+	golang.org/x/text v0.13.0
+	gopkg.in/yaml.v2 v2.4.0
+)
 
 require (
 	github.com/gorilla/mux v1.8.0 // indirect
 	github.com/gorilla/securecookie v1.1.1 // indirect
 	github.com/gorilla/sessions v1.2.1 // indirect
-	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
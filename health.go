@@ -0,0 +1,31 @@
+package lamb
+
+import "github.com/govel-framework/lamb/internal"
+
+// HealthReport describes the state of lamb, to be served by the health or
+// readiness endpoint of the application.
+type HealthReport struct {
+	LoadedTemplates int                 `json:"loaded_templates"` // The number of distinct templates parsed successfully.
+	CacheEntries    int                 `json:"cache_entries"`    // The number of rendered templates in the cache dir.
+	ParseErrors     map[string][]string `json:"parse_errors"`     // The last parse errors of every template that currently fails.
+	Reachable       bool                `json:"reachable"`        // Whether the templates dir can be read.
+	Error           string              `json:"error,omitempty"`  // Why the templates dir can not be read.
+}
+
+// Health returns the HealthReport of lamb.
+func Health() HealthReport {
+	stats := internal.Health()
+
+	report := HealthReport{
+		LoadedTemplates: stats.LoadedTemplates,
+		CacheEntries:    stats.CacheEntries,
+		ParseErrors:     stats.ParseErrors,
+		Reachable:       stats.BaseDirError == nil,
+	}
+
+	if stats.BaseDirError != nil {
+		report.Error = stats.BaseDirError.Error()
+	}
+
+	return report
+}
@@ -0,0 +1,27 @@
+package lamb
+
+import (
+	"time"
+
+	"github.com/govel-framework/lamb/ast"
+	"github.com/govel-framework/lamb/internal"
+)
+
+// Hooks sets the functions called before and after the evaluation of every
+// statement of every template (the includes and the statements inside the
+// blocks too), so an APM integration can measure where the render time goes:
+//
+//	lamb.Hooks(nil, func(node ast.Node, file string, elapsed time.Duration, err error) {
+//		metrics.Observe(file, node.TokenLiteral(), elapsed)
+//	})
+//
+// Either function can be nil, Hooks(nil, nil) removes them.
+func Hooks(before func(node ast.Node, file string), after func(node ast.Node, file string, elapsed time.Duration, err error)) {
+	if before == nil && after == nil {
+		internal.SetHooks(nil)
+
+		return
+	}
+
+	internal.SetHooks(&internal.Hooks{Before: before, After: after})
+}
@@ -0,0 +1,219 @@
+package lamb
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/govel-framework/lamb/internal"
+)
+
+// HTTPLoader loads the templates from a URL, e.g. the header and the footer
+// shared by the apps of a design system:
+//
+//	remote := &lamb.HTTPLoader{BaseURL: "https://design.example.com/templates/", Prefix: "ds."}
+//
+//	lamb.UseLoader(lamb.ChainLoader{remote, lamb.FileLoader{}})
+//
+// so {? include("ds.header") ?} loads https://design.example.com/templates/header.
+// The templates are cached for the max-age of their Cache-Control (or TTL if
+// they have none) and revalidated with their ETag or Last-Modified once it is
+// over. When the remote is down (or responds with a server error), the last
+// good copy of a template is loaded.
+//
+// An HTTPLoader must not be copied after its first use.
+type HTTPLoader struct {
+	BaseURL string // The URL of the templates, the name of a template is appended to it.
+
+	// Prefix is the prefix of the names of the remote templates, it is not in
+	// their URL. The other templates do not exist for the loader, so they are
+	// not requested. Every template is remote if it is empty.
+	Prefix string
+
+	// Client is the client of the requests. If it is nil, the requests time
+	// out after Timeout (5s if it is 0), so a remote that hangs does not block
+	// the renders and the last good copies are loaded.
+	Client  *http.Client
+	Timeout time.Duration
+
+	TTL time.Duration // How long a template without a max-age is cached.
+
+	// MaxSize is the max size in bytes of a template (10MB if it is 0), a
+	// larger response is not read and fails like a remote that is down.
+	MaxSize int64
+
+	mu    sync.Mutex
+	cache map[string]httpTemplate
+}
+
+// httpTemplate is a template cached by an HTTPLoader.
+type httpTemplate struct {
+	source       []byte
+	etag         string
+	lastModified string
+	expires      time.Time
+}
+
+// Load returns the source of the template, its error is fs.ErrNotExist if the
+// name does not have the prefix or the remote responds with a 404.
+func (l *HTTPLoader) Load(name string) ([]byte, error) {
+	if !strings.HasPrefix(name, l.Prefix) {
+		return nil, fmt.Errorf("lamb: template %s is not remote: %w", name, fs.ErrNotExist)
+	}
+
+	l.mu.Lock()
+	cached, isCached := l.cache[name]
+	l.mu.Unlock()
+
+	if isCached && time.Now().Before(cached.expires) {
+		return cached.source, nil
+	}
+
+	url := l.BaseURL + strings.TrimPrefix(name, l.Prefix)
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if isCached {
+		if cached.etag != "" {
+			req.Header.Set("If-None-Match", cached.etag)
+		}
+
+		if cached.lastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.lastModified)
+		}
+	}
+
+	res, err := l.client().Do(req)
+
+	if err != nil {
+		return l.lastGoodCopy(name, cached, isCached, err)
+	}
+
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusNotModified && isCached:
+		cached.expires = l.expires(res.Header)
+
+		l.store(name, cached)
+
+		return cached.source, nil
+
+	case res.StatusCode == http.StatusOK:
+		maxSize := l.maxSize()
+
+		source, err := io.ReadAll(io.LimitReader(res.Body, maxSize+1))
+
+		if err == nil && int64(len(source)) > maxSize {
+			err = fmt.Errorf("%s is larger than the max size of %d bytes", url, maxSize)
+		}
+
+		if err != nil {
+			return l.lastGoodCopy(name, cached, isCached, err)
+		}
+
+		l.store(name, httpTemplate{
+			source:       source,
+			etag:         res.Header.Get("ETag"),
+			lastModified: res.Header.Get("Last-Modified"),
+			expires:      l.expires(res.Header),
+		})
+
+		return source, nil
+
+	case res.StatusCode == http.StatusNotFound:
+		l.mu.Lock()
+		delete(l.cache, name)
+		l.mu.Unlock()
+
+		return nil, fmt.Errorf("lamb: template %s does not exist at %s: %w", name, url, fs.ErrNotExist)
+
+	default:
+		return l.lastGoodCopy(name, cached, isCached, fmt.Errorf("%s responded with %s", url, res.Status))
+	}
+}
+
+// defaultHTTPLoaderTimeout is the timeout of the requests of an HTTPLoader
+// without a Client nor a Timeout.
+const defaultHTTPLoaderTimeout = 5 * time.Second
+
+// client returns the client of the requests.
+func (l *HTTPLoader) client() *http.Client {
+	if l.Client != nil {
+		return l.Client
+	}
+
+	timeout := l.Timeout
+
+	if timeout == 0 {
+		timeout = defaultHTTPLoaderTimeout
+	}
+
+	return &http.Client{Timeout: timeout}
+}
+
+// defaultHTTPLoaderMaxSize is the max size of a template of an HTTPLoader
+// without a MaxSize.
+const defaultHTTPLoaderMaxSize = 10 << 20
+
+// maxSize returns the max size of a template.
+func (l *HTTPLoader) maxSize() int64 {
+	if l.MaxSize > 0 {
+		return l.MaxSize
+	}
+
+	return defaultHTTPLoaderMaxSize
+}
+
+// lastGoodCopy returns the cached source of the template when the remote
+// fails, or the error if it is not cached.
+func (l *HTTPLoader) lastGoodCopy(name string, cached httpTemplate, isCached bool, err error) ([]byte, error) {
+	if !isCached {
+		return nil, fmt.Errorf("lamb: template %s can not be loaded: %w", name, err)
+	}
+
+	internal.Log().Warn("the remote template can not be loaded, its last good copy is used", "template", name, "error", err)
+
+	return cached.source, nil
+}
+
+// expires returns when the response with the headers must be revalidated.
+func (l *HTTPLoader) expires(header http.Header) time.Time {
+	ttl := l.TTL
+
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+
+		if directive == "no-cache" || directive == "no-store" {
+			return time.Time{}
+		}
+
+		if strings.HasPrefix(directive, "max-age=") {
+			if maxAge, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age=")); err == nil {
+				ttl = time.Duration(maxAge) * time.Second
+			}
+		}
+	}
+
+	return time.Now().Add(ttl)
+}
+
+func (l *HTTPLoader) store(name string, template httpTemplate) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.cache == nil {
+		l.cache = make(map[string]httpTemplate)
+	}
+
+	l.cache[name] = template
+}
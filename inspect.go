@@ -0,0 +1,88 @@
+package lamb
+
+import (
+	"github.com/govel-framework/lamb/ast"
+	"github.com/govel-framework/lamb/internal"
+)
+
+// Outline is the structure of a template, as found by Inspect.
+type Outline struct {
+	Name     string
+	Extends  string     // The layout, empty if the template does not extend one.
+	Sections []Slot     // The sections filled by the template for its layout, in source order.
+	Defines  []Slot     // The slots the template defines for the templates that extend it, in source order.
+	Includes []Included // The templates included, in source order.
+}
+
+// Slot is a section or a define of a template.
+type Slot struct {
+	Name     string
+	Line     int
+	Includes []string // The templates included in the slot.
+}
+
+// Included is an include of a template.
+type Included struct {
+	Template string
+	Line     int
+	In       string // The section or the define that includes it, empty if none.
+}
+
+// Inspect parses the template with the given name, without rendering it, and
+// returns its structure, e.g. for an admin UI or a documentation generator
+// that shows the slots of the layouts and the partials of the pages.
+func Inspect(name string) (*Outline, error) {
+	program, err := internal.ParseTemplate(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	outline := &Outline{Name: name}
+
+	ast.Walk(&outliner{outline: outline}, program)
+
+	return outline, nil
+}
+
+// outliner visits the nodes of a template, in the slot at index of slots if
+// slots is not nil.
+type outliner struct {
+	outline *Outline
+	slots   *[]Slot
+	index   int
+}
+
+func (o *outliner) Visit(node ast.Node) ast.Visitor {
+	switch node := node.(type) {
+	case *ast.ExtendsStatement:
+		o.outline.Extends = node.From
+
+	case *ast.SectionStatement:
+		return o.slot(&o.outline.Sections, node.Name, node.Token.Line)
+
+	case *ast.DefineStatement:
+		return o.slot(&o.outline.Defines, node.Name, node.Token.Line)
+
+	case *ast.IncludeStatement:
+		included := Included{Template: node.File, Line: node.Token.Line}
+
+		if o.slots != nil {
+			slot := &(*o.slots)[o.index]
+
+			included.In = slot.Name
+			slot.Includes = append(slot.Includes, node.File)
+		}
+
+		o.outline.Includes = append(o.outline.Includes, included)
+	}
+
+	return o
+}
+
+// slot adds the slot and returns the visitor of its nodes.
+func (o *outliner) slot(slots *[]Slot, name string, line int) ast.Visitor {
+	*slots = append(*slots, Slot{Name: name, Line: line})
+
+	return &outliner{outline: o.outline, slots: slots, index: len(*slots) - 1}
+}
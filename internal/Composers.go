@@ -0,0 +1,78 @@
+package internal
+
+import (
+	"path"
+	"strings"
+	"sync"
+)
+
+// ComposerFunc adds or changes the vars of a template before it renders.
+type ComposerFunc func(vars map[string]interface{})
+
+type composer struct {
+	pattern string
+	fn      ComposerFunc
+}
+
+var (
+	composers   []composer
+	composersMu sync.RWMutex
+)
+
+// AddComposer registers fn to run every time a template whose name matches
+// pattern renders. Patterns use the template names (e.g. "partials.nav") and
+// support the wildcards of path.Match, where '*' does not cross a '.'.
+func AddComposer(pattern string, fn ComposerFunc) error {
+	if _, err := path.Match(toPath(pattern), ""); err != nil {
+		return err
+	}
+
+	composersMu.Lock()
+	defer composersMu.Unlock()
+
+	composers = append(composers, composer{pattern: pattern, fn: fn})
+
+	return nil
+}
+
+// compose runs the composers that match the template name, in the order they
+// were registered, over a copy of vars that it returns. The vars of the caller
+// are never changed, they may be shared by other renders.
+func compose(fileName string, vars map[string]interface{}) map[string]interface{} {
+	composersMu.RLock()
+	defer composersMu.RUnlock()
+
+	composed := vars
+	copied := false
+
+	for _, c := range composers {
+		if !matchTemplate(c.pattern, fileName) {
+			continue
+		}
+
+		// copy the vars before the first composer changes them
+		if !copied {
+			copied = true
+			composed = make(map[string]interface{}, len(vars))
+
+			for key, value := range vars {
+				composed[key] = value
+			}
+		}
+
+		c.fn(composed)
+	}
+
+	return composed
+}
+
+// matchTemplate reports whether the template name matches the pattern.
+func matchTemplate(pattern, fileName string) bool {
+	matched, _ := path.Match(toPath(pattern), toPath(fileName))
+
+	return matched
+}
+
+func toPath(name string) string {
+	return strings.ReplaceAll(name, ".", "/")
+}
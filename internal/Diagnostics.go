@@ -0,0 +1,81 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+
+	"github.com/govel-framework/lamb/object"
+)
+
+// DiagnosticsEnabled reports whether the renders collect their diagnostics,
+// check the unused vars and report them once they end.
+func DiagnosticsEnabled() bool {
+	return Setting("GOVEL_LAMB_DIAGNOSTICS") == "true"
+}
+
+// passedVars returns the names of the vars passed to the render that it should
+// use, not the ones set by lamb (ctx and the "__" options).
+func passedVars(vars map[string]interface{}) []string {
+	var names []string
+
+	for name := range vars {
+		if name != "ctx" && !strings.HasPrefix(name, "__") {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// checkUnusedVars adds a diagnostic for every passed var the render has not
+// read.
+func checkUnusedVars(diagnostics *object.Diagnostics, file string, passed []string) {
+	for _, name := range passed {
+		if !diagnostics.Used(name) {
+			diagnostics.Add(object.Diagnostic{
+				Kind:    "unused-var",
+				Message: fmt.Sprintf("var %s is passed to the template but it is not used", name),
+				File:    file,
+			})
+		}
+	}
+}
+
+// reportDiagnostics logs the diagnostics of the render and, in the debug mode,
+// adds them to its HTML output as an overlay.
+func reportDiagnostics(diagnostics []object.Diagnostic, output []byte, mode string) []byte {
+	if len(diagnostics) == 0 {
+		return output
+	}
+
+	for _, d := range diagnostics {
+		Log().Warn(d.Message, "kind", d.Kind, "file", d.File, "line", d.Line)
+	}
+
+	if Setting("GOVEL_LAMB_DEBUG") != "true" || mode != "" {
+		return output
+	}
+
+	var overlay bytes.Buffer
+
+	overlay.WriteString(`<div id="lamb-diagnostics" style="position:fixed;right:0;bottom:0;z-index:2147483647;max-width:40em;max-height:50vh;overflow:auto;margin:1em;padding:.75em 1em;background:#fff8e1;border:1px solid #f0c36d;color:#333;font:12px/1.5 monospace">`)
+	fmt.Fprintf(&overlay, "<strong>lamb diagnostics (%d)</strong><ul>", len(diagnostics))
+
+	for _, d := range diagnostics {
+		fmt.Fprintf(&overlay, "<li>%s</li>", html.EscapeString(d.String()))
+	}
+
+	overlay.WriteString("</ul></div>")
+
+	// the overlay goes at the end of the body, or of the output if it has none
+	if i := bytes.LastIndex(output, []byte("</body>")); i != -1 {
+		return append(append(append([]byte{}, output[:i]...), overlay.Bytes()...), output[i:]...)
+	}
+
+	return append(append([]byte{}, output...), overlay.Bytes()...)
+}
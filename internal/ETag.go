@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"sync"
+	"time"
+)
+
+// cachedETag is the ETag of a cache file, valid while the file is not
+// rewritten.
+type cachedETag struct {
+	modTime time.Time
+	etag    string
+}
+
+// cacheETags are the ETags of the cache files by their path, so a cached
+// render is revalidated without reading its file.
+var cacheETags sync.Map
+
+// ETag returns the strong ETag of the content.
+func ETag(content []byte) string {
+	sum := sha256.Sum256(content)
+
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// CachedETag returns the ETag of the cached output of the template, and
+// reports whether the template has a cached output that is still fresh.
+func CachedETag(fileName string) (string, bool) {
+	cacheFile := cacheFilePath(fileName)
+
+	stat, fresh := freshCacheFile(cacheFile)
+
+	if !fresh {
+		return "", false
+	}
+
+	if cached, exists := cacheETags.Load(cacheFile); exists && cached.(cachedETag).modTime.Equal(stat.ModTime()) {
+		return cached.(cachedETag).etag, true
+	}
+
+	content, err := os.ReadFile(cacheFile)
+
+	if err != nil {
+		return "", false
+	}
+
+	return storeETag(cacheFile, stat, content), true
+}
+
+// storeETag computes and stores the ETag of the cache file, stat is the one
+// of the file with the content.
+func storeETag(cacheFile string, stat os.FileInfo, content []byte) string {
+	etag := ETag(content)
+
+	cacheETags.Store(cacheFile, cachedETag{modTime: stat.ModTime(), etag: etag})
+
+	return etag
+}
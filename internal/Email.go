@@ -0,0 +1,328 @@
+package internal
+
+import (
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	styleBlocks  = regexp.MustCompile(`(?is)<style[^>]*>(.*?)</style\s*>`)
+	cssComments  = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	startTags    = regexp.MustCompile(`<([a-zA-Z][a-zA-Z0-9-]*)`)
+	attributes   = regexp.MustCompile(`([a-zA-Z_:][-a-zA-Z0-9_:.]*)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'=<>` + "`" + `]+)))?`)
+	simpleSelect = regexp.MustCompile(`^(\*|[a-zA-Z][a-zA-Z0-9-]*)?((?:[.#][-_a-zA-Z0-9]+)*)$`)
+	selectParts  = regexp.MustCompile(`[.#][^.#]+`)
+	styleAttr    = regexp.MustCompile(`(?i)\sstyle\s*=\s*("[^"]*"|'[^']*'|[^\s>]+)`)
+)
+
+// cssRule is a rule of a style block that can be inlined: its selector is a
+// single element, e.g. p, .button or td.cell.
+type cssRule struct {
+	tag          string
+	id           string
+	classes      []string
+	declarations string
+	specificity  int
+	order        int
+}
+
+// InlineCSS moves the rules of the style blocks of the HTML to the style
+// attributes of the elements they select, for the email clients that ignore
+// the style blocks. The rules that can not be inlined (e.g. @media or the
+// selectors with a combinator or a pseudo-class) are kept in a style block.
+// The declarations of a style attribute win over the inlined ones.
+func InlineCSS(document string) string {
+	var (
+		rules []cssRule
+		kept  strings.Builder
+	)
+
+	for _, block := range styleBlocks.FindAllStringSubmatch(document, -1) {
+		rules = parseCSS(block[1], rules, &kept)
+	}
+
+	if len(rules) == 0 {
+		return document
+	}
+
+	// the first style block keeps the rules that are not inlined
+	first := true
+
+	document = styleBlocks.ReplaceAllStringFunc(document, func(string) string {
+		if !first || kept.Len() == 0 {
+			return ""
+		}
+
+		first = false
+
+		return "<style>" + kept.String() + "</style>"
+	})
+
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].specificity != rules[j].specificity {
+			return rules[i].specificity < rules[j].specificity
+		}
+
+		return rules[i].order < rules[j].order
+	})
+
+	var b strings.Builder
+
+	last := 0
+
+	for _, match := range startTags.FindAllStringSubmatchIndex(document, -1) {
+		if match[0] < last {
+			continue
+		}
+
+		end := tagEnd(document, match[0])
+		tag := strings.ToLower(document[match[2]:match[3]])
+
+		b.WriteString(document[last:match[0]])
+		b.WriteString(inlineTag(document[match[0]:end], tag, rules))
+
+		last = end
+	}
+
+	b.WriteString(document[last:])
+
+	return b.String()
+}
+
+// parseCSS adds the rules of the CSS that can be inlined to rules, and writes
+// the others to kept.
+func parseCSS(css string, rules []cssRule, kept *strings.Builder) []cssRule {
+	css = cssComments.ReplaceAllString(css, "")
+
+	for {
+		css = strings.TrimSpace(css)
+
+		if css == "" {
+			return rules
+		}
+
+		// an at-rule is kept whole, with its nested blocks
+		if css[0] == '@' {
+			end := atRuleEnd(css)
+
+			kept.WriteString(css[:end])
+
+			css = css[end:]
+
+			continue
+		}
+
+		open := strings.IndexByte(css, '{')
+		close := strings.IndexByte(css, '}')
+
+		if open == -1 || close < open {
+			return rules
+		}
+
+		selectors, declarations := css[:open], strings.TrimSpace(css[open+1:close])
+
+		css = css[close+1:]
+
+		for _, selector := range strings.Split(selectors, ",") {
+			selector = strings.TrimSpace(selector)
+
+			if rule, ok := parseSelector(selector); ok {
+				rule.declarations = declarations
+				rule.order = len(rules)
+
+				rules = append(rules, rule)
+			} else if selector != "" {
+				kept.WriteString(selector + "{" + declarations + "}")
+			}
+		}
+	}
+}
+
+// atRuleEnd returns the index after the end of the at-rule at the start of
+// css, a statement (e.g. @import) or a block.
+func atRuleEnd(css string) int {
+	depth := 0
+
+	for i := 0; i < len(css); i++ {
+		switch css[i] {
+		case ';':
+			if depth == 0 {
+				return i + 1
+			}
+
+		case '{':
+			depth++
+
+		case '}':
+			depth--
+
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+
+	return len(css)
+}
+
+// parseSelector parses a selector of a single element, ok is false if it can
+// not be inlined.
+func parseSelector(selector string) (cssRule, bool) {
+	parts := simpleSelect.FindStringSubmatch(selector)
+
+	if parts == nil || selector == "" {
+		return cssRule{}, false
+	}
+
+	rule := cssRule{tag: strings.ToLower(parts[1])}
+
+	if rule.tag == "*" {
+		rule.tag = ""
+	} else if rule.tag != "" {
+		rule.specificity = 1
+	}
+
+	for _, part := range selectParts.FindAllString(parts[2], -1) {
+		if part[0] == '#' {
+			rule.id = part[1:]
+			rule.specificity += 10000
+		} else {
+			rule.classes = append(rule.classes, part[1:])
+			rule.specificity += 100
+		}
+	}
+
+	return rule, true
+}
+
+// inlineTag returns the start tag with the declarations of the rules that
+// select it in its style attribute.
+func inlineTag(tag string, name string, rules []cssRule) string {
+	var (
+		id      string
+		classes []string
+		style   string
+		hasAttr bool
+	)
+
+	inner := strings.TrimSuffix(strings.TrimSuffix(tag[1+len(name):], ">"), "/")
+
+	for _, attr := range attributes.FindAllStringSubmatch(inner, -1) {
+		value := attr[2] + attr[3] + attr[4]
+
+		switch strings.ToLower(attr[1]) {
+		case "id":
+			id = value
+
+		case "class":
+			classes = strings.Fields(value)
+
+		case "style":
+			style = value
+			hasAttr = true
+		}
+	}
+
+	var declarations []string
+
+	for _, rule := range rules {
+		if rule.matches(name, id, classes) {
+			declarations = append(declarations, strings.TrimSuffix(rule.declarations, ";"))
+		}
+	}
+
+	if len(declarations) == 0 || !strings.HasSuffix(tag, ">") {
+		return tag
+	}
+
+	if style = strings.TrimSpace(style); style != "" {
+		declarations = append(declarations, strings.TrimSuffix(style, ";"))
+	}
+
+	inlined := strings.ReplaceAll(strings.Join(declarations, "; "), `"`, "&quot;")
+
+	if hasAttr {
+		tag = styleAttr.ReplaceAllLiteralString(tag, "")
+	}
+
+	end := len(tag) - 1
+
+	if strings.HasSuffix(tag, "/>") {
+		end--
+	}
+
+	return strings.TrimRight(tag[:end], " ") + ` style="` + inlined + `"` + tag[end:]
+}
+
+func (r cssRule) matches(tag, id string, classes []string) bool {
+	if (r.tag != "" && r.tag != tag) || (r.id != "" && r.id != id) {
+		return false
+	}
+
+	for _, class := range r.classes {
+		found := false
+
+		for _, c := range classes {
+			if c == class {
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+var (
+	hiddenBlocks = regexp.MustCompile(`(?is)<(head|style|script)(\s[^>]*)?>.*?</(head|style|script)\s*>`)
+	links        = regexp.MustCompile(`(?is)<a\s[^>]*href\s*=\s*["']([^"']*)["'][^>]*>(.*?)</a\s*>`)
+	lineBreaks   = regexp.MustCompile(`(?i)<br\s*/?>|</tr\s*>`)
+	listItems    = regexp.MustCompile(`(?i)<li(\s[^>]*)?>`)
+	paragraphs   = regexp.MustCompile(`(?i)</(p|div|h[1-6]|table|ul|ol|blockquote)\s*>|<hr[^>]*>`)
+	cells        = regexp.MustCompile(`(?i)</t[dh]\s*>`)
+	allTags      = regexp.MustCompile(`(?s)<[^>]*>`)
+	spaces       = regexp.MustCompile(`[ \t]+`)
+	blankLines   = regexp.MustCompile(`\n{3,}`)
+)
+
+// PlainText returns the text of the HTML of an email, for its plain text
+// alternative: the blocks are separated by blank lines, the items of the
+// lists start with a dash and the links are followed by their URL.
+func PlainText(document string) string {
+	text := hiddenBlocks.ReplaceAllString(document, "")
+
+	// the whitespace of the source is not the one of the text
+	text = strings.Join(strings.Fields(text), " ")
+
+	text = links.ReplaceAllStringFunc(text, func(link string) string {
+		parts := links.FindStringSubmatch(link)
+		label := strings.TrimSpace(allTags.ReplaceAllString(parts[2], ""))
+
+		if label == "" || label == parts[1] {
+			return parts[1]
+		}
+
+		return label + " (" + parts[1] + ")"
+	})
+
+	text = lineBreaks.ReplaceAllString(text, "\n")
+	text = listItems.ReplaceAllString(text, "\n- ")
+	text = paragraphs.ReplaceAllString(text, "\n\n")
+	text = cells.ReplaceAllString(text, " ")
+	text = html.UnescapeString(allTags.ReplaceAllString(text, ""))
+
+	lines := strings.Split(text, "\n")
+
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(spaces.ReplaceAllString(line, " "))
+	}
+
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
@@ -0,0 +1,134 @@
+package internal
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// EncoderFunc returns a writer that compresses what is written to it into w,
+// for the content coding of an encoding (e.g. gzip or br).
+type EncoderFunc func(w io.Writer) io.WriteCloser
+
+type encoding struct {
+	name    string
+	encoder EncoderFunc
+}
+
+// encodings are the encodings of the cached renders, gzip is built in.
+var (
+	encodings   = []encoding{{name: "gzip", encoder: gzipEncoder}}
+	encodingsMu sync.RWMutex
+)
+
+func gzipEncoder(w io.Writer) io.WriteCloser {
+	gz, _ := gzip.NewWriterLevel(w, gzip.BestCompression)
+
+	return gz
+}
+
+// AddEncoding registers the encoder of the encoding with the given name, it
+// replaces the one with the same name. A nil encoder removes the encoding.
+func AddEncoding(name string, encoder EncoderFunc) {
+	encodingsMu.Lock()
+	defer encodingsMu.Unlock()
+
+	registered := make([]encoding, 0, len(encodings)+1)
+
+	for _, e := range encodings {
+		if e.name != name {
+			registered = append(registered, e)
+		}
+	}
+
+	if encoder != nil {
+		registered = append(registered, encoding{name: name, encoder: encoder})
+	}
+
+	encodings = registered
+}
+
+// Encodings returns the names of the encodings, the last registered first.
+func Encodings() []string {
+	encodingsMu.RLock()
+	defer encodingsMu.RUnlock()
+
+	names := make([]string, len(encodings))
+
+	for i, e := range encodings {
+		names[len(encodings)-1-i] = e.name
+	}
+
+	return names
+}
+
+// variantPath returns the path of the cached output of the template
+// compressed with the encoding.
+func variantPath(fileName string, encoding string) string {
+	return Setting("GOVEL_LAMB_CACHE_DIR") + "/." + encoding + "/" + fileName
+}
+
+// writeCacheVariants writes the cached output of the template compressed with
+// every encoding, so it is not compressed again for every request.
+func writeCacheVariants(fileName string, content []byte) {
+	encodingsMu.RLock()
+	registered := encodings
+	encodingsMu.RUnlock()
+
+	for _, e := range registered {
+		var compressed bytes.Buffer
+
+		w := e.encoder(&compressed)
+
+		if _, err := w.Write(content); err != nil {
+			Log().Error("the cache file can not be compressed", "template", fileName, "encoding", e.name, "error", err)
+
+			continue
+		}
+
+		if err := w.Close(); err != nil {
+			Log().Error("the cache file can not be compressed", "template", fileName, "encoding", e.name, "error", err)
+
+			continue
+		}
+
+		file := variantPath(fileName, e.name)
+
+		os.MkdirAll(filepath.Dir(file), os.ModePerm)
+
+		if err := writeCacheFile(file, compressed.Bytes()); err != nil {
+			Log().Error("the cache file can not be written", "template", fileName, "encoding", e.name, "error", err)
+		}
+	}
+}
+
+// CachedVariant returns the cached output of the template compressed with the
+// encoding, and reports whether the template has a fresh cached output that
+// is compressed.
+func CachedVariant(fileName string, encoding string) ([]byte, bool) {
+	stat, fresh := freshCacheFile(cacheFilePath(fileName))
+
+	if !fresh {
+		return nil, false
+	}
+
+	file := variantPath(fileName, encoding)
+
+	// a variant older than the cached output is the one of a previous render
+	variantStat, err := os.Stat(file)
+
+	if err != nil || variantStat.ModTime().Before(stat.ModTime()) {
+		return nil, false
+	}
+
+	content, err := os.ReadFile(file)
+
+	if err != nil {
+		return nil, false
+	}
+
+	return content, true
+}
@@ -0,0 +1,30 @@
+package internal
+
+import "sync"
+
+var (
+	formatter   func(v interface{}) (string, bool)
+	formatterMu sync.RWMutex
+)
+
+// SetFormatter sets the function that formats the values written to the
+// output, nil restores the default formatting.
+func SetFormatter(fn func(v interface{}) (string, bool)) {
+	formatterMu.Lock()
+	defer formatterMu.Unlock()
+
+	formatter = fn
+}
+
+// Format formats the value with the formatter, false means the value is left
+// to the default formatting.
+func Format(v interface{}) (string, bool) {
+	formatterMu.RLock()
+	defer formatterMu.RUnlock()
+
+	if formatter == nil {
+		return "", false
+	}
+
+	return formatter(v)
+}
@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/govel-framework/lamb/object"
+)
+
+// GeneratedFunc renders a template generated to Go by lamb generate, it
+// returns the output or an error like the evaluator does for a program.
+type GeneratedFunc func(env *object.Environment) interface{}
+
+var (
+	generated   = make(map[string]GeneratedFunc)
+	generatedMu sync.RWMutex
+)
+
+// AddGenerated registers the generated template with the given name, it is
+// rendered instead of loading and parsing the template. A nil fn removes it.
+func AddGenerated(name string, fn GeneratedFunc) {
+	generatedMu.Lock()
+	defer generatedMu.Unlock()
+
+	if fn == nil {
+		delete(generated, generatedName(name))
+
+		return
+	}
+
+	generated[generatedName(name)] = fn
+}
+
+func getGenerated(name string) (GeneratedFunc, bool) {
+	generatedMu.RLock()
+	defer generatedMu.RUnlock()
+
+	fn, exists := generated[generatedName(name)]
+
+	return fn, exists
+}
+
+// safeGenerated renders the generated template, a panic is returned as an
+// error.
+func safeGenerated(fn GeneratedFunc, env *object.Environment) (evaluated interface{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			evaluated = fmt.Errorf("%s: the template can not be rendered: %v", env.FileName, r)
+		}
+	}()
+
+	return fn(env)
+}
+
+// generatedName returns the name of the template with dots, the renders can
+// name it with dots or slashes.
+func generatedName(name string) string {
+	return strings.ReplaceAll(name, "/", ".")
+}
@@ -0,0 +1,31 @@
+package internal
+
+import "sync"
+
+var (
+	getterMethod   func(field string) string
+	getterMethodMu sync.RWMutex
+)
+
+// SetGetterMethod sets the function that maps an unexported field to the name
+// of its getter method, nil disables the getters.
+func SetGetterMethod(fn func(field string) string) {
+	getterMethodMu.Lock()
+	defer getterMethodMu.Unlock()
+
+	getterMethod = fn
+}
+
+// GetterMethod returns the name of the getter method of an unexported field.
+func GetterMethod(field string) (string, bool) {
+	getterMethodMu.RLock()
+	defer getterMethodMu.RUnlock()
+
+	if getterMethod == nil {
+		return "", false
+	}
+
+	name := getterMethod(field)
+
+	return name, name != ""
+}
@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"os"
+	"sync"
+)
+
+// HealthStats is a snapshot of the state of the template loader.
+type HealthStats struct {
+	LoadedTemplates int                 // The number of distinct templates parsed successfully.
+	CacheEntries    int                 // The number of rendered templates in the cache dir.
+	ParseErrors     map[string][]string // The last parse errors of every template that failed.
+	BaseDirError    error               // Why the templates dir can not be read, if it can't.
+}
+
+var (
+	loadedTemplates = make(map[string]bool)
+	parseErrors     = make(map[string][]string)
+	healthMu        sync.Mutex
+)
+
+// recordParse records the result of parsing a template.
+func recordParse(file string, errs []string) {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+
+	if len(errs) != 0 {
+		parseErrors[file] = errs
+		return
+	}
+
+	loadedTemplates[file] = true
+	delete(parseErrors, file)
+}
+
+// Health returns the current HealthStats.
+func Health() HealthStats {
+	healthMu.Lock()
+
+	stats := HealthStats{
+		LoadedTemplates: len(loadedTemplates),
+		ParseErrors:     make(map[string][]string, len(parseErrors)),
+	}
+
+	for file, errs := range parseErrors {
+		stats.ParseErrors[file] = errs
+	}
+
+	healthMu.Unlock()
+
+	if cacheDir := Setting("GOVEL_LAMB_CACHE_DIR"); cacheDir != "" {
+		entries, _ := os.ReadDir(cacheDir)
+
+		// the compressed variants are in directories
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				stats.CacheEntries++
+			}
+		}
+	}
+
+	baseDir := Setting("GOVEL_LAMB_BASE_DIR")
+
+	if baseDir == "" {
+		baseDir = "."
+	}
+
+	if _, err := os.ReadDir(baseDir); err != nil {
+		stats.BaseDirError = err
+	}
+
+	return stats
+}
@@ -0,0 +1,29 @@
+package internal
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/govel-framework/lamb/ast"
+)
+
+// Hooks are called around the evaluation of every statement, e.g. to measure
+// where the render time goes.
+type Hooks struct {
+	Before func(node ast.Node, file string)
+	After  func(node ast.Node, file string, elapsed time.Duration, err error)
+}
+
+// hooks is read on every statement, an atomic pointer keeps the cost of
+// having no hooks to a single load.
+var hooks atomic.Pointer[Hooks]
+
+// SetHooks sets the hooks of every render, nil removes them.
+func SetHooks(h *Hooks) {
+	hooks.Store(h)
+}
+
+// CurrentHooks returns the hooks, nil if there are none.
+func CurrentHooks() *Hooks {
+	return hooks.Load()
+}
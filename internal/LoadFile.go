@@ -1,11 +1,14 @@
 package internal
 
 import (
-	"errors"
+	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/govel-framework/lamb/ast"
@@ -16,108 +19,471 @@ import (
 
 type evalFunc func(ast.Node, *object.Environment) interface{}
 
-// LoadFile parse the file received and writes the result in the io.Writer.
-func LoadFile(fileName string, vars map[string]interface{}, out io.Writer, evaluator evalFunc, env object.Environment) error {
+// modeExtensions are the extensions of the template files that set the output
+// mode of their template.
+var modeExtensions = []struct {
+	extension string
+	mode      string
+}{
+	{".lamb.xml", "xml"},
+	{".lamb.json", "json"},
+}
+
+// templatePaths caches the paths of the template files that exist, by base
+// dir and name, so the renders do not stat the files of their templates again.
+// The FileLoader resolves the path of a template again if its file is removed
+// (e.g. renamed to .lamb.xml).
+var templatePaths sync.Map
+
+type templatePathKey struct {
+	baseDir string
+	name    string
+}
+
+// TemplatePath returns the path of the template file with the given name.
+func TemplatePath(fileName string) string {
+	path, _ := resolveTemplatePath(fileName)
+
+	return path
+}
+
+// resolveTemplatePath returns the path of the template file with the given
+// name and whether it exists.
+func resolveTemplatePath(fileName string) (string, bool) {
 	// get the base directory from the env.
-	baseDir := os.Getenv("GOVEL_LAMB_BASE_DIR")
+	baseDir := Setting("GOVEL_LAMB_BASE_DIR")
+
+	key := templatePathKey{baseDir, fileName}
+
+	if cached, exists := templatePaths.Load(key); exists {
+		return cached.(string), true
+	}
+
+	// replace every '.' in the file path with '/'
+	file := baseDir + strings.ReplaceAll(fileName, ".", "/")
+
+	if _, err := os.Stat(file + ".lamb.html"); err == nil {
+		templatePaths.Store(key, file+".lamb.html")
+
+		return file + ".lamb.html", true
+	}
+
+	// a template with an output mode is a .lamb.xml or a .lamb.json file
+	for _, e := range modeExtensions {
+		if _, err := os.Stat(file + e.extension); err == nil {
+			templatePaths.Store(key, file+e.extension)
+
+			return file + e.extension, true
+		}
+	}
+
+	return file + ".lamb.html", false
+}
 
-	// replace every '.' in the file path with '/' and append '.lamb.html' at the end.
-	file := strings.ReplaceAll(fileName, ".", "/") + ".lamb.html"
-	file = baseDir + file
+// forgetTemplatePath removes the cached path of the template file, it
+// reports whether there was one.
+func forgetTemplatePath(fileName string) bool {
+	_, forgotten := templatePaths.LoadAndDelete(templatePathKey{Setting("GOVEL_LAMB_BASE_DIR"), fileName})
+
+	return forgotten
+}
+
+// FileMode returns the output mode set by the extension of the template file,
+// empty if it has none.
+func FileMode(file string) string {
+	for _, e := range modeExtensions {
+		if strings.HasSuffix(file, e.extension) {
+			return e.mode
+		}
+	}
+
+	return ""
+}
+
+// LoadFile parse the file received and writes the result in the io.Writer,
+// the name of the file is already resolved (see ResolveRender).
+func LoadFile(fileName string, vars map[string]interface{}, out io.Writer, evaluator evalFunc, env object.Environment) error {
+	if vars == nil {
+		vars = make(map[string]interface{})
+	}
+
+	// the context of the render (e.g. of the request), the includes keep it
+	// with the span of the load as their parent
+	parent := env.Context
+
+	if parent == nil {
+		parent, _ = vars["__context"].(context.Context)
+	}
+
+	ctx, span := StartSpan(parent, "lamb.load")
+	defer span.End()
+
+	env.Context = ctx
+
+	// the resolver of the render, the includes and the layout keep it (the
+	// name of the template is already resolved)
+	if resolve := RenderResolver(vars); resolve != nil && env.Resolve == nil {
+		env.Resolve = resolve
+	}
+
+	written := &countingWriter{Writer: out}
+	start := time.Now()
+
+	err := loadWrapped(fileName, vars, written, evaluator, env)
+
+	elapsed := time.Since(start)
+
+	observeLoad(fileName, elapsed, written.written, err)
+
+	if slow, _ := time.ParseDuration(Setting("GOVEL_LAMB_SLOW_RENDER")); slow > 0 && elapsed > slow {
+		Log().Warn("slow render", "template", fileName, "elapsed", elapsed)
+	}
+
+	span.SetAttribute("lamb.template", fileName)
+	span.SetAttribute("lamb.bytes", written.written)
+
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	return err
+}
+
+// loadWrapped loads the file inside the middlewares that match it.
+func loadWrapped(fileName string, vars map[string]interface{}, out io.Writer, evaluator evalFunc, env object.Environment) error {
+	wrappers := matchingMiddlewares(fileName)
+
+	if len(wrappers) == 0 {
+		return loadFile(fileName, vars, out, evaluator, env)
+	}
+
+	// run the template inside its middlewares, the first registered is the outermost
+	next := func() (string, error) {
+		var buf bytes.Buffer
+
+		err := loadFile(fileName, vars, &buf, evaluator, env)
+
+		return buf.String(), err
+	}
+
+	for i := len(wrappers) - 1; i >= 0; i-- {
+		next = wrapNext(wrappers[i], fileName, vars, next)
+	}
+
+	result, err := next()
+
+	if err != nil {
+		return err
+	}
+
+	out.Write([]byte(result))
+
+	return nil
+}
+
+func wrapNext(middleware MiddlewareFunc, fileName string, vars map[string]interface{}, next func() (string, error)) func() (string, error) {
+	return func() (string, error) {
+		return middleware(fileName, vars, next)
+	}
+}
+
+func loadFile(fileName string, vars map[string]interface{}, out io.Writer, evaluator evalFunc, env object.Environment) error {
+	file := TemplateFile(fileName)
+
+	// the diagnostics of the render, collected to check the vars it does not
+	// use once it ends
+	topLevel := len(env.Includes) == 0 && !env.IsExtends
+	reported := false
+
+	if topLevel && env.Diagnostics == nil && DiagnosticsEnabled() {
+		env.Diagnostics = &object.Diagnostics{}
+		reported = true
+	}
+
+	var passed []string
+
+	if topLevel && env.Diagnostics != nil {
+		passed = passedVars(vars)
+	}
+
+	// let the view composers inject their vars
+	vars = compose(fileName, vars)
 
 	// add the vars
 	for key, value := range vars {
 		env.Set(key, value)
 	}
 
+	// check the sandbox, a template rendered in a sandbox can not leave it
+	if profile, isString := vars["__sandbox"].(string); isString && env.Sandbox == nil {
+		sandbox, exists := getSandbox(profile)
+
+		if !exists {
+			return fmt.Errorf("lamb: sandbox %s does not exist", profile)
+		}
+
+		env.Sandbox = sandbox
+	}
+
+	// the locale of the render, the includes keep it
+	if locale, isString := vars["__locale"].(string); isString && env.Locale == "" {
+		env.Locale = locale
+	}
+
 	// check the cache
 	var cache string
 
-	if cacheValue, exists := vars["__cache"]; exists && fmt.Sprintf("%T", cacheValue) == "string" {
-		cache = fmt.Sprintf("%s", cacheValue)
+	// the output of a fragment is not the one of the template
+	if cacheValue, isString := vars["__cache"].(string); isString && env.Fragment == nil {
+		cache = cacheValue
 	}
 
-	cacheDir := os.Getenv("GOVEL_LAMB_CACHE_DIR")
+	cacheDir := Setting("GOVEL_LAMB_CACHE_DIR")
 
-	cacheFile := cacheDir + "/" + fileName
+	cacheFile := cacheFilePath(fileName)
 
-	// check if the file exists
-	if stat, statError := os.Stat(cacheFile); statError == nil && cache != "" {
-		cacheTime, _ := time.ParseDuration(os.Getenv("GOVEL_LAMB_CACHE_TIME"))
+	if cache != "" {
+		hit, err := loadCache(env.Context, fileName, cacheFile, out)
 
-		// check if the file is older than the cache time
-		modTime := stat.ModTime()
+		if hit || err != nil {
+			return err
+		}
+	}
 
-		if time.Since(modTime) > cacheTime {
-			// delete the file
-			os.Remove(cacheFile)
+	// a template generated to Go is not loaded nor parsed
+	fn, isGenerated := getGenerated(fileName)
 
-		} else {
-			// read the file
-			content, err := os.ReadFile(cacheFile)
+	var program *ast.Program
 
-			if err != nil {
-				return err
-			}
+	if !isGenerated {
+		var err error
 
-			out.Write(content)
+		program, err = loadProgram(fileName)
 
-			return nil
+		if err != nil {
+			return err
 		}
 
+		// the file of the template may have moved since its path was cached
+		file = TemplateFile(fileName)
 	}
 
 	// set the file name
 	env.FileName = file
+	env.Mode = FileMode(file)
+
+	var evaluated interface{}
+
+	if isGenerated {
+		evaluated = safeGenerated(fn, &env)
+	} else {
+		evaluated = safeEval(evaluator, program, &env)
+	}
+
+	if evaluated != nil {
+
+		if err, isError := evaluated.(error); isError {
+			return err
+		}
+
+		output := []byte(fmt.Sprintf("%s", evaluated))
+
+		// the output of the render is minified and filtered once it is whole,
+		// not the one of its includes nor its layout
+		if topLevel {
+			if MinifyEnabled() {
+				output = []byte(Minify(string(output)))
+			}
+
+			output = filterOutput(fileName, output)
+		}
+
+		// the overlay of the diagnostics is not cached
+		written := output
+
+		if topLevel && env.Diagnostics != nil {
+			checkUnusedVars(env.Diagnostics, file, passed)
+
+			if reported {
+				written = reportDiagnostics(env.Diagnostics.List(), output, env.Mode)
+			}
+		}
+
+		out.Write(written)
+
+		// write the cache in background, unless lamb is being closed
+		if cache == "all" && startWrite() {
+			go func() {
+				defer endWrite()
 
-	content, err := os.ReadFile(file)
+				// create the cache directory
+				if _, err := os.Stat(cacheDir); os.IsNotExist(err) {
+					os.Mkdir(cacheDir, os.ModePerm)
+				}
+
+				// write the file and its compressed variants
+				if err := writeCacheFile(cacheFile, output); err != nil {
+					Log().Error("the cache file can not be written", "template", fileName, "error", err)
+				} else {
+					writeCacheVariants(fileName, output)
+				}
+			}()
+		}
+	}
+
+	return nil
+}
+
+// ParseTemplate loads and parses the template, the error is the first parse
+// error.
+func ParseTemplate(name string) (*ast.Program, error) {
+	content, err := LoadSource(name)
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	l := lexer.New(string(content))
+	return parseSource(name, content)
+}
+
+// parseSource parses the source of the template with the given name.
+func parseSource(name string, content []byte) (*ast.Program, error) {
+	file := TemplateFile(name)
 
-	p := parser.New(l)
+	program, errors := safeParse(string(content))
 
-	program := p.ParseProgram()
+	recordParse(file, errors)
 
-	if len(p.Errors()) != 0 {
+	if len(errors) != 0 {
 
-		for _, e := range p.Errors() {
-			return fmt.Errorf("%s: %s\n", file, e)
+		for _, e := range errors {
+			return nil, fmt.Errorf("%s: %s\n", file, e)
 		}
 	}
 
-	evaluated := evaluator(program, &env)
+	return program, nil
+}
 
-	if evaluated != nil {
+// safeParse parses the template, a panic of the lexer or the parser is
+// returned as a parse error, so no template can crash the application.
+func safeParse(content string) (program *ast.Program, errors []string) {
+	defer func() {
+		if r := recover(); r != nil {
+			program, errors = nil, []string{fmt.Sprintf("the template can not be parsed: %v", r)}
+		}
+	}()
+
+	p := parser.New(lexer.New(content))
+
+	program = p.ParseProgram()
 
-		if _, isError := evaluated.(error); isError {
-			return errors.New(fmt.Sprintf("%s", evaluated))
+	return program, p.Errors()
+}
+
+// safeEval evaluates the program, a panic of the evaluator is returned as an
+// error.
+func safeEval(evaluator evalFunc, program *ast.Program, env *object.Environment) (evaluated interface{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			evaluated = fmt.Errorf("%s: the template can not be evaluated: %v", env.FileName, r)
 		}
+	}()
 
-		out.Write([]byte(fmt.Sprintf("%s", evaluated)))
+	return evaluator(program, env)
+}
 
-		go func() {
-			// check if the cache is enabled
-			if cache != "" {
-				switch cache {
-				case "all":
-					// create the cache directory
-					if _, err := os.Stat(cacheDir); os.IsNotExist(err) {
-						os.Mkdir(cacheDir, os.ModePerm)
-					}
+// loadCache writes the cached output of the template if there is one that is
+// not older than the cache time, and reports whether there was.
+func loadCache(ctx context.Context, fileName string, cacheFile string, out io.Writer) (bool, error) {
+	_, span := StartSpan(ctx, "lamb.cache")
+	defer span.End()
 
-					// write the file
-					err = os.WriteFile(cacheFile, []byte(fmt.Sprintf("%s", evaluated)), 0644)
+	span.SetAttribute("lamb.template", fileName)
 
-					if err != nil {
-						panic(err)
-					}
-				}
-			}
-		}()
+	stat, fresh := freshCacheFile(cacheFile)
+
+	if !fresh {
+		span.SetAttribute("lamb.cache.hit", false)
+		observeCache(fileName, false)
+
+		return false, nil
+	}
+
+	// read the file
+	content, err := os.ReadFile(cacheFile)
+
+	if err != nil {
+		span.RecordError(err)
+
+		return false, err
+	}
+
+	out.Write(content)
+
+	storeETag(cacheFile, stat, content)
+
+	span.SetAttribute("lamb.cache.hit", true)
+	observeCache(fileName, true)
+	span.SetAttribute("lamb.bytes", len(content))
+
+	return true, nil
+}
+
+// cacheFilePath returns the path of the cache file of the template.
+func cacheFilePath(fileName string) string {
+	return Setting("GOVEL_LAMB_CACHE_DIR") + "/" + fileName
+}
+
+// freshCacheFile returns the stat of the cache file and reports whether it
+// exists and is not older than the cache time, an old file is deleted.
+func freshCacheFile(cacheFile string) (os.FileInfo, bool) {
+	stat, err := os.Stat(cacheFile)
+
+	if err != nil {
+		return nil, false
+	}
+
+	cacheTime, _ := time.ParseDuration(Setting("GOVEL_LAMB_CACHE_TIME"))
+
+	if time.Since(stat.ModTime()) > cacheTime {
+		os.Remove(cacheFile)
+
+		return nil, false
+	}
+
+	return stat, true
+}
+
+// writeCacheFile writes the file through a temporary file, so a reader (or a
+// process exit) never sees it half written.
+func writeCacheFile(file string, content []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*.tmp")
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+
+		return err
+	}
+
+	os.Chmod(tmp.Name(), 0644)
+
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return err
+	}
+
+	if stat, err := os.Stat(file); err == nil {
+		storeETag(file, stat, content)
 	}
 
 	return nil
@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// Loader returns the source of the templates by their name, e.g. users.show.
+type Loader interface {
+	Load(name string) ([]byte, error)
+}
+
+// FileLoader loads the templates from the files of the base dir.
+type FileLoader struct{}
+
+func (FileLoader) Load(name string) ([]byte, error) {
+	content, err := os.ReadFile(TemplatePath(name))
+
+	// the file was removed or renamed since its path was cached
+	if errors.Is(err, fs.ErrNotExist) && forgetTemplatePath(name) {
+		content, err = os.ReadFile(TemplatePath(name))
+	}
+
+	return content, err
+}
+
+var (
+	loader   Loader = FileLoader{}
+	loaderMu sync.RWMutex
+)
+
+// SetLoader sets the loader of the templates, nil restores the FileLoader.
+func SetLoader(l Loader) {
+	loaderMu.Lock()
+	defer loaderMu.Unlock()
+
+	if l == nil {
+		l = FileLoader{}
+	}
+
+	loader = l
+}
+
+// CurrentLoader returns the loader of the templates.
+func CurrentLoader() Loader {
+	loaderMu.RLock()
+	defer loaderMu.RUnlock()
+
+	return loader
+}
+
+// TemplateFile returns how the template is named in the errors: its path if
+// it is loaded from a file, its name otherwise.
+func TemplateFile(name string) string {
+	if _, isFile := CurrentLoader().(FileLoader); isFile {
+		path, exists := resolveTemplatePath(name)
+
+		// a registered template is not a file unless a file overrides it
+		if _, registered := registeredTemplate(name); registered && !exists {
+			return name
+		}
+
+		return path
+	}
+
+	return name
+}
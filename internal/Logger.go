@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+// Logger receives the diagnostics of lamb, the args are key-value pairs (so a
+// *slog.Logger is a Logger).
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+var (
+	logger   Logger = stdLogger{}
+	loggerMu sync.RWMutex
+)
+
+// SetLogger sets the logger of lamb, nil restores the default one, which
+// writes the warnings and errors with the log package.
+func SetLogger(l Logger) {
+	loggerMu.Lock()
+	defer loggerMu.Unlock()
+
+	if l == nil {
+		l = stdLogger{}
+	}
+
+	logger = l
+}
+
+// Log returns the logger of lamb.
+func Log() Logger {
+	loggerMu.RLock()
+	defer loggerMu.RUnlock()
+
+	return logger
+}
+
+type stdLogger struct{}
+
+func (stdLogger) Debug(msg string, args ...interface{}) {}
+func (stdLogger) Info(msg string, args ...interface{})  {}
+
+func (stdLogger) Warn(msg string, args ...interface{}) {
+	log.Print(stdMessage("WARN", msg, args))
+}
+
+func (stdLogger) Error(msg string, args ...interface{}) {
+	log.Print(stdMessage("ERROR", msg, args))
+}
+
+// stdMessage formats a message as "lamb: LEVEL msg key=value ...".
+func stdMessage(level string, msg string, args []interface{}) string {
+	var out strings.Builder
+
+	out.WriteString("lamb: " + level + " " + msg)
+
+	for i := 0; i+1 < len(args); i += 2 {
+		fmt.Fprintf(&out, " %v=%v", args[i], args[i+1])
+	}
+
+	return out.String()
+}
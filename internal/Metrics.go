@@ -0,0 +1,48 @@
+package internal
+
+import (
+	"sync"
+	"time"
+)
+
+// Metrics receives the measures of the renders.
+type Metrics interface {
+	// ObserveLoad is called after every template is loaded, elapsed includes
+	// the loads of its layout and includes.
+	ObserveLoad(template string, elapsed time.Duration, bytes int, err error)
+
+	// ObserveCache is called after every lookup in the cache.
+	ObserveCache(template string, hit bool)
+}
+
+var (
+	metrics   Metrics
+	metricsMu sync.RWMutex
+)
+
+// SetMetrics sets the metrics of every render, nil disables them.
+func SetMetrics(m Metrics) {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+
+	metrics = m
+}
+
+func currentMetrics() Metrics {
+	metricsMu.RLock()
+	defer metricsMu.RUnlock()
+
+	return metrics
+}
+
+func observeLoad(template string, elapsed time.Duration, bytes int, err error) {
+	if m := currentMetrics(); m != nil {
+		m.ObserveLoad(template, elapsed, bytes, err)
+	}
+}
+
+func observeCache(template string, hit bool) {
+	if m := currentMetrics(); m != nil {
+		m.ObserveCache(template, hit)
+	}
+}
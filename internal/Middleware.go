@@ -0,0 +1,59 @@
+package internal
+
+import (
+	"path"
+	"sync"
+)
+
+// MiddlewareFunc wraps the render of a template: next renders it (or the next
+// middleware), so a middleware can stop the render (e.g. a permission check)
+// or change its output (e.g. wrap it in a layout).
+type MiddlewareFunc func(fileName string, vars map[string]interface{}, next func() (string, error)) (string, error)
+
+type middleware struct {
+	pattern string
+	fn      MiddlewareFunc
+}
+
+var (
+	middlewares   []middleware
+	middlewaresMu sync.RWMutex
+)
+
+// AddMiddleware registers fn to wrap every template whose name matches
+// pattern, patterns work like the ones of AddComposer.
+func AddMiddleware(pattern string, fn MiddlewareFunc) error {
+	if _, err := path.Match(toPath(pattern), ""); err != nil {
+		return err
+	}
+
+	middlewaresMu.Lock()
+	defer middlewaresMu.Unlock()
+
+	middlewares = append(middlewares, middleware{pattern: pattern, fn: fn})
+
+	return nil
+}
+
+// matchingMiddlewares returns the middlewares that wrap the template, in the
+// order they were registered.
+func matchingMiddlewares(fileName string) []MiddlewareFunc {
+	middlewaresMu.RLock()
+	defer middlewaresMu.RUnlock()
+
+	var matching []MiddlewareFunc
+
+	for _, m := range middlewares {
+		if matchTemplate(m.pattern, fileName) {
+			matching = append(matching, m.fn)
+		}
+	}
+
+	return matching
+}
+
+// HasMiddlewares reports whether a middleware wraps the template, so its
+// output is not the cached one.
+func HasMiddlewares(fileName string) bool {
+	return len(matchingMiddlewares(fileName)) != 0
+}
@@ -0,0 +1,130 @@
+package internal
+
+import "strings"
+
+// preservedTags are the elements whose content is kept as is by Minify.
+var preservedTags = []string{"pre", "textarea", "script", "style"}
+
+// MinifyEnabled reports whether the output of the renders is minified.
+func MinifyEnabled() bool {
+	return Setting("GOVEL_LAMB_MINIFY") == "true"
+}
+
+// Minify removes the whitespace between the tags of the HTML and its comments
+// (but the conditional ones), and collapses the whitespace of its text to a
+// single space. The tags and the content of the pre, textarea, script and
+// style elements are kept as is.
+func Minify(html string) string {
+	var b strings.Builder
+
+	b.Grow(len(html))
+
+	for i := 0; i < len(html); {
+		switch c := html[i]; {
+		case strings.HasPrefix(html[i:], "<!--"):
+			end := len(html)
+
+			if close := strings.Index(html[i+4:], "-->"); close != -1 {
+				end = i + 4 + close + 3
+			}
+
+			if strings.HasPrefix(html[i:], "<!--[if") {
+				b.WriteString(html[i:end])
+			}
+
+			i = end
+
+		case c == '<' && i+1 < len(html) && isTagStart(html[i+1]):
+			end := tagEnd(html, i)
+
+			if tag := preservedTag(html[i:end]); tag != "" {
+				end = closingTagEnd(html, end, tag)
+			}
+
+			b.WriteString(html[i:end])
+
+			i = end
+
+		case isSpace(c):
+			j := i
+
+			for j < len(html) && isSpace(html[j]) {
+				j++
+			}
+
+			// the whitespace between two tags (or at the ends) is removed
+			afterTag := i == 0 || html[i-1] == '>'
+			beforeTag := j == len(html) || html[j] == '<'
+
+			if !afterTag || !beforeTag {
+				b.WriteByte(' ')
+			}
+
+			i = j
+
+		default:
+			b.WriteByte(c)
+
+			i++
+		}
+	}
+
+	return b.String()
+}
+
+func isTagStart(c byte) bool {
+	return c == '/' || c == '!' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// tagEnd returns the index after the end of the tag that starts at start, the
+// > in the quoted attribute values do not end it.
+func tagEnd(html string, start int) int {
+	var quote byte
+
+	for i := start + 1; i < len(html); i++ {
+		switch c := html[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+
+		case c == '"' || c == '\'':
+			quote = c
+
+		case c == '>':
+			return i + 1
+		}
+	}
+
+	return len(html)
+}
+
+// preservedTag returns the name of the preserved element opened by the tag,
+// empty if it is not one.
+func preservedTag(tag string) string {
+	for _, name := range preservedTags {
+		if len(tag) > len(name)+1 && strings.EqualFold(tag[1:len(name)+1], name) {
+			if next := tag[len(name)+1]; next == '>' || next == '/' || isSpace(next) {
+				return name
+			}
+		}
+	}
+
+	return ""
+}
+
+// closingTagEnd returns the index after the closing tag of the element from
+// start, or the end of the HTML if it is not closed.
+func closingTagEnd(html string, start int, name string) int {
+	closing := strings.Index(strings.ToLower(html[start:]), "</"+name)
+
+	if closing == -1 {
+		return len(html)
+	}
+
+	return tagEnd(html, start+closing)
+}
@@ -0,0 +1,32 @@
+package internal
+
+import "sync"
+
+// OutputFilterFunc rewrites the output of the render of a template.
+type OutputFilterFunc func(name string, html []byte) []byte
+
+var (
+	outputFilters   []OutputFilterFunc
+	outputFiltersMu sync.RWMutex
+)
+
+// AddOutputFilter registers fn to rewrite the output of every render, after
+// the filters registered before it.
+func AddOutputFilter(fn OutputFilterFunc) {
+	outputFiltersMu.Lock()
+	defer outputFiltersMu.Unlock()
+
+	outputFilters = append(outputFilters, fn)
+}
+
+// filterOutput runs the output filters on the output of the template.
+func filterOutput(name string, html []byte) []byte {
+	outputFiltersMu.RLock()
+	defer outputFiltersMu.RUnlock()
+
+	for _, fn := range outputFilters {
+		html = fn(name, html)
+	}
+
+	return html
+}
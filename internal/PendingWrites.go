@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"context"
+	"sync"
+)
+
+// the cache files that are being written in background
+var (
+	pendingMu sync.Mutex
+	pending   int           // How many files are being written.
+	idle      chan struct{} // Closed when the last file is written.
+	closers   int           // How many WaitPendingWrites are waiting, no write starts meanwhile.
+)
+
+// startWrite registers a cache file written in background, it returns false
+// if lamb is being closed and the file must not be written.
+func startWrite() bool {
+	pendingMu.Lock()
+	defer pendingMu.Unlock()
+
+	if closers > 0 {
+		return false
+	}
+
+	if pending == 0 {
+		idle = make(chan struct{})
+	}
+
+	pending++
+
+	return true
+}
+
+// endWrite registers the end of a write started by startWrite.
+func endWrite() {
+	pendingMu.Lock()
+	defer pendingMu.Unlock()
+
+	pending--
+
+	if pending == 0 {
+		close(idle)
+	}
+}
+
+// WaitPendingWrites blocks until every cache file being written in background
+// is done or ctx is done, no write starts while it waits.
+func WaitPendingWrites(ctx context.Context) error {
+	pendingMu.Lock()
+
+	if pending == 0 {
+		pendingMu.Unlock()
+
+		return nil
+	}
+
+	done := idle
+	closers++
+
+	pendingMu.Unlock()
+
+	defer func() {
+		pendingMu.Lock()
+		closers--
+		pendingMu.Unlock()
+	}()
+
+	select {
+	case <-done:
+		return nil
+
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
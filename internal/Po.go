@@ -0,0 +1,84 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parsePo parses the messages of a gettext .po file, the msgid of each entry
+// is its key. Entries without translation are left out.
+func parsePo(content []byte) (map[string]string, error) {
+	messages := make(map[string]string)
+
+	var msgid, msgstr *strings.Builder
+	var current *strings.Builder
+
+	flush := func() {
+		if msgid != nil && msgstr != nil && msgid.Len() > 0 && msgstr.Len() > 0 {
+			messages[msgid.String()] = msgstr.String()
+		}
+
+		msgid, msgstr, current = nil, nil, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	line := 0
+
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case text == "" || strings.HasPrefix(text, "#"):
+			continue
+
+		case strings.HasPrefix(text, "msgctxt "):
+			flush()
+			current = nil
+
+			continue
+
+		case strings.HasPrefix(text, "msgid "):
+			if msgstr != nil {
+				flush()
+			}
+
+			msgid = &strings.Builder{}
+			current = msgid
+			text = strings.TrimPrefix(text, "msgid ")
+
+		case strings.HasPrefix(text, "msgstr "):
+			msgstr = &strings.Builder{}
+			current = msgstr
+			text = strings.TrimPrefix(text, "msgstr ")
+
+		case strings.HasPrefix(text, `"`):
+			// continuation of the last string
+
+		default:
+			// plurals and other keywords are not supported yet
+			current = nil
+
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+
+		value, err := strconv.Unquote(text)
+
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid string %s", line, text)
+		}
+
+		current.WriteString(value)
+	}
+
+	flush()
+
+	return messages, scanner.Err()
+}
@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"sync"
+
+	"github.com/govel-framework/lamb/ast"
+)
+
+// programs are the parsed and transformed templates by name, cached when
+// lamb.compile is on so the IR the evaluator compiles for a program is reused
+// by the next renders. An entry is used while the source of the template and
+// the transforms do not change.
+var (
+	programs   = make(map[string]cachedProgram)
+	programsMu sync.RWMutex
+)
+
+type cachedProgram struct {
+	source     string
+	transforms int
+	program    *ast.Program
+}
+
+// loadProgram loads, parses and transforms the template with the given name.
+func loadProgram(name string) (*ast.Program, error) {
+	if Setting("GOVEL_LAMB_COMPILE") != "true" {
+		program, err := ParseTemplate(name)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return transform(program), nil
+	}
+
+	content, err := LoadSource(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	version := transformsVersion()
+
+	programsMu.RLock()
+	cached, exists := programs[name]
+	programsMu.RUnlock()
+
+	if exists && cached.source == string(content) && cached.transforms == version {
+		return cached.program, nil
+	}
+
+	program, err := parseSource(name, content)
+
+	if err != nil {
+		return nil, err
+	}
+
+	program = transform(program)
+
+	programsMu.Lock()
+	programs[name] = cachedProgram{source: string(content), transforms: version, program: program}
+	programsMu.Unlock()
+
+	return program, nil
+}
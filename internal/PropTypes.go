@@ -0,0 +1,30 @@
+package internal
+
+import (
+	"reflect"
+	"sync"
+)
+
+var (
+	propTypes   = make(map[string]reflect.Type)
+	propTypesMu sync.RWMutex
+)
+
+// AddPropType registers the Go type of the props declared with the given type
+// name.
+func AddPropType(name string, t reflect.Type) {
+	propTypesMu.Lock()
+	defer propTypesMu.Unlock()
+
+	propTypes[name] = t
+}
+
+// PropType returns the Go type registered with the given name.
+func PropType(name string) (reflect.Type, bool) {
+	propTypesMu.RLock()
+	defer propTypesMu.RUnlock()
+
+	t, exists := propTypes[name]
+
+	return t, exists
+}
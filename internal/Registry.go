@@ -0,0 +1,53 @@
+package internal
+
+import (
+	"errors"
+	"io/fs"
+	"sync"
+)
+
+// registered are the sources of the templates registered in memory, by name.
+var (
+	registered   = make(map[string]string)
+	registeredMu sync.RWMutex
+)
+
+// RegisterTemplate registers the source of the template with the given name,
+// an empty source removes it.
+func RegisterTemplate(name, source string) {
+	registeredMu.Lock()
+	defer registeredMu.Unlock()
+
+	if source == "" {
+		delete(registered, name)
+
+		return
+	}
+
+	registered[name] = source
+}
+
+// registeredTemplate returns the source of the registered template.
+func registeredTemplate(name string) (string, bool) {
+	registeredMu.RLock()
+	defer registeredMu.RUnlock()
+
+	source, exists := registered[name]
+
+	return source, exists
+}
+
+// LoadSource returns the source of the template from the loader or, if the
+// loader does not have it, from the registered templates, so the templates of
+// the loader override them.
+func LoadSource(name string) ([]byte, error) {
+	content, err := CurrentLoader().Load(name)
+
+	if err != nil && errors.Is(err, fs.ErrNotExist) {
+		if source, exists := registeredTemplate(name); exists {
+			return []byte(source), nil
+		}
+	}
+
+	return content, err
+}
@@ -0,0 +1,58 @@
+package internal
+
+import "sync"
+
+// ResolverFunc returns the name of the template to load instead of the
+// requested one, ok is false to load the requested one.
+type ResolverFunc func(requested string) (resolved string, ok bool)
+
+var (
+	resolver   ResolverFunc
+	resolverMu sync.RWMutex
+)
+
+// SetResolver sets the resolver of the names of every render, nil removes it.
+func SetResolver(fn ResolverFunc) {
+	resolverMu.Lock()
+	defer resolverMu.Unlock()
+
+	resolver = fn
+}
+
+// ResolveName returns the name of the template to load for the requested one:
+// the one of the resolver of the render (resolve, it can be nil), or else the
+// one of the global resolver, or else the requested one.
+func ResolveName(name string, resolve func(string) (string, bool)) string {
+	if resolve != nil {
+		if resolved, ok := resolve(name); ok {
+			return resolved
+		}
+	}
+
+	resolverMu.RLock()
+	fn := resolver
+	resolverMu.RUnlock()
+
+	if fn != nil {
+		if resolved, ok := fn(name); ok {
+			return resolved
+		}
+	}
+
+	return name
+}
+
+// RenderResolver returns the resolver of a render with the vars, the var
+// "__resolve", nil if it has none.
+func RenderResolver(vars map[string]interface{}) func(string) (string, bool) {
+	resolve, _ := vars["__resolve"].(func(string) (string, bool))
+
+	return resolve
+}
+
+// ResolveRender returns the name of the template loaded by a render with the
+// vars. A render resolves its name once, before its cache and its middlewares
+// are looked up, and LoadFile loads the resolved name.
+func ResolveRender(name string, vars map[string]interface{}) string {
+	return ResolveName(name, RenderResolver(vars))
+}
@@ -0,0 +1,30 @@
+package internal
+
+import (
+	"sync"
+
+	"github.com/govel-framework/lamb/object"
+)
+
+var (
+	sandboxes   = make(map[string]*object.Sandbox)
+	sandboxesMu sync.RWMutex
+)
+
+// AddSandbox registers a sandbox profile, templates are rendered in it when
+// the var "__sandbox" is set to its name.
+func AddSandbox(name string, sandbox object.Sandbox) {
+	sandboxesMu.Lock()
+	defer sandboxesMu.Unlock()
+
+	sandboxes[name] = &sandbox
+}
+
+func getSandbox(name string) (*object.Sandbox, bool) {
+	sandboxesMu.RLock()
+	defer sandboxesMu.RUnlock()
+
+	sandbox, exists := sandboxes[name]
+
+	return sandbox, exists
+}
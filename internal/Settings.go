@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// settings are the config of lamb by the name of the env var that can also set
+// them (e.g. GOVEL_LAMB_BASE_DIR). They are replaced as a whole, so the
+// renders read them without locks and never see half of a config.
+var (
+	settings   atomic.Pointer[map[string]string]
+	settingsMu sync.Mutex
+)
+
+// SetSettings replaces the settings.
+func SetSettings(s map[string]string) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+
+	copied := make(map[string]string, len(s))
+
+	for name, value := range s {
+		copied[name] = value
+	}
+
+	settings.Store(&copied)
+}
+
+// SetSetting changes one setting.
+func SetSetting(name string, value string) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+
+	copied := make(map[string]string)
+
+	if current := settings.Load(); current != nil {
+		for name, value := range *current {
+			copied[name] = value
+		}
+	}
+
+	copied[name] = value
+
+	settings.Store(&copied)
+}
+
+// Setting returns the setting with the given name, or the env var with the
+// same name if it is not set.
+func Setting(name string) string {
+	value, _ := LookupSetting(name)
+
+	return value
+}
+
+// LookupSetting returns the setting with the given name, or the env var with
+// the same name, and reports whether either is set.
+func LookupSetting(name string) (string, bool) {
+	if current := settings.Load(); current != nil {
+		if value, exists := (*current)[name]; exists {
+			return value, true
+		}
+	}
+
+	return os.LookupEnv(name)
+}
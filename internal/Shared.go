@@ -0,0 +1,26 @@
+package internal
+
+import "sync"
+
+var (
+	shared   = make(map[string]interface{})
+	sharedMu sync.RWMutex
+)
+
+// Share sets a variable that is visible to every template.
+func Share(name string, value interface{}) {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+
+	shared[name] = value
+}
+
+// GetShared returns the value of a shared variable.
+func GetShared(name string) (interface{}, bool) {
+	sharedMu.RLock()
+	defer sharedMu.RUnlock()
+
+	value, exists := shared[name]
+
+	return value, exists
+}
@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// Span is a unit of work of a render, e.g. the load of a template.
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+// Tracer starts the spans of the renders.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+var (
+	tracer   Tracer
+	tracerMu sync.RWMutex
+)
+
+// SetTracer sets the tracer of every render, nil disables the tracing.
+func SetTracer(t Tracer) {
+	tracerMu.Lock()
+	defer tracerMu.Unlock()
+
+	tracer = t
+}
+
+// StartSpan starts a span as a child of the span in ctx. Without a tracer it
+// returns ctx unchanged and a span that does nothing.
+func StartSpan(ctx context.Context, name string) (context.Context, Span) {
+	tracerMu.RLock()
+	t := tracer
+	tracerMu.RUnlock()
+
+	if t == nil {
+		return ctx, noopSpan{}
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return t.Start(ctx, name)
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(key string, value interface{}) {}
+func (noopSpan) RecordError(err error)                      {}
+func (noopSpan) End()                                       {}
+
+// countingWriter counts the bytes written to the io.Writer.
+type countingWriter struct {
+	io.Writer
+	written int
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.Writer.Write(p)
+	cw.written += n
+
+	return n, err
+}
@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"sync"
+
+	"github.com/govel-framework/lamb/ast"
+)
+
+// TransformFunc rewrites the program of a template after it is parsed.
+type TransformFunc func(program *ast.Program) *ast.Program
+
+var (
+	transforms   []TransformFunc
+	transformsMu sync.RWMutex
+)
+
+// transformsAdded counts the transforms ever added, the cached programs are
+// transformed again when it changes.
+var transformsAdded int
+
+// AddTransform registers fn to run on the program of every template, after
+// the transforms registered before it.
+func AddTransform(fn TransformFunc) {
+	transformsMu.Lock()
+	defer transformsMu.Unlock()
+
+	transforms = append(transforms, fn)
+	transformsAdded++
+}
+
+func transformsVersion() int {
+	transformsMu.RLock()
+	defer transformsMu.RUnlock()
+
+	return transformsAdded
+}
+
+// transform runs the transforms on the program, a transform that returns nil
+// leaves the program unchanged.
+func transform(program *ast.Program) *ast.Program {
+	transformsMu.RLock()
+	defer transformsMu.RUnlock()
+
+	if len(transforms) == 0 {
+		return program
+	}
+
+	for _, fn := range transforms {
+		if transformed := fn(program); transformed != nil {
+			program = transformed
+		}
+	}
+
+	// the transforms may have added or changed HTML literals
+	if program.Chunks == nil {
+		program.Chunks = ast.NewChunkTable()
+	}
+
+	ast.Inspect(program, func(node ast.Node) bool {
+		if html, isHtml := node.(*ast.HtmlLiteral); isHtml {
+			html.Chunk = program.Chunks.Intern(html.Value)
+		}
+
+		return true
+	})
+
+	return program
+}
@@ -0,0 +1,157 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/language"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	catalogs   = make(map[string]map[string]string)
+	catalogsMu sync.RWMutex
+)
+
+// AddTranslations adds the messages of a locale, replacing the ones with the
+// same key.
+func AddTranslations(locale string, messages map[string]string) {
+	catalogsMu.Lock()
+	defer catalogsMu.Unlock()
+
+	locale = normalizeLocale(locale)
+
+	if catalogs[locale] == nil {
+		catalogs[locale] = make(map[string]string)
+	}
+
+	for key, message := range messages {
+		catalogs[locale][key] = message
+	}
+}
+
+// LoadTranslations loads every catalog of the dir, the name of each file is
+// its locale: en.json, es-MX.yaml, fr.po... The keys of nested JSON and YAML
+// objects are joined with dots, e.g. "checkout.title".
+func LoadTranslations(dir string) error {
+	files, err := os.ReadDir(dir)
+
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+
+		ext := filepath.Ext(file.Name())
+		locale := strings.TrimSuffix(file.Name(), ext)
+
+		if _, err := language.Parse(locale); err != nil {
+			continue // not a catalog
+		}
+
+		content, err := os.ReadFile(filepath.Join(dir, file.Name()))
+
+		if err != nil {
+			return err
+		}
+
+		messages := make(map[string]string)
+
+		switch ext {
+		case ".json", ".yaml", ".yml":
+			// YAML is a superset of JSON
+			var catalog map[interface{}]interface{}
+
+			if err := yaml.Unmarshal(content, &catalog); err != nil {
+				return fmt.Errorf("%s: %s", file.Name(), err)
+			}
+
+			flattenMessages("", catalog, messages)
+
+		case ".po":
+			if messages, err = parsePo(content); err != nil {
+				return fmt.Errorf("%s: %s", file.Name(), err)
+			}
+
+		default:
+			continue
+		}
+
+		AddTranslations(locale, messages)
+	}
+
+	return nil
+}
+
+// flattenMessages adds the messages of the catalog to messages, joining the
+// keys of the nested objects with dots.
+func flattenMessages(prefix string, catalog map[interface{}]interface{}, messages map[string]string) {
+	for key, value := range catalog {
+		name := fmt.Sprintf("%s%v", prefix, key)
+
+		if nested, isMap := value.(map[interface{}]interface{}); isMap {
+			flattenMessages(name+".", nested, messages)
+
+			continue
+		}
+
+		messages[name] = fmt.Sprintf("%v", value)
+	}
+}
+
+// normalizeLocale returns the canonical form of the locale, e.g. "es-mx" and
+// "es_MX" are "es-MX".
+func normalizeLocale(locale string) string {
+	tag, err := language.Parse(strings.ReplaceAll(locale, "_", "-"))
+
+	if err != nil {
+		return locale
+	}
+
+	return tag.String()
+}
+
+// Translate returns the message of the key in the locale. When the locale has
+// no such message, its language (e.g. "es" for "es-MX") and then the fallback
+// locale are tried.
+func Translate(locale, fallback, key string) (string, bool) {
+	catalogsMu.RLock()
+	defer catalogsMu.RUnlock()
+
+	for _, candidate := range localeCandidates(locale, fallback) {
+		if message, exists := catalogs[candidate][key]; exists {
+			return message, true
+		}
+	}
+
+	return "", false
+}
+
+// localeCandidates returns the locales where a message is looked up, in
+// order.
+func localeCandidates(locales ...string) []string {
+	var candidates []string
+
+	for _, locale := range locales {
+		if locale == "" {
+			continue
+		}
+
+		locale = normalizeLocale(locale)
+		candidates = append(candidates, locale)
+
+		if tag, err := language.Parse(locale); err == nil {
+			if base, confidence := tag.Base(); confidence != language.No && base.String() != locale {
+				candidates = append(candidates, base.String())
+			}
+		}
+	}
+
+	return candidates
+}
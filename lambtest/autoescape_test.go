@@ -0,0 +1,64 @@
+package lambtest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/govel-framework/lamb"
+	"github.com/govel-framework/lamb/internal"
+)
+
+func TestOutputModeFromExtension(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"feeds/rss.lamb.xml":     `<rss>{? for post in posts ?}<item>{? include("feeds.item", {"post": post}) ?}</item>{? endfor ?}</rss>`,
+		"feeds/item.lamb.html":   `<title>{? post ?}</title>`,
+		"api/post.lamb.json":     `{"title": {? posts[0] ?}}`,
+		"pages/feed.lamb.html":   `<p>{? posts[0] ?}</p>`,
+		"pages/feed.lamb.xml":    `ignored`,
+		"feeds/escaped.lamb.xml": `<rss><item><title>{? posts[0] ?}</title></item></rss>`,
+	}
+
+	for name, content := range files {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), os.ModePerm)
+
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lamb.UseLoader(nil)
+	internal.SetSettings(map[string]string{"GOVEL_LAMB_BASE_DIR": dir + "/"})
+
+	t.Cleanup(func() {
+		internal.SetSettings(nil)
+	})
+
+	vars := map[string]interface{}{"posts": []interface{}{"Fish & <Chips>"}}
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"feeds.escaped", `<rss><item><title>Fish &amp; &lt;Chips&gt;</title></item></rss>`},
+		{"feeds.rss", `<rss><item><title>Fish & <Chips></title></item></rss>`},
+		{"api.post", `{"title": "Fish \u0026 \u003cChips\u003e"}`},
+		{"pages.feed", `<p>Fish & <Chips></p>`},
+	}
+
+	for _, tt := range tests {
+		if got := Render(t, tt.name, vars); got != tt.expected {
+			t.Errorf("%s: wrong output. expected=%q, got=%q", tt.name, tt.expected, got)
+		}
+	}
+
+	// the path of a template is resolved again once its file is removed
+	os.Remove(filepath.Join(dir, "pages", "feed.lamb.html"))
+	os.WriteFile(filepath.Join(dir, "pages", "feed.lamb.xml"), []byte(`<p>{? posts[0] ?}</p>`), 0644)
+
+	if got := Render(t, "pages.feed", vars); got != `<p>Fish &amp; &lt;Chips&gt;</p>` {
+		t.Errorf("the moved template is not rendered as XML. got=%q", got)
+	}
+}
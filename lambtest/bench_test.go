@@ -0,0 +1,53 @@
+package lambtest
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/govel-framework/lamb"
+	"github.com/govel-framework/lamb/internal"
+)
+
+// BenchmarkRenderPage renders a representative page: a layout, a loop over
+// rows with an include per row and a lambda.
+func BenchmarkRenderPage(b *testing.B) {
+	benchmarkRenderPage(b)
+}
+
+// BenchmarkRenderPageCompiled renders the page with lamb.compile on.
+func BenchmarkRenderPageCompiled(b *testing.B) {
+	previous := internal.Setting("GOVEL_LAMB_COMPILE")
+
+	internal.SetSetting("GOVEL_LAMB_COMPILE", "true")
+	b.Cleanup(func() { internal.SetSetting("GOVEL_LAMB_COMPILE", previous) })
+
+	benchmarkRenderPage(b)
+}
+
+func benchmarkRenderPage(b *testing.B) {
+	Use(b, Templates{
+		"layouts.app": `<html><head><title>{? title ?}</title></head><body>{? define("content") ?}{? end ?}</body></html>`,
+		"partials.row": `<tr><td>{? row.id ?}</td><td>{? row.name ?}</td>` +
+			`{? if row.active ?}<td>active</td>{? else ?}<td>inactive</td>{? endif ?}</tr>`,
+		"users.index": `{? extends("layouts.app") ?}{? section("content") ?}<h1>{? title ?}</h1><table>` +
+			`{? for row in rows ?}{? include("partials.row", {"row": row}) ?}{? endfor ?}</table>` +
+			`<p>{? len(filter(rows, r => r.active)) ?} active</p>{? endsection ?}`,
+	})
+
+	rows := make([]interface{}, 20)
+
+	for i := range rows {
+		rows[i] = map[string]interface{}{"id": i, "name": fmt.Sprintf("user %d", i), "active": i%2 == 0}
+	}
+
+	vars := map[string]interface{}{"title": "Users", "rows": rows}
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if err := lamb.RenderTo(io.Discard, "users.index", vars); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package lambtest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/govel-framework/lamb"
+	"github.com/govel-framework/lamb/internal"
+)
+
+func TestClose(t *testing.T) {
+	Use(t, Templates{"pages.cached": `<p>{? name ?}</p>`})
+
+	internal.SetSettings(map[string]string{"GOVEL_LAMB_CACHE_DIR": t.TempDir(), "GOVEL_LAMB_CACHE_TIME": "1m"})
+
+	// an encoding that stalls the cache writes until it is released
+	release := make(chan struct{})
+
+	lamb.RegisterEncoding("stalled", func(w io.Writer) io.WriteCloser {
+		<-release
+
+		return nopCloser{w}
+	})
+
+	t.Cleanup(func() {
+		lamb.RegisterEncoding("stalled", nil)
+		internal.SetSettings(nil)
+	})
+
+	render := func(vars map[string]interface{}) {
+		if err := lamb.RenderHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil), "pages.cached", vars, 0); err != nil {
+			t.Errorf("RenderHTTP returned an error: %s", err)
+		}
+	}
+
+	// a render without cache has nothing to wait for
+	render(map[string]interface{}{"name": "Ada"})
+
+	if err := lamb.Close(context.Background()); err != nil {
+		t.Fatalf("Close returned an error: %s", err)
+	}
+
+	render(map[string]interface{}{"name": "Ada", "__cache": "all"})
+
+	goroutines := runtime.NumGoroutine()
+
+	for i := 0; i < 10; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+
+		err := lamb.Close(ctx)
+
+		cancel()
+
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Close of a stalled write returned %v, want %v", err, context.DeadlineExceeded)
+		}
+	}
+
+	if leaked := runtime.NumGoroutine() - goroutines; leaked > 0 {
+		t.Errorf("Close leaked %d goroutines", leaked)
+	}
+
+	close(release)
+
+	if err := lamb.Close(context.Background()); err != nil {
+		t.Fatalf("Close returned an error: %s", err)
+	}
+
+	// renders and closes running together
+	var wg sync.WaitGroup
+
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+
+			render(map[string]interface{}{"name": "Ada", "__cache": "all"})
+		}()
+
+		go func() {
+			defer wg.Done()
+
+			if err := lamb.Close(context.Background()); err != nil {
+				t.Errorf("Close returned an error: %s", err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if err := lamb.Close(context.Background()); err != nil {
+		t.Fatalf("Close returned an error: %s", err)
+	}
+}
@@ -0,0 +1,40 @@
+package lambtest
+
+import (
+	"testing"
+
+	"github.com/govel-framework/lamb/internal"
+)
+
+// TestCompiledRenders renders templates with and without lamb.compile and
+// checks that a compiled template is compiled again when its source changes.
+func TestCompiledRenders(t *testing.T) {
+	templates := Templates{
+		"layouts.app":   `<main>{? define("content") ?}{? end ?}</main>`,
+		"partials.item": `<li>{? if loop.first ?}first {? endif ?}{? item ?}</li>`,
+		"users.index": `{? extends("layouts.app") ?}{? section("content") ?}<ul>` +
+			`{? for item in items ?}{? include("partials.item", {"item": item, "loop": loop}) ?}{? endfor ?}</ul>` +
+			`{? var big = filter(items, x => x > 1) ?}{? len(big) ?} big{? endsection ?}`,
+	}
+
+	Use(t, templates)
+
+	vars := map[string]interface{}{"items": []interface{}{1, 2, 3}}
+
+	walked := Render(t, "users.index", vars)
+
+	previous := internal.Setting("GOVEL_LAMB_COMPILE")
+
+	internal.SetSetting("GOVEL_LAMB_COMPILE", "true")
+	t.Cleanup(func() { internal.SetSetting("GOVEL_LAMB_COMPILE", previous) })
+
+	for i := 0; i < 2; i++ {
+		if compiled := Render(t, "users.index", vars); compiled != walked {
+			t.Errorf("render %d - the compiled template renders %q, want=%q", i, compiled, walked)
+		}
+	}
+
+	templates["partials.item"] = `<li>{? item * 10 ?}</li>`
+
+	AssertHTMLEqual(t, Render(t, "users.index", vars), "<main><ul><li>10</li><li>20</li><li>30</li></ul>2 big</main>")
+}
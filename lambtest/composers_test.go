@@ -0,0 +1,63 @@
+package lambtest
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/govel-framework/lamb"
+)
+
+func init() {
+	lamb.Composer("composers.*", func(vars map[string]interface{}) {
+		if _, exists := vars["user"]; !exists {
+			vars["user"] = "guest"
+		}
+
+		vars["menu"] = "home"
+	})
+}
+
+// TestComposers renders the same vars from many goroutines, run it with -race.
+func TestComposers(t *testing.T) {
+	Use(t, Templates{"composers.nav": `{? user ?}:{? menu ?}`})
+
+	vars := map[string]interface{}{}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			var out strings.Builder
+
+			if err := lamb.RenderTo(&out, "composers.nav", vars); err != nil {
+				t.Errorf("RenderTo returned an error: %s", err)
+			} else if out.String() != "guest:home" {
+				t.Errorf("wrong output. got=%q", out.String())
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	// the vars of the caller are not changed, nor shared by the next renders
+	if len(vars) != 0 {
+		t.Errorf("the composers changed the vars of the caller: %v", vars)
+	}
+
+	var out strings.Builder
+
+	if err := lamb.RenderTo(&out, "composers.nav", map[string]interface{}{"user": "Ada"}); err != nil || out.String() != "Ada:home" {
+		t.Errorf("wrong output. got=%q, err=%v", out.String(), err)
+	}
+
+	out.Reset()
+
+	if err := lamb.RenderTo(&out, "composers.nav", nil); err != nil || out.String() != "guest:home" {
+		t.Errorf("wrong output of nil vars. got=%q, err=%v", out.String(), err)
+	}
+}
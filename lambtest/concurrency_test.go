@@ -0,0 +1,72 @@
+package lambtest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/govel-framework/lamb"
+	"github.com/govel-framework/lamb/evaluator"
+)
+
+// TestConcurrentRenders renders templates with includes, extends and custom
+// functions from many goroutines, run it with -race.
+func TestConcurrentRenders(t *testing.T) {
+	Use(t, Templates{
+		"layouts.app":   `<main>{? define("content") ?}{? end ?}</main><footer>{? app ?}</footer>`,
+		"partials.item": `<li>{? shout(item) ?}</li>`,
+		"users.index": `{? extends("layouts.app") ?}{? section("content") ?}<h1>{? title ?}</h1><ul>` +
+			`{? for item in items ?}{? include("partials.item", {"item": item}) ?}{? endfor ?}</ul>{? endsection ?}`,
+	})
+
+	if _, exists := evaluator.Registry.Get("shout"); !exists {
+		lamb.LoadFuncs(map[string]interface{}{"shout": strings.ToUpper})
+	}
+
+	lamb.Share("app", "lamb")
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			title := fmt.Sprintf("users %d", i)
+			items := []interface{}{fmt.Sprintf("a%d", i), fmt.Sprintf("b%d", i)}
+
+			var out strings.Builder
+
+			// Render can not be used, t.Fatal must be called from the goroutine of the test
+			if err := lamb.RenderTo(&out, "users.index", map[string]interface{}{"title": title, "items": items}); err != nil {
+				t.Errorf("render %d: %s", i, err)
+
+				return
+			}
+
+			output := out.String()
+			expected := fmt.Sprintf("<main><h1>users %d</h1><ul><li>A%d</li><li>B%d</li></ul></main><footer>lamb</footer>", i, i, i)
+
+			if output != expected {
+				t.Errorf("render %d, expected=%q, got=%q", i, expected, output)
+			}
+		}(i)
+	}
+
+	// the shared state can change while rendering
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			lamb.Share(fmt.Sprintf("other%d", i), i)
+			lamb.Init(map[interface{}]interface{}{"lamb": map[interface{}]interface{}{"dir": ""}})
+			lamb.AddTranslations("en", map[string]string{fmt.Sprintf("key%d", i): "value"})
+		}(i)
+	}
+
+	wg.Wait()
+}
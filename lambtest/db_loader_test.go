@@ -0,0 +1,136 @@
+package lambtest
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/govel-framework/lamb"
+)
+
+// dbRows are the rows of the table of templates of the fake driver, by name.
+var dbRows = map[string][2]string{}
+
+// dbQueries counts the queries of the fake driver.
+var dbQueries []string
+
+func init() {
+	sql.Register("lambtest", dbDriver{})
+}
+
+// dbDriver is a database/sql driver that runs the queries of the DBLoader on
+// dbRows.
+type dbDriver struct{}
+
+func (dbDriver) Open(string) (driver.Conn, error) { return dbConn{}, nil }
+
+type dbConn struct{}
+
+func (dbConn) Prepare(query string) (driver.Stmt, error) { return dbStmt{query}, nil }
+func (dbConn) Close() error                              { return nil }
+func (dbConn) Begin() (driver.Tx, error)                 { return nil, errors.New("no transactions") }
+
+type dbStmt struct {
+	query string
+}
+
+func (dbStmt) Close() error                               { return nil }
+func (dbStmt) NumInput() int                              { return 1 }
+func (dbStmt) Exec([]driver.Value) (driver.Result, error) { return nil, errors.New("no exec") }
+
+func (s dbStmt) Query(args []driver.Value) (driver.Rows, error) {
+	dbQueries = append(dbQueries, s.query+" "+args[0].(string))
+
+	columns := strings.Split(strings.TrimSpace(s.query[len("SELECT "):strings.Index(s.query, " FROM")]), ", ")
+	row, exists := dbRows[args[0].(string)]
+
+	rows := &dbResult{columns: columns}
+
+	if exists {
+		values := map[string]string{"body": row[0], "rev": row[1]}
+
+		for _, column := range columns {
+			rows.values = append(rows.values, values[column])
+		}
+	}
+
+	return rows, nil
+}
+
+type dbResult struct {
+	columns []string
+	values  []driver.Value
+	read    bool
+}
+
+func (r *dbResult) Columns() []string { return r.columns }
+func (r *dbResult) Close() error      { return nil }
+
+func (r *dbResult) Next(dest []driver.Value) error {
+	if r.read || r.values == nil {
+		return io.EOF
+	}
+
+	r.read = true
+
+	copy(dest, r.values)
+
+	return nil
+}
+
+func TestDBLoader(t *testing.T) {
+	db, err := sql.Open("lambtest", "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dbRows["pages.home"] = [2]string{`{? extends("layouts.app") ?}{? section("content") ?}v1{? endsection ?}`, "1"}
+
+	loader := &lamb.DBLoader{DB: db, Table: "pages", SourceColumn: "body", VersionColumn: "rev", Placeholder: "$1"}
+
+	layouts := Templates{"layouts.app": `<main>{? define("content") ?}{? end ?}</main>`}
+
+	Use(t, layouts)
+
+	lamb.UseLoader(lamb.ChainLoader{loader, layouts})
+
+	if got := Render(t, "pages.home", nil); got != "<main>v1</main>" {
+		t.Errorf("wrong output. got=%q", got)
+	}
+
+	if expected := "SELECT body, rev FROM pages WHERE name = $1 pages.home"; len(dbQueries) == 0 || dbQueries[0] != expected {
+		t.Errorf("wrong queries. expected=%q first, got=%q", expected, dbQueries)
+	}
+
+	// the version is checked, the source is read again when it changes
+	dbQueries = nil
+
+	Render(t, "pages.home", nil)
+
+	dbRows["pages.home"] = [2]string{`v2`, "2"}
+
+	if got := Render(t, "pages.home", nil); got != "v2" {
+		t.Errorf("the new version is not loaded. got=%q", got)
+	}
+
+	expected := []string{
+		"SELECT rev FROM pages WHERE name = $1 pages.home",
+		"SELECT body, rev FROM pages WHERE name = $1 layouts.app",
+		"SELECT rev FROM pages WHERE name = $1 pages.home",
+		"SELECT body, rev FROM pages WHERE name = $1 pages.home",
+	}
+
+	if strings.Join(dbQueries, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("wrong queries.\nexpected=%q\ngot=%q", expected, dbQueries)
+	}
+
+	// a template that is not in the table is loaded by the next loader
+	if _, err := loader.Load("layouts.app"); err == nil || !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("a missing template is not fs.ErrNotExist. got=%v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package lambtest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/govel-framework/lamb"
+	"github.com/govel-framework/lamb/evaluator"
+	"github.com/govel-framework/lamb/internal"
+	"github.com/govel-framework/lamb/object"
+)
+
+func init() {
+	evaluator.Registry.Register("lambtest_count", &object.Builtin{
+		Fn:         func(args ...interface{}) interface{} { return len(args[0].([]interface{})) },
+		Deprecated: "use len",
+	})
+}
+
+func TestRenderDiagnostics(t *testing.T) {
+	Use(t, Templates{
+		"pages.home":     `{? extends("layouts.app") ?}{? section("content") ?}{? title ?}{? include("pages.items", {"items": items}) ?}{? endsection ?}{? section("sidebar") ?}unused{? endsection ?}`,
+		"pages.items":    `{? for item in items ?}.{? endfor ?}{? lambtest_count(items) ?}`,
+		"layouts.app":    `<main>{? define("content") ?}{? end ?}</main>`,
+		"pages.all_used": `{? title ?}`,
+	})
+
+	internal.SetSettings(map[string]string{"GOVEL_LAMB_LARGE_LOOP": "2"})
+
+	t.Cleanup(func() {
+		internal.SetSettings(nil)
+	})
+
+	var out strings.Builder
+
+	vars := map[string]interface{}{
+		"title":     "Home",
+		"items":     []interface{}{1, 2, 3},
+		"unused":    "x",
+		"__cache":   "",
+		"ctx":       nil,
+		"lazyTitle": lamb.Lazy(func() interface{} { return "never" }),
+	}
+
+	diagnostics, err := lamb.RenderDiagnostics(&out, "pages.home", vars)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the unused sections are no longer an error
+	if out.String() != "<main>Home...3</main>" {
+		t.Errorf("wrong output. got=%q", out.String())
+	}
+
+	expected := []string{
+		"pages.items:1: for loop over 3 elements, more than 2",
+		"pages.items:1: lambtest_count is deprecated: use len",
+		"pages.home:1: section sidebar is not defined by the layout layouts.app",
+		"pages.home: var lazyTitle is passed to the template but it is not used",
+		"pages.home: var unused is passed to the template but it is not used",
+	}
+
+	var got []string
+
+	for _, d := range diagnostics {
+		got = append(got, d.String())
+	}
+
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("wrong diagnostics.\nexpected=%q\ngot=%q", expected, got)
+	}
+
+	if diagnostics, _ := lamb.RenderDiagnostics(&strings.Builder{}, "pages.all_used", map[string]interface{}{"title": "x"}); len(diagnostics) != 0 {
+		t.Errorf("a clean render has diagnostics. got=%v", diagnostics)
+	}
+}
+
+func TestDiagnosticsOverlay(t *testing.T) {
+	Use(t, Templates{"pages.show": `<html><body>{? title ?}</body></html>`})
+
+	internal.SetSettings(map[string]string{"GOVEL_LAMB_DIAGNOSTICS": "true", "GOVEL_LAMB_DEBUG": "true"})
+
+	t.Cleanup(func() {
+		internal.SetSettings(nil)
+	})
+
+	got := Render(t, "pages.show", map[string]interface{}{"title": "Home", "user": nil})
+
+	if !strings.HasPrefix(got, "<html><body>Home<div id=\"lamb-diagnostics\"") || !strings.HasSuffix(got, "</div></body></html>") {
+		t.Errorf("the overlay is not at the end of the body. got=%q", got)
+	}
+
+	if !strings.Contains(got, "<li>pages.show: var user is passed to the template but it is not used</li>") {
+		t.Errorf("the overlay does not have the diagnostic. got=%q", got)
+	}
+
+	// without the debug mode they are only logged
+	internal.SetSettings(map[string]string{"GOVEL_LAMB_DIAGNOSTICS": "true"})
+
+	if got := Render(t, "pages.show", map[string]interface{}{"title": "x", "user": nil}); got != "<html><body>x</body></html>" {
+		t.Errorf("the overlay is added without the debug mode. got=%q", got)
+	}
+}
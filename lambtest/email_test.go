@@ -0,0 +1,47 @@
+package lambtest
+
+import (
+	"testing"
+
+	"github.com/govel-framework/lamb"
+)
+
+func TestRenderEmail(t *testing.T) {
+	Use(t, Templates{
+		"emails.welcome": `<html><head><title>Welcome</title>
+<style>
+	/* the base styles */
+	p, td { color: #333; }
+	.button { background: blue; color: white }
+	a.button#main { padding: 4px; }
+	a:hover { color: red; }
+	@media (max-width: 600px) { p { font-size: 12px; } }
+</style></head>
+<body>
+	<p>Hello {? name ?},</p>
+	<p style="color: black">Thanks for joining &amp; welcome.</p>
+	<a id="main" class="button" href="https://example.com/start">Get started</a>
+	<ul><li>one</li><li>two</li></ul>
+	<table><tr><td>a</td><td>b</td></tr></table>
+</body></html>`,
+	})
+
+	email, err := lamb.RenderEmail("emails.welcome", map[string]interface{}{"name": "Ada"})
+
+	if err != nil {
+		t.Fatalf("RenderEmail returned an error: %s", err)
+	}
+
+	AssertContains(t, email.HTML, `<p style="color: #333">Hello Ada,</p>`)
+	AssertContains(t, email.HTML, `<p style="color: #333; color: black">`)
+	AssertContains(t, email.HTML, `<a id="main" class="button" href="https://example.com/start" style="background: blue; color: white; padding: 4px">`)
+	AssertContains(t, email.HTML, `<td style="color: #333">a</td>`)
+	AssertContains(t, email.HTML, `<style>a:hover{color: red;}@media (max-width: 600px) { p { font-size: 12px; } }</style>`)
+	AssertNotContains(t, email.HTML, "the base styles")
+
+	expected := "Hello Ada,\n\nThanks for joining & welcome.\n\nGet started (https://example.com/start)\n- one\n- two\n\na b"
+
+	if email.Text != expected {
+		t.Errorf("wrong text.\nexpected=%q\ngot=%q", expected, email.Text)
+	}
+}
@@ -0,0 +1,147 @@
+package lambtest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/govel-framework/lamb"
+	"github.com/govel-framework/lamb/internal"
+)
+
+type fieldUser struct {
+	Name    string `json:"name"`
+	Email   string `lamb:"mail" json:"email"`
+	Phone   string `json:"-"`
+	Display string `lamb:"title"`
+	Title   string
+}
+
+// TestStructFields checks the fields resolved by their tags and, with
+// lamb.case_insensitive_fields, in any case.
+func TestStructFields(t *testing.T) {
+	t.Cleanup(func() {
+		internal.SetSettings(nil)
+	})
+
+	user := &fieldUser{Name: "Ada", Email: "ada@example.com", Phone: "555", Display: "Countess", Title: "Lady"}
+
+	tests := []struct {
+		input           string
+		caseInsensitive string
+		expected        string
+	}{
+		{`{? user.Name ?}|{? user.name ?}`, "false", "Ada|Ada"},
+		{`{? user.mail ?}|{? user.email ?}|{? user.Email ?}`, "false", "ada@example.com|ada@example.com|ada@example.com"},
+		{`{? user.title ?}|{? user.Title ?}|{? user.Display ?}`, "false", "Countess|Lady|Countess"},
+		{`{? user.title ?}|{? user.TITLE ?}`, "true", "Countess|Lady"},
+		{`{? user.NAME ?}|{? user.phone ?}|{? user.display ?}`, "true", "Ada|555|Countess"},
+	}
+
+	for i, tt := range tests {
+		internal.SetSetting("GOVEL_LAMB_CASE_INSENSITIVE_FIELDS", tt.caseInsensitive)
+
+		if got := RenderString(t, tt.input, map[string]interface{}{"user": user}); got != tt.expected {
+			t.Errorf("tests[%d] - wrong output. expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+
+	internal.SetSetting("GOVEL_LAMB_CASE_INSENSITIVE_FIELDS", "false")
+
+	// the fields are case sensitive and the "-" tags are not names
+	for _, name := range []string{"NAME", "phone", "Phone2"} {
+		Use(t, Templates{"fields.error": "{? user." + name + " ?}"})
+
+		expected := "fields.error: 1: 8: field " + name + " does not exist in struct user"
+
+		if err := lamb.RenderTo(&strings.Builder{}, "fields.error", map[string]interface{}{"user": user}); err == nil || err.Error() != expected {
+			t.Errorf("%s: wrong error. expected=%q, got=%v", name, expected, err)
+		}
+	}
+}
+
+type getterUser struct {
+	email  string
+	token  string
+	secret string
+	phone  string
+	calls  *int
+}
+
+func (u getterUser) Email() string {
+	*u.calls++
+
+	return u.email
+}
+
+func (u *getterUser) Token() (string, error) {
+	if u.token == "" {
+		return "", errors.New("no token")
+	}
+
+	return u.token, nil
+}
+
+func (u getterUser) Secret(key string) string {
+	return u.secret
+}
+
+// TestGetterMethods checks the unexported fields read through their getters.
+func TestGetterMethods(t *testing.T) {
+	t.Cleanup(func() {
+		lamb.GetterMethods(nil)
+	})
+
+	calls := 0
+
+	vars := map[string]interface{}{
+		"user":  getterUser{email: "ada@example.com", token: "abc", calls: &calls},
+		"guest": &getterUser{calls: &calls},
+	}
+
+	render := func(input string) (string, error) {
+		Use(t, Templates{"getters.show": input})
+
+		var out strings.Builder
+
+		err := lamb.RenderTo(&out, "getters.show", vars)
+
+		return out.String(), err
+	}
+
+	if _, err := render(`{? user.email ?}`); err == nil || err.Error() != "getters.show: 1: 8: field email of lambtest.getterUser is unexported" {
+		t.Errorf("wrong error without getters. got=%v", err)
+	}
+
+	lamb.GetterMethods(func(field string) string {
+		if field == "calls" {
+			return ""
+		}
+
+		return strings.ToUpper(field[:1]) + field[1:]
+	})
+
+	if got, err := render(`{? user.email ?}|{? guest.email ?}|{? user.token ?}`); err != nil || got != "ada@example.com||abc" {
+		t.Errorf("wrong output. got=%q, err=%v", got, err)
+	}
+
+	if calls != 2 {
+		t.Errorf("the getter Email was called %d times, want=2", calls)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{? guest.token ?}`, "getters.show: 1: 9: no token"},
+		{`{? user.secret ?}`, "getters.show: 1: 8: getter Secret of lambtest.getterUser must take no arguments and return a value and optionally an error"},
+		{`{? user.phone ?}`, "getters.show: 1: 8: field phone of lambtest.getterUser is unexported and lambtest.getterUser has no getter Phone"},
+		{`{? user.calls ?}`, "getters.show: 1: 8: field calls of lambtest.getterUser is unexported"},
+	}
+
+	for _, tt := range tests {
+		if _, err := render(tt.input); err == nil || err.Error() != tt.expected {
+			t.Errorf("%s: wrong error. expected=%q, got=%v", tt.input, tt.expected, err)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package lambtest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/govel-framework/lamb"
+)
+
+type money int
+
+// TestFormatter checks the values written through lamb.Formatter.
+func TestFormatter(t *testing.T) {
+	Use(t, Templates{
+		"formatter.show": `{? price ?}|{? n ?}|{? name ?}|{? ok ?}|{? for p in prices ?}{? p ?};{? endfor ?}`,
+	})
+
+	vars := map[string]interface{}{
+		"price":  money(1250),
+		"n":      3,
+		"name":   "Ada",
+		"ok":     true,
+		"prices": []money{5, 100},
+	}
+
+	if got := Render(t, "formatter.show", vars); got != "1250|3|Ada|true|5;100;" {
+		t.Errorf("wrong output without a formatter. got=%q", got)
+	}
+
+	var formatted []interface{}
+
+	lamb.Formatter(func(v interface{}) (string, bool) {
+		formatted = append(formatted, v)
+
+		if m, isMoney := v.(money); isMoney {
+			return fmt.Sprintf("$%d.%02d", m/100, m%100), true
+		}
+
+		return "", false
+	})
+
+	t.Cleanup(func() {
+		lamb.Formatter(nil)
+	})
+
+	if got := Render(t, "formatter.show", vars); got != "$12.50|3|Ada|true|$0.05;$1.00;" {
+		t.Errorf("wrong output. got=%q", got)
+	}
+
+	// the strings and the booleans are not passed to the formatter
+	if expected := fmt.Sprint([]interface{}{money(1250), 3, money(5), money(100)}); fmt.Sprint(formatted) != expected {
+		t.Errorf("wrong values formatted. expected=%s, got=%v", expected, formatted)
+	}
+}
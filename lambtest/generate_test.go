@@ -0,0 +1,157 @@
+package lambtest
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/govel-framework/lamb"
+	"github.com/govel-framework/lamb/generate"
+	"github.com/govel-framework/lamb/internal"
+)
+
+// TestGeneratedRenders generates the templates to Go, builds a program with
+// them and checks that it renders like the parsed templates.
+func TestGeneratedRenders(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a Go program")
+	}
+
+	Use(t, Templates{
+		"gen.layout": `<main>{? define("content") ?}default{? end ?}</main>`,
+		"gen.item":   `<li>{? if loop.first ?}first {? endif ?}{? item ?}</li>`,
+		"gen.page": `{? extends("gen.layout") ?}{? section("content") ?}<ul>` +
+			`{? for item in items ?}{? include("gen.item", {"item": item, "loop": loop}) ?}{? endfor ?}</ul>` +
+			`{? var big = filter(items, x => x > 1) ?}{? len(big) ?} big{? try ?}{? nope ?}{? rescue e ?}!{? endtry ?}{? endsection ?}`,
+	})
+
+	walked := Render(t, "gen.page", map[string]interface{}{"items": []interface{}{1, 2, 3}})
+
+	AssertHTMLEqual(t, walked, "<main><ul><li>first 1</li><li>2</li><li>3</li></ul>2 big!</main>")
+
+	var section strings.Builder
+
+	if err := lamb.RenderSectionTo(&section, "gen.layout", "content", nil); err != nil {
+		t.Fatalf("RenderSectionTo returned an error: %s", err)
+	}
+
+	var templates []generate.Template
+
+	for _, name := range []string{"gen.layout", "gen.item", "gen.page"} {
+		program, err := internal.ParseTemplate(name)
+
+		if err != nil {
+			t.Fatalf("parse %s: %s", name, err)
+		}
+
+		info, err := lamb.Analyze(name)
+
+		if err != nil {
+			t.Fatalf("analyze %s: %s", name, err)
+		}
+
+		templates = append(templates, generate.Template{Name: name, Program: program, Vars: info.Vars})
+	}
+
+	src, err := generate.File("views", templates)
+
+	if err != nil {
+		t.Fatalf("File returned an error: %s", err)
+	}
+
+	got := runGenerated(t, src, `package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/govel-framework/lamb"
+	_ "lambgen/views"
+)
+
+func main() {
+	var page, section strings.Builder
+
+	if err := lamb.RenderTo(&page, "gen.page", map[string]interface{}{"items": []interface{}{1, 2, 3}}); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if err := lamb.RenderSectionTo(&section, "gen.layout", "content", nil); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Print(page.String() + "\n" + section.String())
+}
+`)
+
+	if expected := walked + "\n" + section.String(); got != expected {
+		t.Errorf("the generated templates render %q, want=%q", got, expected)
+	}
+}
+
+// runGenerated builds a module with the generated package views and the
+// program main, and returns the output of the program.
+func runGenerated(t *testing.T, views []byte, main string) string {
+	t.Helper()
+
+	goTool, err := exec.LookPath("go")
+
+	if err != nil {
+		t.Skip("the go tool is not installed")
+	}
+
+	root, err := filepath.Abs("..")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sum, err := os.ReadFile(filepath.Join(root, "go.sum"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+
+	files := map[string][]byte{
+		"go.mod": []byte("module lambgen\n\ngo 1.19\n\nrequire github.com/govel-framework/lamb v0.0.0\n\n" +
+			"replace github.com/govel-framework/lamb => " + root + "\n"),
+		"go.sum":         sum,
+		"main.go":        []byte(main),
+		"views/views.go": views,
+	}
+
+	for name, content := range files {
+		file := filepath.Join(dir, name)
+
+		os.MkdirAll(filepath.Dir(file), os.ModePerm)
+
+		if err := os.WriteFile(file, content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cmd := exec.Command(goTool, "run", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOWORK=off", "GOFLAGS=-mod=mod")
+
+	out, err := cmd.Output()
+
+	if err != nil {
+		stderr := ""
+
+		if exitErr, isExitErr := err.(*exec.ExitError); isExitErr {
+			stderr = string(exitErr.Stderr)
+		}
+
+		t.Fatalf("the generated program failed: %s\n%s\n%s", err, stderr, views)
+	}
+
+	return string(out)
+}
@@ -0,0 +1,183 @@
+package lambtest
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/govel-framework/lamb"
+	"github.com/govel-framework/lamb/internal"
+)
+
+var update = flag.Bool("update", false, "lambtest: write the golden files instead of comparing them")
+
+// AssertGolden renders the template with the given name and vars and compares
+// the output with the golden file, e.g. testdata/users.show.golden. With the
+// -update flag (go test -update) it writes the file instead.
+//
+// If the output changed, the test fails with a line diff where every line is
+// followed by the template line that looks like its source.
+func AssertGolden(t testing.TB, name string, vars map[string]interface{}, golden string) {
+	t.Helper()
+
+	output := Render(t, name, vars)
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(golden), os.ModePerm); err != nil {
+			t.Fatalf("lambtest: %s", err)
+		}
+
+		if err := os.WriteFile(golden, []byte(output), 0644); err != nil {
+			t.Fatalf("lambtest: %s", err)
+		}
+
+		return
+	}
+
+	expected, err := os.ReadFile(golden)
+
+	if err != nil {
+		t.Fatalf("lambtest: %s (run go test -update to create it)", err)
+	}
+
+	if output == string(expected) {
+		return
+	}
+
+	t.Errorf("lambtest: the output of %s is not the one of %s (run go test -update to accept it):\n%s",
+		name, golden, diff(string(expected), output, sourceLines(name)))
+}
+
+// sourceLine is a line of a template, with its HTML without the code blocks.
+type sourceLine struct {
+	position  string // e.g. users.show:12
+	fragments []string
+}
+
+var codeBlocks = regexp.MustCompile(`\{\?.*?\?\}`)
+
+// sourceLines returns the lines of the template, of its includes and of its
+// layouts.
+func sourceLines(name string) []sourceLine {
+	var lines []sourceLine
+
+	seen := make(map[string]bool)
+	pending := []string{name}
+
+	for len(pending) > 0 {
+		name, pending = pending[0], pending[1:]
+
+		if seen[name] {
+			continue
+		}
+
+		seen[name] = true
+
+		src, err := internal.LoadSource(name)
+
+		if err != nil {
+			continue
+		}
+
+		for i, line := range strings.Split(string(src), "\n") {
+			var fragments []string
+
+			for _, fragment := range codeBlocks.Split(line, -1) {
+				if fragment = strings.TrimSpace(fragment); fragment != "" {
+					fragments = append(fragments, fragment)
+				}
+			}
+
+			lines = append(lines, sourceLine{position: fmt.Sprintf("%s:%d", name, i+1), fragments: fragments})
+		}
+
+		if info, err := lamb.Analyze(name); err == nil {
+			pending = append(pending, info.Includes...)
+
+			if info.Extends != "" {
+				pending = append(pending, info.Extends)
+			}
+		}
+	}
+
+	return lines
+}
+
+// origin returns the position of the template line with the longest HTML in
+// the output line, empty if there is none.
+func origin(line string, lines []sourceLine) string {
+	var position string
+	var longest int
+
+	for _, source := range lines {
+		for _, fragment := range source.fragments {
+			if len(fragment) > longest && strings.Contains(line, fragment) {
+				position, longest = source.position, len(fragment)
+			}
+		}
+	}
+
+	return position
+}
+
+// diff returns the lines removed from expected (-) and added to got (+), each
+// with the template line that looks like its source.
+func diff(expected string, got string, lines []sourceLine) string {
+	a := strings.Split(expected, "\n")
+	b := strings.Split(got, "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var out strings.Builder
+
+	write := func(sign string, n int, line string) {
+		out.WriteString(fmt.Sprintf("%s %4d | %s", sign, n, line))
+
+		if position := origin(line, lines); position != "" {
+			out.WriteString("    (" + position + ")")
+		}
+
+		out.WriteString("\n")
+	}
+
+	i, j := 0, 0
+
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			write("-", i+1, a[i])
+			i++
+
+		default:
+			write("+", j+1, b[j])
+			j++
+		}
+	}
+
+	return out.String()
+}
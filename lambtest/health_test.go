@@ -0,0 +1,70 @@
+package lambtest
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/govel-framework/lamb"
+	"github.com/govel-framework/lamb/internal"
+)
+
+// TestHealth checks the HealthReport of a healthy loader and of a template
+// that fails to load.
+func TestHealth(t *testing.T) {
+	Use(t, Templates{
+		"health.ok":     `{? name ?}`,
+		"health.broken": `{? if name ?}`,
+	})
+
+	dir := t.TempDir()
+
+	internal.SetSettings(map[string]string{"GOVEL_LAMB_BASE_DIR": dir})
+
+	t.Cleanup(func() {
+		internal.SetSettings(nil)
+	})
+
+	if got := Render(t, "health.ok", map[string]interface{}{"name": "Ada"}); got != "Ada" {
+		t.Fatalf("wrong output. got=%q", got)
+	}
+
+	report := lamb.Health()
+
+	if !report.Reachable || report.Error != "" {
+		t.Errorf("the templates dir is not reachable: %+v", report)
+	}
+
+	if report.LoadedTemplates == 0 {
+		t.Errorf("health.ok is not counted as loaded: %+v", report)
+	}
+
+	if errs, exists := report.ParseErrors[internal.TemplateFile("health.ok")]; exists {
+		t.Errorf("unexpected parse errors for health.ok: %q", errs)
+	}
+
+	if err := lamb.RenderTo(&strings.Builder{}, "health.broken", nil); err == nil {
+		t.Fatal("expected a parse error")
+	}
+
+	loaded := report.LoadedTemplates
+
+	report = lamb.Health()
+
+	if report.LoadedTemplates != loaded {
+		t.Errorf("the broken template is counted as loaded. expected=%d, got=%d", loaded, report.LoadedTemplates)
+	}
+
+	errs := report.ParseErrors[internal.TemplateFile("health.broken")]
+
+	if len(errs) == 0 {
+		t.Errorf("no parse errors reported for health.broken: %+v", report.ParseErrors)
+	}
+
+	// the templates dir can not be read
+	internal.SetSetting("GOVEL_LAMB_BASE_DIR", filepath.Join(dir, "missing"))
+
+	if report := lamb.Health(); report.Reachable || !strings.Contains(report.Error, "missing") {
+		t.Errorf("the missing templates dir is reachable: %+v", report)
+	}
+}
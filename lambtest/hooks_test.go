@@ -0,0 +1,75 @@
+package lambtest
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/govel-framework/lamb"
+	"github.com/govel-framework/lamb/ast"
+)
+
+// TestHooks checks the order of the hooks around the statements and the
+// errors passed to the after hook.
+func TestHooks(t *testing.T) {
+	Use(t, Templates{
+		"hooks.show":    `<p>{? if n != 1 ?}{? 10 / n ?}{? endif ?}</p>`,
+		"hooks.partial": `{? include("hooks.show", {"n": n}) ?}`,
+	})
+
+	var calls []string
+
+	lamb.Hooks(func(node ast.Node, file string) {
+		calls = append(calls, fmt.Sprintf("before %s %s", file, node.TokenLiteral()))
+	}, func(node ast.Node, file string, elapsed time.Duration, err error) {
+		if elapsed < 0 {
+			t.Errorf("negative elapsed time for %s", node.TokenLiteral())
+		}
+
+		calls = append(calls, fmt.Sprintf("after %s %s: %v", file, node.TokenLiteral(), err))
+	})
+
+	t.Cleanup(func() {
+		lamb.Hooks(nil, nil)
+	})
+
+	if got := Render(t, "hooks.show", map[string]interface{}{"n": 2}); got != "<p>5</p>" {
+		t.Errorf("wrong output. got=%q", got)
+	}
+
+	expected := []string{
+		"before hooks.show if",
+		"before hooks.show 10",
+		"after hooks.show 10: <nil>",
+		"after hooks.show if: <nil>",
+	}
+
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("wrong hooks. expected=%q, got=%q", expected, calls)
+	}
+
+	calls = nil
+
+	// the error of a statement is passed to the after hooks of the statements
+	// around it, up to the include
+	err := lamb.RenderTo(&strings.Builder{}, "hooks.partial", map[string]interface{}{"n": 0})
+
+	if err == nil || err.Error() != "hooks.partial: hooks.show: 1: 25: division by zero" {
+		t.Errorf("wrong error. got=%v", err)
+	}
+
+	expected = []string{
+		"before hooks.partial include",
+		"before hooks.show if",
+		"before hooks.show 10",
+		"after hooks.show 10: 1: 25: division by zero",
+		"after hooks.show if: 1: 25: division by zero",
+		"after hooks.partial include: hooks.show: 1: 25: division by zero",
+	}
+
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("wrong hooks. expected=%q, got=%q", expected, calls)
+	}
+}
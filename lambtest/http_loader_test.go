@@ -0,0 +1,197 @@
+package lambtest
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/govel-framework/lamb"
+)
+
+func TestHTTPLoader(t *testing.T) {
+	header := "<header>v1</header>"
+	down := false
+
+	var requests []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path+" "+r.Header.Get("If-None-Match"))
+
+		switch {
+		case down:
+			w.WriteHeader(http.StatusBadGateway)
+
+		case r.URL.Path != "/header":
+			http.NotFound(w, r)
+
+		case r.Header.Get("If-None-Match") == `"`+header+`"`:
+			w.WriteHeader(http.StatusNotModified)
+
+		default:
+			w.Header().Set("ETag", `"`+header+`"`)
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Write([]byte(header))
+		}
+	}))
+
+	defer server.Close()
+
+	loader := &lamb.HTTPLoader{BaseURL: server.URL + "/", Prefix: "ds."}
+
+	pages := Templates{"pages.home": `{? include("ds.header") ?}<main></main>`}
+
+	Use(t, pages)
+
+	lamb.UseLoader(lamb.ChainLoader{loader, pages})
+
+	if got := Render(t, "pages.home", nil); got != "<header>v1</header><main></main>" {
+		t.Errorf("wrong output. got=%q", got)
+	}
+
+	// no-cache: the template is revalidated with its ETag
+	Render(t, "pages.home", nil)
+
+	header = "<header>v2</header>"
+
+	if got := Render(t, "pages.home", nil); got != "<header>v2</header><main></main>" {
+		t.Errorf("the new version is not loaded. got=%q", got)
+	}
+
+	expected := []string{"/header ", `/header "<header>v1</header>"`, `/header "<header>v1</header>"`}
+
+	if len(requests) != len(expected) {
+		t.Fatalf("wrong requests.\nexpected=%q\ngot=%q", expected, requests)
+	}
+
+	for i := range expected {
+		if requests[i] != expected[i] {
+			t.Errorf("wrong requests.\nexpected=%q\ngot=%q", expected, requests)
+		}
+	}
+
+	// the last good copy is loaded when the remote is down
+	down = true
+
+	if got := Render(t, "pages.home", nil); got != "<header>v2</header><main></main>" {
+		t.Errorf("the last good copy is not loaded. got=%q", got)
+	}
+
+	if _, err := loader.Load("ds.footer"); err == nil {
+		t.Error("a template that was never loaded does not fail when the remote is down")
+	}
+
+	// the templates without the prefix or not found are loaded by the next loader
+	down = false
+
+	if _, err := loader.Load("pages.home"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("a template without the prefix is not fs.ErrNotExist. got=%v", err)
+	}
+
+	if _, err := loader.Load("ds.footer"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("a 404 is not fs.ErrNotExist. got=%v", err)
+	}
+}
+
+func TestHTTPLoaderMaxAge(t *testing.T) {
+	requests := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		w.Header().Set("Cache-Control", "public, max-age=60")
+		w.Write([]byte("<footer></footer>"))
+	}))
+
+	defer server.Close()
+
+	loader := &lamb.HTTPLoader{BaseURL: server.URL + "/"}
+
+	for i := 0; i < 3; i++ {
+		if source, err := loader.Load("footer"); err != nil || string(source) != "<footer></footer>" {
+			t.Fatalf("wrong source. got=%q, %v", source, err)
+		}
+	}
+
+	if requests != 1 {
+		t.Errorf("the template is requested within its max-age. requests=%d", requests)
+	}
+}
+
+func TestHTTPLoaderTimeout(t *testing.T) {
+	stalled := make(chan struct{})
+	stall := false
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if stall {
+			<-stalled
+		}
+
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Write([]byte("<header></header>"))
+	}))
+
+	// the stalled requests end before the server is closed
+	defer server.Close()
+	defer close(stalled)
+
+	loader := &lamb.HTTPLoader{BaseURL: server.URL + "/", Timeout: 50 * time.Millisecond}
+
+	if _, err := loader.Load("header"); err != nil {
+		t.Fatal(err)
+	}
+
+	stall = true
+	start := time.Now()
+
+	if source, err := loader.Load("header"); err != nil || string(source) != "<header></header>" {
+		t.Errorf("the last good copy is not loaded when the remote hangs. got=%q, %v", source, err)
+	}
+
+	if _, err := loader.Load("footer"); err == nil {
+		t.Error("a template that was never loaded does not fail when the remote hangs")
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("the requests to the stalled remote do not time out. elapsed=%s", elapsed)
+	}
+}
+
+func TestHTTPLoaderMaxSize(t *testing.T) {
+	body := "<nav></nav>"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Write([]byte(body))
+	}))
+
+	defer server.Close()
+
+	loader := &lamb.HTTPLoader{BaseURL: server.URL + "/", MaxSize: int64(len(body))}
+
+	if source, err := loader.Load("nav"); err != nil || string(source) != body {
+		t.Fatalf("a template of the max size is not loaded. got=%q, %v", source, err)
+	}
+
+	body = "<nav>larger</nav>"
+
+	if source, err := loader.Load("nav"); err != nil || string(source) != "<nav></nav>" {
+		t.Errorf("the last good copy is not loaded when the template is too large. got=%q, %v", source, err)
+	}
+
+	expected := server.URL + "/menu is larger than the max size of 11 bytes"
+
+	if _, err := loader.Load("menu"); err == nil || !strings.Contains(err.Error(), expected) {
+		t.Errorf("wrong error of a template that is too large. expected=%q, got=%v", expected, err)
+	}
+
+	// the default max size is far larger than a template
+	body = strings.Repeat("a", 1<<20)
+
+	if source, err := (&lamb.HTTPLoader{BaseURL: server.URL + "/"}).Load("nav"); err != nil || len(source) != len(body) {
+		t.Errorf("a template under the default max size is not loaded. got=%d bytes, %v", len(source), err)
+	}
+}
@@ -0,0 +1,52 @@
+package lambtest
+
+import (
+	"testing"
+
+	"github.com/govel-framework/lamb"
+	"github.com/govel-framework/lamb/internal"
+)
+
+func TestInitAssets(t *testing.T) {
+	t.Cleanup(func() {
+		internal.SetSettings(nil)
+	})
+
+	err := lamb.Init(map[interface{}]interface{}{
+		"lamb": map[interface{}]interface{}{
+			"dir": "views/",
+			"assets": map[interface{}]interface{}{
+				"manifest": "public/manifest.json",
+				"dir":      "public/",
+				"version":  "hash",
+				"cdn":      "https://cdn.example.com",
+			},
+		},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"GOVEL_LAMB_ASSETS_MANIFEST": "public/manifest.json",
+		"GOVEL_LAMB_ASSETS_DIR":      "public/",
+		"GOVEL_LAMB_ASSETS_VERSION":  "hash",
+		"GOVEL_LAMB_ASSETS_CDN":      "https://cdn.example.com",
+		"GOVEL_LAMB_BASE_DIR":        "views/",
+	}
+
+	for setting, value := range expected {
+		if got := internal.Setting(setting); got != value {
+			t.Errorf("wrong %s. expected=%q, got=%q", setting, value, got)
+		}
+	}
+
+	invalid := map[interface{}]interface{}{
+		"manifest": 1,
+	}
+
+	if err := lamb.Init(map[interface{}]interface{}{"lamb": map[interface{}]interface{}{"dir": "views/", "assets": invalid}}); err == nil {
+		t.Error("an asset setting that is not a string is accepted")
+	}
+}
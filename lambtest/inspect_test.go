@@ -0,0 +1,43 @@
+package lambtest
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/govel-framework/lamb"
+)
+
+func TestInspect(t *testing.T) {
+	Use(t, Templates{
+		"pages.home": "{? extends(\"layouts.app\") ?}\n" +
+			"{? section(\"content\") ?}{? include(\"partials.hero\") ?}\n{? include(\"partials.list\", {}) ?}{? endsection ?}\n" +
+			"{? section(\"footer\") ?}{? if a ?}{? include(\"partials.links\") ?}{? endif ?}{? endsection ?}\n" +
+			"{? define(\"extra\") ?}{? end ?}{? include(\"partials.analytics\") ?}",
+	})
+
+	outline, err := lamb.Inspect("pages.home")
+
+	if err != nil {
+		t.Fatalf("Inspect returned an error: %s", err)
+	}
+
+	expected := &lamb.Outline{
+		Name:    "pages.home",
+		Extends: "layouts.app",
+		Sections: []lamb.Slot{
+			{Name: "content", Line: 2, Includes: []string{"partials.hero", "partials.list"}},
+			{Name: "footer", Line: 4, Includes: []string{"partials.links"}},
+		},
+		Defines: []lamb.Slot{{Name: "extra", Line: 5}},
+		Includes: []lamb.Included{
+			{Template: "partials.hero", Line: 2, In: "content"},
+			{Template: "partials.list", Line: 3, In: "content"},
+			{Template: "partials.links", Line: 4, In: "footer"},
+			{Template: "partials.analytics", Line: 5},
+		},
+	}
+
+	if !reflect.DeepEqual(outline, expected) {
+		t.Errorf("wrong outline.\nexpected=%+v\ngot=%+v", expected, outline)
+	}
+}
@@ -0,0 +1,132 @@
+// Package lambtest helps to unit test lamb templates: it renders them from an
+// in-memory tree of templates, without the files of the views or govel, and
+// compares their output.
+//
+// The loader of the templates is global, so the tests that use this package
+// must not run in parallel.
+package lambtest
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/govel-framework/lamb"
+	"github.com/govel-framework/lamb/internal"
+)
+
+// Templates is an in-memory tree of templates, by their name (e.g.
+// "layouts.app"), that can be used as the lamb.Loader.
+type Templates map[string]string
+
+// Load returns the source of the template.
+func (t Templates) Load(name string) ([]byte, error) {
+	src, exists := t[name]
+
+	if !exists {
+		return nil, fmt.Errorf("lambtest: template %s: %w", name, fs.ErrNotExist)
+	}
+
+	return []byte(src), nil
+}
+
+// Use loads the templates from the tree until the end of the test.
+func Use(t testing.TB, templates Templates) {
+	t.Helper()
+
+	previous := internal.CurrentLoader()
+
+	lamb.UseLoader(templates)
+
+	t.Cleanup(func() {
+		lamb.UseLoader(previous)
+	})
+}
+
+// Render renders the template with the given name and vars, the test fails if
+// the render does.
+func Render(t testing.TB, name string, vars map[string]interface{}) string {
+	t.Helper()
+
+	var out bytes.Buffer
+
+	if err := lamb.RenderTo(&out, name, vars); err != nil {
+		t.Fatalf("lambtest: render %s: %s", name, err)
+	}
+
+	return out.String()
+}
+
+// renderStringName is the name of the template rendered by RenderString.
+const renderStringName = "__lambtest"
+
+// RenderString renders the template src with the given vars, its includes and
+// its layout come from the templates set with Use.
+func RenderString(t testing.TB, src string, vars map[string]interface{}) string {
+	t.Helper()
+
+	previous := internal.CurrentLoader()
+
+	lamb.UseLoader(overlay{name: renderStringName, src: src, Loader: previous})
+	defer lamb.UseLoader(previous)
+
+	return Render(t, renderStringName, vars)
+}
+
+// overlay adds one template to a loader.
+type overlay struct {
+	name string
+	src  string
+	lamb.Loader
+}
+
+func (o overlay) Load(name string) ([]byte, error) {
+	if name == o.name {
+		return []byte(o.src), nil
+	}
+
+	return o.Loader.Load(name)
+}
+
+// AssertContains fails the test if output does not contain substr.
+func AssertContains(t testing.TB, output string, substr string) {
+	t.Helper()
+
+	if !strings.Contains(output, substr) {
+		t.Errorf("lambtest: the output does not contain %q:\n%s", substr, output)
+	}
+}
+
+// AssertNotContains fails the test if output contains substr.
+func AssertNotContains(t testing.TB, output string, substr string) {
+	t.Helper()
+
+	if strings.Contains(output, substr) {
+		t.Errorf("lambtest: the output contains %q:\n%s", substr, output)
+	}
+}
+
+var (
+	spaces      = regexp.MustCompile(`\s+`)
+	betweenTags = regexp.MustCompile(`>\s+<`)
+)
+
+// AssertHTMLEqual fails the test if the HTML got is not the expected one. The
+// whitespace is not significant: the spaces between tags are ignored and the
+// other runs of spaces are compared as one.
+func AssertHTMLEqual(t testing.TB, got string, expected string) {
+	t.Helper()
+
+	if normalizeHTML(got) != normalizeHTML(expected) {
+		t.Errorf("lambtest: the HTML is not the expected one\ngot:\n%s\nexpected:\n%s", got, expected)
+	}
+}
+
+func normalizeHTML(html string) string {
+	html = strings.TrimSpace(spaces.ReplaceAllString(html, " "))
+
+	return betweenTags.ReplaceAllString(html, "><")
+}
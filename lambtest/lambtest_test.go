@@ -0,0 +1,50 @@
+package lambtest
+
+import "testing"
+
+func TestRenderString(t *testing.T) {
+	Use(t, Templates{
+		"layouts.app":   `<html><body>{? define("content") ?}{? end ?}</body></html>`,
+		"partials.user": `<p>{? name ?}</p>`,
+	})
+
+	output := RenderString(t, `{? extends("layouts.app") ?}
+{? section("content") ?}
+	<h1>Users</h1>
+	{? for name in names ?}
+		{? include("partials.user", {"name": name}) ?}
+	{? endfor ?}
+{? endsection ?}`, map[string]interface{}{"names": []interface{}{"ana", "bob"}})
+
+	AssertContains(t, output, "<p>ana</p>")
+	AssertHTMLEqual(t, output, "<html><body><h1>Users</h1><p>ana</p><p>bob</p></body></html>")
+}
+
+func TestAssertHTMLEqual(t *testing.T) {
+	if normalizeHTML("<ul>\n\t<li>a  b</li>\n</ul>\n") != "<ul><li>a b</li></ul>" {
+		t.Errorf("wrong normalization: %q", normalizeHTML("<ul>\n\t<li>a  b</li>\n</ul>\n"))
+	}
+}
+
+func TestAssertGolden(t *testing.T) {
+	Use(t, Templates{
+		"users.index": "<ul>\n{? for name in names ?}\n\t<li>{? name ?}</li>\n{? endfor ?}\n</ul>\n",
+	})
+
+	AssertGolden(t, "users.index", map[string]interface{}{"names": []interface{}{"ana", "bob"}}, "testdata/users.index.golden")
+}
+
+func TestDiff(t *testing.T) {
+	Use(t, Templates{
+		"users.show": "<h1>{? name ?}</h1>\n<p class=\"email\">{? email ?}</p>\n",
+	})
+
+	got := diff("<h1>ana</h1>\n<p>ana@example.com</p>\n", "<h1>ana</h1>\n<p class=\"email\">ana@example.com</p>\n", sourceLines("users.show"))
+
+	expected := "-    2 | <p>ana@example.com</p>    (users.show:2)\n" +
+		"+    2 | <p class=\"email\">ana@example.com</p>    (users.show:2)\n"
+
+	if got != expected {
+		t.Errorf("wrong diff, expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
@@ -0,0 +1,46 @@
+package lambtest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/govel-framework/lamb"
+)
+
+func TestLazyVars(t *testing.T) {
+	Use(t, Templates{
+		"users.show":   `{? if showOrders ?}{? for order in orders ?}{? order ?},{? endfor ?}{? len(orders) ?}{? include("users.orders", {"orders": orders}) ?}{? endif ?}`,
+		"users.orders": `[{? orders[0] ?}]`,
+	})
+
+	calls := 0
+
+	vars := func(showOrders bool) map[string]interface{} {
+		return map[string]interface{}{
+			"showOrders": showOrders,
+			"orders": lamb.Lazy(func() interface{} {
+				calls++
+
+				return []interface{}{"a", "b"}
+			}),
+		}
+	}
+
+	if got := Render(t, "users.show", vars(false)); got != "" || calls != 0 {
+		t.Errorf("an unused lazy var is computed. got=%q, calls=%d", got, calls)
+	}
+
+	if got := Render(t, "users.show", vars(true)); got != "a,b,2[a]" || calls != 1 {
+		t.Errorf("the lazy var is not computed once. got=%q, calls=%d", got, calls)
+	}
+
+	// an error of the var is an error of the render
+	failing := lamb.Lazy(func() interface{} { return errors.New("orders are not available") })
+
+	err := lamb.RenderTo(&strings.Builder{}, "users.show", map[string]interface{}{"showOrders": true, "orders": failing})
+
+	if err == nil || !strings.Contains(err.Error(), "orders are not available") {
+		t.Errorf("wrong error. got=%v", err)
+	}
+}
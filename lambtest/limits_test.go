@@ -0,0 +1,81 @@
+package lambtest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/govel-framework/lamb"
+	"github.com/govel-framework/lamb/evaluator"
+	"github.com/govel-framework/lamb/internal"
+	"github.com/govel-framework/lamb/object"
+)
+
+func init() {
+	// changes the max output size in the middle of a render
+	evaluator.Registry.Register("lambtest_set_max_output", &object.Builtin{
+		Fn: func(args ...interface{}) interface{} {
+			internal.SetSetting("GOVEL_LAMB_MAX_OUTPUT_SIZE", "1")
+
+			return nil
+		},
+	})
+}
+
+func TestExecutionLimits(t *testing.T) {
+	Use(t, Templates{
+		"limits.loop":    `{? for i in items ?}{? i ?}{? endfor ?}`,
+		"limits.output":  `{? for i in items ?}abcd{? endfor ?}|{? text ?}`,
+		"limits.changed": `{? lambtest_set_max_output() ?}{? text ?}`,
+		"limits.include": `{? if n < depth ?}{? n ?}{? include("limits.include", {"n": n + 1, "depth": depth}) ?}{? endif ?}`,
+		"limits.try":     `{? try ?}{? include("limits.include", {"n": 0, "depth": depth}) ?}{? rescue e ?}rescued{? endtry ?}`,
+	})
+
+	internal.SetSettings(map[string]string{
+		"GOVEL_LAMB_MAX_LOOP_ITERATIONS": "3",
+		"GOVEL_LAMB_MAX_OUTPUT_SIZE":     "10",
+		"GOVEL_LAMB_MAX_INCLUDE_DEPTH":   "2",
+	})
+
+	t.Cleanup(func() {
+		internal.SetSettings(nil)
+	})
+
+	tests := []struct {
+		name     string
+		vars     map[string]interface{}
+		expected string
+	}{
+		{"limits.loop", map[string]interface{}{"items": []int{1, 2, 3}}, "123"},
+		{"limits.output", map[string]interface{}{"items": []int{1, 2}, "text": "a"}, "abcdabcd|a"},
+		{"limits.include", map[string]interface{}{"n": 0, "depth": 2}, "01"},
+		{"limits.changed", map[string]interface{}{"text": "abc"}, "abc"},
+		{"limits.try", map[string]interface{}{"depth": 1}, "0"},
+	}
+
+	for _, tt := range tests {
+		if got := Render(t, tt.name, tt.vars); got != tt.expected {
+			t.Errorf("%s: wrong output. expected=%q, got=%q", tt.name, tt.expected, got)
+		}
+
+		// the limits are read when the render starts
+		internal.SetSetting("GOVEL_LAMB_MAX_OUTPUT_SIZE", "10")
+	}
+
+	errors := []struct {
+		name     string
+		vars     map[string]interface{}
+		expected string
+	}{
+		{"limits.loop", map[string]interface{}{"items": []int{1, 2, 3, 4}}, "for loop over 4 elements exceeds the max of 3 iterations"},
+		{"limits.output", map[string]interface{}{"items": []int{1, 2, 3}}, "output exceeds the max size of 10 bytes"},
+		{"limits.output", map[string]interface{}{"items": []int{1, 2}, "text": "ab"}, "output exceeds the max size of 10 bytes"},
+		{"limits.include", map[string]interface{}{"n": 0, "depth": 3}, "max include depth of 2 exceeded including limits.include"},
+		{"limits.try", map[string]interface{}{"depth": 2}, "max include depth of 2 exceeded including limits.include"},
+	}
+
+	for _, tt := range errors {
+		if err := lamb.RenderTo(&strings.Builder{}, tt.name, tt.vars); err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%s: wrong error. expected=%q, got=%v", tt.name, tt.expected, err)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package lambtest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/govel-framework/lamb"
+)
+
+// recordingLogger records the messages logged by lamb.
+type recordingLogger struct {
+	messages []string
+}
+
+func (l *recordingLogger) record(level string, msg string, args []interface{}) {
+	l.messages = append(l.messages, fmt.Sprint(level, " ", msg, " ", args))
+}
+
+func (l *recordingLogger) Debug(msg string, args ...interface{}) { l.record("DEBUG", msg, args) }
+func (l *recordingLogger) Info(msg string, args ...interface{})  { l.record("INFO", msg, args) }
+func (l *recordingLogger) Warn(msg string, args ...interface{})  { l.record("WARN", msg, args) }
+func (l *recordingLogger) Error(msg string, args ...interface{}) { l.record("ERROR", msg, args) }
+
+// TestLogging checks that the warnings of lamb reach the logger set with
+// lamb.Logging.
+func TestLogging(t *testing.T) {
+	down := false
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down {
+			w.WriteHeader(http.StatusBadGateway)
+
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Write([]byte("<nav></nav>"))
+	}))
+
+	defer server.Close()
+
+	logger := &recordingLogger{}
+
+	lamb.Logging(logger)
+
+	t.Cleanup(func() {
+		lamb.Logging(nil)
+	})
+
+	loader := &lamb.HTTPLoader{BaseURL: server.URL + "/"}
+
+	if _, err := loader.Load("nav"); err != nil {
+		t.Fatalf("Load returned an error: %s", err)
+	}
+
+	if len(logger.messages) != 0 {
+		t.Errorf("unexpected messages: %q", logger.messages)
+	}
+
+	// the last good copy is loaded with a warning
+	down = true
+
+	if source, err := loader.Load("nav"); err != nil || string(source) != "<nav></nav>" {
+		t.Fatalf("the last good copy is not loaded. got=%q, %v", source, err)
+	}
+
+	expected := fmt.Sprint("WARN the remote template can not be loaded, its last good copy is used [template nav error ", server.URL, "/nav responded with 502 Bad Gateway]")
+
+	if len(logger.messages) != 1 || logger.messages[0] != expected {
+		t.Errorf("wrong messages.\nexpected=%q\ngot=%q", expected, logger.messages)
+	}
+
+	// the default logger is restored
+	lamb.Logging(nil)
+
+	loader.Load("nav")
+
+	if len(logger.messages) != 1 {
+		t.Errorf("the logger is not removed. got=%q", strings.Join(logger.messages, "\n"))
+	}
+}
@@ -0,0 +1,93 @@
+package lambtest
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/govel-framework/lamb"
+)
+
+// middlewareCalls records the middlewares run by TestMiddleware.
+var middlewareCalls []string
+
+func init() {
+	lamb.Middleware("middleware.*", func(name string, vars map[string]interface{}, next func() (string, error)) (string, error) {
+		middlewareCalls = append(middlewareCalls, "outer "+name)
+
+		content, err := next()
+
+		return "[" + content + "]", err
+	})
+
+	lamb.Middleware("middleware.admin", func(name string, vars map[string]interface{}, next func() (string, error)) (string, error) {
+		middlewareCalls = append(middlewareCalls, "admin "+name)
+
+		if vars["admin"] != true {
+			return "", errors.New("forbidden")
+		}
+
+		return next()
+	})
+
+	lamb.Middleware("middleware.*", func(name string, vars map[string]interface{}, next func() (string, error)) (string, error) {
+		middlewareCalls = append(middlewareCalls, "inner "+name)
+
+		if vars["cached"] == true {
+			return "cached", nil
+		}
+
+		content, err := next()
+
+		return "(" + content + ")", err
+	})
+}
+
+// TestMiddleware checks the order of the middlewares and that they can stop
+// the render.
+func TestMiddleware(t *testing.T) {
+	Use(t, Templates{
+		"middleware.page":  `{? 1 / n ?}`,
+		"middleware.admin": `{? 1 / n ?}`,
+		"other.page":       `{? 1 / n ?}`,
+	})
+
+	tests := []struct {
+		name     string
+		vars     map[string]interface{}
+		expected string
+		calls    []string
+	}{
+		{"middleware.page", map[string]interface{}{"n": 1}, "[(1)]", []string{"outer middleware.page", "inner middleware.page"}},
+		{"middleware.page", map[string]interface{}{"n": 0, "cached": true}, "[cached]", []string{"outer middleware.page", "inner middleware.page"}},
+		{"middleware.admin", map[string]interface{}{"n": 1, "admin": true}, "[(1)]", []string{"outer middleware.admin", "admin middleware.admin", "inner middleware.admin"}},
+		{"other.page", map[string]interface{}{"n": 1}, "1", nil},
+	}
+
+	for _, tt := range tests {
+		middlewareCalls = nil
+
+		if got := Render(t, tt.name, tt.vars); got != tt.expected {
+			t.Errorf("%s: wrong output. expected=%q, got=%q", tt.name, tt.expected, got)
+		}
+
+		if !reflect.DeepEqual(middlewareCalls, tt.calls) {
+			t.Errorf("%s: wrong middlewares. expected=%q, got=%q", tt.name, tt.calls, middlewareCalls)
+		}
+	}
+
+	// the admin middleware stops the render before the template and the
+	// inner middleware
+	middlewareCalls = nil
+
+	err := lamb.RenderTo(&strings.Builder{}, "middleware.admin", map[string]interface{}{"n": 0})
+
+	if err == nil || !strings.Contains(err.Error(), "forbidden") {
+		t.Errorf("wrong error. expected forbidden, got=%v", err)
+	}
+
+	if expected := []string{"outer middleware.admin", "admin middleware.admin"}; !reflect.DeepEqual(middlewareCalls, expected) {
+		t.Errorf("wrong middlewares. expected=%q, got=%q", expected, middlewareCalls)
+	}
+}
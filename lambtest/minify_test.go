@@ -0,0 +1,35 @@
+package lambtest
+
+import (
+	"testing"
+
+	"github.com/govel-framework/lamb/internal"
+)
+
+func TestMinify(t *testing.T) {
+	Use(t, Templates{
+		"layouts.min": "<html>\n  <body>\n    {? define(\"content\") ?}{? end ?}\n  </body>\n</html>\n",
+		"pages.min": "{? extends(\"layouts.min\") ?}{? section(\"content\") ?}\n" +
+			"<!-- a comment -->\n<p>Hello,   {? name ?}\n  and <b>welcome</b></p>\n" +
+			"{? include(\"partials.code\") ?}\n<a title=\"a   b > c\">link</a>{? endsection ?}",
+		"partials.code": "<pre>\n  indented\n</pre>\n<textarea>  kept  </textarea>\n<script>\n  if (a  <  b) {}\n</script>\n<!--[if IE]><p>old</p><![endif]-->\n",
+	})
+
+	t.Cleanup(func() {
+		internal.SetSettings(nil)
+	})
+
+	internal.SetSettings(map[string]string{"GOVEL_LAMB_MINIFY": "true"})
+
+	expected := "<html><body><p>Hello, Ada and <b>welcome</b></p>" +
+		"<pre>\n  indented\n</pre><textarea>  kept  </textarea><script>\n  if (a  <  b) {}\n</script>" +
+		"<!--[if IE]><p>old</p><![endif]--><a title=\"a   b > c\">link</a></body></html>"
+
+	if got := Render(t, "pages.min", map[string]interface{}{"name": "Ada"}); got != expected {
+		t.Errorf("wrong minified output.\nexpected=%q\ngot=%q", expected, got)
+	}
+
+	internal.SetSettings(nil)
+
+	AssertContains(t, Render(t, "pages.min", map[string]interface{}{"name": "Ada"}), "<!-- a comment -->")
+}
@@ -0,0 +1,47 @@
+package lambtest
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/govel-framework/lamb"
+)
+
+func TestOutputFilter(t *testing.T) {
+	Use(t, Templates{
+		"layouts.filtered":  `<body>{? define("content") ?}{? end ?}</body>`,
+		"pages.filtered":    `{? extends("layouts.filtered") ?}{? section("content") ?}<a href="/old">{? include("partials.filtered") ?}</a>{? endsection ?}`,
+		"partials.filtered": `link`,
+	})
+
+	// the filters are global, they only change the templates of the test
+	var names []string
+
+	lamb.OutputFilter(func(name string, html []byte) []byte {
+		if name != "pages.filtered" && name != "layouts.filtered" && name != "partials.filtered" {
+			return html
+		}
+
+		names = append(names, name)
+
+		return bytes.ReplaceAll(html, []byte(`href="/old"`), []byte(`href="/new"`))
+	})
+
+	lamb.OutputFilter(func(name string, html []byte) []byte {
+		if name != "pages.filtered" {
+			return html
+		}
+
+		return bytes.Replace(html, []byte("</body>"), []byte("<div>toolbar</div></body>"), 1)
+	})
+
+	got := Render(t, "pages.filtered", nil)
+
+	if expected := `<body><a href="/new">link</a><div>toolbar</div></body>`; got != expected {
+		t.Errorf("wrong output. expected=%q, got=%q", expected, got)
+	}
+
+	if len(names) != 1 || names[0] != "pages.filtered" {
+		t.Errorf("the output is filtered for %v, want=[pages.filtered]", names)
+	}
+}
@@ -0,0 +1,56 @@
+package lambtest
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/govel-framework/lamb"
+)
+
+type propAddress struct {
+	City string
+}
+
+type propUser struct {
+	Name    string
+	Address *propAddress
+	Tags    map[string]string
+}
+
+// TestAnalyzeProps checks the props found by Analyze and the fields read from
+// them.
+func TestAnalyzeProps(t *testing.T) {
+	lamb.PropType("PropUser", propUser{})
+
+	Use(t, Templates{
+		"users.show": `{? props(user: PropUser, title: string = "User", extra: Missing) ?}` +
+			`{? user.Name ?}{? user.Address.City ?}{? user.Address.Zip ?}{? user.Tags.any ?}{? user.Nmae ?}` +
+			`{? for user in [1] ?}{? user.Other ?}{? endfor ?}`,
+	})
+
+	info, err := lamb.Analyze("users.show")
+
+	if err != nil {
+		t.Fatalf("Analyze returned an error: %s", err)
+	}
+
+	expectedProps := []lamb.Prop{
+		{Name: "user", Type: "PropUser", Required: true},
+		{Name: "title", Type: "string"},
+		{Name: "extra", Type: "Missing", Required: true},
+	}
+
+	if !reflect.DeepEqual(info.Props, expectedProps) {
+		t.Errorf("wrong props, expected=%+v, got=%+v", expectedProps, info.Props)
+	}
+
+	expectedProblems := []string{
+		"1: 121: field Zip does not exist in lambtest.propAddress",
+		"1: 154: field Nmae does not exist in lambtest.propUser",
+		"1: 50: prop extra: unknown type Missing",
+	}
+
+	if !reflect.DeepEqual(info.Problems, expectedProblems) {
+		t.Errorf("wrong problems, expected=%q, got=%q", expectedProblems, info.Problems)
+	}
+}
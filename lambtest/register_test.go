@@ -0,0 +1,60 @@
+package lambtest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/govel-framework/lamb"
+	"github.com/govel-framework/lamb/internal"
+)
+
+func TestRegisterTemplate(t *testing.T) {
+	dir := t.TempDir()
+
+	os.MkdirAll(filepath.Join(dir, "pages"), os.ModePerm)
+	os.MkdirAll(filepath.Join(dir, "lib"), os.ModePerm)
+	os.WriteFile(filepath.Join(dir, "pages", "home.lamb.html"), []byte(`{? extends("lib.layout") ?}{? section("content") ?}{? include("lib.links", {"page": 2}) ?}{? endsection ?}`), 0644)
+	os.WriteFile(filepath.Join(dir, "lib", "links.lamb.html"), []byte(`<a>custom {? page ?}</a>`), 0644)
+
+	lamb.UseLoader(nil)
+	internal.SetSettings(map[string]string{"GOVEL_LAMB_BASE_DIR": dir + "/"})
+
+	lamb.RegisterTemplate("lib.layout", `<main>{? define("content") ?}{? end ?}</main>`)
+	lamb.RegisterTemplate("lib.links", `<a>default {? page ?}</a>`)
+	lamb.RegisterTemplate("lib.broken", `{? if ?}`)
+
+	t.Cleanup(func() {
+		internal.SetSettings(nil)
+
+		lamb.RegisterTemplate("lib.layout", "")
+		lamb.RegisterTemplate("lib.links", "")
+		lamb.RegisterTemplate("lib.broken", "")
+	})
+
+	// the file of the app overrides the registered template
+	if got := Render(t, "pages.home", nil); got != "<main><a>custom 2</a></main>" {
+		t.Errorf("wrong output. got=%q", got)
+	}
+
+	os.Remove(filepath.Join(dir, "lib", "links.lamb.html"))
+
+	if got := Render(t, "pages.home", nil); got != "<main><a>default 2</a></main>" {
+		t.Errorf("the registered template is not loaded. got=%q", got)
+	}
+
+	// the errors of a registered template are named after it, it has no file
+	err := lamb.RenderTo(&strings.Builder{}, "lib.broken", nil)
+
+	if err == nil || !strings.HasPrefix(err.Error(), "lib.broken: ") {
+		t.Errorf("wrong error. got=%v", err)
+	}
+
+	// a removed template does not exist
+	lamb.RegisterTemplate("lib.links", "")
+
+	if err := lamb.RenderTo(&strings.Builder{}, "lib.links", map[string]interface{}{"page": 1}); err == nil {
+		t.Error("a removed template is rendered")
+	}
+}
@@ -0,0 +1,280 @@
+package lambtest
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/govel-framework/lamb"
+	"github.com/govel-framework/lamb/internal"
+)
+
+func TestRenderHTTP(t *testing.T) {
+	Use(t, Templates{
+		"pages.created": `<p>{? name ?} from {? ctx.Path ?}</p>`,
+		"pages.broken":  `{? missing() ?}`,
+		"errors.500":    `<h1>{? status ?}</h1>{? if isset(error) ?}{? error ?}{? endif ?}`,
+	})
+
+	t.Cleanup(func() {
+		internal.SetSettings(nil)
+	})
+
+	w := httptest.NewRecorder()
+
+	if err := lamb.RenderHTTP(w, httptest.NewRequest("POST", "/users", nil), "pages.created", map[string]interface{}{"name": "Ada"}, 201); err != nil {
+		t.Fatalf("RenderHTTP returned an error: %s", err)
+	}
+
+	if w.Code != 201 || w.Header().Get("Content-Type") != "text/html; charset=utf-8" || w.Body.String() != "<p>Ada from /users</p>" {
+		t.Errorf("wrong response. status=%d, content type=%q, body=%q", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+	}
+
+	tests := []struct {
+		settings map[string]string
+		expected string
+	}{
+		{nil, "Internal Server Error\n"},
+		{map[string]string{"GOVEL_LAMB_ERROR_TEMPLATE": "errors.500"}, "<h1>500</h1>"},
+		{map[string]string{"GOVEL_LAMB_ERROR_TEMPLATE": "errors.missing"}, "Internal Server Error\n"},
+	}
+
+	for _, tt := range tests {
+		internal.SetSettings(tt.settings)
+
+		w := httptest.NewRecorder()
+
+		if err := lamb.RenderHTTP(w, httptest.NewRequest("GET", "/", nil), "pages.broken", nil, 0); err == nil {
+			t.Errorf("%v: expected an error", tt.settings)
+		}
+
+		if w.Code != 500 || w.Body.String() != tt.expected {
+			t.Errorf("%v: wrong response. status=%d, body=%q, want=%q", tt.settings, w.Code, w.Body.String(), tt.expected)
+		}
+	}
+
+	internal.SetSettings(map[string]string{"GOVEL_LAMB_ERROR_TEMPLATE": "errors.500", "GOVEL_LAMB_DEBUG": "true"})
+
+	w = httptest.NewRecorder()
+
+	lamb.RenderHTTP(w, httptest.NewRequest("GET", "/", nil), "pages.broken", nil, 0)
+
+	AssertContains(t, w.Body.String(), "missing")
+}
+
+func TestRenderHTTPETag(t *testing.T) {
+	Use(t, Templates{"pages.cached": `<p>{? name ?}</p>`})
+
+	internal.SetSettings(map[string]string{"GOVEL_LAMB_CACHE_DIR": t.TempDir(), "GOVEL_LAMB_CACHE_TIME": "1m"})
+
+	t.Cleanup(func() {
+		internal.SetSettings(nil)
+	})
+
+	render := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("If-None-Match", ifNoneMatch)
+
+		w := httptest.NewRecorder()
+
+		if err := lamb.RenderHTTP(w, r, "pages.cached", map[string]interface{}{"name": "Ada", "__cache": "all"}, 0); err != nil {
+			t.Fatalf("RenderHTTP returned an error: %s", err)
+		}
+
+		return w
+	}
+
+	render("")
+
+	if err := lamb.Close(context.Background()); err != nil {
+		t.Fatalf("Close returned an error: %s", err)
+	}
+
+	etag, cached := lamb.CachedETag("pages.cached", nil)
+
+	if !cached || etag == "" {
+		t.Fatalf("the cached render has no ETag")
+	}
+
+	if w := render(""); w.Code != 200 || w.Header().Get("ETag") != etag || w.Body.String() != "<p>Ada</p>" {
+		t.Errorf("wrong response. status=%d, ETag=%q, body=%q", w.Code, w.Header().Get("ETag"), w.Body.String())
+	}
+
+	if w := render(`"other", ` + etag); w.Code != 304 || w.Body.Len() != 0 {
+		t.Errorf("wrong response of a conditional request. status=%d, body=%q", w.Code, w.Body.String())
+	}
+
+	if w := render(`"other"`); w.Code != 200 {
+		t.Errorf("wrong response of a stale ETag. status=%d", w.Code)
+	}
+}
+
+func TestRenderHTTPEncodings(t *testing.T) {
+	Use(t, Templates{"pages.compressed": `<p>{? name ?}</p>`})
+
+	internal.SetSettings(map[string]string{"GOVEL_LAMB_CACHE_DIR": t.TempDir(), "GOVEL_LAMB_CACHE_TIME": "1m"})
+
+	// an encoding that only marks its output
+	lamb.RegisterEncoding("test", func(w io.Writer) io.WriteCloser {
+		io.WriteString(w, "test:")
+
+		return nopCloser{w}
+	})
+
+	t.Cleanup(func() {
+		lamb.RegisterEncoding("test", nil)
+		internal.SetSettings(nil)
+	})
+
+	render := func(acceptEncoding string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept-Encoding", acceptEncoding)
+
+		w := httptest.NewRecorder()
+
+		if err := lamb.RenderHTTP(w, r, "pages.compressed", map[string]interface{}{"name": "Ada", "__cache": "all"}, 0); err != nil {
+			t.Fatalf("RenderHTTP returned an error: %s", err)
+		}
+
+		return w
+	}
+
+	render("gzip")
+
+	if err := lamb.Close(context.Background()); err != nil {
+		t.Fatalf("Close returned an error: %s", err)
+	}
+
+	w := render("gzip, test;q=0.5")
+
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("wrong headers. Content-Encoding=%q, Vary=%q", w.Header().Get("Content-Encoding"), w.Header().Get("Vary"))
+	}
+
+	gz, err := gzip.NewReader(w.Body)
+
+	if err != nil {
+		t.Fatalf("the body is not gzip: %s", err)
+	}
+
+	if body, _ := io.ReadAll(gz); string(body) != "<p>Ada</p>" {
+		t.Errorf("wrong gzip body. got=%q", body)
+	}
+
+	etag, _ := lamb.CachedETag("pages.compressed", nil)
+
+	if w.Header().Get("ETag") == etag {
+		t.Errorf("the gzip variant has the ETag of the identity one")
+	}
+
+	tests := []struct {
+		acceptEncoding string
+		encoding       string
+		body           string
+	}{
+		{"gzip, test", "test", "test:<p>Ada</p>"},
+		{"*;q=0.1, test;q=0", "gzip", ""},
+		{"identity", "", "<p>Ada</p>"},
+		{"", "", "<p>Ada</p>"},
+	}
+
+	for _, tt := range tests {
+		w := render(tt.acceptEncoding)
+
+		if w.Header().Get("Content-Encoding") != tt.encoding {
+			t.Errorf("%q: wrong encoding. expected=%q, got=%q", tt.acceptEncoding, tt.encoding, w.Header().Get("Content-Encoding"))
+		}
+
+		if tt.body != "" && w.Body.String() != tt.body {
+			t.Errorf("%q: wrong body. expected=%q, got=%q", tt.acceptEncoding, tt.body, w.Body.String())
+		}
+	}
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+func TestRenderHTTPTenants(t *testing.T) {
+	Use(t, Templates{
+		"pages.home":              `<p>default</p>`,
+		"tenants.acme.pages.home": `<p>acme</p>`,
+	})
+
+	internal.SetSettings(map[string]string{"GOVEL_LAMB_CACHE_DIR": t.TempDir(), "GOVEL_LAMB_CACHE_TIME": "1m"})
+
+	t.Cleanup(func() {
+		internal.SetSettings(nil)
+	})
+
+	tenants := map[string]func(string) (string, bool){
+		"default": func(string) (string, bool) { return "", false },
+		"acme":    func(name string) (string, bool) { return "tenants.acme." + name, true },
+	}
+
+	render := func(tenant, ifNoneMatch string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+		r.Header.Set("If-None-Match", ifNoneMatch)
+
+		w := httptest.NewRecorder()
+
+		if err := lamb.RenderHTTP(w, r, "pages.home", map[string]interface{}{"__cache": "all", "__resolve": tenants[tenant]}, 0); err != nil {
+			t.Fatalf("RenderHTTP returned an error: %s", err)
+		}
+
+		return w
+	}
+
+	render("default", "")
+	render("acme", "")
+
+	if err := lamb.Close(context.Background()); err != nil {
+		t.Fatalf("Close returned an error: %s", err)
+	}
+
+	etags := make(map[string]string)
+
+	for tenant, expected := range map[string]string{"default": "<p>default</p>", "acme": "<p>acme</p>"} {
+		w := render(tenant, "")
+
+		if w.Header().Get("Content-Encoding") != "gzip" {
+			t.Fatalf("%s: the cached variant is not served. Content-Encoding=%q", tenant, w.Header().Get("Content-Encoding"))
+		}
+
+		gz, err := gzip.NewReader(w.Body)
+
+		if err != nil {
+			t.Fatalf("%s: the body is not gzip: %s", tenant, err)
+		}
+
+		if body, _ := io.ReadAll(gz); string(body) != expected {
+			t.Errorf("%s: wrong body. expected=%q, got=%q", tenant, expected, body)
+		}
+
+		etags[tenant] = w.Header().Get("ETag")
+	}
+
+	if etags["default"] == etags["acme"] {
+		t.Fatalf("the tenants have the same ETag %s", etags["acme"])
+	}
+
+	// the ETag of a tenant does not revalidate the render of another one
+	if w := render("acme", etags["default"]); w.Code != 200 {
+		t.Errorf("the ETag of the default tenant revalidates the acme one. status=%d", w.Code)
+	}
+
+	if w := render("acme", etags["acme"]); w.Code != 304 {
+		t.Errorf("the ETag of the acme tenant does not revalidate it. status=%d", w.Code)
+	}
+
+	if etag, _ := lamb.CachedETag("pages.home", map[string]interface{}{"__resolve": tenants["acme"]}); etag == "" || etag == etags["default"] {
+		t.Errorf("wrong ETag of the acme tenant. got=%q", etag)
+	}
+}
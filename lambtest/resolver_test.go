@@ -0,0 +1,78 @@
+package lambtest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/govel-framework/lamb"
+)
+
+func TestResolver(t *testing.T) {
+	Use(t, Templates{
+		"emails.invoice":              `{? extends("emails.layout") ?}{? section("body") ?}Invoice {? include("emails.footer") ?}{? endsection ?}`,
+		"emails.layout":               `<main>{? define("body") ?}{? end ?}</main>`,
+		"emails.footer":               `<footer>lamb</footer>`,
+		"tenants.acme.emails.invoice": `{? extends("emails.layout") ?}{? section("body") ?}ACME invoice {? include("emails.footer") ?}{? endsection ?}`,
+		"tenants.acme.emails.footer":  `<footer>acme</footer>`,
+	})
+
+	overridden := map[string]bool{"tenants.acme.emails.invoice": true, "tenants.acme.emails.footer": true}
+
+	// the tenant of the render comes from its vars, e.g. from the request
+	tenant := func(name string) func(string) (string, bool) {
+		return func(requested string) (string, bool) {
+			resolved := "tenants." + name + "." + requested
+
+			return resolved, overridden[resolved]
+		}
+	}
+
+	tests := []struct {
+		vars     map[string]interface{}
+		expected string
+	}{
+		{nil, "<main>Invoice <footer>lamb</footer></main>"},
+		{map[string]interface{}{"__resolve": tenant("acme")}, "<main>ACME invoice <footer>acme</footer></main>"},
+		{map[string]interface{}{"__resolve": tenant("other")}, "<main>Invoice <footer>lamb</footer></main>"},
+	}
+
+	for _, tt := range tests {
+		if got := Render(t, "emails.invoice", tt.vars); got != tt.expected {
+			t.Errorf("wrong output. expected=%q, got=%q", tt.expected, got)
+		}
+	}
+
+	// the hook of every render goes after the one of the render
+	lamb.Resolver(func(requested string) (string, bool) {
+		if requested == "emails.footer" {
+			return "tenants.acme.emails.footer", true
+		}
+
+		return "", false
+	})
+
+	t.Cleanup(func() {
+		lamb.Resolver(nil)
+	})
+
+	if got := Render(t, "emails.invoice", nil); got != "<main>Invoice <footer>acme</footer></main>" {
+		t.Errorf("the hook is not used. got=%q", got)
+	}
+
+	none := func(string) (string, bool) { return "", false }
+
+	if got := Render(t, "emails.invoice", map[string]interface{}{"__resolve": none}); got != "<main>Invoice <footer>acme</footer></main>" {
+		t.Errorf("the hook is not used after the resolver of the render. got=%q", got)
+	}
+
+	// a cycle is detected through the resolved names
+	lamb.Resolver(func(requested string) (string, bool) {
+		return "emails.loop", strings.HasPrefix(requested, "emails.")
+	})
+
+	Use(t, Templates{"emails.loop": `{? include("emails.other") ?}`})
+
+	if err := lamb.RenderTo(&strings.Builder{}, "emails.start", nil); err == nil || !strings.Contains(err.Error(), "include cycle detected") {
+		t.Errorf("the cycle is not detected. got=%v", err)
+	}
+}
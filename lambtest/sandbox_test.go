@@ -0,0 +1,133 @@
+package lambtest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/govel-framework/lamb"
+	"github.com/govel-framework/lamb/ast"
+	"github.com/govel-framework/lamb/internal"
+	"github.com/govel-framework/lamb/object"
+)
+
+type sandboxUser struct {
+	Name     string
+	Password string
+	Friends  []*sandboxUser
+}
+
+type sandboxBadge struct {
+	Label  string
+	Secret string
+}
+
+func (b sandboxBadge) String() string { return "[" + b.Label + "]" }
+
+func init() {
+	lamb.AddDirective(lamb.Directive{
+		Keyword: "lambtest_sandboxed",
+		Eval: func(d *ast.DirectiveStatement, env *object.Environment) (string, error) {
+			return "directive", nil
+		},
+	})
+
+	lamb.SandboxProfile("lambtest", object.Sandbox{
+		Functions: []string{"json", "dump", "dd", "len", "first", "join", "map", "filter", "count_if", "concat"},
+		Fields: map[string][]string{
+			"lambtest.sandboxUser":  {"Name", "Friends"},
+			"lambtest.sandboxBadge": {"Label"},
+		},
+	})
+}
+
+func TestSandbox(t *testing.T) {
+	Use(t, Templates{
+		"sandbox.name":      `{? user.Name ?}`,
+		"sandbox.password":  `{? user.Password ?}`,
+		"sandbox.json":      `{? json(user) ?}`,
+		"sandbox.json_list": `{? json({"users": [user]}) ?}`,
+		"sandbox.first":     `{? json(first(user.Friends)) ?}`,
+		"sandbox.dump":      `{? dump(user) ?}`,
+		"sandbox.dd":        `{? dd(user) ?}`,
+		"sandbox.output":    `{? user ?}`,
+		"sandbox.stringer":  `{? badge ?}`,
+		"sandbox.include":   `{? include("sandbox.vars", user) ?}`,
+		"sandbox.vars":      `{? isset(Password) ?}|{? Name ?}`,
+		"sandbox.directive": `{? lambtest_sandboxed ?}`,
+		"sandbox.lambda":    `{? join(map(user.Friends, f => f.Name), ",") ?}|{? len(filter([1, 2, 3], x => x > 1)) ?}|{? count_if(user.Friends, f => f.Name == "Bob") ?}`,
+		"sandbox.empty":     `{? len([]) ?}|{? len(concat([1], [])) ?}|{? json([]) ?}|{? json({}) ?}|{? json(none) ?}`,
+	})
+
+	internal.SetSettings(map[string]string{"GOVEL_LAMB_DEBUG": "true"})
+
+	t.Cleanup(func() {
+		internal.SetSettings(nil)
+	})
+
+	friend := &sandboxUser{Name: "Bob", Password: "friend-secret"}
+	user := &sandboxUser{Name: "Ada", Password: "hunter2", Friends: []*sandboxUser{friend}}
+
+	vars := func() map[string]interface{} {
+		return map[string]interface{}{
+			"user":      user,
+			"badge":     sandboxBadge{Label: "admin", Secret: "badge-secret"},
+			"none":      []*sandboxUser{},
+			"__sandbox": "lambtest",
+		}
+	}
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"sandbox.name", "Ada"},
+		{"sandbox.json", `{"Friends":[{"Friends":null,"Name":"Bob"}],"Name":"Ada"}`},
+		{"sandbox.json_list", `{"users":[{"Friends":[{"Friends":null,"Name":"Bob"}],"Name":"Ada"}]}`},
+		{"sandbox.first", `{"Friends":null,"Name":"Bob"}`},
+		{"sandbox.stringer", "[admin]"},
+		{"sandbox.include", "false|Ada"},
+		{"sandbox.lambda", "Bob|2|1"},
+		{"sandbox.empty", "0|1|[]|{}|[]"},
+	}
+
+	for _, tt := range tests {
+		if got := Render(t, tt.name, vars()); got != tt.expected {
+			t.Errorf("%s: wrong output. expected=%q, got=%q", tt.name, tt.expected, got)
+		}
+	}
+
+	// the denied fields are not in the output of any of them
+	for _, name := range []string{"sandbox.dump", "sandbox.dd", "sandbox.output", "sandbox.json", "sandbox.include"} {
+		got := Render(t, name, vars())
+
+		if strings.Contains(got, "hunter2") || strings.Contains(got, "friend-secret") || strings.Contains(got, "Password") {
+			t.Errorf("%s: a denied field is rendered. got=%q", name, got)
+		}
+
+		if !strings.Contains(got, "Ada") {
+			t.Errorf("%s: an allowed field is not rendered. got=%q", name, got)
+		}
+	}
+
+	errors := map[string]string{
+		"sandbox.password":  "field Password of lambtest.sandboxUser is not allowed in the sandbox",
+		"sandbox.directive": "directive lambtest_sandboxed is not allowed in the sandbox",
+	}
+
+	for name, expected := range errors {
+		if err := lamb.RenderTo(&strings.Builder{}, name, vars()); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%s: wrong error. expected=%q, got=%v", name, expected, err)
+		}
+	}
+
+	// outside of the sandbox everything is allowed
+	unsandboxed := map[string]interface{}{"user": user}
+
+	if got := Render(t, "sandbox.json", unsandboxed); !strings.Contains(got, "hunter2") {
+		t.Errorf("the fields are filtered outside of the sandbox. got=%q", got)
+	}
+
+	if got := Render(t, "sandbox.directive", nil); got != "directive" {
+		t.Errorf("wrong output of the directive. got=%q", got)
+	}
+}
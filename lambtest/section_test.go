@@ -0,0 +1,64 @@
+package lambtest
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/govel-framework/lamb"
+)
+
+func TestRenderSection(t *testing.T) {
+	Use(t, Templates{
+		"layouts.app":    `<html>{? define("content") ?}{? end ?}{? define("sidebar") ?}<aside>{? title ?}</aside>{? end ?}</html>`,
+		"pages.home":     `{? extends("layouts.app") ?}{? section("content") ?}<main>{? title ?}{? include("partials.item") ?}</main>{? endsection ?}`,
+		"pages.plain":    `<header></header>{? define("list") ?}<ul>{? for i in [1, 2] ?}<li>{? i ?}</li>{? endfor ?}</ul>{? end ?}<footer></footer>`,
+		"partials.item":  `<p>item</p>`,
+		"pages.broken":   `{? extends("layouts.app") ?}{? section("content") ?}{? missing() ?}{? endsection ?}`,
+		"pages.variable": `{? var title = "set" ?}{? define("list") ?}{? title ?}{? end ?}`,
+	})
+
+	tests := []struct {
+		file     string
+		section  string
+		expected string
+	}{
+		{"pages.home", "content", "<main>Home<p>item</p></main>"},
+		{"pages.home", "sidebar", "<aside>Home</aside>"},
+		{"pages.plain", "list", "<ul><li>1</li><li>2</li></ul>"},
+		{"pages.variable", "list", "set"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+
+		if err := lamb.RenderSectionTo(&out, tt.file, tt.section, map[string]interface{}{"title": "Home"}); err != nil {
+			t.Errorf("%s %s: RenderSectionTo returned an error: %s", tt.file, tt.section, err)
+
+			continue
+		}
+
+		if out.String() != tt.expected {
+			t.Errorf("%s %s: wrong output. expected=%q, got=%q", tt.file, tt.section, tt.expected, out.String())
+		}
+	}
+
+	errors := []struct {
+		file    string
+		section string
+	}{
+		{"pages.home", "footer"},
+		{"pages.broken", "content"},
+	}
+
+	for _, tt := range errors {
+		var out bytes.Buffer
+
+		if err := lamb.RenderSectionTo(&out, tt.file, tt.section, nil); err == nil {
+			t.Errorf("%s %s: expected an error, got output %q", tt.file, tt.section, out.String())
+		}
+
+		if out.Len() != 0 {
+			t.Errorf("%s %s: expected no output, got=%q", tt.file, tt.section, out.String())
+		}
+	}
+}
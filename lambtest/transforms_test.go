@@ -0,0 +1,55 @@
+package lambtest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/govel-framework/lamb"
+	"github.com/govel-framework/lamb/ast"
+)
+
+func init() {
+	// renames lambtest_renamed to name and rewrites the cdn placeholder
+	lamb.Transform(func(program *ast.Program) *ast.Program {
+		ast.Inspect(program, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.Identifier:
+				if node.Value == "lambtest_renamed" {
+					node.Value = "name"
+				}
+
+			case *ast.HtmlLiteral:
+				node.Value = strings.ReplaceAll(node.Value, "{cdn}", "https://cdn.test")
+			}
+
+			return true
+		})
+
+		return program
+	})
+
+	// runs after the first one and keeps the program by returning nil
+	lamb.Transform(func(program *ast.Program) *ast.Program {
+		ast.Inspect(program, func(node ast.Node) bool {
+			if html, isHtml := node.(*ast.HtmlLiteral); isHtml {
+				html.Value = strings.ReplaceAll(html.Value, "https://cdn.test", "https://cdn.test/v2")
+			}
+
+			return true
+		})
+
+		return nil
+	})
+}
+
+// TestTransform checks that the transforms rewrite the program before it is
+// evaluated.
+func TestTransform(t *testing.T) {
+	Use(t, Templates{
+		"transform.show": `<img src="{cdn}/logo.png">{? lambtest_renamed ?}`,
+	})
+
+	if got := Render(t, "transform.show", map[string]interface{}{"name": "Ada"}); got != `<img src="https://cdn.test/v2/logo.png">Ada` {
+		t.Errorf("wrong output. got=%q", got)
+	}
+}
@@ -0,0 +1,18 @@
+package lamb
+
+import "github.com/govel-framework/lamb/object"
+
+// Lazy returns a var that calls fn on its first use in the render and reuses
+// its value, so the expensive data is only fetched if the template uses it:
+//
+//	lamb.Render(c, "users.show", map[string]interface{}{
+//		"user":   user,
+//		"orders": lamb.Lazy(func() interface{} { return user.Orders() }),
+//	})
+//
+// A func() interface{} var without Lazy is still a function of the template,
+// called with {? orders() ?}. An error returned by fn is an error of the
+// render.
+func Lazy(fn func() interface{}) *object.Lazy {
+	return object.NewLazy(fn)
+}
@@ -1,6 +1,8 @@
 package lexer
 
 import (
+	"strings"
+
 	"github.com/govel-framework/lamb/token"
 )
 
@@ -12,6 +14,7 @@ type Lexer struct {
 	Column       int
 	ch           byte
 	inCode       bool
+	end          token.Position // The position just after the input, set when it is reached.
 }
 
 func New(input string) *Lexer {
@@ -27,6 +30,10 @@ func (l *Lexer) readChar() {
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 
+		if l.end.Line == 0 {
+			l.end = token.Position{Offset: len(l.input), Line: l.Line, Col: l.Column + 1}
+		}
+
 		if l.Column == 0 {
 			l.Column = 1
 		}
@@ -51,7 +58,25 @@ func (l *Lexer) readChar() {
 
 }
 
+// NextToken returns the next token, with its start and end positions.
 func (l *Lexer) NextToken() token.Token {
+	tok := l.next()
+	tok.End = l.current()
+
+	return tok
+}
+
+// current returns the position of the current character, or the one just
+// after the input at its end.
+func (l *Lexer) current() token.Position {
+	if l.end.Line != 0 {
+		return l.end
+	}
+
+	return token.Position{Offset: l.position, Line: l.Line, Col: l.Column}
+}
+
+func (l *Lexer) next() token.Token {
 	var tok token.Token
 
 	if !l.inCode && l.ch != 0 {
@@ -67,6 +92,7 @@ func (l *Lexer) NextToken() token.Token {
 			tok.Literal = string(l.ch)
 			tok.Line = l.Line
 			tok.Col = l.Column
+			tok.Offset = l.position
 
 			l.readChar()
 
@@ -78,24 +104,29 @@ func (l *Lexer) NextToken() token.Token {
 	l.skipWhitespace()
 
 	if l.ch == '?' && l.peekChar() == '}' {
-		l.inCode = false
-		l.readChar()
-		l.readChar()
-
 		tok.Col = l.Column
 		tok.Line = l.Line
+		tok.Offset = l.position
 		tok.Literal = ""
 		tok.Type = token.EOC
 
+		l.inCode = false
+		l.readChar()
+		l.readChar()
+
 		return tok
 	}
 
 	switch l.ch {
 	case '=':
 		if l.peekChar() == '=' {
-			ch := l.ch
+			tok = l.newToken(token.EQ, l.ch)
 			l.readChar()
-			tok = token.Token{Type: token.EQ, Literal: string(ch) + string(l.ch)}
+			tok.Literal += string(l.ch)
+		} else if l.peekChar() == '>' {
+			tok = l.newToken(token.ARROW, l.ch)
+			l.readChar()
+			tok.Literal += string(l.ch)
 		} else {
 			tok = l.newToken(token.ASSIGN, l.ch)
 		}
@@ -108,25 +139,43 @@ func (l *Lexer) NextToken() token.Token {
 
 	case '!':
 		if l.peekChar() == '=' {
-			ch := l.ch
+			tok = l.newToken(token.NOT_EQ, l.ch)
 			l.readChar()
-			tok = token.Token{Type: token.NOT_EQ, Literal: string(ch) + string(l.ch)}
+			tok.Literal += string(l.ch)
 
 		} else {
 			tok = l.newToken(token.BANG, l.ch)
 		}
 
 	case '*':
-		tok = l.newToken(token.ASTERISK, l.ch)
+		if l.peekChar() == '*' {
+			tok = l.newToken(token.POWER, l.ch)
+			l.readChar()
+			tok.Literal += string(l.ch)
+		} else {
+			tok = l.newToken(token.ASTERISK, l.ch)
+		}
 
 	case '/':
 		tok = l.newToken(token.SLASH, l.ch)
 
 	case '<':
-		tok = l.newToken(token.LT, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.newToken(token.LT_EQ, l.ch)
+			l.readChar()
+			tok.Literal += string(l.ch)
+		} else {
+			tok = l.newToken(token.LT, l.ch)
+		}
 
 	case '>':
-		tok = l.newToken(token.GT, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.newToken(token.GT_EQ, l.ch)
+			l.readChar()
+			tok.Literal += string(l.ch)
+		} else {
+			tok = l.newToken(token.GT, l.ch)
+		}
 
 	case ';':
 		tok = l.newToken(token.SEMICOLON, l.ch)
@@ -174,11 +223,13 @@ func (l *Lexer) NextToken() token.Token {
 		tok.Type = token.EOF
 		tok.Line = l.Line
 		tok.Col = l.Column
+		tok.Offset = l.current().Offset
 
 	default:
 		if isLetter(l.ch) {
 			tok.Col = l.Column
 			tok.Line = l.Line
+			tok.Offset = l.position
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookUpIdent(tok.Literal)
 
@@ -187,9 +238,14 @@ func (l *Lexer) NextToken() token.Token {
 		} else if isDigit(l.ch) {
 			tok.Col = l.Column
 			tok.Line = l.Line
+			tok.Offset = l.position
 			tok.Type = token.INT
 			tok.Literal = l.readNumber()
 
+			if strings.Contains(tok.Literal, ".") {
+				tok.Type = token.FLOAT
+			}
+
 			return tok
 
 		} else {
@@ -204,7 +260,7 @@ func (l *Lexer) NextToken() token.Token {
 func (l *Lexer) readIdentifier() string {
 	pos := l.position
 
-	for isLetter(l.ch) {
+	for isLetter(l.ch) || isDigit(l.ch) {
 		l.readChar()
 	}
 
@@ -218,6 +274,15 @@ func (l *Lexer) readNumber() string {
 		l.readChar()
 	}
 
+	// the decimals of a float
+	if l.ch == '.' && isDigit(l.peekChar()) {
+		l.readChar()
+
+		for isDigit(l.ch) {
+			l.readChar()
+		}
+	}
+
 	return l.input[pos:l.position]
 }
 
@@ -244,13 +309,14 @@ func isDigit(ch byte) bool {
 }
 
 func (l *Lexer) newToken(tokenType token.TokenType, ch byte) token.Token {
-	return token.Token{Type: tokenType, Literal: string(ch), Col: l.Column, Line: l.Line}
+	return token.Token{Type: tokenType, Literal: string(ch), Col: l.Column, Line: l.Line, Offset: l.position}
 }
 
 func (l *Lexer) readString(char byte) token.Token {
 	var tok token.Token
 	tok.Col = l.Column
 	tok.Line = l.Line
+	tok.Offset = l.position
 
 	position := l.position + 1
 
@@ -280,3 +346,27 @@ func (l *Lexer) readComment() {
 		l.readChar()
 	}
 }
+
+// Tokens returns the tokens of the input, up to the EOF (included), with the
+// consecutive characters of HTML in a single token.
+func Tokens(input string) []token.Token {
+	var tokens []token.Token
+
+	l := New(input)
+
+	for {
+		tok := l.NextToken()
+
+		if last := len(tokens) - 1; tok.Type == token.HTML && last >= 0 && tokens[last].Type == token.HTML {
+			tokens[last].Literal += tok.Literal
+			tokens[last].End = tok.End
+			continue
+		}
+
+		tokens = append(tokens, tok)
+
+		if tok.Type == token.EOF {
+			return tokens
+		}
+	}
+}
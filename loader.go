@@ -0,0 +1,45 @@
+package lamb
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/govel-framework/lamb/internal"
+)
+
+// Loader returns the source of the templates by their name (e.g. users.show),
+// for the renders, the includes and the layouts.
+type Loader = internal.Loader
+
+// FileLoader loads the templates from the files of the dir of the config,
+// it is the default Loader.
+type FileLoader = internal.FileLoader
+
+// UseLoader sets the loader of the templates, nil restores the FileLoader.
+func UseLoader(l Loader) {
+	internal.SetLoader(l)
+}
+
+// ChainLoader loads a template from the first of its loaders that has it, so
+// the templates of several sources can extend and include each other, e.g.
+// the templates of a CMS in a database and the ones of the files:
+//
+//	lamb.UseLoader(lamb.ChainLoader{cms, lamb.FileLoader{}})
+//
+// A loader does not have a template if its error is fs.ErrNotExist (or wraps
+// it), any other error stops the chain.
+type ChainLoader []Loader
+
+// Load returns the source of the template from the first loader that has it.
+func (c ChainLoader) Load(name string) ([]byte, error) {
+	for _, loader := range c {
+		content, err := loader.Load(name)
+
+		if err == nil || !errors.Is(err, fs.ErrNotExist) {
+			return content, err
+		}
+	}
+
+	return nil, fmt.Errorf("lamb: template %s does not exist: %w", name, fs.ErrNotExist)
+}
@@ -0,0 +1,15 @@
+package lamb
+
+import "github.com/govel-framework/lamb/internal"
+
+// Logger receives the diagnostics of lamb: the cache files that can not be
+// written, the use of deprecated APIs, the undefined vars of the lenient mode
+// and the slow renders. The args are key-value pairs, so a *slog.Logger is a
+// Logger.
+type Logger = internal.Logger
+
+// Logging sets the logger of lamb, nil restores the default one, which writes
+// the warnings and errors with the log package.
+func Logging(l Logger) {
+	internal.SetLogger(l)
+}
@@ -0,0 +1,14 @@
+package lamb
+
+import "github.com/govel-framework/lamb/internal"
+
+// Metrics receives the measures of the renders: the duration, output bytes and
+// error of every template loaded (the rendered one, its layout and its
+// includes) and the result of every lookup in the cache. The package
+// github.com/govel-framework/lamb/prometheus exposes them to Prometheus.
+type Metrics = internal.Metrics
+
+// CollectMetrics sets the metrics of every render, nil disables them.
+func CollectMetrics(m Metrics) {
+	internal.SetMetrics(m)
+}
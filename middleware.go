@@ -0,0 +1,29 @@
+package lamb
+
+import (
+	"fmt"
+
+	"github.com/govel-framework/lamb/internal"
+)
+
+// Middleware registers fn to wrap every template whose name matches pattern
+// (like the patterns of Composer), so cross-cutting concerns are configured in
+// Go instead of being repeated in each template:
+//
+//	lamb.Middleware("admin.*", func(name string, vars map[string]interface{}, next func() (string, error)) (string, error) {
+//		if !isAdmin(vars) {
+//			return "", errors.New("forbidden")
+//		}
+//
+//		content, err := next()
+//
+//		return "<main class=\"admin\">" + content + "</main>", err
+//	})
+//
+// Middlewares run in the order they were registered, the first one is the
+// outermost.
+func Middleware(pattern string, fn func(name string, vars map[string]interface{}, next func() (string, error)) (string, error)) {
+	if err := internal.AddMiddleware(pattern, fn); err != nil {
+		panic(fmt.Sprintf("lamb: invalid middleware pattern %s: %s", pattern, err))
+	}
+}
@@ -1,7 +1,19 @@
 package object
 
+import "time"
+
 type BuiltinFunction func(args ...interface{}) interface{}
 
+// EnvBuiltinFunction is a builtin that also receives the environment of the
+// template that calls it.
+type EnvBuiltinFunction func(env *Environment, args ...interface{}) interface{}
+
 type Builtin struct {
-	Fn BuiltinFunction
+	Fn    BuiltinFunction
+	EnvFn EnvBuiltinFunction // Called instead of Fn when set.
+
+	Retries int           // How many times Fn is called again when it returns an error.
+	Backoff time.Duration // The wait before the first retry, doubled on every retry.
+
+	Deprecated string // Why the builtin is deprecated and what replaces it, empty if it is not.
 }
@@ -0,0 +1,82 @@
+package object
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Diagnostic is a problem of a render that does not stop it, e.g. a var
+// passed to the template that it never uses.
+type Diagnostic struct {
+	Kind    string // unused-var, unused-section, deprecated or large-loop.
+	Message string
+	File    string // The file of the template, empty if it is not known.
+	Line    int    // The line in the file, 0 if it is not known.
+}
+
+func (d Diagnostic) String() string {
+	switch {
+	case d.File == "":
+		return d.Message
+
+	case d.Line == 0:
+		return fmt.Sprintf("%s: %s", d.File, d.Message)
+
+	default:
+		return fmt.Sprintf("%s:%d: %s", d.File, d.Line, d.Message)
+	}
+}
+
+// Diagnostics collects the diagnostics of a render and the vars it reads, it
+// is shared by the includes and the layout of the template.
+type Diagnostics struct {
+	mu          sync.Mutex
+	diagnostics []Diagnostic
+	used        map[string]bool
+}
+
+// Add adds the diagnostic, once.
+func (d *Diagnostics) Add(diagnostic Diagnostic) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, added := range d.diagnostics {
+		if added == diagnostic {
+			return
+		}
+	}
+
+	d.diagnostics = append(d.diagnostics, diagnostic)
+}
+
+// Use records that the render has read the var, d can be nil.
+func (d *Diagnostics) Use(name string) {
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.used == nil {
+		d.used = make(map[string]bool)
+	}
+
+	d.used[name] = true
+}
+
+// Used reports whether the render has read the var.
+func (d *Diagnostics) Used(name string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.used[name]
+}
+
+// List returns the diagnostics in the order they were added.
+func (d *Diagnostics) List() []Diagnostic {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return append([]Diagnostic(nil), d.diagnostics...)
+}
@@ -1,7 +1,13 @@
 package object
 
-import "github.com/govel-framework/lamb/token"
+import (
+	"context"
 
+	"github.com/govel-framework/lamb/ast"
+	"github.com/govel-framework/lamb/token"
+)
+
+// NewEnvironment creates the environment of a render, the scope of its vars.
 func NewEnvironment() *Environment {
 	s := make(map[string]interface{})
 	return &Environment{store: s, outer: nil, ExtendsFrom: parentTemplate{
@@ -9,17 +15,52 @@ func NewEnvironment() *Environment {
 	}}
 }
 
+// CopyEnvironment creates the environment of the layout of a template: it
+// reads the vars of env, but the vars it sets (even the ones of env) stay in
+// the copy, so the layout can not change the vars of the template.
 func CopyEnvironment(env *Environment) *Environment {
 	newEnv := NewEnvironment()
-	newEnv.store = env.store
-	newEnv.outer = env.outer
+	newEnv.outer = env
+	newEnv.isolated = true
 	newEnv.ExtendsFrom = env.ExtendsFrom
+	newEnv.Includes = env.Includes
+	newEnv.Sandbox = env.Sandbox
+	newEnv.Locale = env.Locale
+	newEnv.Context = env.Context
+	newEnv.Fragment = env.Fragment
+	newEnv.Resolve = env.Resolve
+	newEnv.Diagnostics = env.Diagnostics
+	newEnv.Limits = env.Limits
 
-	s, _ := env.Get("sessions")
+	return newEnv
+}
 
-	newEnv.Set("sessions", s)
+// NewEnclosedEnvironment creates the scope of a block (e.g. the body of a for
+// loop or a lambda): its vars hide the ones of outer until the end of the
+// block. It shares the file, sandbox, includes and extends state of outer.
+//
+// The scope comes from a pool, Release returns it when the block ends.
+func NewEnclosedEnvironment(outer *Environment) *Environment {
+	env := scopes.Get().(*Environment)
+	env.outer = outer
+	env.FileName = outer.FileName
+	env.Chunks = outer.Chunks
+	env.Includes = outer.Includes
+	env.Sandbox = outer.Sandbox
+	env.Locale = outer.Locale
+	env.Context = outer.Context
+	env.InExtends = outer.InExtends
+	env.IsExtends = outer.IsExtends
+	env.InSection = outer.InSection
+	env.InDefine = outer.InDefine
+	env.ExtendsFrom = outer.ExtendsFrom
+	env.Fragment = outer.Fragment
+	env.Mode = outer.Mode
+	env.Resolve = outer.Resolve
+	env.Diagnostics = outer.Diagnostics
+	env.Limits = outer.Limits
 
-	return newEnv
+	return env
 }
 
 type SectionContent struct {
@@ -28,14 +69,38 @@ type SectionContent struct {
 	Content interface{} // The default or real content of the section.
 }
 
+// Fragment is the section or the define of a template rendered alone (e.g.
+// for an HTMX response), instead of the whole template.
+type Fragment struct {
+	Name    string      // The name of the section or the define.
+	Found   bool        // The template has rendered the section or the define.
+	Content interface{} // The output of the section or the define, once found.
+}
+
+// Record saves the content of the section or the define with the given name
+// if it is the fragment and it has not been found yet.
+func (f *Fragment) Record(name string, content interface{}) {
+	if f == nil || f.Found || f.Name != name {
+		return
+	}
+
+	f.Found = true
+	f.Content = content
+}
+
 type parentTemplate struct {
 	Sections map[string]SectionContent // The sections in the template.
 	From     string                    // The template that extends from.
 }
 
+// Environment holds the vars of a template in layered scopes: the scopes of
+// the blocks, enclosed by the one of the render (its vars). The shared vars of
+// the application are looked up by the evaluator when no scope has the var.
 type Environment struct {
 	store    map[string]interface{}
 	outer    *Environment
+	isolated bool // Assign does not change the vars of outer, see CopyEnvironment.
+	captured bool // A lambda keeps the scope, so it can not go back to the pool.
 	FileName string
 
 	InExtends bool
@@ -44,21 +109,73 @@ type Environment struct {
 	InDefine  bool
 
 	ExtendsFrom parentTemplate // The template that extends from.
+
+	Chunks *ast.ChunkTable // The interned static fragments of the template being rendered.
+
+	Includes []string // The files of the includes that led to this template, outermost first.
+
+	Sandbox *Sandbox // The restrictions of the template, nil if it is not sandboxed.
+
+	Locale string // The locale of the render, empty to use the one of the request.
+
+	Context context.Context // The context of the render, its cancellation stops the loops over channels. nil if there is none.
+
+	Fragment *Fragment // The section rendered alone, nil to render the whole template.
+
+	Mode string // The output mode of the template (html, xml or json), the escaper of the values it writes. Empty for html.
+
+	Resolve func(name string) (string, bool) // The resolver of the names of the templates of the render, nil if there is none.
+
+	Diagnostics *Diagnostics // The diagnostics of the render, nil if they are logged as they are found.
+
+	Limits *Limits // The execution limits of the render, nil until it starts.
 }
 
+// Get returns the var from the innermost scope that has it, the value of a
+// Lazy var.
 func (e *Environment) Get(name string) (interface{}, bool) {
 	obj, ok := e.store[name]
 
 	if !ok && e.outer != nil {
-		obj, ok = e.outer.Get(name)
+		return e.outer.Get(name)
+	}
+
+	if ok {
+		e.Diagnostics.Use(name)
+	}
+
+	if lazy, isLazy := obj.(*Lazy); isLazy {
+		return lazy.Value(), true
 	}
 
 	return obj, ok
 }
+
+// Set sets the var in this scope.
 func (e *Environment) Set(name string, val interface{}) {
 	e.store[name] = val
 }
 
+// Assign sets the var in the innermost scope that has it, or in this scope if
+// none has it (e.g. the var statements: a var of the template set in the body
+// of a loop keeps its value after the loop).
+func (e *Environment) Assign(name string, val interface{}) {
+	for scope := e; scope != nil; scope = scope.outer {
+		if _, exists := scope.store[name]; exists {
+			scope.store[name] = val
+
+			return
+		}
+
+		if scope.isolated {
+			break
+		}
+	}
+
+	e.store[name] = val
+}
+
+// Delete deletes the var from this scope.
 func (e *Environment) Delete(name string) {
 	delete(e.store, name)
 }
@@ -0,0 +1,11 @@
+package object
+
+// HaltError stops the render of a template (e.g. dd()), the response is
+// Output instead of the template.
+type HaltError struct {
+	Output string
+}
+
+func (h *HaltError) Error() string {
+	return "render halted"
+}
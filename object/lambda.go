@@ -0,0 +1,15 @@
+package object
+
+import "github.com/govel-framework/lamb/ast"
+
+// Lambda is a function created in a template with the arrow syntax, e.g.
+// u => u.Active. It keeps the environment where it was created.
+type Lambda struct {
+	Parameter string
+	Body      ast.Expression
+	Env       *Environment
+
+	// Func is the body of a lambda of a template generated to Go, it is
+	// called instead of evaluating Body.
+	Func func(env *Environment) interface{}
+}
@@ -0,0 +1,27 @@
+package object
+
+import "sync"
+
+// Lazy is a var whose value is computed on its first use in the render, e.g.
+// the related records of a model, and reused by the next ones. A template
+// that does not use it never computes it.
+type Lazy struct {
+	fn    func() interface{}
+	once  sync.Once
+	value interface{}
+}
+
+// NewLazy returns a var whose value is the one returned by fn.
+func NewLazy(fn func() interface{}) *Lazy {
+	return &Lazy{fn: fn}
+}
+
+// Value returns the value of the var, fn is only called the first time.
+func (l *Lazy) Value() interface{} {
+	l.once.Do(func() {
+		l.value = l.fn()
+		l.fn = nil
+	})
+
+	return l.value
+}
@@ -0,0 +1,10 @@
+package object
+
+// Limits are the execution limits of a render, read from the settings once
+// when it starts. A limit of 0 means there is no limit.
+type Limits struct {
+	MaxLoopIterations int // How many iterations a for loop can do.
+	MaxOutputSize     int // How many bytes a template can render.
+	MaxIncludeDepth   int // How many includes can be nested inside each other.
+	LargeLoop         int // From how many elements a for loop is reported as a diagnostic.
+}
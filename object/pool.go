@@ -0,0 +1,42 @@
+package object
+
+import "sync"
+
+// scopes are the environments of the blocks that ended, reused with their
+// store, so a loop or a lambda call does not allocate a new map.
+var scopes = sync.Pool{
+	New: func() interface{} {
+		return &Environment{store: make(map[string]interface{})}
+	},
+}
+
+// maxPooledVars is the number of vars above which a scope is not reused, so a
+// block with many vars does not keep a big map alive.
+const maxPooledVars = 64
+
+// Capture marks the environment and the ones that enclose it as kept by a
+// lambda, they are never released.
+func (e *Environment) Capture() {
+	for scope := e; scope != nil && !scope.captured; scope = scope.outer {
+		scope.captured = true
+	}
+}
+
+// Release returns the scope created by NewEnclosedEnvironment to the pool at
+// the end of its block, the scope can not be used after. A scope captured by
+// a lambda is left to the garbage collector.
+func (e *Environment) Release() {
+	if e.captured || len(e.store) > maxPooledVars {
+		return
+	}
+
+	store := e.store
+
+	for name := range store {
+		delete(store, name)
+	}
+
+	*e = Environment{store: store}
+
+	scopes.Put(e)
+}
@@ -0,0 +1,65 @@
+package object
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// BuiltinRegistry holds the builtin functions that templates can call, it is
+// safe to register functions while templates are being rendered.
+type BuiltinRegistry struct {
+	mu       sync.RWMutex
+	builtins map[string]*Builtin
+}
+
+// NewBuiltinRegistry creates a registry with a copy of the builtins.
+func NewBuiltinRegistry(builtins map[string]*Builtin) *BuiltinRegistry {
+	r := &BuiltinRegistry{builtins: make(map[string]*Builtin, len(builtins))}
+
+	for name, builtin := range builtins {
+		r.builtins[name] = builtin
+	}
+
+	return r
+}
+
+// Get returns the builtin with the given name.
+func (r *BuiltinRegistry) Get(name string) (*Builtin, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	builtin, exists := r.builtins[name]
+
+	return builtin, exists
+}
+
+// Register adds a builtin, it fails if a builtin with the same name exists.
+func (r *BuiltinRegistry) Register(name string, builtin *Builtin) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.builtins[name]; exists {
+		return fmt.Errorf("function %s already exists", name)
+	}
+
+	r.builtins[name] = builtin
+
+	return nil
+}
+
+// Names returns the names of the builtins, sorted.
+func (r *BuiltinRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.builtins))
+
+	for name := range r.builtins {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
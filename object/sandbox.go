@@ -0,0 +1,33 @@
+package object
+
+import "reflect"
+
+// Sandbox restricts the builtins a template can call and the struct fields it
+// can access, anything else is an error at eval time.
+type Sandbox struct {
+	Functions []string            // The builtins the template can call.
+	Fields    map[string][]string // The fields the template can access by struct type (e.g. "models.User"), "*" allows all of them.
+}
+
+// CanCall reports whether the builtin can be called in the sandbox.
+func (s *Sandbox) CanCall(name string) bool {
+	for _, function := range s.Functions {
+		if function == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CanAccess reports whether the field of the struct type can be accessed in
+// the sandbox.
+func (s *Sandbox) CanAccess(structType reflect.Type, field string) bool {
+	for _, allowed := range s.Fields[structType.String()] {
+		if allowed == "*" || allowed == field {
+			return true
+		}
+	}
+
+	return false
+}
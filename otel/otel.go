@@ -0,0 +1,60 @@
+// Package lambotel traces the renders of lamb with OpenTelemetry:
+//
+//	lamb.Tracing(lambotel.NewTracer(otel.Tracer("lamb")))
+//
+// It is a module of its own, so lamb does not depend on OpenTelemetry.
+package lambotel
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/govel-framework/lamb"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type tracer struct {
+	tracer trace.Tracer
+}
+
+// NewTracer returns a lamb.Tracer that starts its spans with t.
+func NewTracer(t trace.Tracer) lamb.Tracer {
+	return tracer{tracer: t}
+}
+
+func (t tracer) Start(ctx context.Context, name string) (context.Context, lamb.Span) {
+	ctx, s := t.tracer.Start(ctx, name)
+
+	return ctx, span{span: s}
+}
+
+type span struct {
+	span trace.Span
+}
+
+func (s span) SetAttribute(key string, value interface{}) {
+	switch value := value.(type) {
+	case string:
+		s.span.SetAttributes(attribute.String(key, value))
+
+	case int:
+		s.span.SetAttributes(attribute.Int(key, value))
+
+	case bool:
+		s.span.SetAttributes(attribute.Bool(key, value))
+
+	default:
+		s.span.SetAttributes(attribute.String(key, fmt.Sprint(value)))
+	}
+}
+
+func (s span) RecordError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s span) End() {
+	s.span.End()
+}
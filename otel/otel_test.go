@@ -0,0 +1,86 @@
+package lambotel
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/govel-framework/lamb"
+	"github.com/govel-framework/lamb/lambtest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// TestTracer checks the spans recorded for a render and its includes.
+func TestTracer(t *testing.T) {
+	lambtest.Use(t, lambtest.Templates{
+		"otel.page":   `<h1>{? title ?}</h1>{? include("otel.nav") ?}`,
+		"otel.nav":    `<nav></nav>`,
+		"otel.broken": `{? 1 / n ?}`,
+	})
+
+	recorder := tracetest.NewSpanRecorder()
+
+	lamb.Tracing(NewTracer(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("lamb")))
+
+	t.Cleanup(func() {
+		lamb.Tracing(nil)
+	})
+
+	err := lamb.RenderHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil), "otel.page", map[string]interface{}{"title": "Hi"}, 0)
+
+	if err != nil {
+		t.Fatalf("RenderHTTP returned an error: %s", err)
+	}
+
+	spans := recorder.Ended()
+
+	expected := []struct {
+		name       string
+		attributes []attribute.KeyValue
+	}{
+		{"lamb.load", []attribute.KeyValue{attribute.String("lamb.template", "otel.nav"), attribute.Int("lamb.bytes", 11)}},
+		{"lamb.load", []attribute.KeyValue{attribute.String("lamb.template", "otel.page"), attribute.Int("lamb.bytes", 22)}},
+		{"lamb.render", []attribute.KeyValue{attribute.String("lamb.template", "otel.page"), attribute.Int("lamb.bytes", 22)}},
+	}
+
+	if len(spans) != len(expected) {
+		t.Fatalf("wrong number of spans. expected=%d, got=%d", len(expected), len(spans))
+	}
+
+	for i, tt := range expected {
+		if spans[i].Name() != tt.name {
+			t.Errorf("spans[%d] - wrong name. expected=%q, got=%q", i, tt.name, spans[i].Name())
+		}
+
+		attributes := attribute.NewSet(spans[i].Attributes()...)
+
+		for _, kv := range tt.attributes {
+			if value, exists := attributes.Value(kv.Key); !exists || value != kv.Value {
+				t.Errorf("spans[%d] - wrong %s. expected=%v, got=%v", i, kv.Key, kv.Value.Emit(), value.Emit())
+			}
+		}
+	}
+
+	// the loads are children of the render
+	render := spans[2].SpanContext()
+
+	if spans[1].Parent().SpanID() != render.SpanID() || spans[0].Parent().SpanID() != spans[1].SpanContext().SpanID() {
+		t.Errorf("wrong parents. render=%s, page=%s, nav=%s", render.SpanID(), spans[1].Parent().SpanID(), spans[0].Parent().SpanID())
+	}
+
+	// the error of a render is recorded on its span
+	err = lamb.RenderHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil), "otel.broken", map[string]interface{}{"n": 0}, 0)
+
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	spans = recorder.Ended()
+	last := spans[len(spans)-1]
+
+	if last.Name() != "lamb.render" || last.Status().Code != codes.Error || last.Status().Description != err.Error() {
+		t.Errorf("the error is not recorded. name=%s, status=%+v", last.Name(), last.Status())
+	}
+}
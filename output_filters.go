@@ -0,0 +1,18 @@
+package lamb
+
+import "github.com/govel-framework/lamb/internal"
+
+// OutputFilter registers fn to rewrite the output of every render, with the
+// name of the template, e.g. to rewrite the links or to inject a debug
+// toolbar:
+//
+//	lamb.OutputFilter(func(name string, html []byte) []byte {
+//		return bytes.Replace(html, []byte("</body>"), toolbar, 1)
+//	})
+//
+// The filters run once the output is whole (its includes and its layout are
+// not filtered apart), after the minification and before the output is
+// cached and written, in the order they were registered.
+func OutputFilter(fn func(name string, html []byte) []byte) {
+	internal.AddOutputFilter(fn)
+}
@@ -0,0 +1,155 @@
+package parser
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/govel-framework/lamb/ast"
+	"github.com/govel-framework/lamb/token"
+)
+
+// DirectiveParseFunc parses a directive registered with RegisterDirective,
+// the current token is its keyword and directive has its Token and Name set.
+// It returns false if the directive is not valid, after reporting the error
+// with p.Error.
+type DirectiveParseFunc func(p *DirectiveParser, directive *ast.DirectiveStatement) bool
+
+type directive struct {
+	ends  []token.TokenType
+	parse DirectiveParseFunc
+}
+
+var (
+	directives   = make(map[token.TokenType]directive)
+	directivesMu sync.RWMutex
+)
+
+// RegisterDirective adds the directive with the given keyword (already
+// registered with token.RegisterKeyword) to the parsers created after the
+// call. ends are the keywords that end its block, a nil parse parses the
+// default form: the keyword, optional arguments between parentheses and,
+// if there are ends, a block.
+func RegisterDirective(keyword token.TokenType, ends []token.TokenType, parse DirectiveParseFunc) {
+	directivesMu.Lock()
+	defer directivesMu.Unlock()
+
+	directives[keyword] = directive{ends: ends, parse: parse}
+}
+
+// registerDirectives registers the prefix functions of the directives.
+func (p *Parser) registerDirectives() {
+	directivesMu.RLock()
+	defer directivesMu.RUnlock()
+
+	for keyword := range directives {
+		p.registerPrefix(keyword, p.parseDirective)
+	}
+}
+
+func (p *Parser) parseDirective() ast.Expression {
+	directivesMu.RLock()
+	d := directives[p.curToken.Type]
+	directivesMu.RUnlock()
+
+	expression := &ast.DirectiveStatement{Token: p.curToken, Name: p.curToken.Literal}
+
+	parse := d.parse
+
+	if parse == nil {
+		parse = (*DirectiveParser).parseDefault
+	}
+
+	if !parse(&DirectiveParser{p: p, ends: d.ends}, expression) {
+		return nil
+	}
+
+	return expression
+}
+
+// DirectiveParser is the part of the parser available to the directives.
+type DirectiveParser struct {
+	p    *Parser
+	ends []token.TokenType
+}
+
+func (dp *DirectiveParser) parseDefault(directive *ast.DirectiveStatement) bool {
+	if dp.PeekToken().Type == token.LPAREN {
+		directive.Arguments = dp.ParseArguments()
+
+		if directive.Arguments == nil {
+			return false
+		}
+	}
+
+	if len(dp.ends) == 0 {
+		return true
+	}
+
+	directive.Block = dp.ParseBlock(dp.ends...)
+	directive.EndKeyword = dp.Token().Literal
+
+	return directive.Block != nil
+}
+
+// Token returns the current token.
+func (dp *DirectiveParser) Token() token.Token {
+	return dp.p.curToken
+}
+
+// PeekToken returns the token after the current one.
+func (dp *DirectiveParser) PeekToken() token.Token {
+	return dp.p.peekToken
+}
+
+// NextToken advances to the next token.
+func (dp *DirectiveParser) NextToken() {
+	dp.p.nextToken()
+}
+
+// ExpectPeek advances to the next token if it is of type t, otherwise it
+// reports an error and returns false.
+func (dp *DirectiveParser) ExpectPeek(t token.TokenType) bool {
+	return dp.p.expectPeek(t)
+}
+
+// ParseExpression advances to the next token and parses the expression that
+// starts there.
+func (dp *DirectiveParser) ParseExpression() ast.Expression {
+	dp.p.nextToken()
+
+	return dp.p.parseExpression(LOWEST)
+}
+
+// ParseArguments parses the arguments between parentheses that follow the
+// current token, it returns nil if they are not valid.
+func (dp *DirectiveParser) ParseArguments() []ast.Expression {
+	if !dp.p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	return dp.p.parseExpressionList(token.RPAREN)
+}
+
+// ParseBlock parses the block that starts after the end of the current code
+// block and finishes at one of the ends, which is the current token after the
+// call. It returns nil if there is no block.
+func (dp *DirectiveParser) ParseBlock(ends ...token.TokenType) *ast.BlockStatement {
+	if !dp.p.expectPeek(token.EOC) {
+		return nil
+	}
+
+	limits := make(map[token.TokenType]bool, len(ends))
+
+	for _, end := range ends {
+		limits[end] = true
+	}
+
+	return dp.p.parseBlockStatement(limits)
+}
+
+// Error reports an error at the current position.
+func (dp *DirectiveParser) Error(format string, a ...interface{}) {
+	msg := fmt.Sprintf("%d:%d: %s", dp.p.l.Line, dp.p.l.Column, fmt.Sprintf(format, a...))
+
+	dp.p.errors = append(dp.p.errors, msg)
+}
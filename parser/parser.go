@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"reflect"
 	"strconv"
 
 	"github.com/govel-framework/lamb/ast"
@@ -12,11 +13,13 @@ import (
 const (
 	_ int = iota
 	LOWEST
+	LAMBDA      // x => x.Field
 	EQUALS      // ==
 	LESSGREATER // > or <
 	SUM         // +
 	PRODUCT     // *
 	PREFIX      // -x or !x
+	POWER       // x ** y
 	CALL        // function(x)
 	INDEX       // array[index]
 	IN          // example in examples
@@ -29,14 +32,20 @@ var precedences = map[token.TokenType]int{
 	token.NOT_EQ:   EQUALS,
 	token.LT:       LESSGREATER,
 	token.GT:       LESSGREATER,
+	token.LT_EQ:    LESSGREATER,
+	token.GT_EQ:    LESSGREATER,
+	token.IN:       EQUALS,
+	token.NOT:      EQUALS,
 	token.PLUS:     SUM,
 	token.MINUS:    SUM,
 	token.SLASH:    PRODUCT,
 	token.ASTERISK: PRODUCT,
+	token.POWER:    POWER,
 	token.LPAREN:   CALL,
 	token.LBRACKET: INDEX,
 	token.DOT:      DOT,
 	token.AND:      AND,
+	token.ARROW:    LAMBDA,
 }
 
 type (
@@ -53,17 +62,21 @@ type Parser struct {
 
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
+
+	chunks *ast.ChunkTable // The static HTML fragments found while parsing.
 }
 
 func New(l *lexer.Lexer) *Parser {
-	p := &Parser{l: l, errors: []string{}}
+	p := &Parser{l: l, errors: []string{}, chunks: ast.NewChunkTable()}
 
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
 
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+	p.registerPrefix(token.NOT, p.parsePrefixExpression)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
@@ -78,20 +91,31 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.SECTION, p.parseSectionExpression)
 	p.registerPrefix(token.DEFINE, p.parseDefineExpression)
 	p.registerPrefix(token.INCLUDE, p.parseIncludeExpression)
+	p.registerPrefix(token.TRY, p.parseTryExpression)
+	p.registerPrefix(token.SPACELESS, p.parseSpacelessExpression)
+	p.registerPrefix(token.PROPS, p.parsePropsExpression)
+	p.registerPrefix(token.AUTOESCAPE, p.parseAutoescapeExpression)
+	p.registerDirectives()
 
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
 	p.registerInfix(token.MINUS, p.parseInfixExpression)
 	p.registerInfix(token.SLASH, p.parseInfixExpression)
 	p.registerInfix(token.ASTERISK, p.parseInfixExpression)
+	p.registerInfix(token.POWER, p.parsePowerExpression)
 	p.registerInfix(token.EQ, p.parseInfixExpression)
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.LT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.GT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.IN, p.parseInfixExpression)
+	p.registerInfix(token.NOT, p.parseNotInExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
 	p.registerInfix(token.DOT, p.parseDotExpression)
 	p.registerInfix(token.AND, p.parseAndExpression)
+	p.registerInfix(token.ARROW, p.parseLambdaExpression)
 
 	// Read two tokens so curToken and peekToken are both set
 	p.nextToken()
@@ -122,7 +146,8 @@ func (p *Parser) nextToken() {
 }
 
 func (p *Parser) ParseProgram() *ast.Program {
-	program := &ast.Program{}
+	program := &ast.Program{Chunks: p.chunks}
+	from := p.curToken.Pos()
 
 	program.Statements = []ast.Statement{}
 
@@ -130,22 +155,86 @@ func (p *Parser) ParseProgram() *ast.Program {
 		stmt := p.parseStatement()
 
 		if stmt != nil {
-			program.Statements = append(program.Statements, stmt)
+			program.Statements = p.appendStatement(program.Statements, stmt)
 		}
 
 		p.nextToken()
 	}
 
+	p.internChunks(program.Statements)
+
+	program.SetSpan(from, p.curToken.End)
+
 	return program
 }
 
+// appendStatement appends stmt to stmts, merging consecutive HTML literals
+// into a single one.
+func (p *Parser) appendStatement(stmts []ast.Statement, stmt ast.Statement) []ast.Statement {
+	html, isHtml := htmlLiteral(stmt)
+
+	if isHtml && len(stmts) > 0 {
+		if last, lastIsHtml := htmlLiteral(stmts[len(stmts)-1]); lastIsHtml {
+			last.Value += html.Value
+
+			last.SetSpan(last.Pos(), html.End())
+			stmts[len(stmts)-1].(*ast.ExpressionStatement).SetSpan(last.Pos(), html.End())
+
+			return stmts
+		}
+	}
+
+	return append(stmts, stmt)
+}
+
+// internChunks adds the HTML literals of stmts to the parser's chunk table.
+func (p *Parser) internChunks(stmts []ast.Statement) {
+	for _, stmt := range stmts {
+		if html, isHtml := htmlLiteral(stmt); isHtml {
+			html.Chunk = p.chunks.Intern(html.Value)
+		}
+	}
+}
+
+func htmlLiteral(stmt ast.Statement) (*ast.HtmlLiteral, bool) {
+	es, isExpression := stmt.(*ast.ExpressionStatement)
+
+	if !isExpression {
+		return nil, false
+	}
+
+	html, isHtml := es.Expression.(*ast.HtmlLiteral)
+
+	return html, isHtml
+}
+
 func (p *Parser) parseStatement() ast.Statement {
+	from := p.curToken.Pos()
+
+	var stmt ast.Statement
+
 	switch p.curToken.Type {
 	case token.VAR:
-		return p.parseVarStatement()
+		stmt = p.parseVarStatement()
 	default:
-		return p.parseExpressionStatement()
+		stmt = p.parseExpressionStatement()
 	}
+
+	setSpan(stmt, from, p.curToken.End)
+
+	return stmt
+}
+
+// setSpan sets the range of the source of the node, unless it could not be
+// parsed.
+func setSpan(node ast.Node, from token.Position, to token.Position) {
+	if node == nil || reflect.ValueOf(node).IsNil() {
+		return
+	}
+
+	node.(interface {
+		SetSpan(from token.Position, to token.Position)
+	}).SetSpan(from, to)
 }
 
 func (p *Parser) parseVarStatement() *ast.VarStatement {
@@ -156,6 +245,7 @@ func (p *Parser) parseVarStatement() *ast.VarStatement {
 	}
 
 	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	stmt.Name.SetSpan(p.curToken.Pos(), p.curToken.End)
 
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
@@ -207,9 +297,23 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 		return nil
 	}
 
+	from := p.curToken.Pos()
+
 	leftExp := prefix()
 
+	setSpan(leftExp, from, p.curToken.End)
+
+	// the markup is never an operand, e.g. of the "-" of "<p> {? -1 ?}"
+	if _, isHtml := leftExp.(*ast.HtmlLiteral); isHtml {
+		return leftExp
+	}
+
 	for !p.peekTokenIs(token.SEMICOLON) && precedence < p.peekPrecedence() {
+		// the prefix is not valid, its error is already reported
+		if leftExp == nil {
+			return nil
+		}
+
 		infix := p.infixParseFns[p.peekToken.Type]
 
 		if infix == nil {
@@ -219,6 +323,8 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 		p.nextToken()
 
 		leftExp = infix(leftExp)
+
+		setSpan(leftExp, from, p.curToken.End)
 	}
 
 	return leftExp
@@ -257,6 +363,23 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	return lit
 }
 
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{Token: p.curToken}
+
+	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+
+	if err != nil {
+		msg := fmt.Sprintf("%d:%d: could not parse %q as float", p.l.Line, p.l.Column, p.curToken.Literal)
+
+		p.errors = append(p.errors, msg)
+
+		return nil
+	}
+
+	lit.Value = value
+	return lit
+}
+
 func (p *Parser) noPrefixParseFnError(t token.Token) {
 	msg := fmt.Sprintf("%d:%d: unexpected token %q", t.Line, t.Col, t.Type)
 
@@ -308,6 +431,43 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	return expression
 }
 
+// parsePowerExpression parses "**", which is right-associative: 2 ** 3 ** 2
+// is 2 ** (3 ** 2).
+func (p *Parser) parsePowerExpression(left ast.Expression) ast.Expression {
+	expression := &ast.InfixExpression{
+		Token:    p.curToken,
+		Operator: p.curToken.Literal,
+		Left:     left,
+	}
+
+	p.nextToken()
+
+	expression.Right = p.parseExpression(POWER - 1)
+
+	return expression
+}
+
+// parseNotInExpression parses the composed operator "not in": x not in list.
+func (p *Parser) parseNotInExpression(left ast.Expression) ast.Expression {
+	expression := &ast.InfixExpression{
+		Token:    p.curToken,
+		Operator: "not in",
+		Left:     left,
+	}
+
+	precedence := p.curPrecedence()
+
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+
+	p.nextToken()
+
+	expression.Right = p.parseExpression(precedence)
+
+	return expression
+}
+
 func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
 }
@@ -363,6 +523,9 @@ func (p *Parser) parseBlockStatement(limits map[token.TokenType]bool) *ast.Block
 	block := &ast.BlockStatement{Token: p.curToken}
 	block.Statements = []ast.Statement{}
 
+	// the block starts after the code block that opens it
+	from := p.curToken.End
+
 	p.nextToken()
 
 	for !p.curTokenIs(token.EOF) {
@@ -375,7 +538,7 @@ func (p *Parser) parseBlockStatement(limits map[token.TokenType]bool) *ast.Block
 		stmt := p.parseStatement()
 
 		if stmt != nil {
-			block.Statements = append(block.Statements, stmt)
+			block.Statements = p.appendStatement(block.Statements, stmt)
 		}
 
 		p.nextToken()
@@ -388,6 +551,11 @@ func (p *Parser) parseBlockStatement(limits map[token.TokenType]bool) *ast.Block
 		}
 	}
 
+	p.internChunks(block.Statements)
+
+	// and ends at the keyword that closes it
+	block.SetSpan(from, p.curToken.Pos())
+
 	return block
 }
 
@@ -426,11 +594,20 @@ func (p *Parser) parseStringLiteral() ast.Expression {
 
 	var closed bool
 
-	if literal[0] == literal[len(literal)-1] {
+	if len(literal) > 1 && literal[0] == literal[len(literal)-1] {
 		closed = true
 	}
 
-	return &ast.StringLiteral{Token: p.curToken, Value: literal, Closed: closed}
+	return &ast.StringLiteral{Token: p.curToken, Value: unquote(literal), Closed: closed}
+}
+
+// unquote removes the delimiters of a string literal.
+func unquote(literal string) string {
+	if len(literal) > 1 && literal[0] == literal[len(literal)-1] {
+		return literal[1 : len(literal)-1]
+	}
+
+	return literal[1:]
 }
 
 func (p *Parser) parseArrayLiteral() ast.Expression {
@@ -454,6 +631,12 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
+
+		// a trailing comma
+		if p.peekTokenIs(end) {
+			break
+		}
+
 		p.nextToken()
 		list = append(list, p.parseExpression(LOWEST))
 	}
@@ -478,15 +661,22 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	return exp
 }
 
+// parseMapLiteral parses a map, a bare identifier key is a string like in JS
+// ({name: user.Name} is {"name": user.Name}), a key in parentheses is the
+// value of the expression ({(field): value}).
 func (p *Parser) parseMapLiteral() ast.Expression {
 	mapLiteral := &ast.MapLiteral{Token: p.curToken}
 
-	mapLiteral.Pairs = make(map[ast.Expression]ast.Expression)
-
 	for !p.peekTokenIs(token.RBRACE) {
 		p.nextToken()
 
-		key := p.parseExpression(LOWEST)
+		var key ast.Expression
+
+		if p.curTokenIs(token.IDENT) && p.peekTokenIs(token.COLON) {
+			key = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal, Closed: true}
+		} else {
+			key = p.parseExpression(LOWEST)
+		}
 
 		if !p.expectPeek(token.COLON) {
 			return nil
@@ -496,7 +686,7 @@ func (p *Parser) parseMapLiteral() ast.Expression {
 
 		value := p.parseExpression(LOWEST)
 
-		mapLiteral.Pairs[key] = value
+		mapLiteral.Pairs = append(mapLiteral.Pairs, ast.MapPair{Key: key, Value: value})
 
 		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
 			return nil
@@ -570,7 +760,37 @@ func (p *Parser) parseExtendsExpression() ast.Expression {
 		return nil
 	}
 
-	expression.From = p.curToken.Literal
+	expression.From = unquote(p.curToken.Literal)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.EOC) {
+		return nil
+	}
+
+	return expression
+}
+
+func (p *Parser) parseAutoescapeExpression() ast.Expression {
+	expression := &ast.AutoescapeStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.STRING) {
+		return nil
+	}
+
+	expression.Mode = unquote(p.curToken.Literal)
+
+	if expression.Mode != "html" && expression.Mode != "xml" && expression.Mode != "json" {
+		p.errors = append(p.errors, fmt.Sprintf("%d:%d: unknown autoescape mode %s, expected html, xml or json", p.curToken.Line, p.curToken.Col, expression.Mode))
+
+		return nil
+	}
 
 	if !p.expectPeek(token.RPAREN) {
 		return nil
@@ -594,7 +814,7 @@ func (p *Parser) parseSectionExpression() ast.Expression {
 		return nil
 	}
 
-	expression.Name = p.curToken.Literal
+	expression.Name = unquote(p.curToken.Literal)
 
 	if !p.expectPeek(token.RPAREN) {
 		return nil
@@ -625,7 +845,7 @@ func (p *Parser) parseDefineExpression() ast.Expression {
 		return nil
 	}
 
-	expression.Name = p.curToken.Literal
+	expression.Name = unquote(p.curToken.Literal)
 
 	if !p.expectPeek(token.RPAREN) {
 		return nil
@@ -648,21 +868,22 @@ func (p *Parser) parseDefineExpression() ast.Expression {
 func (p *Parser) parseDotExpression(left ast.Expression) ast.Expression {
 	expression := &ast.DotExpression{Token: p.curToken}
 
-	leftIdent, isIdent := left.(*ast.Identifier)
+	switch left.(type) {
+	case *ast.Identifier, *ast.IndexExpression, *ast.DotExpression, *ast.CallExpression:
+		expression.Left = left
 
-	if !isIdent {
+	default:
 		p.lastTokenError(token.IDENT, left.TokenLiteral())
 		return nil
 	}
 
-	expression.Left = *leftIdent
-
 	// get the right identifier
 	if !p.expectPeek(token.IDENT) {
 		return nil
 	}
 
 	expression.Right = ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	expression.Right.SetSpan(p.curToken.Pos(), p.curToken.End)
 
 	return expression
 }
@@ -678,7 +899,7 @@ func (p *Parser) parseIncludeExpression() ast.Expression {
 		return nil
 	}
 
-	expression.File = p.curToken.Literal
+	expression.File = unquote(p.curToken.Literal)
 
 	if p.peekTokenIs(token.COMMA) {
 		p.nextToken()
@@ -707,3 +928,187 @@ func (p *Parser) parseAndExpression(left ast.Expression) ast.Expression {
 
 	return expression
 }
+
+func (p *Parser) parseLambdaExpression(left ast.Expression) ast.Expression {
+	expression := &ast.LambdaLiteral{Token: p.curToken}
+
+	parameter, isIdentifier := left.(*ast.Identifier)
+
+	if !isIdentifier {
+		p.lastTokenError(token.IDENT, left.TokenLiteral())
+		return nil
+	}
+
+	expression.Parameter = parameter
+
+	p.nextToken()
+
+	expression.Body = p.parseExpression(LOWEST)
+
+	return expression
+}
+
+func (p *Parser) parseTryExpression() ast.Expression {
+	expression := &ast.TryStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.EOC) {
+		return nil
+	}
+
+	limit := map[token.TokenType]bool{
+		token.ENDTRY: true,
+		token.RESCUE: true,
+	}
+
+	expression.Block = p.parseBlockStatement(limit)
+
+	if p.curTokenIs(token.RESCUE) {
+		if p.peekTokenIs(token.IDENT) {
+			p.nextToken()
+
+			expression.Err = p.curToken.Literal
+		}
+
+		if !p.expectPeek(token.EOC) {
+			return nil
+		}
+
+		limit = map[token.TokenType]bool{
+			token.ENDTRY: true,
+		}
+
+		expression.Rescue = p.parseBlockStatement(limit)
+	}
+
+	return expression
+}
+
+func (p *Parser) parsePropsExpression() ast.Expression {
+	expression := &ast.PropsStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	for !p.peekTokenIs(token.RPAREN) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		prop := ast.Prop{Token: p.curToken, Name: p.curToken.Literal}
+
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+
+		p.nextToken()
+
+		if prop.Type = p.parsePropType(); prop.Type == "" {
+			return nil
+		}
+
+		if p.peekTokenIs(token.ASSIGN) {
+			p.nextToken()
+			p.nextToken()
+
+			prop.Default = p.parseExpression(LOWEST)
+		}
+
+		expression.Props = append(expression.Props, prop)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.EOC) {
+		return nil
+	}
+
+	return expression
+}
+
+// parsePropType parses the type of a prop: a name (string, User or
+// models.User), []T, *T or map[K]V. It returns "" if the type is not valid.
+func (p *Parser) parsePropType() string {
+	switch p.curToken.Type {
+	case token.LBRACKET:
+		if !p.expectPeek(token.RBRACKET) {
+			return ""
+		}
+
+		p.nextToken()
+
+		if elem := p.parsePropType(); elem != "" {
+			return "[]" + elem
+		}
+
+	case token.ASTERISK:
+		p.nextToken()
+
+		if elem := p.parsePropType(); elem != "" {
+			return "*" + elem
+		}
+
+	case token.IDENT:
+		name := p.curToken.Literal
+
+		if name == "map" && p.peekTokenIs(token.LBRACKET) {
+			p.nextToken()
+			p.nextToken()
+
+			key := p.parsePropType()
+
+			if key == "" || !p.expectPeek(token.RBRACKET) {
+				return ""
+			}
+
+			p.nextToken()
+
+			if value := p.parsePropType(); value != "" {
+				return "map[" + key + "]" + value
+			}
+
+			return ""
+		}
+
+		if p.peekTokenIs(token.DOT) {
+			p.nextToken()
+
+			if !p.expectPeek(token.IDENT) {
+				return ""
+			}
+
+			name += "." + p.curToken.Literal
+		}
+
+		return name
+
+	default:
+		p.errors = append(p.errors, fmt.Sprintf("%d:%d: expected a type, got %s instead", p.curToken.Line, p.curToken.Col, p.curToken.Type))
+	}
+
+	return ""
+}
+
+func (p *Parser) parseSpacelessExpression() ast.Expression {
+	expression := &ast.SpacelessStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.EOC) {
+		return nil
+	}
+
+	limit := map[token.TokenType]bool{
+		token.ENDSPACELESS: true,
+	}
+
+	expression.Block = p.parseBlockStatement(limit)
+
+	return expression
+}
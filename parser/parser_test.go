@@ -6,6 +6,7 @@ import (
 
 	"github.com/govel-framework/lamb/ast"
 	"github.com/govel-framework/lamb/lexer"
+	"github.com/govel-framework/lamb/token"
 )
 
 func TestVarStatements(t *testing.T) {
@@ -148,6 +149,27 @@ func TestIntegerLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestFloatLiteralExpression(t *testing.T) {
+	input := "{? 1.25 ?}"
+
+	l := lexer.New(input)
+	p := New(l)
+
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	literal, ok := stmt.Expression.(*ast.FloatLiteral)
+
+	if !ok {
+		t.Fatalf("exp not %T. got=%T", &ast.FloatLiteral{}, stmt.Expression)
+	}
+
+	if literal.Value != 1.25 {
+		t.Errorf("literal.Value not %f. got=%f", 1.25, literal.Value)
+	}
+}
+
 func TestParsingPrefixExpressions(t *testing.T) {
 	prefixTests := []struct {
 		input    string
@@ -731,7 +753,9 @@ func TestParsingHashLiteralsStringKeys(t *testing.T) {
 		"three": 3,
 	}
 
-	for key, value := range hash.Pairs {
+	for _, pair := range hash.Pairs {
+		key, value := pair.Key, pair.Value
+
 		literal, ok := key.(*ast.StringLiteral)
 		if !ok {
 			t.Errorf("key is not ast.StringLiteral. got=%T", key)
@@ -793,7 +817,9 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 		},
 	}
 
-	for key, value := range hash.Pairs {
+	for _, pair := range hash.Pairs {
+		key, value := pair.Key, pair.Value
+
 		literal, ok := key.(*ast.StringLiteral)
 		if !ok {
 			t.Errorf("key is not ast.StringLiteral. got=%T", key)
@@ -809,3 +835,471 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 		testFunc(value)
 	}
 }
+
+func TestHtmlChunks(t *testing.T) {
+	input := `<td>{? a ?}<td>{? b ?}</td>`
+
+	l := lexer.New(input)
+	p := New(l)
+
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 5 {
+		t.Fatalf("program.Statements does not contain 5 statements. got=%d", len(program.Statements))
+	}
+
+	tests := []struct {
+		expectedValue string
+		expectedChunk int
+	}{
+		{"<td>", 0},
+		{"<td>", 0},
+		{"</td>", 1},
+	}
+
+	for i, tt := range tests {
+		stmt := program.Statements[i*2].(*ast.ExpressionStatement)
+		html, ok := stmt.Expression.(*ast.HtmlLiteral)
+
+		if !ok {
+			t.Fatalf("exp not *ast.HtmlLiteral. got=%T", stmt.Expression)
+		}
+
+		if html.Value != tt.expectedValue {
+			t.Errorf("html.Value not %q. got=%q", tt.expectedValue, html.Value)
+		}
+
+		if html.Chunk != tt.expectedChunk {
+			t.Errorf("html.Chunk not %d. got=%d", tt.expectedChunk, html.Chunk)
+		}
+	}
+
+	if program.Chunks.Len() != 2 {
+		t.Errorf("program.Chunks has wrong length. got=%d", program.Chunks.Len())
+	}
+}
+
+func TestTryExpression(t *testing.T) {
+	input := `{? try ?}{? widget() ?}{? rescue err ?}{? err ?}{? endtry ?}`
+
+	l := lexer.New(input)
+	p := New(l)
+
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.TryStatement)
+
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.TryStatement. got=%T", stmt.Expression)
+	}
+
+	if exp.Err != "err" {
+		t.Errorf("exp.Err is not %q. got=%q", "err", exp.Err)
+	}
+
+	if exp.Block.String() != "widget()" {
+		t.Errorf("exp.Block is not %q. got=%q", "widget()", exp.Block.String())
+	}
+
+	if exp.Rescue == nil {
+		t.Fatalf("exp.Rescue is nil")
+	}
+
+	if !testIdentifier(t, exp.Rescue.Statements[0].(*ast.ExpressionStatement).Expression, "err") {
+		return
+	}
+}
+
+func TestDotExpressionChains(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{? user.Name ?}`, "user.Name"},
+		{`{? items["missing"].field ?}`, `(items["missing"]).field`},
+		{`{? config.db.host ?}`, "config.db.host"},
+		{`{? auth().user().Name ?}`, "auth().user().Name"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		exp, ok := stmt.Expression.(*ast.DotExpression)
+
+		if !ok {
+			t.Fatalf("stmt.Expression is not ast.DotExpression. got=%T", stmt.Expression)
+		}
+
+		if exp.String() != tt.expected {
+			t.Errorf("exp.String() is not %q. got=%q", tt.expected, exp.String())
+		}
+	}
+}
+
+func TestLambdaExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{? u => u.Active ?}`, "(u => u.Active)"},
+		{`{? x => x * 2 ?}`, "(x => (x * 2))"},
+		{`{? filter(users, u => u.Age > 18) ?}`, "filter(users, (u => (u.Age > 18)))"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+
+		if stmt.Expression.String() != tt.expected {
+			t.Errorf("stmt.Expression.String() is not %q. got=%q", tt.expected, stmt.Expression.String())
+		}
+	}
+}
+
+func TestSpacelessExpression(t *testing.T) {
+	input := `{? spaceless ?}{? widget() ?}{? endspaceless ?}`
+
+	l := lexer.New(input)
+	p := New(l)
+
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.SpacelessStatement)
+
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.SpacelessStatement. got=%T", stmt.Expression)
+	}
+
+	if exp.Block.String() != "widget()" {
+		t.Errorf("exp.Block is not %q. got=%q", "widget()", exp.Block.String())
+	}
+}
+
+func TestPropsExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{? props(user: User, items: []Product) ?}`, "props(user: User, items: []Product)"},
+		{`{? props(m: map[string]*models.User, title: string = "Home") ?}`, `props(m: map[string]*models.User, title: string = "Home")`},
+		{`{? props() ?}`, "props()"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		exp, ok := stmt.Expression.(*ast.PropsStatement)
+
+		if !ok {
+			t.Fatalf("stmt.Expression is not ast.PropsStatement. got=%T", stmt.Expression)
+		}
+
+		if exp.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, exp.String())
+		}
+	}
+
+	for _, input := range []string{`{? props(user) ?}`, `{? props(user: 1) ?}`, `{? props(items: [Product) ?}`} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("%s - expected parse errors", input)
+		}
+	}
+}
+
+func TestAutoescapeExpression(t *testing.T) {
+	p := New(lexer.New(`{? autoescape("xml") ?}`))
+
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.AutoescapeStatement)
+
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.AutoescapeStatement. got=%T", stmt.Expression)
+	}
+
+	if exp.Mode != "xml" {
+		t.Errorf("exp.Mode is not xml. got=%q", exp.Mode)
+	}
+
+	for _, input := range []string{`{? autoescape("yaml") ?}`, `{? autoescape(xml) ?}`, `{? autoescape("json" ?}`} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("%s - expected parse errors", input)
+		}
+	}
+}
+
+func TestComparisonOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		operator string
+	}{
+		{`{? a <= b ?}`, "<="},
+		{`{? a >= b ?}`, ">="},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+
+		if !testInfixExpression(t, stmt.Expression, "a", tt.operator, "b") {
+			return
+		}
+	}
+}
+
+func TestNotExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{? not active ?}`, "(notactive)"},
+		{`{? x in list ?}`, "(x in list)"},
+		{`{? x not in list ?}`, "(x not in list)"},
+		{`<p> {? -1 ?}`, "<p> (-1)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestPowerExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{? 2 ** 3 ** 2 ?}`, "(2 ** (3 ** 2))"},
+		{`{? -2 ** 2 ?}`, "(-(2 ** 2))"},
+		{`{? 2 * 3 ** 2 ?}`, "(2 * (3 ** 2))"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestMapLiteralBareKeys(t *testing.T) {
+	input := `{? {name: user, (key): 1,} ?}`
+
+	l := lexer.New(input)
+	p := New(l)
+
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	literal, ok := stmt.Expression.(*ast.MapLiteral)
+
+	if !ok {
+		t.Fatalf("exp is not ast.MapLiteral. got=%T", stmt.Expression)
+	}
+
+	if len(literal.Pairs) != 2 {
+		t.Fatalf("literal.Pairs has wrong length. got=%d", len(literal.Pairs))
+	}
+
+	for _, pair := range literal.Pairs {
+		key, value := pair.Key, pair.Value
+
+		switch key := key.(type) {
+		case *ast.StringLiteral:
+			if key.Value != "name" || !testIdentifier(t, value, "user") {
+				t.Errorf("wrong pair %s: %s", key, value)
+			}
+
+		case *ast.Identifier:
+			if key.Value != "key" || !testIntegerLiteral(t, value, 1) {
+				t.Errorf("wrong pair %s: %s", key, value)
+			}
+
+		default:
+			t.Errorf("key is not ast.StringLiteral or ast.Identifier. got=%T", key)
+		}
+	}
+}
+
+func TestTrailingCommas(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{? [1, 2,] ?}`, "[1, 2]"},
+		{`{? add(1, 2,) ?}`, "add(1, 2)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestMapLiteralOrder(t *testing.T) {
+	input := `{? {"z": 1, "a": 2, "m": 3} ?}`
+
+	l := lexer.New(input)
+	p := New(l)
+
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if program.String() != `{"z":1, "a":2, "m":3}` {
+		t.Errorf("the pairs are not in source order. got=%q", program.String())
+	}
+}
+
+func TestDirectiveExpression(t *testing.T) {
+	keyword, _ := token.RegisterKeyword("feature")
+	end, _ := token.RegisterKeyword("endfeature")
+
+	RegisterDirective(keyword, []token.TokenType{end}, nil)
+
+	input := `{? feature("flag", user) ?}{? widget() ?}{? endfeature ?}`
+
+	l := lexer.New(input)
+	p := New(l)
+
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.DirectiveStatement)
+
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.DirectiveStatement. got=%T", stmt.Expression)
+	}
+
+	if exp.String() != `feature("flag", user) widget() endfeature` {
+		t.Errorf("exp.String() wrong. got=%q", exp.String())
+	}
+}
+
+// fuzzSeeds are valid and broken templates to start the fuzzing from.
+var fuzzSeeds = []string{
+	`<p>{? name ?}</p>`,
+	`{? var x = [1, 2.5, "a", {"k": true}] ?}{? x[0] + x[1] * -2 ?}`,
+	`{? if a > 1 and !b ?}yes{? else ?}no{? endif ?}`,
+	`{? for i, v in items ?}{? loop.index ?}{? v.Name ?}{? endfor ?}`,
+	`{? extends("layouts.app") ?}{? section("content") ?}x{? endsection ?}`,
+	`{? define("content") ?}default{? end ?}`,
+	`{? include("partials.user", {"user": user}) ?}`,
+	`{? try ?}{? fail() ?}{? rescue err ?}{? err ?}{? endtry ?}`,
+	`{? spaceless ?} <p> a </p> {? endspaceless ?}`,
+	`{? map(items, x => x ** 2) ?}`,
+	`{? # comment # ?}{? "a" ?}`,
+	`{? if ?}`,
+	`{? for in ?}`,
+	`{? x. ?}`,
+	`{? ( ?}`,
+	`{? [1, ?}`,
+	`{? {"a": ?}`,
+	`{? "unclosed ?}`,
+	`{? section( ?}`,
+	`{? => ?}`,
+}
+
+// FuzzParseProgram checks that no input makes the lexer or the parser panic.
+func FuzzParseProgram(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		p := New(lexer.New(input))
+
+		program := p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			// the AST of a valid template can be written back
+			_ = program.String()
+		}
+	})
+}
+
+func TestSpans(t *testing.T) {
+	input := "<p>{? a == upper(b) ?}</p>\n{? if x ?}y{? endif ?}"
+
+	p := New(lexer.New(input))
+
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	source := func(node ast.Node) string {
+		return input[node.Pos().Offset:node.End().Offset]
+	}
+
+	tests := []struct {
+		node     ast.Node
+		expected string
+	}{
+		{program, input},
+		{program.Statements[1], "a == upper(b)"},
+		{program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.InfixExpression).Right, "upper(b)"},
+		{program.Statements[3], "if x ?}y{? endif"},
+		{program.Statements[3].(*ast.ExpressionStatement).Expression.(*ast.IfExpression).Consequence, "y{? "},
+	}
+
+	for i, tt := range tests {
+		if got := source(tt.node); got != tt.expected {
+			t.Errorf("tests[%d] - wrong source, expected=%q, got=%q", i, tt.expected, got)
+		}
+	}
+
+	if end := program.Statements[3].End(); end.Line != 2 || end.Col != 20 {
+		t.Errorf("wrong end of the if, got=%d:%d", end.Line, end.Col)
+	}
+}
@@ -0,0 +1,77 @@
+// Package lambprom exposes the metrics of the renders of lamb to Prometheus:
+//
+//	lamb.CollectMetrics(lambprom.NewMetrics(prometheus.DefaultRegisterer))
+//
+// It is a module of its own, so lamb does not depend on Prometheus.
+package lambprom
+
+import (
+	"time"
+
+	"github.com/govel-framework/lamb"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type metrics struct {
+	duration *prometheus.HistogramVec
+	bytes    *prometheus.HistogramVec
+	errors   *prometheus.CounterVec
+	cache    *prometheus.CounterVec
+}
+
+// NewMetrics registers the collectors of lamb in reg and returns the
+// lamb.Metrics that feed them:
+//
+//   - lamb_render_duration_seconds, the duration of the loads by template.
+//   - lamb_render_bytes, the output bytes of the loads by template.
+//   - lamb_render_errors_total, the loads that failed by template.
+//   - lamb_cache_lookups_total, the lookups in the cache by template and
+//     result (hit or miss).
+func NewMetrics(reg prometheus.Registerer) lamb.Metrics {
+	m := metrics{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "lamb_render_duration_seconds",
+			Help:    "The duration of the loads of the templates.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"template"}),
+
+		bytes: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "lamb_render_bytes",
+			Help:    "The output bytes of the loads of the templates.",
+			Buckets: prometheus.ExponentialBuckets(256, 4, 8),
+		}, []string{"template"}),
+
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "lamb_render_errors_total",
+			Help: "The loads of the templates that failed.",
+		}, []string{"template"}),
+
+		cache: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "lamb_cache_lookups_total",
+			Help: "The lookups in the cache of the rendered templates.",
+		}, []string{"template", "result"}),
+	}
+
+	reg.MustRegister(m.duration, m.bytes, m.errors, m.cache)
+
+	return m
+}
+
+func (m metrics) ObserveLoad(template string, elapsed time.Duration, bytes int, err error) {
+	m.duration.WithLabelValues(template).Observe(elapsed.Seconds())
+	m.bytes.WithLabelValues(template).Observe(float64(bytes))
+
+	if err != nil {
+		m.errors.WithLabelValues(template).Inc()
+	}
+}
+
+func (m metrics) ObserveCache(template string, hit bool) {
+	result := "miss"
+
+	if hit {
+		result = "hit"
+	}
+
+	m.cache.WithLabelValues(template, result).Inc()
+}
@@ -0,0 +1,107 @@
+package lambprom
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/govel-framework/lamb"
+	"github.com/govel-framework/lamb/internal"
+	"github.com/govel-framework/lamb/lambtest"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestMetrics checks the counters and the histograms fed by a few renders.
+func TestMetrics(t *testing.T) {
+	lambtest.Use(t, lambtest.Templates{
+		"prom.page":   `<h1>{? title ?}</h1>{? include("prom.nav") ?}`,
+		"prom.nav":    `{? repeat("-", 300) ?}`,
+		"prom.broken": `{? 1 / n ?}`,
+	})
+
+	internal.SetSettings(map[string]string{"GOVEL_LAMB_CACHE_DIR": t.TempDir()})
+
+	m := NewMetrics(prometheus.NewRegistry())
+
+	lamb.CollectMetrics(m)
+
+	t.Cleanup(func() {
+		lamb.CollectMetrics(nil)
+		internal.SetSettings(nil)
+	})
+
+	// a lookup in the empty cache that does not write it
+	if err := lamb.RenderTo(&strings.Builder{}, "prom.page", map[string]interface{}{"title": "Hi", "__cache": "read"}); err != nil {
+		t.Fatalf("RenderTo returned an error: %s", err)
+	}
+
+	if err := lamb.RenderTo(&strings.Builder{}, "prom.broken", map[string]interface{}{"n": 0}); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	collectors := m.(metrics)
+
+	tests := []struct {
+		collector prometheus.Collector
+		expected  string
+	}{
+		{collectors.cache, `
+# HELP lamb_cache_lookups_total The lookups in the cache of the rendered templates.
+# TYPE lamb_cache_lookups_total counter
+lamb_cache_lookups_total{result="miss",template="prom.page"} 1
+`},
+		{collectors.errors, `
+# HELP lamb_render_errors_total The loads of the templates that failed.
+# TYPE lamb_render_errors_total counter
+lamb_render_errors_total{template="prom.broken"} 1
+`},
+		{collectors.bytes, `
+# HELP lamb_render_bytes The output bytes of the loads of the templates.
+# TYPE lamb_render_bytes histogram
+lamb_render_bytes_bucket{template="prom.broken",le="256"} 1
+lamb_render_bytes_bucket{template="prom.broken",le="1024"} 1
+lamb_render_bytes_bucket{template="prom.broken",le="4096"} 1
+lamb_render_bytes_bucket{template="prom.broken",le="16384"} 1
+lamb_render_bytes_bucket{template="prom.broken",le="65536"} 1
+lamb_render_bytes_bucket{template="prom.broken",le="262144"} 1
+lamb_render_bytes_bucket{template="prom.broken",le="1.048576e+06"} 1
+lamb_render_bytes_bucket{template="prom.broken",le="4.194304e+06"} 1
+lamb_render_bytes_bucket{template="prom.broken",le="+Inf"} 1
+lamb_render_bytes_sum{template="prom.broken"} 0
+lamb_render_bytes_count{template="prom.broken"} 1
+lamb_render_bytes_bucket{template="prom.nav",le="256"} 0
+lamb_render_bytes_bucket{template="prom.nav",le="1024"} 1
+lamb_render_bytes_bucket{template="prom.nav",le="4096"} 1
+lamb_render_bytes_bucket{template="prom.nav",le="16384"} 1
+lamb_render_bytes_bucket{template="prom.nav",le="65536"} 1
+lamb_render_bytes_bucket{template="prom.nav",le="262144"} 1
+lamb_render_bytes_bucket{template="prom.nav",le="1.048576e+06"} 1
+lamb_render_bytes_bucket{template="prom.nav",le="4.194304e+06"} 1
+lamb_render_bytes_bucket{template="prom.nav",le="+Inf"} 1
+lamb_render_bytes_sum{template="prom.nav"} 300
+lamb_render_bytes_count{template="prom.nav"} 1
+lamb_render_bytes_bucket{template="prom.page",le="256"} 0
+lamb_render_bytes_bucket{template="prom.page",le="1024"} 1
+lamb_render_bytes_bucket{template="prom.page",le="4096"} 1
+lamb_render_bytes_bucket{template="prom.page",le="16384"} 1
+lamb_render_bytes_bucket{template="prom.page",le="65536"} 1
+lamb_render_bytes_bucket{template="prom.page",le="262144"} 1
+lamb_render_bytes_bucket{template="prom.page",le="1.048576e+06"} 1
+lamb_render_bytes_bucket{template="prom.page",le="4.194304e+06"} 1
+lamb_render_bytes_bucket{template="prom.page",le="+Inf"} 1
+lamb_render_bytes_sum{template="prom.page"} 311
+lamb_render_bytes_count{template="prom.page"} 1
+`},
+	}
+
+	for i, tt := range tests {
+		if err := testutil.CollectAndCompare(tt.collector, strings.NewReader(tt.expected)); err != nil {
+			t.Errorf("tests[%d] - wrong metrics: %s", i, err)
+		}
+	}
+
+	// the durations vary, one series by template
+	if count := testutil.CollectAndCount(collectors.duration, "lamb_render_duration_seconds"); count != 3 {
+		t.Errorf("wrong number of duration series. expected=3, got=%d", count)
+	}
+}
@@ -0,0 +1,25 @@
+package lamb
+
+import (
+	"reflect"
+
+	"github.com/govel-framework/lamb/internal"
+)
+
+// PropType registers the Go type of value as the type name of the props,
+// e.g. lamb.PropType("User", models.User{}) for props(user: User). The
+// renders check that the props have their types, and Analyze checks that the
+// template only reads the fields of the props that their types have.
+//
+// A pointer to the type is also accepted for the props, and a nil pointer to
+// an interface registers the interface: lamb.PropType("Stringer",
+// (*fmt.Stringer)(nil)).
+func PropType(name string, value interface{}) {
+	t := reflect.TypeOf(value)
+
+	if t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Interface {
+		t = t.Elem()
+	}
+
+	internal.AddPropType(name, t)
+}
@@ -0,0 +1,15 @@
+package lamb
+
+import "github.com/govel-framework/lamb/internal"
+
+// RegisterTemplate registers the source of a template in memory, so it can be
+// rendered, included and extended without a file, e.g. the default partials
+// of a library:
+//
+//	lamb.RegisterTemplate("pagination.links", `<nav>{? ... ?}</nav>`)
+//
+// A template of the loader with the same name overrides it, so the apps can
+// customize the registered templates. An empty source removes the template.
+func RegisterTemplate(name, source string) {
+	internal.RegisterTemplate(name, source)
+}
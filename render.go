@@ -1,7 +1,10 @@
 package lamb
 
 import (
-	"github.com/govel-framework/lamb/evaluator"
+	"context"
+	"errors"
+	"net/http"
+
 	"github.com/govel-framework/lamb/internal"
 	"github.com/govel-framework/lamb/object"
 
@@ -10,32 +13,113 @@ import (
 
 // Render renders a lamb template.
 func Render(c *govel.Context, file string, vars map[string]interface{}) {
+	render(c, file, "", vars)
+}
+
+// RenderSection renders only the section or the define with the given name of
+// a lamb template (e.g. the fragment of a page for an HTMX or Turbo request),
+// the rest of the template and its layout are not written. A section of a
+// template that extends a layout is rendered without the layout.
+func RenderSection(c *govel.Context, file string, section string, vars map[string]interface{}) {
+	render(c, file, section, vars)
+}
+
+// render renders the template, or its section if section is not empty.
+func render(c *govel.Context, file string, section string, vars map[string]interface{}) {
+	file = internal.ResolveRender(file, vars)
+
+	vars, span := requestVars(c.ResponseWriter, c.Request, vars)
+	defer span.End()
+
+	// load the file
+	start := c.Buf.Len()
+
+	err := loadFragment(file, section, vars, c.Buf)
+
+	span.SetAttribute("lamb.template", file)
+	span.SetAttribute("lamb.bytes", c.Buf.Len()-start)
+
+	// dd() replaces the response with its dump
+	var halt *object.HaltError
+
+	if errors.As(err, &halt) {
+		c.Buf.Write([]byte(halt.Output))
+
+		return
+	}
+
+	if err != nil {
+		span.RecordError(err)
+
+		panic(err.Error())
+	}
+
+	// keep the flashes read by the template from showing again
+	if ctx, isContext := vars["ctx"].(*RenderContext); isContext {
+		if err := ctx.saveSessions(); err != nil {
+			panic(err.Error())
+		}
+	}
+
+	// the output of a cached render can be revalidated
+	if section == "" {
+		if etag := renderETag(file, vars); etag != "" {
+			c.ResponseWriter.Header().Set("ETag", etag)
+		}
+	}
+}
+
+// requestVars adds the vars of the request to vars (ctx, the sessions and the
+// context of the render) and starts the span of the render.
+func requestVars(w http.ResponseWriter, r *http.Request, vars map[string]interface{}) (map[string]interface{}, internal.Span) {
+	if vars == nil {
+		vars = make(map[string]interface{})
+	}
+
+	// expose the info of the request
+	if _, exists := vars["ctx"]; !exists {
+		vars["ctx"] = NewRenderContext(r)
+	}
+
+	// the context of the render, the loops over channels stop when the request
+	// is cancelled and the spans of the render are children of its span
+	parent, isContext := vars["__context"].(context.Context)
+
+	if !isContext {
+		parent = r.Context()
+	}
+
+	renderContext, span := internal.StartSpan(parent, "lamb.render")
+
+	vars["__context"] = renderContext
+
 	if govel.Store != nil {
 		// get all the cookies and check if the session is valid
 		sessions := make(map[string]interface{})
 
-		for _, cookie := range c.Request.Cookies() {
-			session, err := govel.Store.Get(c.Request, cookie.Name)
+		ctx, isContext := vars["ctx"].(*RenderContext)
+
+		for _, cookie := range r.Cookies() {
+			s, err := govel.Store.Get(r, cookie.Name)
 
 			if err != nil {
 				continue // it is not a valid session
 			}
 
-			sessions[cookie.Name] = session.Values
-		}
+			sessions[cookie.Name] = s.Values
 
-		if vars == nil {
-			vars = make(map[string]interface{})
+			// the builtins read the sessions through ctx
+			if isContext {
+				save := func() error {
+					return s.Save(r, w)
+				}
+
+				ctx.sessions = append(ctx.sessions, session{name: cookie.Name, values: s.Values, save: save})
+			}
 		}
 
 		vars["sessions"] = sessions
 	}
 
-	// load the file
-	err := internal.LoadFile(file, vars, c.Buf, evaluator.Eval, *object.NewEnvironment())
-
-	if err != nil {
-		panic(err.Error())
-	}
-
+	return vars, span
 }
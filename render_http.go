@@ -0,0 +1,140 @@
+package lamb
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+
+	"github.com/govel-framework/lamb/internal"
+	"github.com/govel-framework/lamb/object"
+)
+
+// RenderHTTP renders a lamb template as the response of a request with the
+// given status (200 if it is 0), e.g. from a net/http handler without a govel
+// Context. The template is rendered before anything is written, so a failed
+// render responds with a 500 instead of half a page: the error template of
+// the config (error_template) if it is set, with the status and, in debug
+// mode, the error in its vars. The error of the render is returned, once the
+// error response is written.
+//
+// The Content-Type is text/html with the UTF-8 charset unless the handler
+// has set one. A render cached with the __cache var has the ETag of its
+// cached output, and a GET with the ETag in If-None-Match gets a 304 without
+// rendering the template. Its output is served compressed with the encoding
+// of the Accept-Encoding of the request, see RegisterEncoding.
+func RenderHTTP(w http.ResponseWriter, r *http.Request, name string, vars map[string]interface{}, status int) error {
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	// the cache of the render is the one of the template its name resolves to
+	name = internal.ResolveRender(name, vars)
+
+	// a cached render is revalidated, or served compressed, before it is
+	// rendered
+	if etag := renderETag(name, vars); etag != "" {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding, variant := cachedVariant(name, r.Header.Get("Accept-Encoding"))
+
+		if encoding != "" {
+			etag = variantETag(etag, encoding)
+		}
+
+		if notModified(r, etag) {
+			w.Header().Set("ETag", etag)
+			w.WriteHeader(http.StatusNotModified)
+
+			return nil
+		}
+
+		if encoding != "" {
+			w.Header().Set("ETag", etag)
+			w.Header().Set("Content-Encoding", encoding)
+
+			writeHTML(w, status, variant)
+
+			return nil
+		}
+	}
+
+	vars, span := requestVars(w, r, vars)
+	defer span.End()
+
+	var body bytes.Buffer
+
+	err := loadFragment(name, "", vars, &body)
+
+	span.SetAttribute("lamb.template", name)
+	span.SetAttribute("lamb.bytes", body.Len())
+
+	// dd() replaces the response with its dump
+	var halt *object.HaltError
+
+	if errors.As(err, &halt) {
+		body.Reset()
+		body.WriteString(halt.Output)
+
+		err = nil
+	}
+
+	// keep the flashes read by the template from showing again
+	if ctx, isContext := vars["ctx"].(*RenderContext); isContext && err == nil {
+		err = ctx.saveSessions()
+	}
+
+	if err != nil {
+		span.RecordError(err)
+
+		writeErrorPage(w, vars, err)
+
+		return err
+	}
+
+	if etag := renderETag(name, vars); etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+
+	writeHTML(w, status, body.Bytes())
+
+	return nil
+}
+
+// writeErrorPage responds with the error template, or with the text of the
+// status if there is none or it fails too.
+func writeErrorPage(w http.ResponseWriter, vars map[string]interface{}, err error) {
+	status := http.StatusInternalServerError
+
+	if template := internal.Setting("GOVEL_LAMB_ERROR_TEMPLATE"); template != "" {
+		errorVars := map[string]interface{}{
+			"ctx":       vars["ctx"],
+			"status":    status,
+			"__context": vars["__context"],
+			"__resolve": vars["__resolve"],
+		}
+
+		if internal.Setting("GOVEL_LAMB_DEBUG") == "true" {
+			errorVars["error"] = err.Error()
+		}
+
+		var body bytes.Buffer
+
+		if loadFragment(internal.ResolveRender(template, errorVars), "", errorVars, &body) == nil {
+			writeHTML(w, status, body.Bytes())
+
+			return
+		}
+	}
+
+	http.Error(w, http.StatusText(status), status)
+}
+
+// writeHTML writes the HTML body with the status.
+func writeHTML(w http.ResponseWriter, status int, body []byte) {
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	}
+
+	w.WriteHeader(status)
+	w.Write(body)
+}
@@ -0,0 +1,61 @@
+package lamb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/govel-framework/lamb/evaluator"
+	"github.com/govel-framework/lamb/internal"
+	"github.com/govel-framework/lamb/object"
+)
+
+// RenderTo renders a lamb template to w, outside of a request (e.g. to
+// generate a static page or an email), so ctx and sessions are not set unless
+// vars has them.
+func RenderTo(w io.Writer, file string, vars map[string]interface{}) error {
+	return RenderSectionTo(w, file, "", vars)
+}
+
+// RenderSectionTo renders only the section or the define with the given name
+// of a lamb template to w, like RenderSection outside of a request. An empty
+// section renders the whole template.
+func RenderSectionTo(w io.Writer, file string, section string, vars map[string]interface{}) error {
+	err := loadFragment(internal.ResolveRender(file, vars), section, vars, w)
+
+	// dd() replaces the output with its dump
+	var halt *object.HaltError
+
+	if errors.As(err, &halt) {
+		_, err = w.Write([]byte(halt.Output))
+	}
+
+	return err
+}
+
+// loadFragment renders the template to w, or only its section if section is not
+// empty. The name of the template is already resolved.
+func loadFragment(file string, section string, vars map[string]interface{}, w io.Writer) error {
+	if section == "" {
+		return internal.LoadFile(file, vars, w, evaluator.Eval, *object.NewEnvironment())
+	}
+
+	env := object.NewEnvironment()
+	env.Fragment = &object.Fragment{Name: section}
+
+	// the output is the whole template until the section is found
+	var out bytes.Buffer
+
+	if err := internal.LoadFile(file, vars, &out, evaluator.Eval, *env); err != nil {
+		return err
+	}
+
+	if !env.Fragment.Found {
+		return fmt.Errorf("lamb: section %s does not exist in %s", section, file)
+	}
+
+	_, err := w.Write(out.Bytes())
+
+	return err
+}
@@ -0,0 +1,23 @@
+package lamb
+
+import "github.com/govel-framework/lamb/internal"
+
+// Resolver sets the hook that resolves the names of the templates of every
+// render (the renders, the includes and the layouts), e.g. to load the
+// templates a tenant overrides:
+//
+//	lamb.Resolver(func(requested string) (string, bool) {
+//		if strings.HasPrefix(requested, "emails.") {
+//			return "tenants.acme." + requested, true
+//		}
+//
+//		return "", false
+//	})
+//
+// The requested template is loaded when ok is false. A render resolves with
+// the request data through the var "__resolve" set to a func(string) (string,
+// bool), it is kept by its includes and its layout and goes before the hook.
+// Resolver(nil) removes the hook.
+func Resolver(fn func(requested string) (resolved string, ok bool)) {
+	internal.SetResolver(fn)
+}
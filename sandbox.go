@@ -0,0 +1,23 @@
+package lamb
+
+import (
+	"github.com/govel-framework/lamb/internal"
+	"github.com/govel-framework/lamb/object"
+)
+
+// SandboxProfile registers a sandbox profile. A template rendered with the var
+// "__sandbox" set to the name of the profile (and everything it includes or
+// extends) can only call the builtins and access the struct fields allowed by
+// the profile:
+//
+//	lamb.SandboxProfile("email", object.Sandbox{
+//		Functions: []string{"len", "yesno"},
+//		Fields:    map[string][]string{"models.User": {"Name", "Email"}},
+//	})
+//
+// The directives are called like the builtins, so the profile must allow
+// them too. The builtins (e.g. json() or dump()) and the output of the
+// template only see the allowed fields: a struct is a map of them.
+func SandboxProfile(name string, sandbox object.Sandbox) {
+	internal.AddSandbox(name, sandbox)
+}
@@ -0,0 +1,10 @@
+package lamb
+
+import "github.com/govel-framework/lamb/internal"
+
+// Share makes a value available to every render (app name, build version,
+// feature flags...), including the templates loaded through include and
+// extends. Vars passed to a render take precedence over shared ones.
+func Share(name string, value interface{}) {
+	internal.Share(name, value)
+}
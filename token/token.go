@@ -1,12 +1,28 @@
 package token
 
+import "sync"
+
 type TokenType string
 
 type Token struct {
-	Type    TokenType
-	Literal string
-	Col     int
-	Line    int
+	Type    TokenType `json:"type"`
+	Literal string    `json:"literal"`
+	Col     int       `json:"col"`
+	Line    int       `json:"line"`
+	Offset  int       `json:"offset"` // The byte offset of the first character.
+	End     Position  `json:"end"`    // The position just after the last character.
+}
+
+// Position is a position in the source of a template.
+type Position struct {
+	Offset int `json:"offset"` // The byte offset, from 0.
+	Line   int `json:"line"`
+	Col    int `json:"col"`
+}
+
+// Pos returns the position of the first character of the token.
+func (t Token) Pos() Position {
+	return Position{Offset: t.Offset, Line: t.Line, Col: t.Col}
 }
 
 const (
@@ -17,6 +33,7 @@ const (
 	// Identifiers
 	IDENT  = "IDENT"
 	INT    = "INT"
+	FLOAT  = "FLOAT"
 	STRING = "STRING"
 	HTML   = "HTML"
 
@@ -26,12 +43,16 @@ const (
 	MINUS    = "-"
 	BANG     = "!"
 	ASTERISK = "*"
+	POWER    = "**"
 	SLASH    = "/"
 
 	LT     = "<"
 	GT     = ">"
+	LT_EQ  = "<="
+	GT_EQ  = ">="
 	EQ     = "=="
 	NOT_EQ = "!="
+	ARROW  = "=>"
 
 	// Delimiters
 	COMMA     = ","
@@ -49,47 +70,84 @@ const (
 	RBRACKET = "]"
 
 	// Keywords
-	VAR        = "var"
-	TRUE       = "true"
-	FALSE      = "false"
-	IF         = "if"
-	ELSE       = "else"
-	ENDIF      = "endif"
-	FOR        = "for"
-	ENDFOR     = "endfor"
-	IN         = "in"
-	EXTENDS    = "extends"
-	SECTION    = "section"
-	ENDSECTION = "endsection"
-	DEFINE     = "define"
-	END        = "end"
-	INCLUDE    = "include"
-	AND        = "and"
+	VAR          = "var"
+	TRUE         = "true"
+	FALSE        = "false"
+	IF           = "if"
+	ELSE         = "else"
+	ENDIF        = "endif"
+	FOR          = "for"
+	ENDFOR       = "endfor"
+	IN           = "in"
+	EXTENDS      = "extends"
+	SECTION      = "section"
+	ENDSECTION   = "endsection"
+	DEFINE       = "define"
+	END          = "end"
+	INCLUDE      = "include"
+	AND          = "and"
+	NOT          = "not"
+	TRY          = "try"
+	RESCUE       = "rescue"
+	ENDTRY       = "endtry"
+	SPACELESS    = "spaceless"
+	ENDSPACELESS = "endspaceless"
+	PROPS        = "props"
+	AUTOESCAPE   = "autoescape"
 )
 
 var keywords = map[string]TokenType{
-	"var":        VAR,
-	"true":       TRUE,
-	"false":      FALSE,
-	"if":         IF,
-	"else":       ELSE,
-	"endif":      ENDIF,
-	"for":        FOR,
-	"endfor":     ENDFOR,
-	"in":         IN,
-	"extends":    EXTENDS,
-	"section":    SECTION,
-	"endsection": ENDSECTION,
-	"define":     DEFINE,
-	"end":        END,
-	"include":    INCLUDE,
-	"and":        AND,
+	"var":          VAR,
+	"true":         TRUE,
+	"false":        FALSE,
+	"if":           IF,
+	"else":         ELSE,
+	"endif":        ENDIF,
+	"for":          FOR,
+	"endfor":       ENDFOR,
+	"in":           IN,
+	"extends":      EXTENDS,
+	"section":      SECTION,
+	"endsection":   ENDSECTION,
+	"define":       DEFINE,
+	"end":          END,
+	"include":      INCLUDE,
+	"and":          AND,
+	"not":          NOT,
+	"try":          TRY,
+	"rescue":       RESCUE,
+	"endtry":       ENDTRY,
+	"spaceless":    SPACELESS,
+	"endspaceless": ENDSPACELESS,
+	"props":        PROPS,
+	"autoescape":   AUTOESCAPE,
 }
 
+// keywordsMu guards keywords, the directives register theirs at run time.
+var keywordsMu sync.RWMutex
+
 func LookUpIdent(ident string) TokenType {
+	keywordsMu.RLock()
+	defer keywordsMu.RUnlock()
+
 	if tok, ok := keywords[ident]; ok {
 		return tok
 	}
 
 	return IDENT
 }
+
+// RegisterKeyword makes word a keyword, its token type is the word itself.
+// It returns false if word is already a keyword.
+func RegisterKeyword(word string) (TokenType, bool) {
+	keywordsMu.Lock()
+	defer keywordsMu.Unlock()
+
+	if _, exists := keywords[word]; exists {
+		return "", false
+	}
+
+	keywords[word] = TokenType(word)
+
+	return TokenType(word), true
+}
@@ -0,0 +1,19 @@
+package lamb
+
+import "github.com/govel-framework/lamb/internal"
+
+// Tracer starts the spans of the renders: "lamb.render" for every Render,
+// "lamb.load" for every template loaded (the rendered one, its layout and its
+// includes) and "lamb.cache" for every lookup in the cache. The spans have
+// the attributes lamb.template, lamb.bytes and, for the cache,
+// lamb.cache.hit. The package github.com/govel-framework/lamb/otel adapts an
+// OpenTelemetry tracer.
+type Tracer = internal.Tracer
+
+// Span is a span started by a Tracer.
+type Span = internal.Span
+
+// Tracing sets the tracer of every render, nil disables the tracing.
+func Tracing(t Tracer) {
+	internal.SetTracer(t)
+}
@@ -0,0 +1,28 @@
+package lamb
+
+import (
+	"github.com/govel-framework/lamb/ast"
+	"github.com/govel-framework/lamb/internal"
+)
+
+// Transform registers fn to rewrite the program of every template after it
+// is parsed and before it is evaluated, e.g. to rewrite the asset URLs or to
+// desugar a custom directive:
+//
+//	lamb.Transform(func(program *ast.Program) *ast.Program {
+//		ast.Inspect(program, func(node ast.Node) bool {
+//			if html, isHtml := node.(*ast.HtmlLiteral); isHtml {
+//				html.Value = strings.ReplaceAll(html.Value, "/assets/", cdn+"/assets/")
+//			}
+//
+//			return true
+//		})
+//
+//		return program
+//	})
+//
+// The transforms run in the order they were registered, a transform that
+// returns nil leaves the program unchanged.
+func Transform(fn func(program *ast.Program) *ast.Program) {
+	internal.AddTransform(fn)
+}
@@ -0,0 +1,28 @@
+package lamb
+
+import "github.com/govel-framework/lamb/internal"
+
+// LoadTranslations loads the message catalogs of dir for the t() builtin.
+// Each file is named after its locale and can be JSON, YAML or a gettext .po
+// file (en.json, es-MX.yaml, fr.po); nested keys are joined with dots:
+//
+//	{"checkout": {"title": "Checkout", "greeting": "Hello, :name!"}}
+//
+// gives t("checkout.title") and t("checkout.greeting", {"name": user.Name}).
+// The locale of a render is the var "__locale", the Accept-Language of the
+// request or lamb.locale, in that order.
+//
+// Plural messages are objects keyed by the CLDR plural categories of the
+// locale (zero, one, two, few, many and other) and are used with tn():
+//
+//	{"cart": {"items": {"one": "One item", "other": ":count items"}}}
+//
+// gives tn("cart.items", count).
+func LoadTranslations(dir string) error {
+	return internal.LoadTranslations(dir)
+}
+
+// AddTranslations adds messages to the catalog of a locale.
+func AddTranslations(locale string, messages map[string]string) {
+	internal.AddTranslations(locale, messages)
+}